@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+	"github.com/mywordoftheday/backend/internal/importer"
+	"github.com/mywordoftheday/backend/internal/server"
+)
+
+const importUsage = "usage: mywordoftheday import [-format csv|jsonl|anki|apkg] [-user username] [-dry-run] [-on-duplicate skip|merge|error] [-skip-invalid] <file|->"
+
+// runImport handles the import subcommand, importing the words in a file for a
+// user in a single transaction. Errors are returned rather than logged fatally
+// so the file and server are closed before exiting.
+func runImport(c server.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), importUsage)
+		flags.PrintDefaults()
+	}
+
+	var (
+		format      = flags.String("format", "", "format of the file, guessed from its extension if not given")
+		username    = flags.String("user", db.DefaultUsername, "user to import the words for")
+		dryRun      = flags.Bool("dry-run", false, "report what would be imported without changing anything")
+		onDuplicate = flags.String("on-duplicate", string(db.DuplicateSkip), "what to do with words that already exist: skip, merge or error")
+		skipInvalid = flags.Bool("skip-invalid", false, "import the valid words even if others fail")
+	)
+
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+
+	path := flags.Arg(0)
+
+	f := importer.FormatFromFilename(path)
+	if *format != "" {
+		var err error
+		if f, err = importer.ParseFormat(*format); err != nil {
+			return err
+		}
+	}
+
+	if f == "" {
+		return fmt.Errorf("unable to tell the format of %q, set it with -format", path)
+	}
+
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("unable to open file: %w", err)
+		}
+		defer file.Close()
+
+		in = file
+	}
+
+	r, err := importer.NewReader(f, in)
+	if err != nil {
+		return fmt.Errorf("unable to read file: %w", err)
+	}
+	defer r.Close()
+
+	svr, err := server.New(c)
+	if err != nil {
+		return fmt.Errorf("unable to initialise server: %w", err)
+	}
+	defer svr.Close()
+
+	ctx := context.Background()
+
+	u, err := svr.GetUser(ctx, *username)
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("unknown user %q", *username)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	rsp, err := svr.ImportFrom(identity.NewContext(ctx, identity.User{ID: u.ID, Username: u.Username}), r, server.ImportOptions{
+		DryRun:      *dryRun,
+		OnDuplicate: *onDuplicate,
+		SkipInvalid: *skipInvalid,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to import words: %w", err)
+	}
+
+	if len(rsp.Errors) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "LINE\tWORD\tERROR")
+		for _, e := range rsp.Errors {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", e.Line, e.Word, e.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("unable to write import errors: %w", err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"added":     rsp.Added,
+		"merged":    rsp.Merged,
+		"skipped":   rsp.Skipped,
+		"failed":    rsp.Failed,
+		"committed": rsp.Committed,
+	}).Info("Import finished")
+
+	if rsp.Failed > 0 && !rsp.Committed && !*dryRun {
+		return errors.New("nothing was imported as some rows failed, fix them or use -skip-invalid")
+	}
+
+	return nil
+}
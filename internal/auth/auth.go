@@ -0,0 +1,310 @@
+// Package auth authenticates callers of the gRPC server and the HTTP proxy
+// using static API keys or HMAC signed JWT bearer tokens
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Scope is a permission granted to a caller
+type Scope string
+
+const (
+	// ScopeNone is required by endpoints that anybody can call
+	ScopeNone Scope = ""
+	// ScopeRead allows words to be read
+	ScopeRead Scope = "read"
+	// ScopeWrite allows words to be read and modified
+	ScopeWrite Scope = "write"
+)
+
+// methodScopes is the scope required by each RPC. RPCs that aren't listed
+// require ScopeWrite.
+var methodScopes = map[string]Scope{
+	fullMethod("Heartbeat"):  ScopeNone,
+	fullMethod("ListWords"):  ScopeRead,
+	fullMethod("RandomWord"): ScopeRead,
+	fullMethod("AddWord"):    ScopeWrite,
+	fullMethod("DeleteWord"): ScopeWrite,
+
+	// Health checks are made by load balancers and orchestrators without credentials
+	"/grpc.health.v1.Health/Check": ScopeNone,
+	"/grpc.health.v1.Health/Watch": ScopeNone,
+}
+
+// serviceName is the fully qualified name of the MyWordOfTheDayService
+const serviceName = "mywordoftheday.v1alpha1.MyWordOfTheDayService"
+
+func fullMethod(name string) string {
+	return fmt.Sprintf("/%s/%s", serviceName, name)
+}
+
+// MethodScope returns the scope required to call the RPC with the given full method name
+func MethodScope(fullMethod string) Scope {
+	if s, ok := methodScopes[fullMethod]; ok {
+		return s
+	}
+
+	return ScopeWrite
+}
+
+// Principal is an authenticated caller
+type Principal struct {
+	Username string
+	Scopes   []Scope
+}
+
+// HasScope reports whether the principal has been granted s. ScopeWrite implies ScopeRead.
+func (p Principal) HasScope(s Scope) bool {
+	if s == ScopeNone {
+		return true
+	}
+
+	for _, granted := range p.Scopes {
+		if granted == s || (granted == ScopeWrite && s == ScopeRead) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the Principal carried by ctx, if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
+
+// APIKey is a static key granting the named user the given scopes
+type APIKey struct {
+	Key      string  `mapstructure:"key"`
+	Username string  `mapstructure:"username"`
+	Scopes   []Scope `mapstructure:"scopes"`
+}
+
+type Config struct {
+	// Enabled turns on authentication. When disabled every request is allowed.
+	Enabled bool
+
+	APIKeys []APIKey
+
+	// JWTSecret is the HMAC secret bearer tokens are signed with. JWTs are
+	// not accepted when it is empty.
+	JWTSecret string
+	// JWTIssuer, if set, must match the iss claim of bearer tokens
+	JWTIssuer string
+	// JWTAudience, if set, must be one of the aud claims of bearer tokens
+	JWTAudience string
+}
+
+// Authenticator validates the credentials presented by callers
+type Authenticator struct {
+	enabled bool
+	apiKeys []APIKey
+
+	jwtSecret   []byte
+	jwtIssuer   string
+	jwtAudience string
+}
+
+// jwtClaims are the claims read from bearer tokens. The subject is the
+// username and scope is a space separated list of scopes.
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope string `json:"scope"`
+}
+
+var errUnauthenticated = status.Error(codes.Unauthenticated, "invalid credentials")
+
+func New(c Config) (*Authenticator, error) {
+	if !c.Enabled {
+		return &Authenticator{}, nil
+	}
+
+	if len(c.APIKeys) == 0 && c.JWTSecret == "" {
+		return nil, errors.New("authentication enabled but no api keys or jwt secret defined")
+	}
+
+	for i, k := range c.APIKeys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("api key %d has no key", i)
+		}
+
+		if k.Username == "" {
+			return nil, fmt.Errorf("api key %d has no username", i)
+		}
+
+		for _, s := range k.Scopes {
+			if s != ScopeRead && s != ScopeWrite {
+				return nil, fmt.Errorf("api key %d has unknown scope %q", i, s)
+			}
+		}
+	}
+
+	return &Authenticator{
+		enabled: true,
+		apiKeys: c.APIKeys,
+
+		jwtSecret:   []byte(c.JWTSecret),
+		jwtIssuer:   c.JWTIssuer,
+		jwtAudience: c.JWTAudience,
+	}, nil
+}
+
+// Enabled reports whether requests must be authenticated
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.enabled
+}
+
+// Authenticate returns the Principal identified by token, which is either an
+// API key or a signed JWT
+func (a *Authenticator) Authenticate(token string) (Principal, error) {
+	if token == "" {
+		return Principal{}, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	for _, k := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(k.Key), []byte(token)) == 1 {
+			return Principal{Username: k.Username, Scopes: k.Scopes}, nil
+		}
+	}
+
+	if len(a.jwtSecret) == 0 {
+		return Principal{}, errUnauthenticated
+	}
+
+	claims := &jwtClaims{}
+
+	_, err := jwt.NewParser(jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"})).ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.jwtSecret, nil
+	})
+	if err != nil {
+		return Principal{}, errUnauthenticated
+	}
+
+	// Tokens without an expiry would be valid forever
+	if claims.Subject == "" || claims.ExpiresAt == nil {
+		return Principal{}, errUnauthenticated
+	}
+
+	if a.jwtIssuer != "" && !claims.VerifyIssuer(a.jwtIssuer, true) {
+		return Principal{}, errUnauthenticated
+	}
+
+	if a.jwtAudience != "" && !claims.VerifyAudience(a.jwtAudience, true) {
+		return Principal{}, errUnauthenticated
+	}
+
+	p := Principal{Username: claims.Subject}
+	for _, s := range strings.Fields(claims.Scope) {
+		p.Scopes = append(p.Scopes, Scope(s))
+	}
+
+	return p, nil
+}
+
+// authorize authenticates the bearer token in the authorization value and
+// checks it has been granted scope
+func (a *Authenticator) authorize(authorization string, scope Scope) (Principal, error) {
+	token := authorization
+	if len(token) > len("bearer ") && strings.EqualFold(token[:len("bearer ")], "bearer ") {
+		token = strings.TrimSpace(token[len("bearer "):])
+	}
+
+	p, err := a.Authenticate(token)
+	if err != nil {
+		return p, err
+	}
+
+	if !p.HasScope(scope) {
+		return p, status.Errorf(codes.PermissionDenied, "%s scope required", scope)
+	}
+
+	return p, nil
+}
+
+// UnaryServerInterceptor rejects requests that don't carry credentials granting
+// the scope required by the RPC, and adds the Principal to the request context
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		scope := MethodScope(info.FullMethod)
+		if !a.Enabled() || scope == ScopeNone {
+			return handler(ctx, req)
+		}
+
+		p, err := a.authorize(incomingAuthorization(ctx), scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(NewContext(ctx, p), req)
+	}
+}
+
+// StreamServerInterceptor is the streaming counterpart of
+// UnaryServerInterceptor, so streaming RPCs such as health Watch and
+// reflection are held to their scopes too
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		scope := MethodScope(info.FullMethod)
+		if !a.Enabled() || scope == ScopeNone {
+			return handler(srv, ss)
+		}
+
+		p, err := a.authorize(incomingAuthorization(ss.Context()), scope)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &principalStream{ServerStream: ss, ctx: NewContext(ss.Context(), p)})
+	}
+}
+
+// principalStream is a server stream whose context carries the Principal
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
+
+// incomingAuthorization returns the authorization metadata of the request, if any
+func incomingAuthorization(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("authorization"); len(v) > 0 {
+			return v[0]
+		}
+	}
+
+	return ""
+}
+
+// AuthenticateHTTP checks the Authorization header of r grants scope. If
+// authentication is disabled an empty Principal is returned.
+func (a *Authenticator) AuthenticateHTTP(r *http.Request, scope Scope) (Principal, error) {
+	if !a.Enabled() || scope == ScopeNone {
+		return Principal{}, nil
+	}
+
+	return a.authorize(r.Header.Get("Authorization"), scope)
+}
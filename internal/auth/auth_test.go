@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const testSecret = "supersecret"
+
+func signToken(t *testing.T, method jwt.SigningMethod, secret string, claims jwtClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+
+	return token
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		conf        Config
+		expectedErr string
+	}{
+		{
+			desc: "Disabled config should not return error",
+			conf: Config{},
+		},
+		{
+			desc:        "Enabled config without credentials should return error",
+			conf:        Config{Enabled: true},
+			expectedErr: "authentication enabled but no api keys or jwt secret defined",
+		},
+		{
+			desc:        "API key without a key should return error",
+			conf:        Config{Enabled: true, APIKeys: []APIKey{{Username: "simon"}}},
+			expectedErr: "api key 0 has no key",
+		},
+		{
+			desc:        "API key without a username should return error",
+			conf:        Config{Enabled: true, APIKeys: []APIKey{{Key: "key"}}},
+			expectedErr: "api key 0 has no username",
+		},
+		{
+			desc:        "API key with an unknown scope should return error",
+			conf:        Config{Enabled: true, APIKeys: []APIKey{{Key: "key", Username: "simon", Scopes: []Scope{"admin"}}}},
+			expectedErr: `api key 0 has unknown scope "admin"`,
+		},
+		{
+			desc: "Valid config should not return error",
+			conf: Config{Enabled: true, APIKeys: []APIKey{{Key: "key", Username: "simon", Scopes: []Scope{ScopeRead}}}, JWTSecret: testSecret},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			a, err := New(tC.conf)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.conf.Enabled, a.Enabled())
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := New(Config{
+		Enabled:     true,
+		APIKeys:     []APIKey{{Key: "read-key", Username: "simon", Scopes: []Scope{ScopeRead}}},
+		JWTSecret:   testSecret,
+		JWTIssuer:   "mywordoftheday",
+		JWTAudience: "backend",
+	})
+	assert.NoError(t, err)
+
+	valid := jwtClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "alex",
+			Issuer:    "mywordoftheday",
+			Audience:  jwt.ClaimStrings{"backend"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Scope: "read write",
+	}
+
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "someone-else"
+
+	wrongAudience := valid
+	wrongAudience.Audience = jwt.ClaimStrings{"frontend"}
+
+	noSubject := valid
+	noSubject.Subject = ""
+
+	noExpiry := valid
+	noExpiry.ExpiresAt = nil
+
+	testCases := []struct {
+		desc     string
+		token    string
+		expected Principal
+		code     codes.Code
+	}{
+		{
+			desc:     "API key should be accepted",
+			token:    "read-key",
+			expected: Principal{Username: "simon", Scopes: []Scope{ScopeRead}},
+		},
+		{
+			desc:     "Valid JWT should be accepted",
+			token:    signToken(t, jwt.SigningMethodHS256, testSecret, valid),
+			expected: Principal{Username: "alex", Scopes: []Scope{ScopeRead, ScopeWrite}},
+		},
+		{desc: "Missing token should be rejected", token: "", code: codes.Unauthenticated},
+		{desc: "Unknown API key should be rejected", token: "unknown-key", code: codes.Unauthenticated},
+		{desc: "JWT signed with another secret should be rejected", token: signToken(t, jwt.SigningMethodHS256, "another", valid), code: codes.Unauthenticated},
+		{desc: "JWT signed with the none algorithm should be rejected", token: func() string {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			assert.NoError(t, err)
+			return token
+		}(), code: codes.Unauthenticated},
+		{desc: "Expired JWT should be rejected", token: signToken(t, jwt.SigningMethodHS256, testSecret, expired), code: codes.Unauthenticated},
+		{desc: "JWT with the wrong issuer should be rejected", token: signToken(t, jwt.SigningMethodHS256, testSecret, wrongIssuer), code: codes.Unauthenticated},
+		{desc: "JWT with the wrong audience should be rejected", token: signToken(t, jwt.SigningMethodHS256, testSecret, wrongAudience), code: codes.Unauthenticated},
+		{desc: "JWT without a subject should be rejected", token: signToken(t, jwt.SigningMethodHS256, testSecret, noSubject), code: codes.Unauthenticated},
+		{desc: "JWT without an expiry should be rejected", token: signToken(t, jwt.SigningMethodHS256, testSecret, noExpiry), code: codes.Unauthenticated},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := a.Authenticate(tC.token)
+			if tC.code != codes.OK {
+				assert.Equal(t, tC.code, status.Code(err))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, p)
+		})
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	reader := Principal{Scopes: []Scope{ScopeRead}}
+	writer := Principal{Scopes: []Scope{ScopeWrite}}
+
+	assert.True(t, reader.HasScope(ScopeNone))
+	assert.True(t, reader.HasScope(ScopeRead))
+	assert.False(t, reader.HasScope(ScopeWrite))
+
+	assert.True(t, writer.HasScope(ScopeRead))
+	assert.True(t, writer.HasScope(ScopeWrite))
+
+	assert.False(t, Principal{}.HasScope(ScopeRead))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	a, err := New(Config{
+		Enabled: true,
+		APIKeys: []APIKey{
+			{Key: "read-key", Username: "simon", Scopes: []Scope{ScopeRead}},
+			{Key: "write-key", Username: "alex", Scopes: []Scope{ScopeWrite}},
+		},
+	})
+	assert.NoError(t, err)
+
+	interceptor := a.UnaryServerInterceptor()
+
+	var got Principal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = FromContext(ctx)
+		return nil, nil
+	}
+
+	call := func(method string, authorization string) error {
+		got = Principal{}
+
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+		}
+
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod(method)}, handler)
+		return err
+	}
+
+	t.Run("Given authentication is enabled", func(t *testing.T) {
+		t.Run("When a public RPC is called without credentials", func(t *testing.T) {
+			t.Run("Then the request is allowed", func(t *testing.T) {
+				assert.NoError(t, call("Heartbeat", ""))
+			})
+		})
+		t.Run("When the health check is called without credentials", func(t *testing.T) {
+			t.Run("Then the request is allowed", func(t *testing.T) {
+				_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler)
+				assert.NoError(t, err)
+			})
+		})
+		t.Run("When a read RPC is called without credentials", func(t *testing.T) {
+			t.Run("Then an Unauthenticated error is returned", func(t *testing.T) {
+				assert.Equal(t, codes.Unauthenticated, status.Code(call("ListWords", "")))
+			})
+		})
+		t.Run("When a read RPC is called with a read scoped key", func(t *testing.T) {
+			t.Run("Then the request is allowed and the principal is added to the context", func(t *testing.T) {
+				assert.NoError(t, call("ListWords", "Bearer read-key"))
+				assert.Equal(t, "simon", got.Username)
+			})
+		})
+		t.Run("When a write RPC is called with a read scoped key", func(t *testing.T) {
+			t.Run("Then a PermissionDenied error is returned", func(t *testing.T) {
+				assert.Equal(t, codes.PermissionDenied, status.Code(call("AddWord", "Bearer read-key")))
+			})
+		})
+		t.Run("When a write RPC is called with a write scoped key", func(t *testing.T) {
+			t.Run("Then the request is allowed", func(t *testing.T) {
+				assert.NoError(t, call("DeleteWord", "bearer write-key"))
+				assert.Equal(t, "alex", got.Username)
+			})
+		})
+		t.Run("When an unknown RPC is called with a read scoped key", func(t *testing.T) {
+			t.Run("Then a PermissionDenied error is returned", func(t *testing.T) {
+				assert.Equal(t, codes.PermissionDenied, status.Code(call("SomethingNew", "Bearer read-key")))
+			})
+		})
+	})
+
+	t.Run("Given authentication is disabled", func(t *testing.T) {
+		disabled, err := New(Config{})
+		assert.NoError(t, err)
+
+		t.Run("When an RPC is called without credentials", func(t *testing.T) {
+			t.Run("Then the request is allowed", func(t *testing.T) {
+				_, err := disabled.UnaryServerInterceptor()(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: fullMethod("AddWord")}, handler)
+				assert.NoError(t, err)
+			})
+		})
+	})
+}
+
+// serverStream is a grpc.ServerStream carrying ctx
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	a, err := New(Config{
+		Enabled: true,
+		APIKeys: []APIKey{
+			{Key: "read-key", Username: "simon", Scopes: []Scope{ScopeRead}},
+			{Key: "write-key", Username: "alex", Scopes: []Scope{ScopeWrite}},
+		},
+	})
+	assert.NoError(t, err)
+
+	interceptor := a.StreamServerInterceptor()
+
+	var got Principal
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		got, _ = FromContext(ss.Context())
+		return nil
+	}
+
+	call := func(method string, authorization string) error {
+		got = Principal{}
+
+		ctx := context.Background()
+		if authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+		}
+
+		return interceptor(nil, serverStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, handler)
+	}
+
+	t.Run("Given authentication is enabled", func(t *testing.T) {
+		t.Run("When the health check is watched without credentials", func(t *testing.T) {
+			t.Run("Then the stream is allowed", func(t *testing.T) {
+				assert.NoError(t, call("/grpc.health.v1.Health/Watch", ""))
+			})
+		})
+		t.Run("When reflection is used without credentials", func(t *testing.T) {
+			t.Run("Then an Unauthenticated error is returned", func(t *testing.T) {
+				assert.Equal(t, codes.Unauthenticated, status.Code(call("/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "")))
+			})
+		})
+		t.Run("When reflection is used with a read scoped key", func(t *testing.T) {
+			t.Run("Then a PermissionDenied error is returned", func(t *testing.T) {
+				assert.Equal(t, codes.PermissionDenied, status.Code(call("/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "Bearer read-key")))
+			})
+		})
+		t.Run("When reflection is used with a write scoped key", func(t *testing.T) {
+			t.Run("Then the stream is allowed and the principal is added to its context", func(t *testing.T) {
+				assert.NoError(t, call("/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", "Bearer write-key"))
+				assert.Equal(t, "alex", got.Username)
+			})
+		})
+	})
+}
+
+func TestAuthenticateHTTP(t *testing.T) {
+	a, err := New(Config{
+		Enabled: true,
+		APIKeys: []APIKey{{Key: "read-key", Username: "simon", Scopes: []Scope{ScopeRead}}},
+	})
+	assert.NoError(t, err)
+
+	t.Run("Given a HTTP request with a read scoped key", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", "Bearer read-key")
+
+		t.Run("When read scope is required", func(t *testing.T) {
+			t.Run("Then the principal is returned", func(t *testing.T) {
+				p, err := a.AuthenticateHTTP(r, ScopeRead)
+				assert.NoError(t, err)
+				assert.Equal(t, "simon", p.Username)
+			})
+		})
+		t.Run("When write scope is required", func(t *testing.T) {
+			t.Run("Then a PermissionDenied error is returned", func(t *testing.T) {
+				_, err := a.AuthenticateHTTP(r, ScopeWrite)
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Channel is somewhere other than their inbox that a user is sent their word
+// of the day
+type Channel struct {
+	ID     int32
+	UserID int32
+	// URL is the kind of channel and how to reach it, as understood by
+	// notifier.New
+	URL       string
+	CreatedAt time.Time
+}
+
+const channelColumns = "id, user_id, url, created_at"
+
+func scanChannel(row pgx.Row) (Channel, error) {
+	c := Channel{}
+
+	err := row.Scan(&c.ID, &c.UserID, &c.URL, &c.CreatedAt)
+
+	return c, err
+}
+
+// InsertChannel adds a channel the user is sent their word of the day to
+func (m *Manager) InsertChannel(ctx context.Context, userID int32, url string) (Channel, error) {
+	c, err := scanChannel(m.pool.QueryRow(
+		ctx,
+		"INSERT INTO channels(user_id, url) VALUES($1, $2) RETURNING "+channelColumns,
+		userID, url,
+	))
+	if err != nil {
+		return c, errors.Wrap(err, "unable to insert channel")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     c.ID,
+		"userID": c.UserID,
+	}).Info("Channel inserted successfully")
+
+	return c, nil
+}
+
+// DeleteChannel deletes the user's channel with the id, or returns ErrNotFound
+// if they don't have one
+func (m *Manager) DeleteChannel(ctx context.Context, userID int32, id int32) (Channel, error) {
+	c, err := scanChannel(m.pool.QueryRow(
+		ctx,
+		"DELETE FROM channels WHERE user_id=$1 AND id=$2 RETURNING "+channelColumns,
+		userID, id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c, ErrNotFound
+	}
+
+	if err != nil {
+		return c, errors.Wrap(err, "unable to delete channel")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     c.ID,
+		"userID": c.UserID,
+	}).Info("Channel deleted successfully")
+
+	return c, nil
+}
+
+// ListChannels returns the user's channels in the order they were added
+func (m *Manager) ListChannels(ctx context.Context, userID int32) ([]Channel, error) {
+	channels := []Channel{}
+
+	rows, err := m.pool.Query(ctx, "SELECT "+channelColumns+" FROM channels WHERE user_id=$1 ORDER BY id", userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list channels")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		c, err := scanChannel(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		channels = append(channels, c)
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return channels, nil
+}
@@ -3,7 +3,9 @@ package db
 import (
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -11,8 +13,31 @@ import (
 
 type Word struct {
 	ID               int32
+	UserID           int32
 	Word             string
 	CustomDefinition string
+
+	// Spaced-repetition scheduling state, see the srs package
+	EaseFactor   float64
+	IntervalDays int32
+	Repetitions  int32
+	DueAt        time.Time
+
+	// Version is incremented every time the word is updated
+	Version   int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// wordColumns are the columns scanned by scanWord, in order
+const wordColumns = "id, user_id, word, custom_definition, ease_factor, interval_days, repetitions, due_at, version, created_at, updated_at"
+
+func scanWord(row pgx.Row) (Word, error) {
+	w := Word{}
+
+	err := row.Scan(&w.ID, &w.UserID, &w.Word, &w.CustomDefinition, &w.EaseFactor, &w.IntervalDays, &w.Repetitions, &w.DueAt, &w.Version, &w.CreatedAt, &w.UpdatedAt)
+
+	return w, err
 }
 
 type Config struct {
@@ -21,10 +46,13 @@ type Config struct {
 	Username string
 	Password string
 	Database string
+
+	// MigrateOnStartup applies any pending migrations when the Manager is created
+	MigrateOnStartup bool
 }
 
 type Manager struct {
-	pool *pgxpool.Pool
+	pool *tracedPool
 }
 
 func New(c Config) (*Manager, error) {
@@ -55,76 +83,106 @@ func New(c Config) (*Manager, error) {
 		return nil, fmt.Errorf("error creating connection pool: %w", err)
 	}
 
-	return &Manager{pool: pool}, nil
+	m := &Manager{pool: &tracedPool{Pool: pool}}
+
+	if c.MigrateOnStartup {
+		if _, err := m.MigrateUp(context.Background()); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("error applying migrations: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// Close closes every connection in the pool
+func (m *Manager) Close() {
+	m.pool.Close()
 }
 
 func (m *Manager) Ping(ctx context.Context) error {
 	return m.pool.Ping(ctx)
 }
 
+// InsertWord inserts the word for the user in word.UserID. If the user already
+// has a word with the same normalised spelling a *DuplicateWordError is returned.
 func (m *Manager) InsertWord(ctx context.Context, word Word) (Word, error) {
-	w := Word{}
-
-	err := m.pool.QueryRow(
+	w, err := scanWord(m.pool.QueryRow(
 		ctx,
-		"INSERT INTO words(word, custom_definition) VALUES($1, $2) RETURNING id, word, custom_definition",
-		word.Word, word.CustomDefinition,
-	).Scan(&w.ID, &w.Word, &w.CustomDefinition)
+		`INSERT INTO words(user_id, word, normalised_word, custom_definition) VALUES($1, $2, $3, $4)
+ON CONFLICT (user_id, normalised_word) DO NOTHING
+RETURNING `+wordColumns,
+		word.UserID, cleanWord(word.Word), NormaliseWord(word.Word), word.CustomDefinition,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, m.duplicateWordError(ctx, word.UserID, word.Word)
+	}
+
 	if err != nil {
 		return w, errors.Wrap(err, "unable to insert word")
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"id": w.ID,
+		"id":     w.ID,
+		"userID": w.UserID,
 	}).Info("Word inserted successfully")
 
 	return w, nil
 }
 
-func (m *Manager) ListWords(ctx context.Context) ([]Word, error) {
-	words := make([]Word, 0)
+// GetWord returns the word with the given id, provided it belongs to the user,
+// or ErrNotFound
+func (m *Manager) GetWord(ctx context.Context, userID int32, id int32) (Word, error) {
+	w, err := scanWord(m.pool.QueryRow(ctx, "SELECT "+wordColumns+" FROM words WHERE id=$1 AND user_id=$2", id, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, ErrNotFound
+	}
 
-	rows, err := m.pool.Query(ctx, "SELECT id, word, custom_definition FROM words")
 	if err != nil {
-		return words, errors.Wrap(err, "unable to get words")
+		return w, errors.Wrap(err, "unable to get word")
 	}
 
-	rowCount := 0
-	for rows.Next() {
-		w := Word{}
-
-		if err := rows.Scan(&w.ID, &w.Word, &w.CustomDefinition); err != nil {
-			return nil, errors.Wrap(err, "unable to scan row")
-		}
-
-		words = append(words, w)
+	return w, nil
+}
 
-		rowCount++
+// FindWord returns the user's word with the same normalised spelling, so case
+// and surrounding whitespace are ignored, or ErrNotFound
+func (m *Manager) FindWord(ctx context.Context, userID int32, spelling string) (Word, error) {
+	w, err := scanWord(m.pool.QueryRow(
+		ctx,
+		"SELECT "+wordColumns+" FROM words WHERE user_id=$1 AND normalised_word=$2",
+		userID, NormaliseWord(spelling),
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, ErrNotFound
 	}
 
-	if rows.Err() != nil {
-		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	if err != nil {
+		return w, errors.Wrap(err, "unable to find word")
 	}
 
-	logrus.WithFields(logrus.Fields{"rowCount": rowCount}).Info("Words queried successfully")
-
-	return words, nil
+	return w, nil
 }
 
-func (m *Manager) DeleteWord(ctx context.Context, id int32) (Word, error) {
-	w := Word{}
-
-	err := m.pool.QueryRow(
+// DeleteWord deletes the word with the given id, provided it belongs to the
+// user, returning ErrNotFound if it doesn't exist
+func (m *Manager) DeleteWord(ctx context.Context, userID int32, id int32) (Word, error) {
+	w, err := scanWord(m.pool.QueryRow(
 		ctx,
-		"DELETE FROM words WHERE id=$1 RETURNING id, word, custom_definition",
-		id,
-	).Scan(&w.ID, &w.Word, &w.CustomDefinition)
+		"DELETE FROM words WHERE id=$1 AND user_id=$2 RETURNING "+wordColumns,
+		id, userID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, ErrNotFound
+	}
+
 	if err != nil {
 		return w, errors.Wrap(err, "unable to delete word")
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"id": w.ID,
+		"id":     w.ID,
+		"userID": w.UserID,
 	}).Info("Word deleted successfully")
 
 	return w, nil
@@ -6,7 +6,9 @@ package db_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"testing"
@@ -14,12 +16,23 @@ import (
 
 	_ "github.com/lib/pq"
 	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/srs"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var mgr *db.Manager
+var (
+	mgr *db.Manager
+
+	// conn is used to seed data that would be slow to insert through mgr
+	conn *sql.DB
+)
 
 func TestMain(m *testing.M) {
 	// uses a sensible default on windows (tcp/http) and linux/osx (socket)
@@ -56,8 +69,6 @@ func TestMain(m *testing.M) {
 
 	resource.Expire(120) // Tell docker to hard kill the container in 120 seconds
 
-	var conn *sql.DB
-
 	// exponential backoff-retry, because the application in the container might not be ready to accept connections yet
 	pool.MaxWait = 120 * time.Second
 	if err = pool.Retry(func() error {
@@ -70,16 +81,14 @@ func TestMain(m *testing.M) {
 		log.Fatalf("Could not connect to docker: %s", err)
 	}
 
-	if err := createTables(conn); err != nil {
-		log.Fatalf("Could not create tables: %s", err)
-	}
-
 	mgr, err = db.New(db.Config{
 		Host:     "localhost",
 		Port:     resource.GetPort("5432/tcp"),
 		Username: "username",
 		Password: "secret",
 		Database: "dbname",
+
+		MigrateOnStartup: true,
 	})
 	if err != nil {
 		log.Fatalf("Could not create new db instance: %s", err)
@@ -96,19 +105,69 @@ func TestMain(m *testing.M) {
 	os.Exit(code)
 }
 
-func createTables(conn *sql.DB) error {
-	// Words Table
-	query := `CREATE TABLE IF NOT EXISTS "words" (
-  "id" SERIAL PRIMARY KEY NOT NULL,
-  "word" VARCHAR(255) DEFAULT '',
-  "custom_definition" VARCHAR(255) DEFAULT ''
-	);`
+func TestMigrations(t *testing.T) {
+	t.Run("Given a db manager with migrations applied on startup", func(t *testing.T) {
+		t.Run("When MigrationStatus is called", func(t *testing.T) {
+			t.Run("Then every migration is reported as applied", func(t *testing.T) {
+				status, err := mgr.MigrationStatus(context.Background())
+				assert.NoError(t, err)
+				assert.NotEmpty(t, status)
 
-	if _, err := conn.Exec(query); err != nil {
-		return err
-	}
+				for _, s := range status {
+					assert.True(t, s.Applied, "migration %d_%s should be applied", s.Version, s.Name)
+				}
+			})
+		})
+
+		t.Run("When MigrationStatus is called while migrations are being applied", func(t *testing.T) {
+			t.Run("Then it doesn't wait for them", func(t *testing.T) {
+				ctx := context.Background()
+
+				lockConn, err := conn.Conn(ctx)
+				assert.NoError(t, err)
+				defer lockConn.Close()
+
+				_, err = lockConn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", 7_283_920_104)
+				assert.NoError(t, err)
+				defer func() {
+					_, _ = lockConn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", 7_283_920_104)
+				}()
+
+				statusCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+				defer cancel()
 
-	return nil
+				_, err = mgr.MigrationStatus(statusCtx)
+				assert.NoError(t, err)
+			})
+		})
+
+		t.Run("When MigrateUp is called again", func(t *testing.T) {
+			t.Run("Then no migrations are applied", func(t *testing.T) {
+				applied, err := mgr.MigrateUp(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, 0, applied)
+			})
+		})
+
+		t.Run("When MigrateDown and MigrateUp are called", func(t *testing.T) {
+			t.Run("Then the latest migration is reverted and re-applied", func(t *testing.T) {
+				before, err := mgr.SchemaVersion(context.Background())
+				assert.NoError(t, err)
+
+				reverted, err := mgr.MigrateDown(context.Background(), 1)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, reverted)
+
+				after, err := mgr.SchemaVersion(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, before-1, after)
+
+				applied, err := mgr.MigrateUp(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, 1, applied)
+			})
+		})
+	})
 }
 
 func TestPing(t *testing.T) {
@@ -126,7 +185,7 @@ func TestWords(t *testing.T) {
 		var inserted db.Word
 		var err error
 
-		word := db.Word{Word: "floccinaucinihilipilification"}
+		word := db.Word{UserID: db.DefaultUserID, Word: "floccinaucinihilipilification"}
 
 		t.Run("When it is passed to InsertWord", func(t *testing.T) {
 			t.Run("Then it should create the record without error", func(t *testing.T) {
@@ -141,7 +200,7 @@ func TestWords(t *testing.T) {
 
 		t.Run("When ListWord is called", func(t *testing.T) {
 			t.Run("Then the inserted Word should exist", func(t *testing.T) {
-				w, err := mgr.ListWords(context.Background())
+				w, err := mgr.ListWords(context.Background(), db.DefaultUserID, db.ListWordsOptions{})
 				assert.NoError(t, err)
 
 				assert.Len(t, w, 1)
@@ -152,9 +211,35 @@ func TestWords(t *testing.T) {
 			})
 		})
 
+		t.Run("When GetWord is called", func(t *testing.T) {
+			t.Run("Then the inserted Word is returned", func(t *testing.T) {
+				w, err := mgr.GetWord(context.Background(), db.DefaultUserID, inserted.ID)
+				assert.NoError(t, err)
+
+				assert.Equal(t, inserted.ID, w.ID)
+				assert.Equal(t, word.Word, w.Word)
+			})
+		})
+
+		t.Run("When FindWord is called with a different case and whitespace", func(t *testing.T) {
+			t.Run("Then the inserted Word is returned", func(t *testing.T) {
+				w, err := mgr.FindWord(context.Background(), db.DefaultUserID, "  Floccinaucinihilipilification ")
+				assert.NoError(t, err)
+
+				assert.Equal(t, inserted.ID, w.ID)
+			})
+		})
+
+		t.Run("When FindWord is called with an unknown spelling", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.FindWord(context.Background(), db.DefaultUserID, "unknown")
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
 		t.Run("When DeleteWord is called", func(t *testing.T) {
 			t.Run("Then the Word is deleted", func(t *testing.T) {
-				f, err := mgr.DeleteWord(context.Background(), inserted.ID)
+				f, err := mgr.DeleteWord(context.Background(), db.DefaultUserID, inserted.ID)
 				assert.NoError(t, err)
 				assert.NotNil(t, f)
 
@@ -163,11 +248,1302 @@ func TestWords(t *testing.T) {
 				assert.Equal(t, word.CustomDefinition, f.CustomDefinition)
 
 				// Make sure the word doesn't exist
-				lf, err := mgr.ListWords(context.Background())
+				lf, err := mgr.ListWords(context.Background(), db.DefaultUserID, db.ListWordsOptions{})
 				assert.NoError(t, err)
 
 				assert.Len(t, lf, 0)
 			})
 		})
+
+		t.Run("When GetWord and DeleteWord are called for the deleted Word", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.GetWord(context.Background(), db.DefaultUserID, inserted.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				_, err = mgr.DeleteWord(context.Background(), db.DefaultUserID, inserted.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestRotation(t *testing.T) {
+	t.Run("Given a set of words", func(t *testing.T) {
+		ctx := context.Background()
+
+		ids := make(map[int32]bool)
+		for _, word := range []string{"sesquipedalian", "defenestration", "petrichor"} {
+			w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: word})
+			assert.NoError(t, err)
+
+			ids[w.ID] = true
+		}
+
+		defer func() {
+			for id := range ids {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+		}()
+
+		t.Run("When NextWord is called in shuffle-cycle mode", func(t *testing.T) {
+			t.Run("Then every word is delivered once before any repeats", func(t *testing.T) {
+				seen := make(map[int32]bool)
+				for i := 0; i < len(ids); i++ {
+					w, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationShuffleCycle, "")
+					assert.NoError(t, err)
+					assert.True(t, ids[w.ID])
+					assert.False(t, seen[w.ID], "word %d delivered twice in one cycle", w.ID)
+
+					seen[w.ID] = true
+					assert.NoError(t, mgr.RecordDelivery(ctx, db.DefaultUserID, w.ID))
+				}
+
+				w, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationShuffleCycle, "")
+				assert.NoError(t, err)
+				assert.True(t, ids[w.ID])
+			})
+		})
+
+		t.Run("When NextWord is called in least-recently-sent mode", func(t *testing.T) {
+			t.Run("Then the word sent longest ago is returned", func(t *testing.T) {
+				first, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationLeastRecentlySent, "")
+				assert.NoError(t, err)
+				assert.NoError(t, mgr.RecordDelivery(ctx, db.DefaultUserID, first.ID))
+
+				second, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationLeastRecentlySent, "")
+				assert.NoError(t, err)
+				assert.NotEqual(t, first.ID, second.ID)
+			})
+		})
+
+		t.Run("When NextWord is called in random mode", func(t *testing.T) {
+			t.Run("Then one of the words is returned", func(t *testing.T) {
+				w, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationRandom, "")
+				assert.NoError(t, err)
+				assert.True(t, ids[w.ID])
+			})
+		})
+	})
+}
+
+func TestReviewWord(t *testing.T) {
+	t.Run("Given a word that has never been reviewed", func(t *testing.T) {
+		ctx := context.Background()
+
+		inserted, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "apricity"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteWord(ctx, db.DefaultUserID, inserted.ID)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When it is first inserted", func(t *testing.T) {
+			t.Run("Then it is due for review", func(t *testing.T) {
+				assert.Equal(t, srs.DefaultEaseFactor, inserted.EaseFactor)
+
+				w, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationSpacedRepetition, "")
+				assert.NoError(t, err)
+				assert.Equal(t, inserted.ID, w.ID)
+			})
+		})
+
+		t.Run("When it is reviewed", func(t *testing.T) {
+			t.Run("Then it is rescheduled", func(t *testing.T) {
+				w, err := mgr.ReviewWord(ctx, db.DefaultUserID, inserted.ID, srs.Good)
+				assert.NoError(t, err)
+
+				assert.Equal(t, int32(1), w.Repetitions)
+				assert.Equal(t, int32(1), w.IntervalDays)
+				assert.True(t, w.DueAt.After(time.Now()))
+			})
+		})
+
+		t.Run("When a word that doesn't exist is reviewed", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.ReviewWord(ctx, db.DefaultUserID, -1, srs.Good)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestUsers(t *testing.T) {
+	t.Run("Given a second user", func(t *testing.T) {
+		ctx := context.Background()
+
+		u, err := mgr.InsertUser(ctx, db.User{Username: "simon", Email: "simon@example.com"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteUser(ctx, u.Username)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When GetUserByUsername is called", func(t *testing.T) {
+			t.Run("Then the user is returned", func(t *testing.T) {
+				got, err := mgr.GetUserByUsername(ctx, "simon")
+				assert.NoError(t, err)
+				assert.Equal(t, u, got)
+
+				_, err = mgr.GetUserByUsername(ctx, "nobody")
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When ListUsers is called", func(t *testing.T) {
+			t.Run("Then the default user and the new user are returned", func(t *testing.T) {
+				users, err := mgr.ListUsers(ctx)
+				assert.NoError(t, err)
+				assert.Len(t, users, 2)
+				assert.Equal(t, db.DefaultUserID, users[0].ID)
+				assert.Equal(t, u.ID, users[1].ID)
+			})
+		})
+
+		t.Run("When the user adds a word", func(t *testing.T) {
+			t.Run("Then it is only visible to that user", func(t *testing.T) {
+				w, err := mgr.InsertWord(ctx, db.Word{UserID: u.ID, Word: "susurrus"})
+				assert.NoError(t, err)
+
+				words, err := mgr.ListWords(ctx, u.ID, db.ListWordsOptions{})
+				assert.NoError(t, err)
+				assert.Len(t, words, 1)
+
+				words, err = mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{})
+				assert.NoError(t, err)
+				assert.Len(t, words, 0)
+
+				_, err = mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+				assert.Error(t, err)
+
+				assert.ErrorIs(t, mgr.RecordDelivery(ctx, db.DefaultUserID, w.ID), db.ErrNotFound)
+				assert.NoError(t, mgr.RecordDelivery(ctx, u.ID, w.ID))
+			})
+		})
+	})
+}
+
+func TestUpdateWord(t *testing.T) {
+	t.Run("Given an existing word", func(t *testing.T) {
+		ctx := context.Background()
+
+		inserted, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "sonder", CustomDefinition: "a typo"})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), inserted.Version)
+
+		defer func() {
+			_, err := mgr.DeleteWord(ctx, db.DefaultUserID, inserted.ID)
+			assert.NoError(t, err)
+		}()
+
+		definition := "the realisation that everyone has a life as vivid as your own"
+
+		t.Run("When only the custom definition is updated", func(t *testing.T) {
+			t.Run("Then the other fields are unchanged and the version is incremented", func(t *testing.T) {
+				w, err := mgr.UpdateWord(ctx, db.DefaultUserID, inserted.ID, db.WordUpdate{CustomDefinition: &definition, ExpectedVersion: 1})
+				assert.NoError(t, err)
+
+				assert.Equal(t, inserted.ID, w.ID)
+				assert.Equal(t, inserted.Word, w.Word)
+				assert.Equal(t, definition, w.CustomDefinition)
+				assert.Equal(t, int32(2), w.Version)
+			})
+		})
+
+		t.Run("When it is updated with an out of date version", func(t *testing.T) {
+			t.Run("Then ErrVersionConflict is returned", func(t *testing.T) {
+				_, err := mgr.UpdateWord(ctx, db.DefaultUserID, inserted.ID, db.WordUpdate{CustomDefinition: &definition, ExpectedVersion: 1})
+				assert.ErrorIs(t, err, db.ErrVersionConflict)
+			})
+		})
+
+		t.Run("When another user updates it", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.UpdateWord(ctx, db.DefaultUserID+1000, inserted.ID, db.WordUpdate{CustomDefinition: &definition})
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestListWordsPagination(t *testing.T) {
+	t.Run("Given several words", func(t *testing.T) {
+		ctx := context.Background()
+
+		var ids []int32
+		for _, w := range []string{"charlie", "Alpha", "bravo", "alphabet"} {
+			inserted, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: w})
+			assert.NoError(t, err)
+
+			ids = append(ids, inserted.ID)
+		}
+
+		defer func() {
+			for _, id := range ids {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+		}()
+
+		spellings := func(words []db.Word) []string {
+			s := make([]string, len(words))
+			for i, w := range words {
+				s[i] = w.Word
+			}
+			return s
+		}
+
+		t.Run("When they are paged through alphabetically", func(t *testing.T) {
+			t.Run("Then each page carries on from the cursor", func(t *testing.T) {
+				first, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Order: db.OrderAlphabetical, Limit: 2})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"Alpha", "alphabet"}, spellings(first))
+
+				last := first[len(first)-1]
+
+				second, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Order: db.OrderAlphabetical, Limit: 2, After: &db.WordCursor{ID: last.ID, Word: last.Word}})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"bravo", "charlie"}, spellings(second))
+			})
+		})
+
+		t.Run("When they are listed newest first", func(t *testing.T) {
+			t.Run("Then the most recently added word is first", func(t *testing.T) {
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Order: db.OrderNewest, Limit: 1})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"alphabet"}, spellings(words))
+			})
+		})
+
+		t.Run("When they are filtered", func(t *testing.T) {
+			t.Run("Then only matching words are returned", func(t *testing.T) {
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Prefix: "ALPHA"})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"Alpha", "alphabet"}, spellings(words))
+
+				words, err = mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Contains: "av"})
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"bravo"}, spellings(words))
+			})
+		})
+	})
+}
+
+func TestRandomWord(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given a user without any words", func(t *testing.T) {
+		t.Run("When RandomWord is called", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.RandomWord(ctx, db.DefaultUserID, "")
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+
+	t.Run("Given words with gaps left by deletes", func(t *testing.T) {
+		var kept []int32
+		for i := 0; i < 10; i++ {
+			w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: fmt.Sprintf("word%d", i)})
+			assert.NoError(t, err)
+
+			if i%3 == 0 {
+				kept = append(kept, w.ID)
+				continue
+			}
+
+			_, err = mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+			assert.NoError(t, err)
+		}
+
+		defer func() {
+			for _, id := range kept {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+		}()
+
+		t.Run("When RandomWord is called repeatedly", func(t *testing.T) {
+			t.Run("Then only remaining words are returned and each of them is picked", func(t *testing.T) {
+				seen := make(map[int32]int)
+				for i := 0; i < 200; i++ {
+					w, err := mgr.RandomWord(ctx, db.DefaultUserID, "")
+					assert.NoError(t, err)
+					assert.Contains(t, kept, w.ID)
+
+					seen[w.ID]++
+				}
+
+				assert.Len(t, seen, len(kept))
+			})
+		})
+	})
+}
+
+// BenchmarkRandomWord compares RandomWord with picking from the result of
+// ListWords as the number of words grows. RandomWord should take roughly the
+// same time at every size.
+func BenchmarkRandomWord(b *testing.B) {
+	ctx := context.Background()
+
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.WarnLevel)
+	defer logrus.SetLevel(level)
+
+	for _, n := range []int{100, 1000, 10000, 100000} {
+		u, err := mgr.InsertUser(ctx, db.User{Username: fmt.Sprintf("benchmark-%d", n)})
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		// Delete every other word to leave gaps in the ids
+		if _, err := conn.Exec(`INSERT INTO words(user_id, word, normalised_word) SELECT $1, 'word' || g, 'word' || g FROM generate_series(1, $2) g`, u.ID, n*2); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := conn.Exec(`DELETE FROM words WHERE user_id = $1 AND id % 2 = 0`, u.ID); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(fmt.Sprintf("RandomWord/words=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := mgr.RandomWord(ctx, u.ID, ""); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("ListWords/words=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := mgr.ListWords(ctx, u.ID, db.ListWordsOptions{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		if _, err := mgr.DeleteUser(ctx, u.Username); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestDuplicateWords(t *testing.T) {
+	t.Run("Given an existing word", func(t *testing.T) {
+		ctx := context.Background()
+
+		inserted, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: " Café ", CustomDefinition: "a coffee shop"})
+		assert.NoError(t, err)
+		assert.Equal(t, "Café", inserted.Word)
+
+		other, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "tea room"})
+		assert.NoError(t, err)
+
+		defer func() {
+			for _, id := range []int32{inserted.ID, other.ID} {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+		}()
+
+		t.Run("When it is inserted again with different case, whitespace and normalisation", func(t *testing.T) {
+			t.Run("Then a DuplicateWordError holding the existing word is returned", func(t *testing.T) {
+				_, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "CAFE\u0301"})
+				assert.ErrorIs(t, err, db.ErrAlreadyExists)
+
+				var dup *db.DuplicateWordError
+				if assert.ErrorAs(t, err, &dup) {
+					assert.Equal(t, inserted.ID, dup.Existing.ID)
+				}
+			})
+		})
+
+		t.Run("When it is merged with a new definition", func(t *testing.T) {
+			t.Run("Then the definitions are combined on the existing word", func(t *testing.T) {
+				w, err := mgr.MergeWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "café", CustomDefinition: "a small restaurant"})
+				assert.NoError(t, err)
+
+				assert.Equal(t, inserted.ID, w.ID)
+				assert.Equal(t, "a coffee shop; a small restaurant", w.CustomDefinition)
+			})
+		})
+
+		t.Run("When another word is renamed to it", func(t *testing.T) {
+			t.Run("Then a DuplicateWordError is returned", func(t *testing.T) {
+				spelling := "cafe\u0301"
+
+				_, err := mgr.UpdateWord(ctx, db.DefaultUserID, other.ID, db.WordUpdate{Word: &spelling})
+
+				var dup *db.DuplicateWordError
+				if assert.ErrorAs(t, err, &dup) {
+					assert.Equal(t, inserted.ID, dup.Existing.ID)
+				}
+			})
+		})
+	})
+}
+
+func TestStats(t *testing.T) {
+	t.Run("Given a user with words", func(t *testing.T) {
+		ctx := context.Background()
+
+		w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "petrichor"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When CountWords is called", func(t *testing.T) {
+			t.Run("Then the words are counted per user", func(t *testing.T) {
+				counts, err := mgr.CountWords(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, map[string]int64{db.DefaultUsername: 1}, counts)
+			})
+		})
+
+		t.Run("When PoolStats is called", func(t *testing.T) {
+			t.Run("Then the pool has open connections", func(t *testing.T) {
+				stats := mgr.PoolStats()
+				assert.Greater(t, stats.TotalConns, int32(0))
+				assert.Greater(t, stats.AcquireCount, int64(0))
+			})
+		})
+	})
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("Given a tracer provider recording spans", func(t *testing.T) {
+		ctx := context.Background()
+
+		sr := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+		defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+		t.Run("When a word is merged", func(t *testing.T) {
+			w, err := mgr.MergeWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "sonder"})
+			assert.NoError(t, err)
+
+			defer func() {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+			}()
+
+			t.Run("Then each query is recorded inside the transaction's span", func(t *testing.T) {
+				spans := sr.Ended()
+
+				var tx sdktrace.ReadOnlySpan
+				for _, s := range spans {
+					if s.Name() == "postgres transaction" {
+						tx = s
+					}
+				}
+
+				if assert.NotNil(t, tx) {
+					var queries []string
+					for _, s := range spans {
+						if s.Parent().SpanID() == tx.SpanContext().SpanID() {
+							queries = append(queries, s.Name())
+						}
+					}
+
+					assert.Equal(t, []string{"postgres SELECT", "postgres INSERT"}, queries)
+				}
+			})
+		})
+	})
+}
+
+func TestDefinitions(t *testing.T) {
+	t.Run("Given a word", func(t *testing.T) {
+		ctx := context.Background()
+
+		w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "sonder"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When it has no definitions", func(t *testing.T) {
+			t.Run("Then empty definitions are returned", func(t *testing.T) {
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+				assert.Empty(t, d.Senses)
+				assert.Empty(t, d.Pronunciations)
+			})
+		})
+
+		t.Run("When definitions from a dictionary are replaced", func(t *testing.T) {
+			t.Run("Then only the latest definitions from that dictionary are kept", func(t *testing.T) {
+				assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, w.ID, "wiktionary", db.Definitions{
+					Senses: []db.Sense{{PartOfSpeech: "verb", Definition: "To sound out."}},
+				}))
+
+				assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, w.ID, "wiktionary", db.Definitions{
+					Senses: []db.Sense{
+						{PartOfSpeech: "noun", Definition: "The realization that each passerby has a life as vivid and complex as one's own."},
+						{PartOfSpeech: "verb", Definition: "To sound out or probe.", Examples: []string{"We sondered the depths."}},
+					},
+					Pronunciations: []db.Pronunciation{{IPA: "/ˈsɒndə/"}},
+				}))
+
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+
+				for i := range d.Senses {
+					assert.NotZero(t, d.Senses[i].ID)
+					assert.Equal(t, w.ID, d.Senses[i].WordID)
+					d.Senses[i].ID, d.Senses[i].WordID = 0, 0
+				}
+
+				assert.Equal(t, db.Definitions{
+					Senses: []db.Sense{
+						{Source: "wiktionary", PartOfSpeech: "noun", Definition: "The realization that each passerby has a life as vivid and complex as one's own.", Examples: []string{}, Synonyms: []string{}, Antonyms: []string{}},
+						{Source: "wiktionary", PartOfSpeech: "verb", Definition: "To sound out or probe.", Examples: []string{"We sondered the depths."}, Synonyms: []string{}, Antonyms: []string{}},
+					},
+					Pronunciations: []db.Pronunciation{{Source: "wiktionary", IPA: "/ˈsɒndə/"}},
+				}, d)
+			})
+		})
+
+		t.Run("When a sense is written by hand", func(t *testing.T) {
+			t.Run("Then it comes first and survives the word being looked up again", func(t *testing.T) {
+				s, err := mgr.AddSense(ctx, db.DefaultUserID, w.ID, db.Sense{
+					PartOfSpeech: "noun",
+					Definition:   "A feeling of wonder at strangers' lives.",
+					Synonyms:     []string{"empathy"},
+					Etymology:    "Coined by John Koenig in The Dictionary of Obscure Sorrows.",
+					IPA:          "/ˈsɒndə/",
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "", s.Source)
+				assert.Equal(t, []string{}, s.Examples)
+				assert.Equal(t, []string{"empathy"}, s.Synonyms)
+
+				assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, w.ID, "wiktionary", db.Definitions{
+					Senses: []db.Sense{{PartOfSpeech: "verb", Definition: "To sound out or probe."}},
+				}))
+
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+				assert.Len(t, d.Senses, 2)
+				assert.Equal(t, s, d.Senses[0])
+			})
+		})
+
+		t.Run("When a dictionary sense is updated", func(t *testing.T) {
+			t.Run("Then it becomes one written by hand", func(t *testing.T) {
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+
+				sense := d.Senses[1]
+				sense.Antonyms = []string{"ignore"}
+
+				updated, err := mgr.UpdateSense(ctx, db.DefaultUserID, w.ID, sense)
+				assert.NoError(t, err)
+				assert.Equal(t, "", updated.Source)
+				assert.Equal(t, []string{"ignore"}, updated.Antonyms)
+
+				assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, w.ID, "wiktionary", db.Definitions{}))
+
+				d, err = mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+				assert.Len(t, d.Senses, 2)
+			})
+		})
+
+		t.Run("When the dictionary definitions are deleted", func(t *testing.T) {
+			t.Run("Then only those written by hand are kept", func(t *testing.T) {
+				assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, w.ID, "wiktionary", db.Definitions{
+					Senses:         []db.Sense{{PartOfSpeech: "verb", Definition: "To sound out or probe."}},
+					Pronunciations: []db.Pronunciation{{IPA: "/ˈsɒndə/"}},
+				}))
+
+				assert.NoError(t, mgr.DeleteDictionaryDefinitions(ctx, db.DefaultUserID, w.ID))
+
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+				assert.Len(t, d.Senses, 2)
+				assert.Empty(t, d.Pronunciations)
+				for _, s := range d.Senses {
+					assert.Equal(t, "", s.Source)
+				}
+
+				err = mgr.DeleteDictionaryDefinitions(ctx, db.DefaultUserID+1, w.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When a sense is deleted", func(t *testing.T) {
+			t.Run("Then it is no longer returned", func(t *testing.T) {
+				d, err := mgr.GetDefinitions(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+
+				deleted, err := mgr.DeleteSense(ctx, db.DefaultUserID, w.ID, d.Senses[0].ID)
+				assert.NoError(t, err)
+				assert.Equal(t, d.Senses[0], deleted)
+
+				_, err = mgr.DeleteSense(ctx, db.DefaultUserID, w.ID, d.Senses[0].ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When the word belongs to another user", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.GetDefinitions(ctx, db.DefaultUserID+1, w.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				err = mgr.ReplaceDefinitions(ctx, db.DefaultUserID+1, w.ID, "wiktionary", db.Definitions{})
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				_, err = mgr.AddSense(ctx, db.DefaultUserID+1, w.ID, db.Sense{Definition: "a definition"})
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestTags(t *testing.T) {
+	t.Run("Given some words", func(t *testing.T) {
+		ctx := context.Background()
+
+		var ids []int32
+		for _, word := range []string{"abrogate", "estoppel", "tort"} {
+			w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: word})
+			assert.NoError(t, err)
+
+			ids = append(ids, w.ID)
+		}
+
+		defer func() {
+			for _, id := range ids {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+
+			_, err := mgr.DeleteTag(ctx, db.DefaultUserID, "legal")
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When two of them are tagged", func(t *testing.T) {
+			t.Run("Then the tag is created once, keeping its first spelling", func(t *testing.T) {
+				tag, err := mgr.TagWord(ctx, db.DefaultUserID, ids[1], "Legal")
+				assert.NoError(t, err)
+				assert.Equal(t, "Legal", tag.Name)
+				assert.Equal(t, int32(1), tag.WordCount)
+
+				tag, err = mgr.TagWord(ctx, db.DefaultUserID, ids[2], " legal")
+				assert.NoError(t, err)
+				assert.Equal(t, "Legal", tag.Name)
+				assert.Equal(t, int32(2), tag.WordCount)
+
+				// Tagging again changes nothing
+				tag, err = mgr.TagWord(ctx, db.DefaultUserID, ids[2], "LEGAL")
+				assert.NoError(t, err)
+				assert.Equal(t, int32(2), tag.WordCount)
+
+				tags, err := mgr.ListTags(ctx, db.DefaultUserID)
+				assert.NoError(t, err)
+				assert.Len(t, tags, 1)
+
+				tags, err = mgr.WordTags(ctx, db.DefaultUserID, ids[0])
+				assert.NoError(t, err)
+				assert.Empty(t, tags)
+			})
+		})
+
+		t.Run("When words are listed and picked by tag", func(t *testing.T) {
+			t.Run("Then only the tagged words are returned", func(t *testing.T) {
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Tag: "legal"})
+				assert.NoError(t, err)
+				assert.Len(t, words, 2)
+
+				for i := 0; i < 20; i++ {
+					w, err := mgr.RandomWord(ctx, db.DefaultUserID, "legal")
+					assert.NoError(t, err)
+					assert.Contains(t, ids[1:], w.ID)
+				}
+
+				for _, mode := range []db.RotationMode{db.RotationRandom, db.RotationShuffleCycle, db.RotationLeastRecentlySent, db.RotationSpacedRepetition} {
+					w, err := mgr.NextWord(ctx, db.DefaultUserID, mode, "legal")
+					assert.NoError(t, err)
+					assert.Contains(t, ids[1:], w.ID)
+				}
+
+				w, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationRandom, "GRE")
+				assert.NoError(t, err)
+				assert.Zero(t, w.ID)
+
+				_, err = mgr.RandomWord(ctx, db.DefaultUserID, "GRE")
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When a word is untagged", func(t *testing.T) {
+			t.Run("Then the tag is kept for the others", func(t *testing.T) {
+				assert.NoError(t, mgr.UntagWord(ctx, db.DefaultUserID, ids[1], "legal"))
+				assert.ErrorIs(t, mgr.UntagWord(ctx, db.DefaultUserID, ids[1], "legal"), db.ErrNotFound)
+
+				tags, err := mgr.WordTags(ctx, db.DefaultUserID, ids[2])
+				assert.NoError(t, err)
+				assert.Len(t, tags, 1)
+				assert.Equal(t, int32(1), tags[0].WordCount)
+			})
+		})
+
+		t.Run("When the word belongs to another user", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := mgr.TagWord(ctx, db.DefaultUserID+1, ids[0], "legal")
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				_, err = mgr.WordTags(ctx, db.DefaultUserID+1, ids[0])
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+// importRows returns a function returning each of rows in turn, then io.EOF
+func importRows(rows ...db.ImportRow) func() (db.ImportRow, error) {
+	return func() (db.ImportRow, error) {
+		if len(rows) == 0 {
+			return db.ImportRow{}, io.EOF
+		}
+
+		row := rows[0]
+		rows = rows[1:]
+
+		return row, nil
+	}
+}
+
+func TestImportWords(t *testing.T) {
+	t.Run("Given an existing word", func(t *testing.T) {
+		ctx := context.Background()
+
+		existing, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "quixotic", CustomDefinition: "idealistic"})
+		assert.NoError(t, err)
+
+		defer func() {
+			words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{})
+			assert.NoError(t, err)
+
+			for _, w := range words {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+				assert.NoError(t, err)
+			}
+
+			_, err = mgr.DeleteTag(ctx, db.DefaultUserID, "imported")
+			assert.NoError(t, err)
+		}()
+
+		rows := func() func() (db.ImportRow, error) {
+			return importRows(
+				db.ImportRow{Line: 1, Word: "petrichor", CustomDefinition: "the smell of rain", Tags: []string{"imported"}},
+				db.ImportRow{Line: 2, Word: "Quixotic", CustomDefinition: "unrealistic", Tags: []string{"imported"}},
+				db.ImportRow{Line: 3, Word: "PETRICHOR"},
+				db.ImportRow{Line: 4, Word: "<bad>", Err: errors.New("word must not contain '<'")},
+			)
+		}
+
+		t.Run("When a dry run is imported", func(t *testing.T) {
+			result, err := mgr.ImportWords(ctx, db.DefaultUserID, rows(), db.ImportOptions{DryRun: true, OnDuplicate: db.DuplicateSkip, SkipInvalid: true})
+
+			t.Run("Then the outcome is counted but nothing is changed", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, result.Added)
+				assert.Equal(t, 2, result.Skipped)
+				assert.Equal(t, 1, result.Failed)
+				assert.False(t, result.Committed)
+
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{})
+				assert.NoError(t, err)
+				assert.Len(t, words, 1)
+			})
+		})
+
+		t.Run("When a row fails without skipping invalid rows", func(t *testing.T) {
+			result, err := mgr.ImportWords(ctx, db.DefaultUserID, rows(), db.ImportOptions{OnDuplicate: db.DuplicateError})
+
+			t.Run("Then the whole import is rolled back and each failure reported", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.False(t, result.Committed)
+				assert.Equal(t, 1, result.Added)
+				assert.Equal(t, 3, result.Failed)
+
+				if assert.Len(t, result.Errors, 3) {
+					assert.Equal(t, 2, result.Errors[0].Line)
+					assert.ErrorIs(t, result.Errors[0].Err, db.ErrAlreadyExists)
+					assert.Equal(t, 3, result.Errors[1].Line)
+					assert.Equal(t, "line 4: word must not contain '<'", result.Errors[2].Error())
+				}
+
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{})
+				assert.NoError(t, err)
+				assert.Len(t, words, 1)
+			})
+		})
+
+		t.Run("When duplicates are merged and invalid rows skipped", func(t *testing.T) {
+			result, err := mgr.ImportWords(ctx, db.DefaultUserID, rows(), db.ImportOptions{OnDuplicate: db.DuplicateMerge, SkipInvalid: true})
+
+			t.Run("Then the valid rows are committed and definitions and tags merged", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.True(t, result.Committed)
+				assert.Equal(t, 1, result.Added)
+				assert.Equal(t, 2, result.Merged)
+				assert.Equal(t, 1, result.Failed)
+
+				w, err := mgr.GetWord(ctx, db.DefaultUserID, existing.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, "idealistic; unrealistic", w.CustomDefinition)
+				assert.Equal(t, existing.Version+1, w.Version)
+
+				words, err := mgr.ListWords(ctx, db.DefaultUserID, db.ListWordsOptions{Tag: "imported"})
+				assert.NoError(t, err)
+				assert.Len(t, words, 2)
+			})
+		})
+
+		t.Run("When reading the rows fails", func(t *testing.T) {
+			_, err := mgr.ImportWords(ctx, db.DefaultUserID, func() (db.ImportRow, error) {
+				return db.ImportRow{}, errors.New("connection reset")
+			}, db.ImportOptions{})
+
+			t.Run("Then the error is returned", func(t *testing.T) {
+				assert.EqualError(t, err, "unable to import words: connection reset")
+			})
+		})
+	})
+}
+
+func TestExportWords(t *testing.T) {
+	t.Run("Given words with tags and senses", func(t *testing.T) {
+		ctx := context.Background()
+
+		first, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "petrichor", CustomDefinition: "the smell of rain"})
+		assert.NoError(t, err)
+
+		second, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "sonder"})
+		assert.NoError(t, err)
+
+		defer func() {
+			for _, id := range []int32{first.ID, second.ID} {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+
+			for _, tag := range []string{"weather", "GRE"} {
+				_, err := mgr.DeleteTag(ctx, db.DefaultUserID, tag)
+				assert.NoError(t, err)
+			}
+		}()
+
+		for _, tag := range []string{"weather", "GRE"} {
+			_, err = mgr.TagWord(ctx, db.DefaultUserID, first.ID, tag)
+			assert.NoError(t, err)
+		}
+
+		assert.NoError(t, mgr.ReplaceDefinitions(ctx, db.DefaultUserID, first.ID, "wiktionary", db.Definitions{
+			Senses: []db.Sense{{PartOfSpeech: "noun", Definition: "The scent of rain on dry earth.", Examples: []string{"The petrichor rose."}}},
+		}))
+
+		_, err = mgr.AddSense(ctx, db.DefaultUserID, first.ID, db.Sense{Definition: "A smell"})
+		assert.NoError(t, err)
+
+		t.Run("When they are exported", func(t *testing.T) {
+			var exported []db.ExportedWord
+			err := mgr.ExportWords(ctx, db.DefaultUserID, "", func(w db.ExportedWord) error {
+				exported = append(exported, w)
+				return nil
+			})
+
+			t.Run("Then each word has its tags and senses, in the order they were added", func(t *testing.T) {
+				assert.NoError(t, err)
+
+				if assert.Len(t, exported, 2) {
+					assert.Equal(t, first.ID, exported[0].ID)
+					assert.Equal(t, first.CreatedAt.Unix(), exported[0].CreatedAt.Unix())
+					assert.Equal(t, []string{"GRE", "weather"}, exported[0].Tags)
+
+					if assert.Len(t, exported[0].Senses, 2) {
+						assert.Equal(t, "A smell", exported[0].Senses[0].Definition)
+						assert.Equal(t, "wiktionary", exported[0].Senses[1].Source)
+						assert.Equal(t, []string{"The petrichor rose."}, exported[0].Senses[1].Examples)
+					}
+
+					assert.Empty(t, exported[1].Tags)
+					assert.Empty(t, exported[1].Senses)
+				}
+			})
+		})
+
+		t.Run("When those with a tag are exported", func(t *testing.T) {
+			var exported []db.ExportedWord
+			err := mgr.ExportWords(ctx, db.DefaultUserID, "gre", func(w db.ExportedWord) error {
+				exported = append(exported, w)
+				return nil
+			})
+
+			t.Run("Then only they are exported", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Len(t, exported, 1)
+			})
+		})
+
+		t.Run("When writing a word fails", func(t *testing.T) {
+			err := mgr.ExportWords(ctx, db.DefaultUserID, "", func(w db.ExportedWord) error {
+				return errors.New("broken pipe")
+			})
+
+			t.Run("Then the export stops with the error", func(t *testing.T) {
+				assert.EqualError(t, err, "broken pipe")
+			})
+		})
+	})
+}
+
+func TestNormalisedWordMigration(t *testing.T) {
+	t.Run("Given duplicate words added before they were normalised", func(t *testing.T) {
+		ctx := context.Background()
+
+		version, err := mgr.SchemaVersion(ctx)
+		assert.NoError(t, err)
+
+		// Back to before migration 0007
+		_, err = mgr.MigrateDown(ctx, int(version-6))
+		assert.NoError(t, err)
+
+		var street, road, cafe int32
+		assert.NoError(t, conn.QueryRow("INSERT INTO words(user_id, word, custom_definition) VALUES($1, 'Straße', 'a street') RETURNING id", db.DefaultUserID).Scan(&street))
+		assert.NoError(t, conn.QueryRow("INSERT INTO words(user_id, word, custom_definition) VALUES($1, ' STRASSE', 'a road') RETURNING id", db.DefaultUserID).Scan(&road))
+		assert.NoError(t, conn.QueryRow("INSERT INTO words(user_id, word) VALUES($1, 'cafe\u0301') RETURNING id", db.DefaultUserID).Scan(&cafe))
+
+		_, err = conn.Exec("INSERT INTO word_deliveries(word_id, user_id) VALUES($1, $2)", road, db.DefaultUserID)
+		assert.NoError(t, err)
+
+		defer func() {
+			for _, id := range []int32{street, cafe} {
+				_, err := mgr.DeleteWord(ctx, db.DefaultUserID, id)
+				assert.NoError(t, err)
+			}
+		}()
+
+		t.Run("When the migrations are applied", func(t *testing.T) {
+			_, err := mgr.MigrateUp(ctx)
+			assert.NoError(t, err)
+
+			t.Run("Then the duplicate is merged into the oldest copy", func(t *testing.T) {
+				w, err := mgr.GetWord(ctx, db.DefaultUserID, street)
+				assert.NoError(t, err)
+				assert.Equal(t, "a street; a road", w.CustomDefinition)
+
+				_, err = mgr.GetWord(ctx, db.DefaultUserID, road)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				found, err := mgr.FindWord(ctx, db.DefaultUserID, "strasse")
+				assert.NoError(t, err)
+				assert.Equal(t, street, found.ID)
+			})
+
+			t.Run("Then the duplicate's deliveries are kept", func(t *testing.T) {
+				var deliveries int
+				assert.NoError(t, conn.QueryRow("SELECT COUNT(*) FROM word_deliveries WHERE word_id = $1", street).Scan(&deliveries))
+				assert.Equal(t, 1, deliveries)
+			})
+
+			t.Run("Then words are normalised as NormaliseWord does", func(t *testing.T) {
+				found, err := mgr.FindWord(ctx, db.DefaultUserID, "CAFÉ")
+				assert.NoError(t, err)
+				assert.Equal(t, cafe, found.ID)
+				assert.Equal(t, "caf\u00e9", found.Word)
+			})
+		})
+	})
+}
+
+func TestOutbox(t *testing.T) {
+	t.Run("Given a word to email", func(t *testing.T) {
+		ctx := context.Background()
+
+		w, err := mgr.InsertWord(ctx, db.Word{UserID: db.DefaultUserID, Word: "petrichor"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteWord(ctx, db.DefaultUserID, w.ID)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When messages are enqueued for a word that doesn't exist", func(t *testing.T) {
+			err := mgr.EnqueueMessages(ctx, db.DefaultUserID, w.ID+1000, []db.OutboxMessage{{Recipient: "alice@example.com", Message: []byte("hi")}})
+
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When messages are enqueued for two recipients", func(t *testing.T) {
+			err := mgr.EnqueueMessages(ctx, db.DefaultUserID, w.ID, []db.OutboxMessage{
+				{Recipient: "alice@example.com", Subject: "My Word Of The Day: petrichor", Message: []byte("to alice")},
+				{Recipient: "bob@example.com", Subject: "My Word Of The Day: petrichor", Message: []byte("to bob")},
+			})
+			assert.NoError(t, err)
+
+			t.Run("Then the delivery is recorded", func(t *testing.T) {
+				next, err := mgr.NextWord(ctx, db.DefaultUserID, db.RotationLeastRecentlySent, "")
+				assert.NoError(t, err)
+				assert.NotEqual(t, "", next.Word)
+			})
+
+			claimed, err := mgr.ClaimMessages(ctx, 10, time.Minute)
+
+			t.Run("Then each is claimed once as a first attempt", func(t *testing.T) {
+				assert.NoError(t, err)
+
+				if assert.Len(t, claimed, 2) {
+					assert.Equal(t, "alice@example.com", claimed[0].Recipient)
+					assert.Equal(t, []byte("to alice"), claimed[0].Message)
+					assert.Equal(t, w.ID, claimed[0].WordID)
+					assert.Equal(t, int32(1), claimed[0].Attempts)
+					assert.Equal(t, db.OutboxPending, claimed[0].Status)
+				}
+
+				again, err := mgr.ClaimMessages(ctx, 10, time.Minute)
+				assert.NoError(t, err)
+				assert.Empty(t, again, "claimed messages shouldn't be claimed again until their lease expires")
+			})
+
+			if len(claimed) != 2 {
+				return
+			}
+
+			assert.NoError(t, mgr.MarkMessageSent(ctx, claimed[0].ID))
+			assert.NoError(t, mgr.FailMessage(ctx, claimed[1].ID, "550 no such user"))
+
+			t.Run("Then they're listed with their outcomes, newest first", func(t *testing.T) {
+				messages, err := mgr.ListMessages(ctx, db.DefaultUserID, "", 10)
+				assert.NoError(t, err)
+
+				if assert.GreaterOrEqual(t, len(messages), 2) {
+					assert.Equal(t, db.OutboxFailed, messages[0].Status)
+					assert.Equal(t, "550 no such user", messages[0].LastError)
+					assert.Nil(t, messages[0].SentAt)
+					assert.Equal(t, db.OutboxSent, messages[1].Status)
+					assert.NotNil(t, messages[1].SentAt)
+				}
+
+				failed, err := mgr.ListMessages(ctx, db.DefaultUserID, db.OutboxFailed, 10)
+				assert.NoError(t, err)
+				assert.Len(t, failed, 1)
+			})
+
+			t.Run("Then the failed message can be retried", func(t *testing.T) {
+				retried, err := mgr.RetryMessages(ctx, db.DefaultUserID, nil)
+				assert.NoError(t, err)
+
+				if assert.Len(t, retried, 1) {
+					assert.Equal(t, claimed[1].ID, retried[0].ID)
+					assert.Equal(t, db.OutboxPending, retried[0].Status)
+					assert.Zero(t, retried[0].Attempts)
+				}
+
+				reclaimed, err := mgr.ClaimMessages(ctx, 10, time.Minute)
+				assert.NoError(t, err)
+				assert.Len(t, reclaimed, 1)
+
+				assert.NoError(t, mgr.ReleaseMessages(ctx, []int32{claimed[1].ID}))
+
+				released, err := mgr.ClaimMessages(ctx, 10, time.Minute)
+				assert.NoError(t, err)
+
+				if assert.Len(t, released, 1, "released messages should be claimed again straight away") {
+					assert.Equal(t, int32(1), released[0].Attempts)
+				}
+
+				assert.NoError(t, mgr.RetryMessageAt(ctx, claimed[1].ID, time.Now().Add(time.Hour), "451 try again later"))
+			})
+
+			t.Run("Then retrying a message that hasn't failed does nothing", func(t *testing.T) {
+				retried, err := mgr.RetryMessages(ctx, db.DefaultUserID, []int32{claimed[0].ID})
+				assert.NoError(t, err)
+				assert.Empty(t, retried)
+			})
+		})
+	})
+}
+
+func TestChannels(t *testing.T) {
+	t.Run("Given a user", func(t *testing.T) {
+		ctx := context.Background()
+
+		u, err := mgr.InsertUser(ctx, db.User{Username: "grace"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteUser(ctx, u.Username)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When channels are added", func(t *testing.T) {
+			slack, err := mgr.InsertChannel(ctx, u.ID, "slack+https://hooks.slack.com/services/T000/B000/XXXX")
+			assert.NoError(t, err)
+
+			ntfy, err := mgr.InsertChannel(ctx, u.ID, "ntfy+https://ntfy.sh/my-words")
+			assert.NoError(t, err)
+
+			t.Run("Then they're listed in the order they were added", func(t *testing.T) {
+				channels, err := mgr.ListChannels(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, []db.Channel{slack, ntfy}, channels)
+			})
+
+			t.Run("Then they're not listed for other users", func(t *testing.T) {
+				channels, err := mgr.ListChannels(ctx, db.DefaultUserID)
+				assert.NoError(t, err)
+				assert.NotContains(t, channels, slack)
+			})
+
+			t.Run("Then another user can't delete them", func(t *testing.T) {
+				_, err := mgr.DeleteChannel(ctx, db.DefaultUserID, slack.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+
+			t.Run("Then they can be deleted", func(t *testing.T) {
+				deleted, err := mgr.DeleteChannel(ctx, u.ID, slack.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, slack, deleted)
+
+				channels, err := mgr.ListChannels(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, []db.Channel{ntfy}, channels)
+			})
+		})
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	t.Run("Given a user", func(t *testing.T) {
+		ctx := context.Background()
+
+		u, err := mgr.InsertUser(ctx, db.User{Username: "ada"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteUser(ctx, u.Username)
+			assert.NoError(t, err)
+		}()
+
+		t.Run("When they haven't activated a template", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned for their active template", func(t *testing.T) {
+				_, err := mgr.ActiveTemplate(ctx, u.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+
+		t.Run("When templates are added", func(t *testing.T) {
+			plain, err := mgr.InsertTemplate(ctx, db.Template{UserID: u.ID, Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"})
+			assert.NoError(t, err)
+			assert.False(t, plain.Active)
+
+			fancy, err := mgr.InsertTemplate(ctx, db.Template{UserID: u.ID, Name: "fancy", HTML: "<h1>{{.Word}}</h1>", Text: "# {{.Word}}"})
+			assert.NoError(t, err)
+
+			t.Run("Then another with the same name is rejected", func(t *testing.T) {
+				_, err := mgr.InsertTemplate(ctx, db.Template{UserID: u.ID, Name: "plain", HTML: "", Text: ""})
+				assert.ErrorIs(t, err, db.ErrAlreadyExists)
+			})
+
+			t.Run("Then they're listed by name", func(t *testing.T) {
+				templates, err := mgr.ListTemplates(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, []db.Template{fancy, plain}, templates)
+			})
+
+			t.Run("Then activating one makes it the only active template", func(t *testing.T) {
+				_, err := mgr.ActivateTemplate(ctx, u.ID, plain.ID)
+				assert.NoError(t, err)
+
+				activated, err := mgr.ActivateTemplate(ctx, u.ID, fancy.ID)
+				assert.NoError(t, err)
+				assert.True(t, activated.Active)
+
+				active, err := mgr.ActiveTemplate(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, fancy.ID, active.ID)
+
+				got, err := mgr.GetTemplate(ctx, u.ID, plain.ID)
+				assert.NoError(t, err)
+				assert.False(t, got.Active)
+			})
+
+			t.Run("Then another user can't activate or delete them", func(t *testing.T) {
+				_, err := mgr.ActivateTemplate(ctx, db.DefaultUserID, plain.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+
+				_, err = mgr.DeleteTemplate(ctx, db.DefaultUserID, plain.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+
+			t.Run("Then deactivating them goes back to the default", func(t *testing.T) {
+				assert.NoError(t, mgr.DeactivateTemplates(ctx, u.ID))
+
+				_, err := mgr.ActiveTemplate(ctx, u.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+
+			t.Run("Then they can be deleted", func(t *testing.T) {
+				deleted, err := mgr.DeleteTemplate(ctx, u.ID, plain.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, plain.ID, deleted.ID)
+
+				_, err = mgr.GetTemplate(ctx, u.ID, plain.ID)
+				assert.ErrorIs(t, err, db.ErrNotFound)
+			})
+		})
+	})
+}
+
+func TestDeliveryStreak(t *testing.T) {
+	t.Run("Given a user", func(t *testing.T) {
+		ctx := context.Background()
+
+		u, err := mgr.InsertUser(ctx, db.User{Username: "mary"})
+		assert.NoError(t, err)
+
+		defer func() {
+			_, err := mgr.DeleteUser(ctx, u.Username)
+			assert.NoError(t, err)
+		}()
+
+		w, err := mgr.InsertWord(ctx, db.Word{UserID: u.ID, Word: "sonder"})
+		assert.NoError(t, err)
+
+		t.Run("When they've never been sent a word", func(t *testing.T) {
+			t.Run("Then today starts their streak", func(t *testing.T) {
+				streak, err := mgr.DeliveryStreak(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, 1, streak)
+			})
+		})
+
+		t.Run("When they've been sent words for the last two days, and before a gap", func(t *testing.T) {
+			for _, daysAgo := range []int{1, 2, 2, 4, 5} {
+				_, err := conn.Exec(
+					`INSERT INTO word_deliveries(user_id, word_id, sent_at) VALUES($1, $2, now() - make_interval(days => $3))`,
+					u.ID, w.ID, daysAgo,
+				)
+				assert.NoError(t, err)
+			}
+
+			t.Run("Then their streak is those days and today", func(t *testing.T) {
+				streak, err := mgr.DeliveryStreak(ctx, u.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, 3, streak)
+			})
+		})
 	})
 }
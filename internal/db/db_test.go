@@ -45,3 +45,55 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRotationMode(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		mode        string
+		expected    RotationMode
+		expectedErr string
+	}{
+		{desc: "Empty mode should default to random", mode: "", expected: RotationRandom},
+		{desc: "Random mode should be accepted", mode: "random", expected: RotationRandom},
+		{desc: "Shuffle cycle mode should be accepted", mode: "shuffle-cycle", expected: RotationShuffleCycle},
+		{desc: "Least recently sent mode should be accepted", mode: "least-recently-sent", expected: RotationLeastRecentlySent},
+		{desc: "Unknown mode should return error", mode: "sequential", expectedErr: `unknown rotation mode "sequential"`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := ParseRotationMode(tC.mode)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+}
+
+func TestParseOutboxStatus(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		status      string
+		expected    OutboxStatus
+		expectedErr string
+	}{
+		{desc: "Empty status should mean any status", status: "", expected: ""},
+		{desc: "Failed status should be accepted", status: "failed", expected: OutboxFailed},
+		{desc: "Unknown status should return error", status: "bounced", expectedErr: `unknown outbox status "bounced"`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := ParseOutboxStatus(tC.status)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, s)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RotationMode controls how the next word to deliver is chosen
+type RotationMode string
+
+const (
+	// RotationRandom picks uniformly from every word, so repeats are possible
+	RotationRandom RotationMode = "random"
+	// RotationShuffleCycle picks from the words not yet sent in the current
+	// cycle, starting a new cycle once every word has been sent
+	RotationShuffleCycle RotationMode = "shuffle-cycle"
+	// RotationLeastRecentlySent picks the word that has gone the longest
+	// without being sent, preferring words that have never been sent
+	RotationLeastRecentlySent RotationMode = "least-recently-sent"
+	// RotationSpacedRepetition picks the most overdue word according to its
+	// review schedule, falling back to least-recently-sent when nothing is due
+	RotationSpacedRepetition RotationMode = "spaced-repetition"
+)
+
+// ParseRotationMode validates s as a RotationMode, defaulting to RotationRandom
+// when s is empty
+func ParseRotationMode(s string) (RotationMode, error) {
+	switch mode := RotationMode(s); mode {
+	case "":
+		return RotationRandom, nil
+	case RotationRandom, RotationShuffleCycle, RotationLeastRecentlySent, RotationSpacedRepetition:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown rotation mode %q", s)
+	}
+}
+
+// The rotation queries pick from the words with the tag in $2, or every word if
+// it's empty
+const (
+	unsentWordQuery = `SELECT ` + wordColumns + ` FROM words w
+WHERE w.user_id = $1 AND ` + taggedWith + ` AND NOT EXISTS (
+  SELECT 1 FROM word_deliveries d
+  WHERE d.word_id = w.id AND d.cycle = (SELECT COALESCE(MAX(cycle), 1) FROM word_deliveries WHERE user_id = $1)
+)
+ORDER BY random() LIMIT 1`
+
+	leastRecentlySentWordQuery = `SELECT ` + wordColumns + ` FROM words w
+LEFT JOIN (SELECT word_id, MAX(sent_at) AS last_sent FROM word_deliveries WHERE user_id = $1 GROUP BY word_id) d ON d.word_id = w.id
+WHERE w.user_id = $1 AND ` + taggedWith + `
+ORDER BY d.last_sent ASC NULLS FIRST, random() LIMIT 1`
+
+	dueWordQuery = `SELECT ` + wordColumns + ` FROM words w WHERE w.user_id = $1 AND ` + taggedWith + ` AND w.due_at <= now()
+ORDER BY w.due_at ASC, random() LIMIT 1`
+)
+
+// NextWord returns the next word to deliver to the user according to mode. If
+// tag isn't empty, only words with that tag are considered. If the user has no
+// words to choose from, an empty Word is returned.
+func (m *Manager) NextWord(ctx context.Context, userID int32, mode RotationMode, tag string) (Word, error) {
+	var query string
+
+	tag = NormaliseWord(tag)
+
+	switch mode {
+	case RotationRandom:
+		query = randomWordQuery
+	case RotationShuffleCycle:
+		query = unsentWordQuery
+	case RotationLeastRecentlySent:
+		query = leastRecentlySentWordQuery
+	case RotationSpacedRepetition:
+		query = dueWordQuery
+	default:
+		return Word{}, fmt.Errorf("unknown rotation mode %q", mode)
+	}
+
+	w, err := m.queryWord(ctx, query, userID, tag)
+	if errors.Is(err, pgx.ErrNoRows) && mode == RotationShuffleCycle {
+		// Every word has been sent in the current cycle, so the next
+		// delivery starts a new one and any word is fair game
+		w, err = m.queryWord(ctx, randomWordQuery, userID, tag)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) && mode == RotationSpacedRepetition {
+		// Nothing is due for review, so keep things moving with the word
+		// that has gone the longest without being sent
+		w, err = m.queryWord(ctx, leastRecentlySentWordQuery, userID, tag)
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Word{}, nil
+	}
+
+	if err != nil {
+		return w, errors.Wrap(err, "unable to get next word")
+	}
+
+	return w, nil
+}
+
+// RecordDelivery stores that the word was sent to the user. The delivery
+// belongs to the user's current cycle unless the word was already sent in it,
+// in which case it starts the next cycle. ErrNotFound is returned if the word
+// doesn't belong to the user.
+func (m *Manager) RecordDelivery(ctx context.Context, userID int32, wordID int32) error {
+	if _, err := recordDelivery(ctx, m.pool, userID, wordID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeliveryStreak returns how many days in a row the user will have been sent
+// a word once today's is sent, counting back from today through each day
+// before it they were sent one. Days are UTC days.
+func (m *Manager) DeliveryStreak(ctx context.Context, userID int32) (int, error) {
+	var streak int
+
+	// Each day before today is part of the streak if it's n days before today,
+	// where n is its position counting back from the most recent
+	err := m.pool.QueryRow(
+		ctx,
+		`WITH days AS (
+  SELECT DISTINCT (sent_at AT TIME ZONE 'UTC')::date AS day FROM word_deliveries
+  WHERE user_id = $1 AND (sent_at AT TIME ZONE 'UTC')::date < (now() AT TIME ZONE 'UTC')::date
+)
+SELECT count(*) + 1 FROM (SELECT day, row_number() OVER (ORDER BY day DESC) AS n FROM days) d
+WHERE d.day = (now() AT TIME ZONE 'UTC')::date - d.n::int`,
+		userID,
+	).Scan(&streak)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to get delivery streak")
+	}
+
+	return streak, nil
+}
+
+// queryRower is either the pool or a transaction
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// recordDelivery records a delivery as RecordDelivery does, with q, returning
+// the cycle it belongs to
+func recordDelivery(ctx context.Context, q queryRower, userID int32, wordID int32) (int32, error) {
+	var cycle int32
+
+	err := q.QueryRow(
+		ctx,
+		`INSERT INTO word_deliveries(user_id, word_id, cycle)
+SELECT w.user_id, w.id, CASE WHEN EXISTS (SELECT 1 FROM word_deliveries WHERE word_id = w.id AND cycle = c.cycle) THEN c.cycle + 1 ELSE c.cycle END
+FROM words w, (SELECT COALESCE(MAX(cycle), 1) AS cycle FROM word_deliveries WHERE user_id = $1) c
+WHERE w.id = $2 AND w.user_id = $1
+RETURNING cycle`,
+		userID, wordID,
+	).Scan(&cycle)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to record delivery")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     wordID,
+		"userID": userID,
+		"cycle":  cycle,
+	}).Info("Delivery recorded successfully")
+
+	return cycle, nil
+}
+
+func (m *Manager) queryWord(ctx context.Context, query string, args ...interface{}) (Word, error) {
+	return scanWord(m.pool.QueryRow(ctx, query, args...))
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DuplicateWordError is returned when the user already has a word with the
+// same normalised spelling. It matches ErrAlreadyExists with errors.Is.
+type DuplicateWordError struct {
+	Existing Word
+}
+
+func (e *DuplicateWordError) Error() string {
+	return fmt.Sprintf("word %q already exists with id %d", e.Existing.Word, e.Existing.ID)
+}
+
+func (e *DuplicateWordError) Is(target error) bool {
+	return target == ErrAlreadyExists
+}
+
+// duplicateWordError looks up the user's word with the same normalised
+// spelling as word and returns it in a *DuplicateWordError
+func (m *Manager) duplicateWordError(ctx context.Context, userID int32, word string) error {
+	existing, err := m.FindWord(ctx, userID, word)
+	if err != nil {
+		return errors.Wrap(err, "unable to get existing word")
+	}
+
+	return &DuplicateWordError{Existing: existing}
+}
+
+// MergeWord inserts the word for the user in word.UserID like InsertWord, but if
+// the user already has a word with the same normalised spelling its custom
+// definition is merged into that word instead
+func (m *Manager) MergeWord(ctx context.Context, word Word) (Word, error) {
+	var (
+		w      Word
+		merged bool
+	)
+
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		existing, err := scanWord(tx.QueryRow(
+			ctx,
+			"SELECT "+wordColumns+" FROM words WHERE user_id=$1 AND normalised_word=$2 FOR UPDATE",
+			word.UserID, NormaliseWord(word.Word),
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			w, err = scanWord(tx.QueryRow(
+				ctx,
+				"INSERT INTO words(user_id, word, normalised_word, custom_definition) VALUES($1, $2, $3, $4) RETURNING "+wordColumns,
+				word.UserID, cleanWord(word.Word), NormaliseWord(word.Word), word.CustomDefinition,
+			))
+			return err
+		}
+
+		if err != nil {
+			return err
+		}
+
+		definition := mergeDefinitions(existing.CustomDefinition, word.CustomDefinition)
+		if definition == existing.CustomDefinition {
+			w = existing
+			return nil
+		}
+
+		merged = true
+
+		w, err = scanWord(tx.QueryRow(
+			ctx,
+			"UPDATE words SET custom_definition=$2, version=version+1, updated_at=now() WHERE id=$1 RETURNING "+wordColumns,
+			existing.ID, definition,
+		))
+
+		return err
+	})
+	if err != nil {
+		return w, errors.Wrap(err, "unable to merge word")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     w.ID,
+		"userID": w.UserID,
+		"merged": merged,
+	}).Info("Word merged successfully")
+
+	return w, nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrNotFound is returned when the requested record does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists is returned when a record clashes with one that already exists
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrVersionConflict is returned when a record has been modified since
+	// the version the caller expected
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrTimeout is the kind of error caused by a query taking too long
+	ErrTimeout = errors.New("timeout")
+	// ErrUnavailable is the kind of error caused by the database being unreachable
+	ErrUnavailable = errors.New("database unavailable")
+	// ErrTooLong is the kind of error caused by a value too long for its column
+	ErrTooLong = errors.New("value too long")
+)
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	uniqueViolation          = "23505"
+	stringDataRightTrunc     = "22001"
+	queryCanceled            = "57014"
+	lockNotAvailable         = "55P03"
+	tooManyConnections       = "53300"
+	cannotConnectNow         = "57P03"
+	adminShutdown            = "57P01"
+	connectionExceptionClass = "08"
+)
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// Classify returns the kind of error err is, one of ErrNotFound,
+// ErrAlreadyExists, ErrVersionConflict, ErrTimeout, ErrUnavailable, ErrTooLong,
+// context.Canceled or context.DeadlineExceeded, or nil if it isn't known
+func Classify(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrAlreadyExists, ErrVersionConflict, ErrTimeout, ErrUnavailable, ErrTooLong, context.Canceled, context.DeadlineExceeded} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == uniqueViolation:
+			return ErrAlreadyExists
+		case pgErr.Code == stringDataRightTrunc:
+			return ErrTooLong
+		case pgErr.Code == queryCanceled, pgErr.Code == lockNotAvailable:
+			return ErrTimeout
+		case pgErr.Code == tooManyConnections, pgErr.Code == cannotConnectNow, pgErr.Code == adminShutdown,
+			strings.HasPrefix(pgErr.Code, connectionExceptionClass):
+			return ErrUnavailable
+		}
+
+		return nil
+	}
+
+	if pgconn.Timeout(err) {
+		return ErrTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrUnavailable
+	}
+
+	return nil
+}
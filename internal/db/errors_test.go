@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		err      error
+		expected error
+	}{
+		{desc: "Wrapped sentinel should be returned", err: errors.Wrap(ErrVersionConflict, "unable to update word"), expected: ErrVersionConflict},
+		{desc: "Duplicate word should be already exists", err: &DuplicateWordError{}, expected: ErrAlreadyExists},
+		{desc: "No rows should be not found", err: errors.Wrap(pgx.ErrNoRows, "unable to get word"), expected: ErrNotFound},
+		{desc: "Unique violation should be already exists", err: &pgconn.PgError{Code: "23505"}, expected: ErrAlreadyExists},
+		{desc: "Value too long for its column should be too long", err: &pgconn.PgError{Code: "22001"}, expected: ErrTooLong},
+		{desc: "Statement timeout should be a timeout", err: &pgconn.PgError{Code: "57014"}, expected: ErrTimeout},
+		{desc: "Connection exception should be unavailable", err: &pgconn.PgError{Code: "08006"}, expected: ErrUnavailable},
+		{desc: "Other Postgres errors should be unknown", err: &pgconn.PgError{Code: "42P01"}, expected: nil},
+		{desc: "Deadline exceeded should be returned", err: errors.Wrap(context.DeadlineExceeded, "unable to get words"), expected: context.DeadlineExceeded},
+		{desc: "Network errors should be unavailable", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: ErrUnavailable},
+		{desc: "Other errors should be unknown", err: errors.New("an error"), expected: nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, Classify(tC.err))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportedWord is a word with its tags and senses, as exported
+type ExportedWord struct {
+	Word
+	// Tags are the names of the word's tags, in alphabetical order
+	Tags []string
+	// Senses are ordered like those returned by GetDefinitions
+	Senses []Sense
+}
+
+// exportWordsQuery selects the user's words with the tag whose normalised name
+// is $2, or every word if it's empty, in the order they were added. The senses
+// are aggregated as JSON objects keyed by the fields of Sense.
+const exportWordsQuery = `SELECT ` + wordColumns + `,
+  ARRAY(SELECT t.name FROM word_tags wt JOIN tags t ON t.id = wt.tag_id WHERE wt.word_id = w.id ORDER BY t.normalised_name),
+  COALESCE((
+    SELECT json_agg(json_build_object(
+      'ID', s.id, 'WordID', s.word_id, 'Source', s.source, 'PartOfSpeech', s.part_of_speech, 'Definition', s.definition,
+      'Examples', s.examples, 'Synonyms', s.synonyms, 'Antonyms', s.antonyms, 'Etymology', s.etymology, 'IPA', s.ipa
+    ) ORDER BY s.source <> '', s.position, s.id)
+    FROM word_senses s WHERE s.word_id = w.id
+  ), '[]')
+FROM words w
+WHERE w.user_id = $1 AND ` + taggedWith + `
+ORDER BY w.id`
+
+// extraColumns scans the columns following those of a word into dest, so
+// scanWord can be used for queries selecting more than wordColumns
+type extraColumns struct {
+	row  pgx.Row
+	dest []interface{}
+}
+
+func (e extraColumns) Scan(dest ...interface{}) error {
+	return e.row.Scan(append(dest, e.dest...)...)
+}
+
+// ExportWords calls fn with each of the user's words with the tag, or every
+// word if tag is empty, in the order they were added. Words are read from the
+// database as fn consumes them rather than all at once, so lists of any size
+// can be exported. An error returned by fn stops the export and is returned.
+func (m *Manager) ExportWords(ctx context.Context, userID int32, tag string, fn func(ExportedWord) error) error {
+	rows, err := m.pool.Query(ctx, exportWordsQuery, userID, NormaliseWord(tag))
+	if err != nil {
+		return errors.Wrap(err, "unable to export words")
+	}
+	defer rows.Close()
+
+	rowCount := 0
+	for rows.Next() {
+		e := ExportedWord{}
+
+		e.Word, err = scanWord(extraColumns{row: rows, dest: []interface{}{&e.Tags, &e.Senses}})
+		if err != nil {
+			return errors.Wrap(err, "unable to scan row")
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+
+		rowCount++
+	}
+
+	if rows.Err() != nil {
+		return errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	logrus.WithFields(logrus.Fields{"rowCount": rowCount, "userID": userID}).Info("Words exported successfully")
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DuplicatePolicy controls what ImportWords does with a word the user already
+// has, including one added by an earlier row of the same import
+type DuplicatePolicy string
+
+const (
+	// DuplicateSkip leaves the existing word as it is
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateMerge merges the row's custom definition into the existing
+	// word, as MergeWord does, and gives it the row's tags
+	DuplicateMerge DuplicatePolicy = "merge"
+	// DuplicateError fails the row
+	DuplicateError DuplicatePolicy = "error"
+)
+
+// ParseDuplicatePolicy validates s as a DuplicatePolicy, defaulting to
+// DuplicateSkip when s is empty
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(s); p {
+	case "":
+		return DuplicateSkip, nil
+	case DuplicateSkip, DuplicateMerge, DuplicateError:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q", s)
+	}
+}
+
+// ImportRow is a word to import
+type ImportRow struct {
+	// Line locates the row in the file it came from, for reporting errors
+	Line             int
+	Word             string
+	CustomDefinition string
+	Tags             []string
+
+	// Err is set if the row couldn't be read or is invalid, so it fails
+	// without being imported
+	Err error
+}
+
+// ImportOptions controls how ImportWords treats the rows
+type ImportOptions struct {
+	// DryRun rolls back the import once every row has been tried, so the
+	// result shows what would happen without changing anything
+	DryRun bool
+	// OnDuplicate controls what happens to rows for words that already exist
+	OnDuplicate DuplicatePolicy
+	// SkipInvalid commits the rows that succeeded even if others failed.
+	// Otherwise any failure rolls back the whole import.
+	SkipInvalid bool
+}
+
+// ImportRowError is a row that failed to import
+type ImportRowError struct {
+	Line int
+	Word string
+	Err  error
+}
+
+func (e ImportRowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// maxImportErrors limits how many row errors an ImportResult holds, so a file
+// in the wrong format doesn't produce a report as large as itself
+const maxImportErrors = 1000
+
+// ImportResult counts what happened to the rows of an import. The counts are
+// of what was tried, even if the import was rolled back.
+type ImportResult struct {
+	Added   int
+	Merged  int
+	Skipped int
+	Failed  int
+	// Errors holds the first rows that failed
+	Errors []ImportRowError
+	// Committed reports whether the import was committed
+	Committed bool
+}
+
+func (r *ImportResult) fail(row ImportRow, err error) {
+	r.Failed++
+
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, ImportRowError{Line: row.Line, Word: row.Word, Err: err})
+	}
+}
+
+// errRollback rolls back an import without it being reported as an error
+var errRollback = errors.New("rollback import")
+
+// ImportWords imports the rows returned by next, until it returns io.EOF, for
+// the user in a single transaction. Each row is tried in its own savepoint, so
+// a failed row doesn't stop the rest being tried, and its error is collected in
+// the result. Any other error from next stops the import and rolls it back.
+func (m *Manager) ImportWords(ctx context.Context, userID int32, next func() (ImportRow, error), opts ImportOptions) (ImportResult, error) {
+	result := ImportResult{Errors: []ImportRowError{}}
+
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		for {
+			row, err := next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if row.Err != nil {
+				result.fail(row, row.Err)
+				continue
+			}
+
+			if err := importRow(ctx, tx, userID, row, opts.OnDuplicate, &result); err != nil {
+				if kind := Classify(err); kind != nil && kind != ErrAlreadyExists {
+					// The database or context failed rather than the row
+					return err
+				}
+
+				result.fail(row, err)
+			}
+		}
+
+		if opts.DryRun || (result.Failed > 0 && !opts.SkipInvalid) {
+			return errRollback
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRollback) {
+		return result, errors.Wrap(err, "unable to import words")
+	}
+
+	result.Committed = err == nil
+
+	logrus.WithFields(logrus.Fields{
+		"userID":    userID,
+		"added":     result.Added,
+		"merged":    result.Merged,
+		"skipped":   result.Skipped,
+		"failed":    result.Failed,
+		"committed": result.Committed,
+	}).Info("Words imported successfully")
+
+	return result, nil
+}
+
+// importRow imports a row within a savepoint of tx, counting the outcome in
+// result unless it fails
+func importRow(ctx context.Context, tx pgx.Tx, userID int32, row ImportRow, policy DuplicatePolicy, result *ImportResult) error {
+	var outcome *int
+
+	err := tx.BeginFunc(ctx, func(sp pgx.Tx) error {
+		w, err := scanWord(sp.QueryRow(
+			ctx,
+			`INSERT INTO words(user_id, word, normalised_word, custom_definition) VALUES($1, $2, $3, $4)
+ON CONFLICT (user_id, normalised_word) DO NOTHING
+RETURNING `+wordColumns,
+			userID, cleanWord(row.Word), NormaliseWord(row.Word), row.CustomDefinition,
+		))
+		outcome = &result.Added
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			w, err = scanWord(sp.QueryRow(
+				ctx,
+				"SELECT "+wordColumns+" FROM words WHERE user_id=$1 AND normalised_word=$2 FOR UPDATE",
+				userID, NormaliseWord(row.Word),
+			))
+			if err != nil {
+				return err
+			}
+
+			switch policy {
+			case DuplicateMerge:
+				outcome = &result.Merged
+			case DuplicateError:
+				return &DuplicateWordError{Existing: w}
+			default:
+				outcome = &result.Skipped
+				return nil
+			}
+
+			if definition := mergeDefinitions(w.CustomDefinition, row.CustomDefinition); definition != w.CustomDefinition {
+				if _, err := sp.Exec(ctx, "UPDATE words SET custom_definition=$2, version=version+1, updated_at=now() WHERE id=$1", w.ID, definition); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range row.Tags {
+			if _, err := addTag(ctx, sp, userID, w.ID, tag); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*outcome++
+
+	return nil
+}
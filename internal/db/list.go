@@ -0,0 +1,157 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// WordOrder controls the order ListWords returns words in
+type WordOrder string
+
+const (
+	// OrderOldest returns the words in the order they were added
+	OrderOldest WordOrder = "oldest"
+	// OrderNewest returns the most recently added words first
+	OrderNewest WordOrder = "newest"
+	// OrderAlphabetical returns the words in case-insensitive alphabetical order
+	OrderAlphabetical WordOrder = "alphabetical"
+)
+
+// ParseWordOrder validates s as a WordOrder, defaulting to OrderOldest when s
+// is empty
+func ParseWordOrder(s string) (WordOrder, error) {
+	switch order := WordOrder(s); order {
+	case "":
+		return OrderOldest, nil
+	case OrderOldest, OrderNewest, OrderAlphabetical:
+		return order, nil
+	default:
+		return "", fmt.Errorf("unknown word order %q", s)
+	}
+}
+
+// WordCursor identifies the position of a word within a listing, so that the
+// next page can carry on from it
+type WordCursor struct {
+	ID   int32
+	Word string
+}
+
+// ListWordsOptions narrows down and orders the words returned by ListWords
+type ListWordsOptions struct {
+	// Limit is the maximum number of words returned, 0 meaning no limit
+	Limit int32
+	// After, if set, skips every word up to and including the one it identifies
+	After *WordCursor
+
+	// Prefix, if set, only returns words starting with it, ignoring case
+	Prefix string
+	// Contains, if set, only returns words containing it, ignoring case
+	Contains string
+	// Tag, if set, only returns words with the tag
+	Tag string
+
+	Order WordOrder
+}
+
+// ListWords returns the words belonging to the user matching opts
+func (m *Manager) ListWords(ctx context.Context, userID int32, opts ListWordsOptions) ([]Word, error) {
+	words := make([]Word, 0)
+
+	query, args, err := listWordsQuery(userID, opts)
+	if err != nil {
+		return words, err
+	}
+
+	rows, err := m.pool.Query(ctx, query, args...)
+	if err != nil {
+		return words, errors.Wrap(err, "unable to get words")
+	}
+	defer rows.Close()
+
+	rowCount := 0
+	for rows.Next() {
+		w, err := scanWord(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		words = append(words, w)
+
+		rowCount++
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	logrus.WithFields(logrus.Fields{"rowCount": rowCount, "userID": userID}).Info("Words queried successfully")
+
+	return words, nil
+}
+
+// listWordsQuery builds the SQL and arguments used by ListWords
+func listWordsQuery(userID int32, opts ListWordsOptions) (string, []interface{}, error) {
+	order, err := ParseWordOrder(string(opts.Order))
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	args := []interface{}{userID}
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	b.WriteString("SELECT " + wordColumns + " FROM words WHERE user_id=$1")
+
+	if opts.Prefix != "" {
+		b.WriteString(" AND lower(word) LIKE lower(" + arg(escapeLike(opts.Prefix)+"%") + ")")
+	}
+
+	if opts.Contains != "" {
+		b.WriteString(" AND lower(word) LIKE lower(" + arg("%"+escapeLike(opts.Contains)+"%") + ")")
+	}
+
+	if opts.Tag != "" {
+		b.WriteString(" AND EXISTS (SELECT 1 FROM word_tags wt JOIN tags t ON t.id = wt.tag_id WHERE wt.word_id = words.id AND t.normalised_name = " + arg(NormaliseWord(opts.Tag)) + ")")
+	}
+
+	switch order {
+	case OrderOldest:
+		if opts.After != nil {
+			b.WriteString(" AND id > " + arg(opts.After.ID))
+		}
+
+		b.WriteString(" ORDER BY id ASC")
+	case OrderNewest:
+		if opts.After != nil {
+			b.WriteString(" AND id < " + arg(opts.After.ID))
+		}
+
+		b.WriteString(" ORDER BY id DESC")
+	case OrderAlphabetical:
+		if opts.After != nil {
+			b.WriteString(" AND (lower(word), id) > (lower(" + arg(opts.After.Word) + "), " + arg(opts.After.ID) + ")")
+		}
+
+		b.WriteString(" ORDER BY lower(word) ASC, id ASC")
+	}
+
+	if opts.Limit > 0 {
+		b.WriteString(" LIMIT " + arg(opts.Limit))
+	}
+
+	return b.String(), args, nil
+}
+
+// escapeLike escapes the characters that have a special meaning in a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
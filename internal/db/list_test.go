@@ -0,0 +1,94 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseWordOrder(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		order       string
+		expected    WordOrder
+		expectedErr string
+	}{
+		{desc: "Empty order should default to oldest", order: "", expected: OrderOldest},
+		{desc: "Newest order should be accepted", order: "newest", expected: OrderNewest},
+		{desc: "Alphabetical order should be accepted", order: "alphabetical", expected: OrderAlphabetical},
+		{desc: "Unknown order should return error", order: "random", expectedErr: `unknown word order "random"`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			o, err := ParseWordOrder(tC.order)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, o)
+		})
+	}
+}
+
+func TestListWordsQuery(t *testing.T) {
+	const base = "SELECT " + wordColumns + " FROM words WHERE user_id=$1"
+
+	testCases := []struct {
+		desc         string
+		opts         ListWordsOptions
+		expected     string
+		expectedArgs []interface{}
+		expectedErr  string
+	}{
+		{
+			desc:         "No options should return every word oldest first",
+			opts:         ListWordsOptions{},
+			expected:     base + " ORDER BY id ASC",
+			expectedArgs: []interface{}{int32(1)},
+		},
+		{
+			desc:         "Newest order with a cursor and limit should page backwards by id",
+			opts:         ListWordsOptions{Order: OrderNewest, After: &WordCursor{ID: 10}, Limit: 5},
+			expected:     base + " AND id < $2 ORDER BY id DESC LIMIT $3",
+			expectedArgs: []interface{}{int32(1), int32(10), int32(5)},
+		},
+		{
+			desc:         "Alphabetical order with a cursor should page by word then id",
+			opts:         ListWordsOptions{Order: OrderAlphabetical, After: &WordCursor{ID: 10, Word: "Bravo"}},
+			expected:     base + " AND (lower(word), id) > (lower($2), $3) ORDER BY lower(word) ASC, id ASC",
+			expectedArgs: []interface{}{int32(1), "Bravo", int32(10)},
+		},
+		{
+			desc:         "Filters should be escaped LIKE patterns",
+			opts:         ListWordsOptions{Prefix: "a_", Contains: "50%"},
+			expected:     base + " AND lower(word) LIKE lower($2) AND lower(word) LIKE lower($3) ORDER BY id ASC",
+			expectedArgs: []interface{}{int32(1), `a\_%`, `%50\%%`},
+		},
+		{
+			desc:         "Tag filter should match the normalised tag name",
+			opts:         ListWordsOptions{Tag: " GRE "},
+			expected:     base + " AND EXISTS (SELECT 1 FROM word_tags wt JOIN tags t ON t.id = wt.tag_id WHERE wt.word_id = words.id AND t.normalised_name = $2) ORDER BY id ASC",
+			expectedArgs: []interface{}{int32(1), "gre"},
+		},
+		{
+			desc:        "Unknown order should return error",
+			opts:        ListWordsOptions{Order: "random"},
+			expectedErr: `unknown word order "random"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			q, args, err := listWordsQuery(1, tC.opts)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, q)
+			assert.Equal(t, tC.expectedArgs, args)
+		})
+	}
+}
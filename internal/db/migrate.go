@@ -0,0 +1,328 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the key used with pg_advisory_lock so that only one
+// instance applies migrations at a time
+const migrationLockID = 7_283_920_104
+
+var migrationFileRegex = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a single versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes whether a known migration has been applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// loadMigrations reads every migration in fsys, pairing up and down files by
+// version and returning them in ascending version order
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read migrations")
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		match := migrationFileRegex.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration filename %q", e.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version in %q", e.Name())
+		}
+
+		contents, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to read migration %q", e.Name())
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, mig.Name, match[2])
+		}
+
+		switch match[3] {
+		case "up":
+			mig.Up = string(contents)
+		case "down":
+			mig.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", mig.Version, mig.Name)
+		}
+
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", mig.Version, mig.Name)
+		}
+
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// embeddedMigrations returns the migrations compiled into the binary
+func embeddedMigrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open embedded migrations")
+	}
+
+	return loadMigrations(sub)
+}
+
+// MigrateUp applies every pending migration, returning the number applied
+func (m *Manager) MigrateUp(ctx context.Context) (int, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+
+	err = m.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if _, ok := versions[mig.Version]; ok {
+				continue
+			}
+
+			if err := runMigration(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations(version, name) VALUES($1, $2)", mig.Version, mig.Name); err != nil {
+				return errors.Wrapf(err, "unable to apply migration %d_%s", mig.Version, mig.Name)
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"version": mig.Version,
+				"name":    mig.Name,
+			}).Info("Migration applied successfully")
+
+			applied++
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the most recently applied migrations, up to steps of them
+func (m *Manager) MigrateDown(ctx context.Context, steps int) (int, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+
+	err = m.withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			mig := migrations[i]
+			if _, ok := versions[mig.Version]; !ok {
+				continue
+			}
+
+			if err := runMigration(ctx, conn, mig.Down,
+				"DELETE FROM schema_migrations WHERE version=$1", mig.Version); err != nil {
+				return errors.Wrapf(err, "unable to revert migration %d_%s", mig.Version, mig.Name)
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"version": mig.Version,
+				"name":    mig.Name,
+			}).Info("Migration reverted successfully")
+
+			reverted++
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// MigrationStatus lists every known migration alongside whether it has been
+// applied. It only reads the database, so it doesn't wait for migrations being
+// applied elsewhere, and reports every migration as pending if none have been.
+func (m *Manager) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := embeddedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := m.pool.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, errors.Wrap(err, "unable to check for schema_migrations table")
+	}
+
+	versions := make(map[int64]time.Time)
+	if exists {
+		if versions, err = appliedVersions(ctx, m.pool); err != nil {
+			return nil, err
+		}
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, mig := range migrations {
+		appliedAt, ok := versions[mig.Version]
+
+		status[i] = MigrationStatus{
+			Version:   mig.Version,
+			Name:      mig.Name,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		}
+	}
+
+	return status, nil
+}
+
+// SchemaVersion returns the highest applied migration version, or 0 if none
+// have been applied
+func (m *Manager) SchemaVersion(ctx context.Context) (int64, error) {
+	status, err := m.MigrationStatus(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var version int64
+	for _, s := range status {
+		if s.Applied && s.Version > version {
+			version = s.Version
+		}
+	}
+
+	return version, nil
+}
+
+// withMigrationLock holds a session level advisory lock on a single connection
+// for the duration of fn, making sure the tracking table exists first
+func (m *Manager) withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "unable to acquire connection")
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return errors.Wrap(err, "unable to acquire migration lock")
+	}
+
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx has been cancelled
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Unable to release migration lock")
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS "schema_migrations" (
+  "version" BIGINT PRIMARY KEY NOT NULL,
+  "name" VARCHAR(255) NOT NULL,
+  "applied_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return errors.Wrap(err, "unable to create schema_migrations table")
+	}
+
+	return fn(conn)
+}
+
+// querier is either the pool or a connection
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+func appliedVersions(ctx context.Context, q querier) (map[int64]time.Time, error) {
+	rows, err := q.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get applied migrations")
+	}
+	defer rows.Close()
+
+	versions := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		versions[version] = appliedAt
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return versions, nil
+}
+
+// runMigration executes the migration sql and the tracking statement in a single transaction
+func runMigration(ctx context.Context, conn *pgxpool.Conn, sql string, track string, args ...interface{}) error {
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(ctx, track, args...)
+		return err
+	})
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		files       fstest.MapFS
+		expected    []Migration
+		expectedErr string
+	}{
+		{
+			desc: "Migrations should be paired and sorted by version",
+			files: fstest.MapFS{
+				"0002_second.up.sql":   {Data: []byte("up 2")},
+				"0002_second.down.sql": {Data: []byte("down 2")},
+				"0001_first.up.sql":    {Data: []byte("up 1")},
+				"0001_first.down.sql":  {Data: []byte("down 1")},
+			},
+			expected: []Migration{
+				{Version: 1, Name: "first", Up: "up 1", Down: "down 1"},
+				{Version: 2, Name: "second", Up: "up 2", Down: "down 2"},
+			},
+		},
+		{
+			desc: "Invalid filename should return error",
+			files: fstest.MapFS{
+				"first.sql": {Data: []byte("up 1")},
+			},
+			expectedErr: `invalid migration filename "first.sql"`,
+		},
+		{
+			desc: "Missing down file should return error",
+			files: fstest.MapFS{
+				"0001_first.up.sql": {Data: []byte("up 1")},
+			},
+			expectedErr: "migration 1_first has no down file",
+		},
+		{
+			desc: "Missing up file should return error",
+			files: fstest.MapFS{
+				"0001_first.down.sql": {Data: []byte("down 1")},
+			},
+			expectedErr: "migration 1_first has no up file",
+		},
+		{
+			desc: "Conflicting names should return error",
+			files: fstest.MapFS{
+				"0001_first.up.sql":   {Data: []byte("up 1")},
+				"0001_other.down.sql": {Data: []byte("down 1")},
+			},
+			expectedErr: `migration version 1 has conflicting names "first" and "other"`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := loadMigrations(tC.files)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	t.Run("Given the embedded migrations", func(t *testing.T) {
+		t.Run("When they are loaded", func(t *testing.T) {
+			t.Run("Then they parse without error and versions are contiguous", func(t *testing.T) {
+				m, err := embeddedMigrations()
+				assert.NoError(t, err)
+				assert.NotEmpty(t, m)
+
+				for i, mig := range m {
+					assert.Equal(t, int64(i+1), mig.Version)
+				}
+			})
+		})
+	})
+}
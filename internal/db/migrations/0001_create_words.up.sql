@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS "words" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "word" VARCHAR(255) DEFAULT '',
+  "custom_definition" VARCHAR(255) DEFAULT ''
+);
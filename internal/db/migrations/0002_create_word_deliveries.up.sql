@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "word_deliveries" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "word_id" INTEGER NOT NULL REFERENCES "words" ("id") ON DELETE CASCADE,
+  "cycle" INTEGER NOT NULL DEFAULT 1,
+  "sent_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "word_deliveries_word_id_cycle_idx" ON "word_deliveries" ("word_id", "cycle");
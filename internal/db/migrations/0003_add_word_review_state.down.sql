@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "words_due_at_idx";
+
+ALTER TABLE "words"
+  DROP COLUMN IF EXISTS "ease_factor",
+  DROP COLUMN IF EXISTS "interval_days",
+  DROP COLUMN IF EXISTS "repetitions",
+  DROP COLUMN IF EXISTS "due_at";
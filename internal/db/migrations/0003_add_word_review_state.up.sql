@@ -0,0 +1,7 @@
+ALTER TABLE "words"
+  ADD COLUMN IF NOT EXISTS "ease_factor" DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+  ADD COLUMN IF NOT EXISTS "interval_days" INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "repetitions" INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS "due_at" TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS "words_due_at_idx" ON "words" ("due_at");
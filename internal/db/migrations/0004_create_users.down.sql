@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "word_deliveries_user_id_cycle_idx";
+ALTER TABLE "word_deliveries" DROP COLUMN IF EXISTS "user_id";
+
+DROP INDEX IF EXISTS "words_user_id_idx";
+ALTER TABLE "words" DROP COLUMN IF EXISTS "user_id";
+
+DROP TABLE IF EXISTS "users";
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS "users" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "username" VARCHAR(255) NOT NULL UNIQUE,
+  "email" VARCHAR(255) NOT NULL DEFAULT '',
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Words added before users existed belong to the default user
+INSERT INTO "users" ("id", "username") VALUES (1, 'default') ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('users', 'id'), (SELECT MAX("id") FROM "users"));
+
+ALTER TABLE "words" ADD COLUMN IF NOT EXISTS "user_id" INTEGER NOT NULL DEFAULT 1 REFERENCES "users" ("id") ON DELETE CASCADE;
+ALTER TABLE "words" ALTER COLUMN "user_id" DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS "words_user_id_idx" ON "words" ("user_id");
+
+ALTER TABLE "word_deliveries" ADD COLUMN IF NOT EXISTS "user_id" INTEGER NOT NULL DEFAULT 1 REFERENCES "users" ("id") ON DELETE CASCADE;
+UPDATE "word_deliveries" d SET "user_id" = w."user_id" FROM "words" w WHERE w."id" = d."word_id";
+ALTER TABLE "word_deliveries" ALTER COLUMN "user_id" DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS "word_deliveries_user_id_cycle_idx" ON "word_deliveries" ("user_id", "cycle");
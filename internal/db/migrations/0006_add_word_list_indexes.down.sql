@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "words_user_id_lower_word_idx";
+DROP INDEX IF EXISTS "words_user_id_id_idx";
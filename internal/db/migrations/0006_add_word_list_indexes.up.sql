@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS "words_user_id_id_idx" ON "words" ("user_id", "id");
+CREATE INDEX IF NOT EXISTS "words_user_id_lower_word_idx" ON "words" ("user_id", lower("word"), "id");
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS "words_user_id_normalised_word_key";
+ALTER TABLE "words" DROP COLUMN IF EXISTS "normalised_word";
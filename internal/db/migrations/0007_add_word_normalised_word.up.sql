@@ -0,0 +1,80 @@
+DROP INDEX IF EXISTS "words_user_id_normalised_word_key";
+ALTER TABLE "words" DROP COLUMN IF EXISTS "normalised_word";
+ALTER TABLE "words" ADD COLUMN IF NOT EXISTS "normalised_word" VARCHAR(255);
+
+-- Existing words are cleaned and normalised as cleanWord and NormaliseWord do.
+-- Postgres has no case folding, so it's made of lower-casing, which needs a
+-- UTF-8 database, and the folds that differ from it: iota subscripts, which
+-- decomposing separates out, Greek and Cyrillic letter variants, ß and the
+-- ligatures. NormaliseWord folds Cherokee by swapping its case, so that's
+-- swapped rather than lower-cased.
+CREATE FUNCTION pg_temp."normalise_word"(s TEXT) RETURNS TEXT LANGUAGE plpgsql IMMUTABLE AS $$
+DECLARE
+  cherokee CONSTANT TEXT := 'ᎠᎡᎢᎣᎤᎥᎦᎧᎨᎩᎪᎫᎬᎭᎮᎯᎰᎱᎲᎳᎴᎵᎶᎷᎸᎹᎺᎻᎼᎽᎾᎿᏀᏁᏂᏃᏄᏅᏆᏇᏈᏉᏊᏋᏌᏍᏎᏏᏐᏑᏒᏓᏔᏕᏖᏗᏘᏙᏚᏛᏜᏝᏞᏟᏠᏡᏢᏣᏤᏥᏦᏧᏨᏩᏪᏫᏬᏭᏮᏯᏰᏱᏲᏳᏴᏵ' || 'ꭰꭱꭲꭳꭴꭵꭶꭷꭸꭹꭺꭻꭼꭽꭾꭿꮀꮁꮂꮃꮄꮅꮆꮇꮈꮉꮊꮋꮌꮍꮎꮏꮐꮑꮒꮓꮔꮕꮖꮗꮘꮙꮚꮛꮜꮝꮞꮟꮠꮡꮢꮣꮤꮥꮦꮧꮨꮩꮪꮫꮬꮭꮮꮯꮰꮱꮲꮳꮴꮵꮶꮷꮸꮹꮺꮻꮼꮽꮾꮿᏸᏹᏺᏻᏼᏽ';
+  swapped CONSTANT TEXT := 'ꭰꭱꭲꭳꭴꭵꭶꭷꭸꭹꭺꭻꭼꭽꭾꭿꮀꮁꮂꮃꮄꮅꮆꮇꮈꮉꮊꮋꮌꮍꮎꮏꮐꮑꮒꮓꮔꮕꮖꮗꮘꮙꮚꮛꮜꮝꮞꮟꮠꮡꮢꮣꮤꮥꮦꮧꮨꮩꮪꮫꮬꮭꮮꮯꮰꮱꮲꮳꮴꮵꮶꮷꮸꮹꮺꮻꮼꮽꮾꮿᏸᏹᏺᏻᏼᏽ' || 'ᎠᎡᎢᎣᎤᎥᎦᎧᎨᎩᎪᎫᎬᎭᎮᎯᎰᎱᎲᎳᎴᎵᎶᎷᎸᎹᎺᎻᎼᎽᎾᎿᏀᏁᏂᏃᏄᏅᏆᏇᏈᏉᏊᏋᏌᏍᏎᏏᏐᏑᏒᏓᏔᏕᏖᏗᏘᏙᏚᏛᏜᏝᏞᏟᏠᏡᏢᏣᏤᏥᏦᏧᏨᏩᏪᏫᏬᏭᏮᏯᏰᏱᏲᏳᏴᏵ';
+  folded TEXT := '';
+  c TEXT;
+  f TEXT[];
+BEGIN
+  FOREACH c IN ARRAY regexp_split_to_array(normalize(s, NFD), '') LOOP
+    IF strpos(cherokee, c) > 0 THEN
+      folded := folded || translate(c, cherokee, swapped);
+    ELSE
+      folded := folded || lower(c);
+    END IF;
+  END LOOP;
+
+  folded := translate(folded, 'ͅµſςϐϑϕϖϰϱϵᲀᲁᲂᲃᲄᲅᲆᲇᲈ', 'ιμsσβθφπκρεвдосттъѣꙋ');
+
+  FOREACH f SLICE 1 IN ARRAY ARRAY[
+    ['ß', 'ss'], ['ŉ', 'ʼn'], ['և', 'եւ'], ['ẚ', 'aʾ'],
+    ['ﬀ', 'ff'], ['ﬁ', 'fi'], ['ﬂ', 'fl'], ['ﬃ', 'ffi'],
+    ['ﬄ', 'ffl'], ['ﬅ', 'st'], ['ﬆ', 'st'], ['ﬓ', 'մն'],
+    ['ﬔ', 'մե'], ['ﬕ', 'մի'], ['ﬖ', 'վն'], ['ﬗ', 'մխ']
+  ] LOOP
+    folded := replace(folded, f[1], f[2]);
+  END LOOP;
+
+  RETURN normalize(folded, NFC);
+END
+$$;
+
+UPDATE "words" SET "word" = normalize(btrim(COALESCE("word", ''), E' \t\n\x0B\f\r\u0085\u00A0\u1680\u2000\u2001\u2002\u2003\u2004\u2005\u2006\u2007\u2008\u2009\u200A\u2028\u2029\u202F\u205F\u3000'), NFC);
+UPDATE "words" SET "normalised_word" = pg_temp."normalise_word"("word");
+
+DROP FUNCTION pg_temp."normalise_word"(TEXT);
+
+-- Duplicates are merged into the oldest copy of the word rather than deleted,
+-- combining their definitions as mergeDefinitions does and keeping their
+-- deliveries
+CREATE TEMPORARY TABLE "word_duplicates" ON COMMIT DROP AS
+SELECT "id", "kept_id" FROM (
+  SELECT "id", MIN("id") OVER (PARTITION BY "user_id", "normalised_word") AS "kept_id" FROM "words"
+) w
+WHERE "id" <> "kept_id";
+
+UPDATE "words" k SET "custom_definition" = d."definitions"
+FROM (
+  SELECT "kept_id", left(string_agg("definition", '; ' ORDER BY "first_id"), 255) AS "definitions"
+  FROM (
+    SELECT
+      COALESCE(d."kept_id", w."id") AS "kept_id",
+      MIN(w."id") AS "first_id",
+      (array_agg(btrim(w."custom_definition") ORDER BY w."id"))[1] AS "definition"
+    FROM "words" w
+    LEFT JOIN "word_duplicates" d ON d."id" = w."id"
+    WHERE btrim(COALESCE(w."custom_definition", '')) <> ''
+    GROUP BY 1, lower(btrim(w."custom_definition"))
+  ) u
+  GROUP BY "kept_id"
+) d
+WHERE k."id" = d."kept_id" AND k."id" IN (SELECT "kept_id" FROM "word_duplicates");
+
+UPDATE "word_deliveries" SET "word_id" = d."kept_id"
+FROM "word_duplicates" d
+WHERE "word_deliveries"."word_id" = d."id";
+
+DELETE FROM "words" WHERE "id" IN (SELECT "id" FROM "word_duplicates");
+
+ALTER TABLE "words" ALTER COLUMN "normalised_word" SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS "words_user_id_normalised_word_key" ON "words" ("user_id", "normalised_word");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "word_pronunciations";
+DROP TABLE IF EXISTS "word_senses";
@@ -0,0 +1,24 @@
+-- Senses and pronunciations looked up from a dictionary. The source names the
+-- dictionary so they can be replaced without touching any added by hand.
+CREATE TABLE IF NOT EXISTS "word_senses" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "word_id" INTEGER NOT NULL REFERENCES "words" ("id") ON DELETE CASCADE,
+  "position" INTEGER NOT NULL,
+  "source" VARCHAR(255) NOT NULL DEFAULT '',
+  "part_of_speech" VARCHAR(64) NOT NULL DEFAULT '',
+  "definition" TEXT NOT NULL,
+  "examples" TEXT[] NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS "word_senses_word_id_position_idx" ON "word_senses" ("word_id", "position");
+
+CREATE TABLE IF NOT EXISTS "word_pronunciations" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "word_id" INTEGER NOT NULL REFERENCES "words" ("id") ON DELETE CASCADE,
+  "position" INTEGER NOT NULL,
+  "source" VARCHAR(255) NOT NULL DEFAULT '',
+  "ipa" VARCHAR(255) NOT NULL DEFAULT '',
+  "audio_url" TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS "word_pronunciations_word_id_position_idx" ON "word_pronunciations" ("word_id", "position");
@@ -0,0 +1,5 @@
+ALTER TABLE "word_senses"
+  DROP COLUMN IF EXISTS "ipa",
+  DROP COLUMN IF EXISTS "etymology",
+  DROP COLUMN IF EXISTS "antonyms",
+  DROP COLUMN IF EXISTS "synonyms";
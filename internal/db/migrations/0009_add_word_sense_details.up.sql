@@ -0,0 +1,7 @@
+-- Senses written by hand have an empty source, so looking a word up in a
+-- dictionary again never replaces them
+ALTER TABLE "word_senses"
+  ADD COLUMN IF NOT EXISTS "synonyms" TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS "antonyms" TEXT[] NOT NULL DEFAULT '{}',
+  ADD COLUMN IF NOT EXISTS "etymology" TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS "ipa" VARCHAR(255) NOT NULL DEFAULT '';
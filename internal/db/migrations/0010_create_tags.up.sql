@@ -0,0 +1,19 @@
+-- Tags are matched by their normalised name, like words, so "GRE" and "gre"
+-- are the same tag
+CREATE TABLE IF NOT EXISTS "tags" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" INTEGER NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(64) NOT NULL,
+  "normalised_name" VARCHAR(64) NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "tags_user_id_normalised_name_key" ON "tags" ("user_id", "normalised_name");
+
+CREATE TABLE IF NOT EXISTS "word_tags" (
+  "word_id" INTEGER NOT NULL REFERENCES "words" ("id") ON DELETE CASCADE,
+  "tag_id" INTEGER NOT NULL REFERENCES "tags" ("id") ON DELETE CASCADE,
+  PRIMARY KEY ("word_id", "tag_id")
+);
+
+CREATE INDEX IF NOT EXISTS "word_tags_tag_id_idx" ON "word_tags" ("tag_id", "word_id");
@@ -0,0 +1,9 @@
+-- Words added before this column existed are given the time they were last
+-- updated, the earliest time known for them
+ALTER TABLE "words" ADD COLUMN IF NOT EXISTS "created_at" TIMESTAMPTZ;
+
+UPDATE "words" SET "created_at" = "updated_at" WHERE "created_at" IS NULL;
+
+ALTER TABLE "words"
+  ALTER COLUMN "created_at" SET DEFAULT now(),
+  ALTER COLUMN "created_at" SET NOT NULL;
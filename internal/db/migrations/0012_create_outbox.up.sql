@@ -0,0 +1,20 @@
+-- Each row is an email to a single recipient, rendered when it was queued so
+-- retrying it sends exactly the same message. Rows stay pending until they're
+-- sent, or fail permanently and are dead-lettered as failed.
+CREATE TABLE IF NOT EXISTS "outbox" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" INTEGER NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "word_id" INTEGER REFERENCES "words" ("id") ON DELETE SET NULL,
+  "recipient" VARCHAR(320) NOT NULL,
+  "subject" TEXT NOT NULL,
+  "message" BYTEA NOT NULL,
+  "status" VARCHAR(16) NOT NULL DEFAULT 'pending',
+  "attempts" INTEGER NOT NULL DEFAULT 0,
+  "last_error" TEXT NOT NULL DEFAULT '',
+  "next_attempt_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+  "sent_at" TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS "outbox_next_attempt_at_idx" ON "outbox" ("next_attempt_at") WHERE "status" = 'pending';
+CREATE INDEX IF NOT EXISTS "outbox_user_id_status_idx" ON "outbox" ("user_id", "status", "id");
@@ -0,0 +1,11 @@
+-- Each row is somewhere other than their inbox that a user is sent their word
+-- of the day, such as a chat room or push service. The url holds the kind of
+-- channel and any secrets it needs, as understood by notifier.New.
+CREATE TABLE IF NOT EXISTS "channels" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" INTEGER NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "url" TEXT NOT NULL,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "channels_user_id_idx" ON "channels" ("user_id");
@@ -0,0 +1,14 @@
+-- Each row is a pair of email templates a user has written. The one that's
+-- active, if any, is used for their emails instead of the embedded default.
+CREATE TABLE IF NOT EXISTS "templates" (
+  "id" SERIAL PRIMARY KEY NOT NULL,
+  "user_id" INTEGER NOT NULL REFERENCES "users" ("id") ON DELETE CASCADE,
+  "name" VARCHAR(100) NOT NULL,
+  "html" TEXT NOT NULL,
+  "text" TEXT NOT NULL,
+  "active" BOOLEAN NOT NULL DEFAULT false,
+  "created_at" TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE ("user_id", "name")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "templates_user_id_active_idx" ON "templates" ("user_id") WHERE "active";
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// cleanWord returns the form of s that is stored: trimmed and NFC normalised,
+// so visually identical spellings are stored identically
+func cleanWord(s string) string {
+	return norm.NFC.String(strings.TrimSpace(s))
+}
+
+// NormaliseWord returns the form of s used to detect duplicate words. It is
+// cleaned and then case folded, so "Café", "café " and "CAFÉ" are all
+// the same word.
+func NormaliseWord(s string) string {
+	// Folding can leave the string denormalised, so normalise again afterwards
+	return norm.NFC.String(cases.Fold().String(cleanWord(s)))
+}
+
+// mergeDefinitions appends next to current, unless it is empty or current
+// already contains it. The result is cut to maxDefinitionLength characters, as
+// the definitions combined by migration 0007 are.
+func mergeDefinitions(current, next string) string {
+	next = strings.TrimSpace(next)
+	if next == "" {
+		return current
+	}
+
+	if current == "" {
+		return next
+	}
+
+	for _, d := range strings.Split(current, definitionSeparator) {
+		if strings.EqualFold(strings.TrimSpace(d), next) {
+			return current
+		}
+	}
+
+	return truncate(current+definitionSeparator+next, maxDefinitionLength)
+}
+
+const (
+	// definitionSeparator separates the definitions combined by
+	// mergeDefinitions
+	definitionSeparator = "; "
+	// maxDefinitionLength is the size of the custom_definition column, in
+	// characters
+	maxDefinitionLength = 255
+)
+
+// truncate returns the first n characters of s, as Postgres' left does
+func truncate(s string, n int) string {
+	chars := 0
+	for i := range s {
+		if chars == n {
+			return s[:i]
+		}
+
+		chars++
+	}
+
+	return s
+}
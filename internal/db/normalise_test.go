@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormaliseWord(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		word     string
+		expected string
+	}{
+		{desc: "Surrounding whitespace should be trimmed", word: "  sonder\t\n", expected: "sonder"},
+		{desc: "Capitals should be folded", word: "Floccinaucinihilipilification", expected: "floccinaucinihilipilification"},
+		{desc: "Decomposed characters should be composed", word: "cafe\u0301", expected: "caf\u00e9"},
+		{desc: "Composed capitals should be folded", word: "CAF\u00c9", expected: "caf\u00e9"},
+		{desc: "Characters without a lower case should be fully folded", word: "Straße", expected: "strasse"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, NormaliseWord(tC.word))
+		})
+	}
+}
+
+func TestCleanWord(t *testing.T) {
+	assert.Equal(t, "Café", cleanWord(" Café "))
+}
+
+func TestMergeDefinitions(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		current  string
+		next     string
+		expected string
+	}{
+		{desc: "Empty new definition should keep the current one", current: "a definition", next: " ", expected: "a definition"},
+		{desc: "Empty current definition should be replaced", current: "", next: "a definition", expected: "a definition"},
+		{desc: "Known definition should not be repeated", current: "first; Second", next: "second", expected: "first; Second"},
+		{desc: "New definition should be appended", current: "first", next: "second", expected: "first; second"},
+		{desc: "Merged definitions should be cut to the column's length", current: strings.Repeat("é", 250), next: "second", expected: strings.Repeat("é", 250) + "; sec"},
+		{desc: "A full definition should be kept", current: strings.Repeat("a", 255), next: "second", expected: strings.Repeat("a", 255)},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, mergeDefinitions(tC.current, tC.next))
+		})
+	}
+}
+
+func TestDuplicateWordError(t *testing.T) {
+	var err error = &DuplicateWordError{Existing: Word{ID: 45, Word: "sonder"}}
+
+	assert.True(t, errors.Is(errors.Wrap(err, "unable to add word"), ErrAlreadyExists))
+	assert.EqualError(t, err, `word "sonder" already exists with id 45`)
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// OutboxStatus is where an OutboxMessage is in being delivered
+type OutboxStatus string
+
+const (
+	// OutboxPending messages are waiting to be sent, or retried after a
+	// temporary failure
+	OutboxPending OutboxStatus = "pending"
+	// OutboxSent messages have been accepted by the mail server
+	OutboxSent OutboxStatus = "sent"
+	// OutboxFailed messages failed permanently, or too many times, and won't
+	// be tried again unless they're retried by hand
+	OutboxFailed OutboxStatus = "failed"
+)
+
+// ParseOutboxStatus validates s as an OutboxStatus. An empty s is returned as
+// is, to mean any status.
+func ParseOutboxStatus(s string) (OutboxStatus, error) {
+	switch st := OutboxStatus(s); st {
+	case "", OutboxPending, OutboxSent, OutboxFailed:
+		return st, nil
+	default:
+		return "", fmt.Errorf("unknown outbox status %q", s)
+	}
+}
+
+// OutboxMessage is an email to a single recipient, queued to be sent
+type OutboxMessage struct {
+	ID     int32
+	UserID int32
+	// WordID is the word the message is about, or 0 if it has been deleted
+	WordID    int32
+	Recipient string
+	Subject   string
+	// Message is the rendered RFC 5322 message
+	Message []byte
+
+	Status   OutboxStatus
+	Attempts int32
+	// LastError is why the last attempt to send the message failed
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	// SentAt is nil until the message has been sent
+	SentAt *time.Time
+}
+
+// outboxColumns are the columns scanned by scanOutboxMessage, in order
+const outboxColumns = "id, user_id, COALESCE(word_id, 0), recipient, subject, message, status, attempts, last_error, next_attempt_at, created_at, sent_at"
+
+func scanOutboxMessage(row pgx.Row) (OutboxMessage, error) {
+	m := OutboxMessage{}
+
+	var status string
+	err := row.Scan(
+		&m.ID, &m.UserID, &m.WordID, &m.Recipient, &m.Subject, &m.Message,
+		&status, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.CreatedAt, &m.SentAt,
+	)
+	m.Status = OutboxStatus(status)
+
+	return m, err
+}
+
+// EnqueueMessages queues messages about the word to be sent to the user, and
+// records the word's delivery as RecordDelivery does, in a single transaction
+// so the rotation only moves on once the messages are sure to be sent.
+// ErrNotFound is returned if the word doesn't belong to the user.
+func (m *Manager) EnqueueMessages(ctx context.Context, userID int32, wordID int32, messages []OutboxMessage) error {
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := recordDelivery(ctx, tx, userID, wordID); err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if _, err := tx.Exec(
+				ctx,
+				"INSERT INTO outbox(user_id, word_id, recipient, subject, message) VALUES($1, $2, $3, $4, $5)",
+				userID, wordID, msg.Recipient, msg.Subject, msg.Message,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to enqueue messages")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":       wordID,
+		"userID":   userID,
+		"messages": len(messages),
+	}).Info("Messages enqueued successfully")
+
+	return nil
+}
+
+// ClaimMessages returns up to limit pending messages that are due to be sent,
+// oldest first. Each is counted as an attempt and hidden from other callers for
+// lease, so several workers can share the outbox, and a message claimed by a
+// worker that dies is tried again once its lease expires.
+func (m *Manager) ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	rows, err := m.pool.Query(
+		ctx,
+		`UPDATE outbox SET attempts = attempts + 1, next_attempt_at = now() + $2::interval
+WHERE id IN (
+  SELECT id FROM outbox WHERE status = 'pending' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED
+)
+RETURNING `+outboxColumns,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to claim messages")
+	}
+
+	return collectOutboxMessages(rows)
+}
+
+// MarkMessageSent records that the message was accepted by the mail server
+func (m *Manager) MarkMessageSent(ctx context.Context, id int32) error {
+	return m.updateMessage(
+		ctx,
+		"UPDATE outbox SET status = 'sent', sent_at = now(), last_error = '' WHERE id = $1",
+		id,
+	)
+}
+
+// RetryMessageAt records that sending the message failed temporarily, so it's
+// tried again at next
+func (m *Manager) RetryMessageAt(ctx context.Context, id int32, next time.Time, lastError string) error {
+	return m.updateMessage(
+		ctx,
+		"UPDATE outbox SET next_attempt_at = $2, last_error = $3 WHERE id = $1",
+		id, next, lastError,
+	)
+}
+
+// FailMessage dead-letters the message, so it isn't tried again unless it's
+// retried with RetryMessages
+func (m *Manager) FailMessage(ctx context.Context, id int32, lastError string) error {
+	return m.updateMessage(
+		ctx,
+		"UPDATE outbox SET status = 'failed', last_error = $2 WHERE id = $1",
+		id, lastError,
+	)
+}
+
+// ReleaseMessages returns claimed messages that weren't tried to the queue,
+// to be claimed again straight away without counting as an attempt
+func (m *Manager) ReleaseMessages(ctx context.Context, ids []int32) error {
+	tag, err := m.pool.Exec(
+		ctx,
+		"UPDATE outbox SET attempts = GREATEST(attempts - 1, 0), next_attempt_at = now() WHERE id = ANY($1::int[]) AND status = 'pending'",
+		ids,
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to release messages")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"released": tag.RowsAffected(),
+	}).Info("Messages released successfully")
+
+	return nil
+}
+
+func (m *Manager) updateMessage(ctx context.Context, query string, id int32, args ...interface{}) error {
+	tag, err := m.pool.Exec(ctx, query, append([]interface{}{id}, args...)...)
+	if err != nil {
+		return errors.Wrap(err, "unable to update message")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ListMessages returns the user's messages with the status, or every message
+// if status is empty, newest first
+func (m *Manager) ListMessages(ctx context.Context, userID int32, status OutboxStatus, limit int) ([]OutboxMessage, error) {
+	rows, err := m.pool.Query(
+		ctx,
+		"SELECT "+outboxColumns+" FROM outbox WHERE user_id = $1 AND ($2::text = '' OR status = $2::text) ORDER BY id DESC LIMIT $3",
+		userID, string(status), limit,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list messages")
+	}
+
+	return collectOutboxMessages(rows)
+}
+
+// RetryMessages returns the user's failed messages with the given ids, or all
+// of them if ids is empty, to the queue to be sent as soon as possible with
+// their attempts reset. The messages that were retried are returned, so ids of
+// messages that don't exist, or haven't failed, are left out.
+func (m *Manager) RetryMessages(ctx context.Context, userID int32, ids []int32) ([]OutboxMessage, error) {
+	if ids == nil {
+		// A nil slice is sent as NULL rather than an empty array
+		ids = []int32{}
+	}
+
+	rows, err := m.pool.Query(
+		ctx,
+		`UPDATE outbox SET status = 'pending', attempts = 0, next_attempt_at = now()
+WHERE user_id = $1 AND status = 'failed' AND (cardinality($2::int[]) = 0 OR id = ANY($2::int[]))
+RETURNING `+outboxColumns,
+		userID, ids,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to retry messages")
+	}
+
+	retried, err := collectOutboxMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID":  userID,
+		"retried": len(retried),
+	}).Info("Messages retried successfully")
+
+	return retried, nil
+}
+
+func collectOutboxMessages(rows pgx.Rows) ([]OutboxMessage, error) {
+	defer rows.Close()
+
+	messages := []OutboxMessage{}
+	for rows.Next() {
+		msg, err := scanOutboxMessage(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		messages = append(messages, msg)
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return messages, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+)
+
+// randomWordCandidates is the number of ids RandomWord samples before falling
+// back to randomWordQuery
+const randomWordCandidates = 16
+
+// randomWordQuery picks a uniformly random word belonging to the user, with
+// the tag in $2 if it isn't empty, by skipping a random number of rows along
+// the (user_id, id) index, which avoids sorting or transferring the whole table
+const randomWordQuery = `SELECT ` + wordColumns + ` FROM words w WHERE w.user_id=$1 AND ` + taggedWith + ` ORDER BY w.id
+OFFSET floor(random() * (SELECT count(*) FROM words w WHERE w.user_id=$1 AND ` + taggedWith + `))::bigint LIMIT 1`
+
+// RandomWord returns a uniformly random word belonging to the user, or
+// ErrNotFound if they have none. If tag isn't empty, only words with that tag
+// are picked from.
+//
+// Ids are sampled from between the user's lowest and highest word ids and the
+// first sampled id that exists is returned. Rejecting the ids left behind by
+// deleted words, and those of other users, keeps the choice uniform. If none
+// of the samples exist randomWordQuery is used instead.
+func (m *Manager) RandomWord(ctx context.Context, userID int32, tag string) (Word, error) {
+	var lo, hi int32
+
+	tag = NormaliseWord(tag)
+
+	err := m.pool.QueryRow(ctx, "SELECT COALESCE(MIN(w.id), 0), COALESCE(MAX(w.id), 0) FROM words w WHERE w.user_id=$1 AND "+taggedWith, userID, tag).Scan(&lo, &hi)
+	if err != nil {
+		return Word{}, errors.Wrap(err, "unable to get word id range")
+	}
+
+	if hi == 0 {
+		return Word{}, ErrNotFound
+	}
+
+	candidates, err := sampleIDs(lo, hi, randomWordCandidates)
+	if err != nil {
+		return Word{}, errors.Wrap(err, "unable to sample word ids")
+	}
+
+	rows, err := m.pool.Query(ctx, "SELECT "+wordColumns+" FROM words w WHERE w.user_id=$1 AND "+taggedWith+" AND w.id = ANY($3)", userID, tag, candidates)
+	if err != nil {
+		return Word{}, errors.Wrap(err, "unable to get words")
+	}
+	defer rows.Close()
+
+	found := make(map[int32]Word)
+	for rows.Next() {
+		w, err := scanWord(rows)
+		if err != nil {
+			return Word{}, errors.Wrap(err, "unable to scan row")
+		}
+
+		found[w.ID] = w
+	}
+
+	if rows.Err() != nil {
+		return Word{}, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	for _, id := range candidates {
+		if w, ok := found[id]; ok {
+			return w, nil
+		}
+	}
+
+	w, err := m.queryWord(ctx, randomWordQuery, userID, tag)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Word{}, ErrNotFound
+	}
+
+	if err != nil {
+		return w, errors.Wrap(err, "unable to get random word")
+	}
+
+	return w, nil
+}
+
+// sampleIDs returns n ids chosen uniformly at random from [lo, hi]
+func sampleIDs(lo, hi int32, n int) ([]int32, error) {
+	ids := make([]int32, n)
+	span := big.NewInt(int64(hi) - int64(lo) + 1)
+
+	for i := range ids {
+		r, err := rand.Int(rand.Reader, span)
+		if err != nil {
+			return nil, err
+		}
+
+		ids[i] = lo + int32(r.Int64())
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleIDs(t *testing.T) {
+	t.Run("Given an id range", func(t *testing.T) {
+		t.Run("When ids are sampled from it", func(t *testing.T) {
+			t.Run("Then every id is within the range", func(t *testing.T) {
+				ids, err := sampleIDs(40, 45, 1000)
+				assert.NoError(t, err)
+				assert.Len(t, ids, 1000)
+
+				seen := make(map[int32]bool)
+				for _, id := range ids {
+					assert.True(t, id >= 40 && id <= 45)
+					seen[id] = true
+				}
+
+				// With 1000 samples every id in the range is all but certain to appear
+				assert.Len(t, seen, 6)
+			})
+		})
+		t.Run("When the range holds a single id", func(t *testing.T) {
+			t.Run("Then only that id is returned", func(t *testing.T) {
+				ids, err := sampleIDs(7, 7, 3)
+				assert.NoError(t, err)
+				assert.Equal(t, []int32{7, 7, 7}, ids)
+			})
+		})
+	})
+}
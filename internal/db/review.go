@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mywordoftheday/backend/internal/srs"
+)
+
+// ReviewWord grades how well the word was recalled and reschedules it
+// accordingly. ErrNotFound is returned if the word does not exist or doesn't
+// belong to the user.
+func (m *Manager) ReviewWord(ctx context.Context, userID int32, id int32, grade srs.Grade) (Word, error) {
+	var w Word
+
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		current, err := scanWord(tx.QueryRow(ctx, "SELECT "+wordColumns+" FROM words WHERE id=$1 AND user_id=$2 FOR UPDATE", id, userID))
+		if err != nil {
+			return err
+		}
+
+		next := srs.Schedule(srs.State{
+			EaseFactor:   current.EaseFactor,
+			IntervalDays: current.IntervalDays,
+			Repetitions:  current.Repetitions,
+			DueAt:        current.DueAt,
+		}, grade, time.Now())
+
+		w, err = scanWord(tx.QueryRow(
+			ctx,
+			"UPDATE words SET ease_factor=$2, interval_days=$3, repetitions=$4, due_at=$5 WHERE id=$1 RETURNING "+wordColumns,
+			id, next.EaseFactor, next.IntervalDays, next.Repetitions, next.DueAt,
+		))
+
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return w, ErrNotFound
+	}
+
+	if err != nil {
+		return w, errors.Wrap(err, "unable to review word")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":          w.ID,
+		"grade":       grade.String(),
+		"dueAt":       w.DueAt,
+		"repetitions": w.Repetitions,
+	}).Info("Word reviewed successfully")
+
+	return w, nil
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Sense is one meaning of a word
+type Sense struct {
+	ID     int32
+	WordID int32
+	// Source names the dictionary the sense was looked up in, and is empty for
+	// senses written by hand
+	Source       string
+	PartOfSpeech string
+	Definition   string
+	Examples     []string
+	Synonyms     []string
+	Antonyms     []string
+	Etymology    string
+	// IPA is the pronunciation of the word in this sense, for words like "lead"
+	// whose pronunciation depends on their meaning
+	IPA string
+}
+
+// senseColumns are the columns scanned by scanSense, in order
+const senseColumns = "id, word_id, source, part_of_speech, definition, examples, synonyms, antonyms, etymology, ipa"
+
+func scanSense(row pgx.Row) (Sense, error) {
+	s := Sense{}
+
+	err := row.Scan(&s.ID, &s.WordID, &s.Source, &s.PartOfSpeech, &s.Definition, &s.Examples, &s.Synonyms, &s.Antonyms, &s.Etymology, &s.IPA)
+
+	return s, err
+}
+
+// senseOrder puts the senses written by hand before those from dictionaries
+const senseOrder = "ORDER BY source <> '', position, id"
+
+// Pronunciation is one way of saying a word
+type Pronunciation struct {
+	// Source names the dictionary the pronunciation was looked up in
+	Source   string
+	IPA      string
+	AudioURL string
+}
+
+// Definitions are the senses and pronunciations of a word. Senses written by
+// hand come first.
+type Definitions struct {
+	Senses         []Sense
+	Pronunciations []Pronunciation
+}
+
+// ReplaceDefinitions replaces the senses and pronunciations of the user's word
+// that came from source with those in d, whose own ID, WordID and Source fields
+// are ignored. ErrNotFound is returned if the word doesn't exist.
+func (m *Manager) ReplaceDefinitions(ctx context.Context, userID int32, wordID int32, source string, d Definitions) error {
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var id int32
+		err := tx.QueryRow(ctx, "SELECT id FROM words WHERE id=$1 AND user_id=$2 FOR UPDATE", wordID, userID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM word_senses WHERE word_id=$1 AND source=$2", wordID, source); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM word_pronunciations WHERE word_id=$1 AND source=$2", wordID, source); err != nil {
+			return err
+		}
+
+		for i, s := range d.Senses {
+			if _, err := tx.Exec(
+				ctx,
+				"INSERT INTO word_senses(word_id, position, source, part_of_speech, definition, examples, synonyms, antonyms, etymology, ipa) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+				wordID, i, source, s.PartOfSpeech, s.Definition, nonNil(s.Examples), nonNil(s.Synonyms), nonNil(s.Antonyms), s.Etymology, s.IPA,
+			); err != nil {
+				return err
+			}
+		}
+
+		for i, p := range d.Pronunciations {
+			if _, err := tx.Exec(
+				ctx,
+				"INSERT INTO word_pronunciations(word_id, position, source, ipa, audio_url) VALUES($1, $2, $3, $4, $5)",
+				wordID, i, source, p.IPA, p.AudioURL,
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to replace definitions")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     wordID,
+		"userID": userID,
+		"source": source,
+		"senses": len(d.Senses),
+	}).Info("Definitions replaced successfully")
+
+	return nil
+}
+
+// DeleteDictionaryDefinitions deletes the senses and pronunciations of the
+// user's word that came from any dictionary, keeping those written by hand.
+// ErrNotFound is returned if the word doesn't exist.
+func (m *Manager) DeleteDictionaryDefinitions(ctx context.Context, userID int32, wordID int32) error {
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var id int32
+		err := tx.QueryRow(ctx, "SELECT id FROM words WHERE id=$1 AND user_id=$2 FOR UPDATE", wordID, userID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM word_senses WHERE word_id=$1 AND source <> ''", wordID); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, "DELETE FROM word_pronunciations WHERE word_id=$1 AND source <> ''", wordID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err != nil {
+		return errors.Wrap(err, "unable to delete dictionary definitions")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     wordID,
+		"userID": userID,
+	}).Info("Dictionary definitions deleted successfully")
+
+	return nil
+}
+
+// GetDefinitions returns the senses and pronunciations of the user's word, or
+// ErrNotFound if the word doesn't exist
+func (m *Manager) GetDefinitions(ctx context.Context, userID int32, wordID int32) (Definitions, error) {
+	d := Definitions{
+		Senses:         make([]Sense, 0),
+		Pronunciations: make([]Pronunciation, 0),
+	}
+
+	var id int32
+	err := m.pool.QueryRow(ctx, "SELECT id FROM words WHERE id=$1 AND user_id=$2", wordID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return d, ErrNotFound
+	}
+
+	if err != nil {
+		return d, errors.Wrap(err, "unable to get word")
+	}
+
+	rows, err := m.pool.Query(ctx, "SELECT "+senseColumns+" FROM word_senses WHERE word_id=$1 "+senseOrder, wordID)
+	if err != nil {
+		return d, errors.Wrap(err, "unable to get senses")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSense(rows)
+		if err != nil {
+			return d, errors.Wrap(err, "unable to scan row")
+		}
+
+		d.Senses = append(d.Senses, s)
+	}
+
+	if rows.Err() != nil {
+		return d, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	pronunciations, err := m.pool.Query(ctx, "SELECT source, ipa, audio_url FROM word_pronunciations WHERE word_id=$1 ORDER BY position, id", wordID)
+	if err != nil {
+		return d, errors.Wrap(err, "unable to get pronunciations")
+	}
+	defer pronunciations.Close()
+
+	for pronunciations.Next() {
+		p := Pronunciation{}
+		if err := pronunciations.Scan(&p.Source, &p.IPA, &p.AudioURL); err != nil {
+			return d, errors.Wrap(err, "unable to scan row")
+		}
+
+		d.Pronunciations = append(d.Pronunciations, p)
+	}
+
+	if pronunciations.Err() != nil {
+		return d, errors.Wrap(pronunciations.Err(), "erroring reading rows")
+	}
+
+	return d, nil
+}
+
+// AddSense adds a sense written by hand to the user's word, after any others
+// written by hand. ErrNotFound is returned if the word doesn't exist.
+func (m *Manager) AddSense(ctx context.Context, userID int32, wordID int32, sense Sense) (Sense, error) {
+	s, err := scanSense(m.pool.QueryRow(
+		ctx,
+		`INSERT INTO word_senses(word_id, position, source, part_of_speech, definition, examples, synonyms, antonyms, etymology, ipa)
+SELECT w.id, (SELECT COALESCE(MAX(position) + 1, 0) FROM word_senses WHERE word_id=w.id AND source=''), '', $3, $4, $5, $6, $7, $8, $9
+FROM words w WHERE w.id=$1 AND w.user_id=$2
+RETURNING `+senseColumns,
+		wordID, userID,
+		sense.PartOfSpeech, sense.Definition, nonNil(sense.Examples), nonNil(sense.Synonyms), nonNil(sense.Antonyms), sense.Etymology, sense.IPA,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+
+	if err != nil {
+		return s, errors.Wrap(err, "unable to add sense")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     s.ID,
+		"wordID": s.WordID,
+		"userID": userID,
+	}).Info("Sense added successfully")
+
+	return s, nil
+}
+
+// UpdateSense replaces the fields of a sense of the user's word. A sense from a
+// dictionary becomes one written by hand, so it's kept if the word is looked up
+// again. ErrNotFound is returned if the word or sense doesn't exist.
+func (m *Manager) UpdateSense(ctx context.Context, userID int32, wordID int32, sense Sense) (Sense, error) {
+	s, err := scanSense(m.pool.QueryRow(
+		ctx,
+		`UPDATE word_senses SET
+  source = '',
+  part_of_speech = $4,
+  definition = $5,
+  examples = $6,
+  synonyms = $7,
+  antonyms = $8,
+  etymology = $9,
+  ipa = $10
+WHERE id=$1 AND word_id=$2 AND EXISTS (SELECT 1 FROM words WHERE id=$2 AND user_id=$3)
+RETURNING `+senseColumns,
+		sense.ID, wordID, userID,
+		sense.PartOfSpeech, sense.Definition, nonNil(sense.Examples), nonNil(sense.Synonyms), nonNil(sense.Antonyms), sense.Etymology, sense.IPA,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+
+	if err != nil {
+		return s, errors.Wrap(err, "unable to update sense")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     s.ID,
+		"wordID": s.WordID,
+		"userID": userID,
+	}).Info("Sense updated successfully")
+
+	return s, nil
+}
+
+// DeleteSense deletes a sense of the user's word, returning ErrNotFound if the
+// word or sense doesn't exist
+func (m *Manager) DeleteSense(ctx context.Context, userID int32, wordID int32, id int32) (Sense, error) {
+	s, err := scanSense(m.pool.QueryRow(
+		ctx,
+		`DELETE FROM word_senses
+WHERE id=$1 AND word_id=$2 AND EXISTS (SELECT 1 FROM words WHERE id=$2 AND user_id=$3)
+RETURNING `+senseColumns,
+		id, wordID, userID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, ErrNotFound
+	}
+
+	if err != nil {
+		return s, errors.Wrap(err, "unable to delete sense")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     s.ID,
+		"wordID": s.WordID,
+		"userID": userID,
+	}).Info("Sense deleted successfully")
+
+	return s, nil
+}
+
+// nonNil returns s, or an empty slice if it's nil, as the array columns can't
+// be NULL
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
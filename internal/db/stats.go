@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// PoolStats is a snapshot of the connection pool
+type PoolStats struct {
+	AcquiredConns        int32
+	IdleConns            int32
+	ConstructingConns    int32
+	TotalConns           int32
+	MaxConns             int32
+	AcquireCount         int64
+	AcquireDuration      time.Duration
+	EmptyAcquireCount    int64
+	CanceledAcquireCount int64
+}
+
+// PoolStats returns the current state of the connection pool
+func (m *Manager) PoolStats() PoolStats {
+	s := m.pool.Stat()
+
+	return PoolStats{
+		AcquiredConns:        s.AcquiredConns(),
+		IdleConns:            s.IdleConns(),
+		ConstructingConns:    s.ConstructingConns(),
+		TotalConns:           s.TotalConns(),
+		MaxConns:             s.MaxConns(),
+		AcquireCount:         s.AcquireCount(),
+		AcquireDuration:      s.AcquireDuration(),
+		EmptyAcquireCount:    s.EmptyAcquireCount(),
+		CanceledAcquireCount: s.CanceledAcquireCount(),
+	}
+}
+
+// CountWords returns how many words each user has, keyed by username. Users
+// without any words are included with a count of zero.
+func (m *Manager) CountWords(ctx context.Context) (map[string]int64, error) {
+	rows, err := m.pool.Query(ctx, "SELECT u.username, count(w.id) FROM users u LEFT JOIN words w ON w.user_id = u.id GROUP BY u.username")
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to count words")
+	}
+	defer rows.Close()
+
+	counts := map[string]int64{}
+	for rows.Next() {
+		var (
+			username string
+			count    int64
+		)
+
+		if err := rows.Scan(&username, &count); err != nil {
+			return nil, errors.Wrap(err, "unable to scan word count")
+		}
+
+		counts[username] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to count words")
+	}
+
+	return counts, nil
+}
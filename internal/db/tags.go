@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Tag is a label the user has given to some of their words, such as "GRE"
+type Tag struct {
+	ID     int32
+	UserID int32
+	Name   string
+	// WordCount is the number of the user's words with the tag
+	WordCount int32
+}
+
+// taggedWith restricts a query over the words aliased w to those with the tag
+// whose normalised name is $2, or leaves it unrestricted if $2 is empty
+const taggedWith = `($2::text = '' OR EXISTS (
+  SELECT 1 FROM word_tags wt JOIN tags t ON t.id = wt.tag_id WHERE wt.word_id = w.id AND t.normalised_name = $2::text
+))`
+
+// tagColumns are the columns scanned by scanTag, in order, from tags aliased t
+const tagColumns = "t.id, t.user_id, t.name, (SELECT COUNT(*) FROM word_tags WHERE tag_id = t.id)"
+
+func scanTag(row pgx.Row) (Tag, error) {
+	t := Tag{}
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.WordCount)
+
+	return t, err
+}
+
+// TagWord gives the user's word the named tag, creating the tag if the user
+// doesn't have it yet. Tagging a word twice with the same tag has no effect.
+// ErrNotFound is returned if the word doesn't exist.
+func (m *Manager) TagWord(ctx context.Context, userID int32, wordID int32, name string) (Tag, error) {
+	var t Tag
+
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var id int32
+		err := tx.QueryRow(ctx, "SELECT id FROM words WHERE id=$1 AND user_id=$2", wordID, userID).Scan(&id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		tagID, err := addTag(ctx, tx, userID, wordID, name)
+		if err != nil {
+			return err
+		}
+
+		t, err = scanTag(tx.QueryRow(ctx, "SELECT "+tagColumns+" FROM tags t WHERE t.id=$1", tagID))
+
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return t, err
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to tag word")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     wordID,
+		"userID": userID,
+		"tag":    t.Name,
+	}).Info("Word tagged successfully")
+
+	return t, nil
+}
+
+// addTag gives the word the named tag within tx, creating the tag if the user
+// doesn't have it yet, and returns the tag's id. The word must belong to the
+// user.
+func addTag(ctx context.Context, tx pgx.Tx, userID int32, wordID int32, name string) (int32, error) {
+	var tagID int32
+	// The no-op update makes the existing tag's id be returned on conflict
+	err := tx.QueryRow(
+		ctx,
+		`INSERT INTO tags(user_id, name, normalised_name) VALUES($1, $2, $3)
+ON CONFLICT (user_id, normalised_name) DO UPDATE SET name = tags.name
+RETURNING id`,
+		userID, cleanWord(name), NormaliseWord(name),
+	).Scan(&tagID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO word_tags(word_id, tag_id) VALUES($1, $2) ON CONFLICT DO NOTHING", wordID, tagID)
+
+	return tagID, err
+}
+
+// UntagWord removes the named tag from the user's word. The tag itself is kept,
+// even if no words have it any more. ErrNotFound is returned if the word
+// doesn't exist or doesn't have the tag.
+func (m *Manager) UntagWord(ctx context.Context, userID int32, wordID int32, name string) error {
+	tag, err := m.pool.Exec(
+		ctx,
+		`DELETE FROM word_tags wt USING tags t, words w
+WHERE wt.tag_id = t.id AND wt.word_id = w.id AND w.id = $1 AND w.user_id = $2 AND t.user_id = $2 AND t.normalised_name = $3`,
+		wordID, userID, NormaliseWord(name),
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to untag word")
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     wordID,
+		"userID": userID,
+		"tag":    name,
+	}).Info("Word untagged successfully")
+
+	return nil
+}
+
+// DeleteTag deletes the user's named tag, removing it from all of their words.
+// ErrNotFound is returned if the user doesn't have the tag.
+func (m *Manager) DeleteTag(ctx context.Context, userID int32, name string) (Tag, error) {
+	t := Tag{}
+
+	err := m.pool.QueryRow(
+		ctx,
+		"DELETE FROM tags WHERE user_id=$1 AND normalised_name=$2 RETURNING id, user_id, name",
+		userID, NormaliseWord(name),
+	).Scan(&t.ID, &t.UserID, &t.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to delete tag")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     t.ID,
+		"userID": t.UserID,
+	}).Info("Tag deleted successfully")
+
+	return t, nil
+}
+
+// ListTags returns the user's tags in alphabetical order
+func (m *Manager) ListTags(ctx context.Context, userID int32) ([]Tag, error) {
+	return m.queryTags(ctx, "SELECT "+tagColumns+" FROM tags t WHERE t.user_id=$1 ORDER BY t.normalised_name", userID)
+}
+
+// WordTags returns the tags of the user's word in alphabetical order, or
+// ErrNotFound if the word doesn't exist
+func (m *Manager) WordTags(ctx context.Context, userID int32, wordID int32) ([]Tag, error) {
+	var id int32
+	err := m.pool.QueryRow(ctx, "SELECT id FROM words WHERE id=$1 AND user_id=$2", wordID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return make([]Tag, 0), ErrNotFound
+	}
+
+	if err != nil {
+		return make([]Tag, 0), errors.Wrap(err, "unable to get word")
+	}
+
+	return m.queryTags(
+		ctx,
+		"SELECT "+tagColumns+" FROM tags t JOIN word_tags wt ON wt.tag_id = t.id WHERE wt.word_id=$1 ORDER BY t.normalised_name",
+		wordID,
+	)
+}
+
+func (m *Manager) queryTags(ctx context.Context, query string, args ...interface{}) ([]Tag, error) {
+	tags := make([]Tag, 0)
+
+	rows, err := m.pool.Query(ctx, query, args...)
+	if err != nil {
+		return tags, errors.Wrap(err, "unable to get tags")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTag(rows)
+		if err != nil {
+			return tags, errors.Wrap(err, "unable to scan row")
+		}
+
+		tags = append(tags, t)
+	}
+
+	if rows.Err() != nil {
+		return tags, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Template is a pair of email templates written by a user, rendered with
+// mail.TemplateData
+type Template struct {
+	ID     int32
+	UserID int32
+	Name   string
+	HTML   string
+	Text   string
+	// Active is true for the template the user's emails are rendered from. A
+	// user has at most one, and without one the embedded default is used.
+	Active    bool
+	CreatedAt time.Time
+}
+
+const templateColumns = "id, user_id, name, html, text, active, created_at"
+
+func scanTemplate(row pgx.Row) (Template, error) {
+	t := Template{}
+
+	err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.HTML, &t.Text, &t.Active, &t.CreatedAt)
+
+	return t, err
+}
+
+// InsertTemplate adds an inactive template for the user, or returns
+// ErrAlreadyExists if they have one with the same name
+func (m *Manager) InsertTemplate(ctx context.Context, template Template) (Template, error) {
+	t, err := scanTemplate(m.pool.QueryRow(
+		ctx,
+		"INSERT INTO templates(user_id, name, html, text) VALUES($1, $2, $3, $4) RETURNING "+templateColumns,
+		template.UserID, template.Name, template.HTML, template.Text,
+	))
+	if isUniqueViolation(err) {
+		return t, ErrAlreadyExists
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to insert template")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     t.ID,
+		"userID": t.UserID,
+	}).Info("Template inserted successfully")
+
+	return t, nil
+}
+
+// GetTemplate returns the user's template with the id, or ErrNotFound
+func (m *Manager) GetTemplate(ctx context.Context, userID int32, id int32) (Template, error) {
+	t, err := scanTemplate(m.pool.QueryRow(ctx, "SELECT "+templateColumns+" FROM templates WHERE user_id=$1 AND id=$2", userID, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to get template")
+	}
+
+	return t, nil
+}
+
+// ActiveTemplate returns the template the user's emails are rendered from, or
+// ErrNotFound if they use the default
+func (m *Manager) ActiveTemplate(ctx context.Context, userID int32) (Template, error) {
+	t, err := scanTemplate(m.pool.QueryRow(ctx, "SELECT "+templateColumns+" FROM templates WHERE user_id=$1 AND active", userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to get active template")
+	}
+
+	return t, nil
+}
+
+// ListTemplates returns the user's templates in alphabetical order
+func (m *Manager) ListTemplates(ctx context.Context, userID int32) ([]Template, error) {
+	templates := []Template{}
+
+	rows, err := m.pool.Query(ctx, "SELECT "+templateColumns+" FROM templates WHERE user_id=$1 ORDER BY name", userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to list templates")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		templates = append(templates, t)
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return templates, nil
+}
+
+// ActivateTemplate makes the user's template with the id the one their emails
+// are rendered from, in place of any that was, or returns ErrNotFound
+func (m *Manager) ActivateTemplate(ctx context.Context, userID int32, id int32) (Template, error) {
+	var t Template
+
+	err := m.pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// Deactivated first, as only one template can be active at a time
+		if _, err := tx.Exec(ctx, "UPDATE templates SET active = false WHERE user_id=$1 AND active AND id <> $2", userID, id); err != nil {
+			return err
+		}
+
+		var err error
+		t, err = scanTemplate(tx.QueryRow(
+			ctx,
+			"UPDATE templates SET active = true WHERE user_id=$1 AND id=$2 RETURNING "+templateColumns,
+			userID, id,
+		))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+
+		return err
+	})
+	if errors.Is(err, ErrNotFound) {
+		return t, err
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to activate template")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     t.ID,
+		"userID": t.UserID,
+	}).Info("Template activated successfully")
+
+	return t, nil
+}
+
+// DeactivateTemplates makes the user's emails be rendered from the default
+// template again
+func (m *Manager) DeactivateTemplates(ctx context.Context, userID int32) error {
+	if _, err := m.pool.Exec(ctx, "UPDATE templates SET active = false WHERE user_id=$1 AND active", userID); err != nil {
+		return errors.Wrap(err, "unable to deactivate templates")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"userID": userID,
+	}).Info("Templates deactivated successfully")
+
+	return nil
+}
+
+// DeleteTemplate deletes the user's template with the id, or returns
+// ErrNotFound. Deleting the active template makes the user's emails be
+// rendered from the default again.
+func (m *Manager) DeleteTemplate(ctx context.Context, userID int32, id int32) (Template, error) {
+	t, err := scanTemplate(m.pool.QueryRow(
+		ctx,
+		"DELETE FROM templates WHERE user_id=$1 AND id=$2 RETURNING "+templateColumns,
+		userID, id,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return t, ErrNotFound
+	}
+
+	if err != nil {
+		return t, errors.Wrap(err, "unable to delete template")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":     t.ID,
+		"userID": t.UserID,
+	}).Info("Template deleted successfully")
+
+	return t, nil
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/mywordoftheday/backend/internal/db")
+
+// tracedPool records a span for every query run through the pool, including
+// those run in transactions started by BeginFunc
+type tracedPool struct {
+	*pgxpool.Pool
+}
+
+func (p *tracedPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startQuerySpan(ctx, sql)
+	return &tracedRow{row: p.Pool.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (p *tracedPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startQuerySpan(ctx, sql)
+
+	rows, err := p.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		endSpan(span, err)
+		return rows, err
+	}
+
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (p *tracedPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startQuerySpan(ctx, sql)
+
+	tag, err := p.Pool.Exec(ctx, sql, args...)
+	endSpan(span, err)
+
+	return tag, err
+}
+
+// BeginFunc runs f in a transaction, recording a span for the transaction as a
+// whole as well as for each query run by f
+func (p *tracedPool) BeginFunc(ctx context.Context, f func(pgx.Tx) error) error {
+	ctx, span := tracer.Start(ctx, "postgres transaction",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL),
+	)
+
+	err := p.Pool.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return f(&tracedTx{Tx: tx, span: span})
+	})
+	endSpan(span, err)
+
+	return err
+}
+
+// tracedTx records a span for every query run in the transaction, as a child
+// of the transaction's span
+type tracedTx struct {
+	pgx.Tx
+	span trace.Span
+}
+
+func (t *tracedTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, span := startQuerySpan(trace.ContextWithSpan(ctx, t.span), sql)
+	return &tracedRow{row: t.Tx.QueryRow(ctx, sql, args...), span: span}
+}
+
+func (t *tracedTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, span := startQuerySpan(trace.ContextWithSpan(ctx, t.span), sql)
+
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if err != nil {
+		endSpan(span, err)
+		return rows, err
+	}
+
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func (t *tracedTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, span := startQuerySpan(trace.ContextWithSpan(ctx, t.span), sql)
+
+	tag, err := t.Tx.Exec(ctx, sql, args...)
+	endSpan(span, err)
+
+	return tag, err
+}
+
+// tracedRow ends its span once the row has been scanned, as that's when the
+// query is actually run
+type tracedRow struct {
+	row  pgx.Row
+	span trace.Span
+}
+
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	endSpan(r.span, err)
+
+	return err
+}
+
+// tracedRows ends its span once the rows have been closed
+type tracedRows struct {
+	pgx.Rows
+	span  trace.Span
+	ended bool
+}
+
+func (r *tracedRows) Close() {
+	r.Rows.Close()
+
+	if !r.ended {
+		r.ended = true
+		endSpan(r.span, r.Rows.Err())
+	}
+}
+
+func startQuerySpan(ctx context.Context, sql string) (context.Context, trace.Span) {
+	op := queryOperation(sql)
+
+	return tracer.Start(ctx, "postgres "+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationKey.String(op),
+			semconv.DBStatementKey.String(sql),
+		),
+	)
+}
+
+// endSpan ends span, marking it as failed if err is an error other than no rows
+// being found, which callers treat as a result rather than a failure
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			span.SetAttributes(attribute.String("db.postgresql.error_code", pgErr.Code))
+		}
+	}
+
+	span.End()
+}
+
+// queryOperation returns the SQL command of the query, e.g. SELECT
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "QUERY"
+	}
+
+	return strings.ToUpper(fields[0])
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQueryOperation(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		sql      string
+		expected string
+	}{
+		{desc: "select", sql: "SELECT id FROM words", expected: "SELECT"},
+		{desc: "leading whitespace and lower case", sql: "\n\tinsert INTO words(word) VALUES($1)", expected: "INSERT"},
+		{desc: "common table expression", sql: "WITH next AS (SELECT 1) SELECT * FROM next", expected: "WITH"},
+		{desc: "empty", sql: "  ", expected: "QUERY"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, queryOperation(tC.sql))
+		})
+	}
+}
+
+func TestEndSpan(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+
+	testCases := []struct {
+		desc      string
+		err       error
+		code      codes.Code
+		errorCode string
+	}{
+		{desc: "success", err: nil, code: codes.Unset},
+		{desc: "no rows isn't a failure", err: pgx.ErrNoRows, code: codes.Unset},
+		{desc: "an error fails the span", err: errors.New("connection reset"), code: codes.Error},
+		{desc: "postgres errors record their code", err: &pgconn.PgError{Code: uniqueViolation}, code: codes.Error, errorCode: uniqueViolation},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, span := tracer.Start(context.Background(), tC.desc)
+			endSpan(span, tC.err)
+
+			spans := sr.Ended()
+			got := spans[len(spans)-1]
+
+			assert.Equal(t, tC.desc, got.Name())
+			assert.Equal(t, tC.code, got.Status().Code)
+
+			var errorCode string
+			for _, a := range got.Attributes() {
+				if a.Key == "db.postgresql.error_code" {
+					errorCode = a.Value.AsString()
+				}
+			}
+			assert.Equal(t, tC.errorCode, errorCode)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// WordUpdate describes a partial update to a word. Only the fields that are
+// non-nil are changed.
+type WordUpdate struct {
+	Word             *string
+	CustomDefinition *string
+
+	// ExpectedVersion, if non-zero, must match the word's current version for
+	// the update to be applied
+	ExpectedVersion int32
+}
+
+// UpdateWord applies the update to the word with the given id, provided it
+// belongs to the user. ErrNotFound is returned if the word doesn't exist,
+// ErrVersionConflict if it has been modified since update.ExpectedVersion and a
+// *DuplicateWordError if the new spelling belongs to another of the user's words.
+func (m *Manager) UpdateWord(ctx context.Context, userID int32, id int32, update WordUpdate) (Word, error) {
+	w, err := scanWord(m.pool.QueryRow(
+		ctx,
+		`UPDATE words SET
+  word = CASE WHEN $3 THEN $4 ELSE word END,
+  normalised_word = CASE WHEN $3 THEN $5 ELSE normalised_word END,
+  custom_definition = CASE WHEN $6 THEN $7 ELSE custom_definition END,
+  version = version + 1,
+  updated_at = now()
+WHERE id=$1 AND user_id=$2 AND ($8 = 0 OR version = $8)
+RETURNING `+wordColumns,
+		id, userID,
+		update.Word != nil, cleanWord(stringOrEmpty(update.Word)), NormaliseWord(stringOrEmpty(update.Word)),
+		update.CustomDefinition != nil, stringOrEmpty(update.CustomDefinition),
+		update.ExpectedVersion,
+	))
+	if isUniqueViolation(err) {
+		return w, m.duplicateWordError(ctx, userID, stringOrEmpty(update.Word))
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Work out whether the word is missing or has moved on
+		var version int32
+
+		err = m.pool.QueryRow(ctx, "SELECT version FROM words WHERE id=$1 AND user_id=$2", id, userID).Scan(&version)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return w, ErrNotFound
+		}
+
+		if err == nil {
+			return w, ErrVersionConflict
+		}
+	}
+
+	if err != nil {
+		return w, errors.Wrap(err, "unable to update word")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id":      w.ID,
+		"userID":  w.UserID,
+		"version": w.Version,
+	}).Info("Word updated successfully")
+
+	return w, nil
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
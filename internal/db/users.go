@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultUserID is the user that owns every word added before users
+	// existed, and that requests are scoped to when no user is given
+	DefaultUserID int32 = 1
+	// DefaultUsername is the username of the default user
+	DefaultUsername = "default"
+)
+
+type User struct {
+	ID        int32
+	Username  string
+	Email     string
+	CreatedAt time.Time
+}
+
+const userColumns = "id, username, email, created_at"
+
+func scanUser(row pgx.Row) (User, error) {
+	u := User{}
+
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt)
+
+	return u, err
+}
+
+func (m *Manager) InsertUser(ctx context.Context, user User) (User, error) {
+	u, err := scanUser(m.pool.QueryRow(
+		ctx,
+		"INSERT INTO users(username, email) VALUES($1, $2) RETURNING "+userColumns,
+		user.Username, user.Email,
+	))
+	if err != nil {
+		return u, errors.Wrap(err, "unable to insert user")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": u.ID,
+	}).Info("User inserted successfully")
+
+	return u, nil
+}
+
+// GetUserByUsername returns the user with the given username, or ErrNotFound
+func (m *Manager) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	u, err := scanUser(m.pool.QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE username=$1", username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrNotFound
+	}
+
+	if err != nil {
+		return u, errors.Wrap(err, "unable to get user")
+	}
+
+	return u, nil
+}
+
+func (m *Manager) ListUsers(ctx context.Context) ([]User, error) {
+	users := make([]User, 0)
+
+	rows, err := m.pool.Query(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return users, errors.Wrap(err, "unable to get users")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to scan row")
+		}
+
+		users = append(users, u)
+	}
+
+	if rows.Err() != nil {
+		return nil, errors.Wrap(rows.Err(), "erroring reading rows")
+	}
+
+	return users, nil
+}
+
+// DeleteUser deletes the user with the given username along with all of their
+// words, returning ErrNotFound if they don't exist
+func (m *Manager) DeleteUser(ctx context.Context, username string) (User, error) {
+	u, err := scanUser(m.pool.QueryRow(ctx, "DELETE FROM users WHERE username=$1 RETURNING "+userColumns, username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return u, ErrNotFound
+	}
+
+	if err != nil {
+		return u, errors.Wrap(err, "unable to delete user")
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"id": u.ID,
+	}).Info("User deleted successfully")
+
+	return u, nil
+}
@@ -0,0 +1,81 @@
+// Package definitions looks up what dictionaries know about a word, so words
+// don't need every definition typing in by hand
+package definitions
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotFound is returned by a Provider that doesn't know the word
+var ErrNotFound = errors.New("definition not found")
+
+// Entry is what a dictionary knows about a word
+type Entry struct {
+	// Source names the dictionary the entry came from
+	Source string
+
+	Pronunciations []Pronunciation
+	Senses         []Sense
+}
+
+// Pronunciation is one way of saying a word
+type Pronunciation struct {
+	// IPA is the pronunciation in the International Phonetic Alphabet, e.g. /ˈwɜːd/
+	IPA string
+	// AudioURL links to a recording of the pronunciation
+	AudioURL string
+}
+
+// Sense is one meaning of a word
+type Sense struct {
+	PartOfSpeech string
+	Definition   string
+	Examples     []string
+	Synonyms     []string
+	Antonyms     []string
+	// Etymology describes where the word came from in this sense
+	Etymology string
+	// IPA is how the word is pronounced in this sense, if the dictionary
+	// distinguishes it
+	IPA string
+}
+
+// Provider looks up words in a dictionary
+type Provider interface {
+	// Lookup returns the entry for word, or ErrNotFound
+	Lookup(ctx context.Context, word string) (Entry, error)
+}
+
+// Chain is a Provider that tries each of its providers in turn, returning the
+// first entry found. If none of them know the word, ErrNotFound is returned
+// unless one of them failed, in which case the first error is returned.
+type Chain []Provider
+
+func (c Chain) Lookup(ctx context.Context, word string) (Entry, error) {
+	var firstErr error
+
+	for _, p := range c {
+		e, err := p.Lookup(ctx, word)
+		if err == nil {
+			return e, nil
+		}
+
+		if !errors.Is(err, ErrNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return Entry{}, firstErr
+	}
+
+	return Entry{}, ErrNotFound
+}
+
+// key is how words are matched against dictionary headwords
+func key(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
@@ -0,0 +1,82 @@
+package definitions
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type providerMock struct {
+	entry Entry
+	err   error
+	calls int
+}
+
+func (f *providerMock) Lookup(context.Context, string) (Entry, error) {
+	f.calls++
+	return f.entry, f.err
+}
+
+func TestChain(t *testing.T) {
+	found := Entry{Source: "found", Senses: []Sense{{Definition: "a definition"}}}
+
+	testCases := []struct {
+		desc      string
+		providers []*providerMock
+		expected  Entry
+		err       string
+		calls     []int
+	}{
+		{
+			desc:      "the first provider that knows the word is used",
+			providers: []*providerMock{{err: ErrNotFound}, {entry: found}, {entry: Entry{Source: "unused"}}},
+			expected:  found,
+			calls:     []int{1, 1, 0},
+		},
+		{
+			desc:      "failing providers are skipped",
+			providers: []*providerMock{{err: errors.New("connection refused")}, {entry: found}},
+			expected:  found,
+			calls:     []int{1, 1},
+		},
+		{
+			desc:      "not found if no provider knows the word",
+			providers: []*providerMock{{err: ErrNotFound}, {err: ErrNotFound}},
+			err:       ErrNotFound.Error(),
+			calls:     []int{1, 1},
+		},
+		{
+			desc:      "the first failure is returned if no provider found the word",
+			providers: []*providerMock{{err: ErrNotFound}, {err: errors.New("timeout")}, {err: errors.New("connection refused")}},
+			err:       "timeout",
+			calls:     []int{1, 1, 1},
+		},
+		{
+			desc: "not found without any providers",
+			err:  ErrNotFound.Error(),
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var c Chain
+			for _, p := range tC.providers {
+				c = append(c, p)
+			}
+
+			e, err := c.Lookup(context.Background(), "word")
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tC.expected, e)
+
+			for i, p := range tC.providers {
+				assert.Equal(t, tC.calls[i], p.calls)
+			}
+		})
+	}
+}
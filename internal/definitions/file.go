@@ -0,0 +1,180 @@
+package definitions
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// wiktionaryEntry is the part of a line of a Wiktextract JSON Lines dump, as
+// published at https://kaikki.org, that is used
+type wiktionaryEntry struct {
+	Word          string `json:"word"`
+	POS           string `json:"pos"`
+	EtymologyText string `json:"etymology_text"`
+	Senses        []struct {
+		Glosses  []string `json:"glosses"`
+		Examples []struct {
+			Text string `json:"text"`
+		} `json:"examples"`
+		Synonyms []wiktionaryLink `json:"synonyms"`
+		Antonyms []wiktionaryLink `json:"antonyms"`
+	} `json:"senses"`
+	Sounds []struct {
+		IPA    string `json:"ipa"`
+		MP3URL string `json:"mp3_url"`
+		OggURL string `json:"ogg_url"`
+	} `json:"sounds"`
+}
+
+// wiktionaryLink refers to another word, such as a synonym
+type wiktionaryLink struct {
+	Word string `json:"word"`
+}
+
+func linkedWords(links []wiktionaryLink) []string {
+	var words []string
+	for _, l := range links {
+		if l.Word != "" {
+			words = append(words, l.Word)
+		}
+	}
+
+	return words
+}
+
+// File is a Provider backed by a Wiktionary dump loaded into memory
+type File struct {
+	entries map[string]Entry
+}
+
+// NewFile loads the Wiktextract JSON Lines dump at path. Dumps of a whole
+// language are large, so it's best to use one filtered to the words you need.
+func NewFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open dictionary file")
+	}
+	defer f.Close()
+
+	return ReadFile(f)
+}
+
+// ReadFile reads a Wiktextract JSON Lines dump from r
+func ReadFile(r io.Reader) (*File, error) {
+	entries := map[string]Entry{}
+
+	s := bufio.NewScanner(r)
+	// Entries with many senses and translations can run to hundreds of KB
+	s.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for s.Scan() {
+		line++
+
+		if len(s.Bytes()) == 0 {
+			continue
+		}
+
+		var we wiktionaryEntry
+		if err := json.Unmarshal(s.Bytes(), &we); err != nil {
+			return nil, errors.Wrapf(err, "unable to parse line %d", line)
+		}
+
+		k := key(we.Word)
+		if k == "" {
+			continue
+		}
+
+		// A word has a line per part of speech, which are combined into one entry
+		e := entries[k]
+		e.Source = "wiktionary"
+		addWiktionaryEntry(&e, we)
+		entries[k] = e
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "unable to read dictionary file")
+	}
+
+	return &File{entries: entries}, nil
+}
+
+func addWiktionaryEntry(e *Entry, we wiktionaryEntry) {
+	// Each line is a single etymology, so its pronunciation applies to all of
+	// its senses
+	ipa := ""
+	for _, snd := range we.Sounds {
+		if snd.IPA != "" {
+			ipa = snd.IPA
+			break
+		}
+	}
+
+	for _, s := range we.Senses {
+		if len(s.Glosses) == 0 {
+			continue
+		}
+
+		sense := Sense{
+			PartOfSpeech: we.POS,
+			// Glosses run from the general meaning to the specific one
+			Definition: s.Glosses[len(s.Glosses)-1],
+			Synonyms:   linkedWords(s.Synonyms),
+			Antonyms:   linkedWords(s.Antonyms),
+			Etymology:  we.EtymologyText,
+			IPA:        ipa,
+		}
+
+		for _, ex := range s.Examples {
+			if ex.Text != "" {
+				sense.Examples = append(sense.Examples, ex.Text)
+			}
+		}
+
+		e.Senses = append(e.Senses, sense)
+	}
+
+	for _, snd := range we.Sounds {
+		p := Pronunciation{IPA: snd.IPA, AudioURL: snd.MP3URL}
+		if p.AudioURL == "" {
+			p.AudioURL = snd.OggURL
+		}
+
+		if p.IPA == "" && p.AudioURL == "" {
+			continue
+		}
+
+		if !hasPronunciation(e.Pronunciations, p) {
+			e.Pronunciations = append(e.Pronunciations, p)
+		}
+	}
+}
+
+func hasPronunciation(ps []Pronunciation, p Pronunciation) bool {
+	for _, existing := range ps {
+		if existing == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Len returns the number of words in the dictionary
+func (f *File) Len() int {
+	return len(f.entries)
+}
+
+func (f *File) Lookup(ctx context.Context, word string) (Entry, error) {
+	e, ok := f.entries[key(word)]
+	if !ok || len(e.Senses) == 0 {
+		return Entry{}, ErrNotFound
+	}
+
+	return e, nil
+}
@@ -0,0 +1,77 @@
+package definitions
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	t.Run("Given a Wiktionary dump", func(t *testing.T) {
+		f, err := NewFile("testdata/wiktionary.jsonl")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, f.Len())
+
+		t.Run("When a word is looked up", func(t *testing.T) {
+			t.Run("Then its senses, examples and pronunciations are returned", func(t *testing.T) {
+				e, err := f.Lookup(context.Background(), "Petrichor ")
+				assert.NoError(t, err)
+				assert.Equal(t, Entry{
+					Source: "wiktionary",
+					Pronunciations: []Pronunciation{
+						{IPA: "/ˈpɛtɹɪkɔː/"},
+						{IPA: "/ˈpɛtɹɪkɔɹ/"},
+						{AudioURL: "https://upload.wikimedia.org/wikipedia/commons/En-us-petrichor.mp3"},
+					},
+					Senses: []Sense{{
+						PartOfSpeech: "noun",
+						Definition:   "The distinctive scent which accompanies the first rain after a long warm dry spell.",
+						Examples:     []string{"The petrichor rose from the pavement as the storm broke."},
+						IPA:          "/ˈpɛtɹɪkɔː/",
+					}},
+				}, e)
+			})
+		})
+
+		t.Run("When a word has several parts of speech", func(t *testing.T) {
+			t.Run("Then they are combined, using the most specific gloss and each line's etymology", func(t *testing.T) {
+				e, err := f.Lookup(context.Background(), "sonder")
+				assert.NoError(t, err)
+				assert.Equal(t, []Sense{
+					{PartOfSpeech: "noun", Definition: "The realization that each passerby has a life as vivid and complex as one's own."},
+					{
+						PartOfSpeech: "verb",
+						Definition:   "To sound out or probe.",
+						Examples:     []string{"We sondered the depths."},
+						Synonyms:     []string{"probe"},
+						Antonyms:     []string{"ignore"},
+						Etymology:    "From German sondern, from Old High German suntarōn.",
+						IPA:          "/ˈsɒndə/",
+					},
+				}, e.Senses)
+				assert.Equal(t, []Pronunciation{{IPA: "/ˈsɒndə/"}}, e.Pronunciations)
+			})
+		})
+
+		t.Run("When a word isn't in the dump or has no definitions", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := f.Lookup(context.Background(), "floccinaucinihilipilification")
+				assert.ErrorIs(t, err, ErrNotFound)
+
+				_, err = f.Lookup(context.Background(), "glossless")
+				assert.ErrorIs(t, err, ErrNotFound)
+			})
+		})
+	})
+
+	t.Run("Given a malformed dump", func(t *testing.T) {
+		t.Run("When it is read", func(t *testing.T) {
+			t.Run("Then the line that couldn't be parsed is reported", func(t *testing.T) {
+				_, err := ReadFile(strings.NewReader("{\"word\": \"ok\"}\n{\"word\": \n"))
+				assert.EqualError(t, err, "unable to parse line 2: unexpected end of JSON input")
+			})
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package definitions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// DefaultAPIURL is the English dictionary of the Free Dictionary API
+const DefaultAPIURL = "https://api.dictionaryapi.dev/api/v2/entries/en"
+
+// apiEntry is an entry returned by the Free Dictionary API
+type apiEntry struct {
+	Word      string `json:"word"`
+	Phonetic  string `json:"phonetic"`
+	Origin    string `json:"origin"`
+	Phonetics []struct {
+		Text  string `json:"text"`
+		Audio string `json:"audio"`
+	} `json:"phonetics"`
+	Meanings []struct {
+		PartOfSpeech string `json:"partOfSpeech"`
+		Definitions  []struct {
+			Definition string   `json:"definition"`
+			Example    string   `json:"example"`
+			Synonyms   []string `json:"synonyms"`
+			Antonyms   []string `json:"antonyms"`
+		} `json:"definitions"`
+		Synonyms []string `json:"synonyms"`
+		Antonyms []string `json:"antonyms"`
+	} `json:"meanings"`
+}
+
+// API is a Provider backed by an HTTP dictionary API that responds in the
+// format of https://dictionaryapi.dev
+type API struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAPI returns an API looking words up at baseURL/{word}. If client is nil
+// a default client is used.
+func NewAPI(baseURL string, client *http.Client) *API {
+	if client == nil {
+		client = &http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}
+	}
+
+	return &API{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (a *API) Lookup(ctx context.Context, word string) (Entry, error) {
+	k := key(word)
+	if k == "" {
+		return Entry{}, ErrNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.baseURL+"/"+url.PathEscape(k), nil)
+	if err != nil {
+		return Entry{}, errors.Wrap(err, "unable to create request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	rsp, err := a.client.Do(req)
+	if err != nil {
+		return Entry{}, errors.Wrap(err, "unable to look up word")
+	}
+	defer rsp.Body.Close()
+
+	if rsp.StatusCode == http.StatusNotFound {
+		return Entry{}, ErrNotFound
+	}
+
+	if rsp.StatusCode != http.StatusOK {
+		// Drained so the connection can be reused
+		_, _ = io.Copy(io.Discard, rsp.Body)
+		return Entry{}, fmt.Errorf("unexpected response looking up word: %s", rsp.Status)
+	}
+
+	var entries []apiEntry
+	if err := json.NewDecoder(rsp.Body).Decode(&entries); err != nil {
+		return Entry{}, errors.Wrap(err, "unable to decode response")
+	}
+
+	e := Entry{Source: a.source()}
+
+	for _, ae := range entries {
+		ipa := ae.Phonetic
+
+		for _, ph := range ae.Phonetics {
+			p := Pronunciation{IPA: ph.Text, AudioURL: ph.Audio}
+			if (p.IPA != "" || p.AudioURL != "") && !hasPronunciation(e.Pronunciations, p) {
+				e.Pronunciations = append(e.Pronunciations, p)
+			}
+
+			if ipa == "" {
+				ipa = ph.Text
+			}
+		}
+
+		for _, m := range ae.Meanings {
+			for _, d := range m.Definitions {
+				if d.Definition == "" {
+					continue
+				}
+
+				sense := Sense{
+					PartOfSpeech: m.PartOfSpeech,
+					Definition:   d.Definition,
+					// The synonyms of a meaning apply to each of its definitions
+					Synonyms:  appendUnique(d.Synonyms, m.Synonyms...),
+					Antonyms:  appendUnique(d.Antonyms, m.Antonyms...),
+					Etymology: ae.Origin,
+					IPA:       ipa,
+				}
+				if d.Example != "" {
+					sense.Examples = []string{d.Example}
+				}
+
+				e.Senses = append(e.Senses, sense)
+			}
+		}
+	}
+
+	if len(e.Senses) == 0 {
+		return Entry{}, ErrNotFound
+	}
+
+	return e, nil
+}
+
+// source names the API by its host
+func (a *API) source() string {
+	u, err := url.Parse(a.baseURL)
+	if err != nil || u.Host == "" {
+		return a.baseURL
+	}
+
+	return u.Host
+}
+
+// appendUnique appends the words that aren't already in s
+func appendUnique(s []string, words ...string) []string {
+	for _, w := range words {
+		found := false
+		for _, existing := range s {
+			if existing == w {
+				found = true
+				break
+			}
+		}
+
+		if !found && w != "" {
+			s = append(s, w)
+		}
+	}
+
+	return s
+}
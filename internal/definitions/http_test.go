@@ -0,0 +1,103 @@
+package definitions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const apiResponse = `[
+  {
+    "word": "sonder",
+    "phonetics": [{"text": "/ˈsɒndə/", "audio": ""}, {"text": "", "audio": ""}],
+    "origin": "Coined in The Dictionary of Obscure Sorrows.",
+    "meanings": [
+      {
+        "partOfSpeech": "noun",
+        "definitions": [
+          {"definition": "The realization that each passerby has a life as vivid and complex as one's own.", "example": "A sudden sense of sonder.", "synonyms": ["empathy"]},
+          {"definition": ""}
+        ],
+        "synonyms": ["empathy", "wonder"],
+        "antonyms": ["solipsism"]
+      }
+    ]
+  },
+  {
+    "word": "sonder",
+    "phonetic": "/ˈsɔndər/",
+    "phonetics": [{"text": "/ˈsɒndə/", "audio": ""}],
+    "meanings": [{"partOfSpeech": "verb", "definitions": [{"definition": "To sound out or probe."}]}]
+  }
+]`
+
+func TestAPI(t *testing.T) {
+	var path string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+
+		switch r.URL.Path {
+		case "/entries/en/sonder":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(apiResponse))
+		case "/entries/en/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title": "No Definitions Found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	api := NewAPI(srv.URL+"/entries/en/", srv.Client())
+
+	t.Run("Given a dictionary API", func(t *testing.T) {
+		t.Run("When a word is looked up", func(t *testing.T) {
+			t.Run("Then the meanings of every entry are combined with their entry's origin and phonetic", func(t *testing.T) {
+				e, err := api.Lookup(context.Background(), " Sonder")
+				assert.NoError(t, err)
+				assert.Equal(t, "/entries/en/sonder", path)
+
+				assert.Equal(t, Entry{
+					Source:         srv.Listener.Addr().String(),
+					Pronunciations: []Pronunciation{{IPA: "/ˈsɒndə/"}},
+					Senses: []Sense{
+						{
+							PartOfSpeech: "noun",
+							Definition:   "The realization that each passerby has a life as vivid and complex as one's own.",
+							Examples:     []string{"A sudden sense of sonder."},
+							Synonyms:     []string{"empathy", "wonder"},
+							Antonyms:     []string{"solipsism"},
+							Etymology:    "Coined in The Dictionary of Obscure Sorrows.",
+							IPA:          "/ˈsɒndə/",
+						},
+						{PartOfSpeech: "verb", Definition: "To sound out or probe.", IPA: "/ˈsɔndər/"},
+					},
+				}, e)
+			})
+		})
+		t.Run("When the word contains characters that aren't allowed in a path", func(t *testing.T) {
+			t.Run("Then they are escaped", func(t *testing.T) {
+				_, err := api.Lookup(context.Background(), "a/b c")
+				assert.ErrorIs(t, err, ErrNotFound)
+				assert.Equal(t, "/entries/en/a%2Fb%20c", path)
+			})
+		})
+		t.Run("When the word isn't known", func(t *testing.T) {
+			t.Run("Then ErrNotFound is returned", func(t *testing.T) {
+				_, err := api.Lookup(context.Background(), "floccinaucinihilipilification")
+				assert.ErrorIs(t, err, ErrNotFound)
+			})
+		})
+		t.Run("When the API fails", func(t *testing.T) {
+			t.Run("Then an error is returned", func(t *testing.T) {
+				_, err := api.Lookup(context.Background(), "broken")
+				assert.EqualError(t, err, "unexpected response looking up word: 500 Internal Server Error")
+			})
+		})
+	})
+}
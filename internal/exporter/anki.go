@@ -0,0 +1,107 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// ankiHeader tells Anki how to import the file: as tab separated Basic notes
+// with HTML fields and tags in the third column
+var ankiHeader = []string{
+	"#separator:tab",
+	"#html:true",
+	"#notetype:Basic",
+	"#tags column:3",
+}
+
+type ankiWriter struct {
+	w  io.Writer
+	cw *csv.Writer
+
+	headerWritten bool
+}
+
+func newAnkiWriter(w io.Writer) *ankiWriter {
+	cw := csv.NewWriter(w)
+	cw.Comma = '\t'
+
+	return &ankiWriter{w: w, cw: cw}
+}
+
+func (a *ankiWriter) writeHeader() error {
+	if a.headerWritten {
+		return nil
+	}
+
+	a.headerWritten = true
+
+	_, err := io.WriteString(a.w, strings.Join(ankiHeader, "\n")+"\n")
+
+	return err
+}
+
+func (a *ankiWriter) Write(word db.ExportedWord) error {
+	if err := a.writeHeader(); err != nil {
+		return err
+	}
+
+	// Anki tags can't contain spaces
+	tags := make([]string, len(word.Tags))
+	for i, t := range word.Tags {
+		tags[i] = strings.ReplaceAll(t, " ", "_")
+	}
+
+	return a.cw.Write([]string{
+		html.EscapeString(word.Word.Word),
+		ankiBack(word),
+		strings.Join(tags, " "),
+	})
+}
+
+// ankiBack is the back of a word's card: its custom definition followed by a
+// list of its senses
+func ankiBack(word db.ExportedWord) string {
+	var parts []string
+	if word.CustomDefinition != "" {
+		parts = append(parts, html.EscapeString(word.CustomDefinition))
+	}
+
+	if len(word.Senses) > 0 {
+		var b strings.Builder
+		b.WriteString("<ol>")
+
+		for _, s := range word.Senses {
+			b.WriteString("<li>")
+			if s.PartOfSpeech != "" {
+				b.WriteString("<i>" + html.EscapeString(s.PartOfSpeech) + "</i> ")
+			}
+
+			b.WriteString(html.EscapeString(s.Definition))
+
+			for _, ex := range s.Examples {
+				b.WriteString("<br><small>" + html.EscapeString(ex) + "</small>")
+			}
+
+			b.WriteString("</li>")
+		}
+
+		b.WriteString("</ol>")
+		parts = append(parts, b.String())
+	}
+
+	return strings.Join(parts, "<br>")
+}
+
+func (a *ankiWriter) Close() error {
+	if err := a.writeHeader(); err != nil {
+		return err
+	}
+
+	a.cw.Flush()
+
+	return a.cw.Error()
+}
@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// csvHeader names the columns of a CSV export, the first three of which are
+// recognised by the importer
+var csvHeader = []string{"word", "customDefinition", "tags", "definitions", "createdAt", "updatedAt"}
+
+type csvWriter struct {
+	w *csv.Writer
+
+	headerWritten bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.headerWritten {
+		return nil
+	}
+
+	c.headerWritten = true
+
+	return c.w.Write(csvHeader)
+}
+
+func (c *csvWriter) Write(word db.ExportedWord) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	definitions := make([]string, len(word.Senses))
+	for i, s := range word.Senses {
+		definitions[i] = senseText(s)
+	}
+
+	return c.w.Write([]string{
+		word.Word.Word,
+		word.CustomDefinition,
+		strings.Join(word.Tags, "; "),
+		strings.Join(definitions, "\n"),
+		word.CreatedAt.UTC().Format(timeFormat),
+		word.UpdatedAt.UTC().Format(timeFormat),
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	c.w.Flush()
+
+	return c.w.Error()
+}
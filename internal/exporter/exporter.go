@@ -0,0 +1,107 @@
+// Package exporter writes words to files that can be backed up, shared or
+// loaded into other tools, one word at a time so lists of any size can be
+// exported
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// Format is a kind of file words can be exported to
+type Format string
+
+const (
+	// FormatCSV is comma separated values with a header row. The word,
+	// customDefinition and tags columns can be imported again.
+	FormatCSV Format = "csv"
+	// FormatJSONL is JSON Lines, with an object per word whose word,
+	// customDefinition and tags fields can be imported again
+	FormatJSONL Format = "jsonl"
+	// FormatMarkdown is a glossary with a section per word
+	FormatMarkdown Format = "markdown"
+	// FormatAnki is a Notes in Plain Text file Anki can import as a deck of
+	// Basic notes, with the word on the front and its definitions on the back
+	FormatAnki Format = "anki"
+)
+
+// ParseFormat validates s as a Format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSONL, FormatMarkdown, FormatAnki:
+		return f, nil
+	case "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, must be one of csv, jsonl, markdown or anki", s)
+	}
+}
+
+// ContentType returns the media type of files in format f
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension of files in format f, including the dot
+func (f Format) Extension() string {
+	switch f {
+	case FormatCSV:
+		return ".csv"
+	case FormatJSONL:
+		return ".jsonl"
+	case FormatMarkdown:
+		return ".md"
+	default:
+		return ".txt"
+	}
+}
+
+// Writer writes words to a file
+type Writer interface {
+	// Write writes a word
+	Write(db.ExportedWord) error
+	// Close writes anything still buffered, including the header of a file
+	// without words, but doesn't close the io.Writer it was created with
+	Close() error
+}
+
+// NewWriter returns a Writer of words in format f to w
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatJSONL:
+		return newJSONLWriter(w), nil
+	case FormatMarkdown:
+		return newMarkdownWriter(w), nil
+	case FormatAnki:
+		return newAnkiWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+// timeFormat is how times are written
+const timeFormat = time.RFC3339
+
+// senseText describes a sense on one line, e.g. "(noun) A word or phrase"
+func senseText(s db.Sense) string {
+	if s.PartOfSpeech == "" {
+		return s.Definition
+	}
+
+	return "(" + s.PartOfSpeech + ") " + s.Definition
+}
@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/importer"
+)
+
+// words are exported by each test, covering custom definitions, senses, tags
+// and text that needs escaping or quoting
+var words = []db.ExportedWord{
+	{
+		Word: db.Word{
+			ID:               1,
+			Word:             "petrichor",
+			CustomDefinition: "The smell of rain, after a dry spell",
+			CreatedAt:        time.Date(2022, 1, 2, 9, 30, 0, 0, time.UTC),
+			UpdatedAt:        time.Date(2022, 3, 4, 18, 0, 0, 0, time.UTC),
+		},
+		Tags: []string{"GRE prep", "weather"},
+		Senses: []db.Sense{
+			{Source: "wiktionary", PartOfSpeech: "noun", Definition: "The distinctive scent of rain on dry earth.", Examples: []string{"The petrichor rose from the pavement."}},
+			{Definition: `A "smell" of *rain* <3`},
+		},
+	},
+	{
+		Word: db.Word{
+			ID:        2,
+			Word:      "sonder",
+			CreatedAt: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+			UpdatedAt: time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+	},
+}
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		format   string
+		expected Format
+		err      string
+	}{
+		{desc: "CSV should be parsed", format: "CSV", expected: FormatCSV},
+		{desc: "md should be Markdown", format: "md", expected: FormatMarkdown},
+		{desc: "Unknown formats should be rejected", format: "xlsx", err: `unknown format "xlsx", must be one of csv, jsonl, markdown or anki`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f, err := ParseFormat(tC.format)
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, f)
+		})
+	}
+}
+
+func export(t *testing.T, f Format, words []db.ExportedWord) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	w, err := NewWriter(f, buf)
+	assert.NoError(t, err)
+
+	for _, word := range words {
+		assert.NoError(t, w.Write(word))
+	}
+
+	assert.NoError(t, w.Close())
+
+	return buf.Bytes()
+}
+
+func TestWriters(t *testing.T) {
+	for _, f := range []Format{FormatCSV, FormatJSONL, FormatMarkdown, FormatAnki} {
+		f := f
+
+		t.Run("Given words to export as "+string(f), func(t *testing.T) {
+			t.Run("When they are written", func(t *testing.T) {
+				got := export(t, f, words)
+
+				t.Run("Then the file matches the golden file", func(t *testing.T) {
+					expected, err := os.ReadFile(filepath.Join("testdata", "words"+f.Extension()))
+					assert.NoError(t, err)
+					assert.Equal(t, string(expected), string(got))
+				})
+			})
+
+			t.Run("When there are no words", func(t *testing.T) {
+				got := export(t, f, nil)
+
+				t.Run("Then only the header is written", func(t *testing.T) {
+					expected, err := os.ReadFile(filepath.Join("testdata", "empty"+f.Extension()))
+					assert.NoError(t, err)
+					assert.Equal(t, string(expected), string(got))
+				})
+			})
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	formats := map[Format]importer.Format{
+		FormatCSV:   importer.FormatCSV,
+		FormatJSONL: importer.FormatJSONL,
+		FormatAnki:  importer.FormatAnkiText,
+	}
+
+	for f, imported := range formats {
+		f, imported := f, imported
+
+		t.Run("Given words exported as "+string(f), func(t *testing.T) {
+			b := export(t, f, words)
+
+			t.Run("When the file is imported", func(t *testing.T) {
+				r, err := importer.NewReader(imported, bytes.NewReader(b))
+				assert.NoError(t, err)
+
+				first, err := r.Read()
+				assert.NoError(t, err)
+
+				second, err := r.Read()
+				assert.NoError(t, err)
+
+				t.Run("Then the words and their tags are read back", func(t *testing.T) {
+					assert.Equal(t, "petrichor", first.Word)
+					assert.Contains(t, first.Definition, "The smell of rain, after a dry spell")
+					assert.Equal(t, "sonder", second.Word)
+					assert.Empty(t, second.Tags)
+
+					if f == FormatAnki {
+						assert.Equal(t, []string{"GRE_prep", "weather"}, first.Tags)
+					} else {
+						assert.Equal(t, []string{"GRE prep", "weather"}, first.Tags)
+					}
+				})
+			})
+		})
+	}
+}
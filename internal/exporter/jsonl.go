@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// jsonlWord is a line of a JSON Lines export. The word, customDefinition and
+// tags fields are recognised by the importer.
+type jsonlWord struct {
+	Word             string       `json:"word"`
+	CustomDefinition string       `json:"customDefinition,omitempty"`
+	Tags             []string     `json:"tags"`
+	Senses           []jsonlSense `json:"senses"`
+	CreatedAt        time.Time    `json:"createdAt"`
+	UpdatedAt        time.Time    `json:"updatedAt"`
+}
+
+type jsonlSense struct {
+	Source       string   `json:"source,omitempty"`
+	PartOfSpeech string   `json:"partOfSpeech,omitempty"`
+	Definition   string   `json:"definition"`
+	Examples     []string `json:"examples,omitempty"`
+	Synonyms     []string `json:"synonyms,omitempty"`
+	Antonyms     []string `json:"antonyms,omitempty"`
+	Etymology    string   `json:"etymology,omitempty"`
+	IPA          string   `json:"ipa,omitempty"`
+}
+
+type jsonlWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newJSONLWriter(w io.Writer) *jsonlWriter {
+	bw := bufio.NewWriter(w)
+
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+
+	return &jsonlWriter{w: bw, enc: enc}
+}
+
+func (j *jsonlWriter) Write(word db.ExportedWord) error {
+	jw := jsonlWord{
+		Word:             word.Word.Word,
+		CustomDefinition: word.CustomDefinition,
+		Tags:             word.Tags,
+		Senses:           make([]jsonlSense, len(word.Senses)),
+		CreatedAt:        word.CreatedAt.UTC(),
+		UpdatedAt:        word.UpdatedAt.UTC(),
+	}
+
+	if jw.Tags == nil {
+		jw.Tags = []string{}
+	}
+
+	for i, s := range word.Senses {
+		jw.Senses[i] = jsonlSense{
+			Source:       s.Source,
+			PartOfSpeech: s.PartOfSpeech,
+			Definition:   s.Definition,
+			Examples:     s.Examples,
+			Synonyms:     s.Synonyms,
+			Antonyms:     s.Antonyms,
+			Etymology:    s.Etymology,
+			IPA:          s.IPA,
+		}
+	}
+
+	// Encode ends each object with a new line
+	return j.enc.Encode(jw)
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// markdownTitle heads a Markdown export
+const markdownTitle = "# Glossary\n"
+
+type markdownWriter struct {
+	w *bufio.Writer
+
+	titleWritten bool
+}
+
+func newMarkdownWriter(w io.Writer) *markdownWriter {
+	return &markdownWriter{w: bufio.NewWriter(w)}
+}
+
+func (m *markdownWriter) writeTitle() error {
+	if m.titleWritten {
+		return nil
+	}
+
+	m.titleWritten = true
+
+	_, err := m.w.WriteString(markdownTitle)
+
+	return err
+}
+
+func (m *markdownWriter) Write(word db.ExportedWord) error {
+	if err := m.writeTitle(); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "\n## %s\n\n", markdownEscape(word.Word.Word))
+
+	if word.CustomDefinition != "" {
+		fmt.Fprintf(&b, "%s\n\n", markdownEscape(word.CustomDefinition))
+	}
+
+	for i, s := range word.Senses {
+		fmt.Fprintf(&b, "%d. ", i+1)
+		if s.PartOfSpeech != "" {
+			fmt.Fprintf(&b, "*%s* ", markdownEscape(s.PartOfSpeech))
+		}
+
+		fmt.Fprintf(&b, "%s\n", markdownEscape(s.Definition))
+
+		for _, ex := range s.Examples {
+			fmt.Fprintf(&b, "   > %s\n", markdownEscape(ex))
+		}
+	}
+
+	if len(word.Senses) > 0 {
+		b.WriteString("\n")
+	}
+
+	if len(word.Tags) > 0 {
+		tags := make([]string, len(word.Tags))
+		for i, t := range word.Tags {
+			tags[i] = markdownEscape(t)
+		}
+
+		// The trailing spaces break the line
+		fmt.Fprintf(&b, "Tags: %s  \n", strings.Join(tags, ", "))
+	}
+
+	fmt.Fprintf(&b, "Added %s, updated %s\n", word.CreatedAt.UTC().Format("2006-01-02"), word.UpdatedAt.UTC().Format("2006-01-02"))
+
+	_, err := m.w.WriteString(b.String())
+
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	if err := m.writeTitle(); err != nil {
+		return err
+	}
+
+	return m.w.Flush()
+}
+
+var (
+	markdownSpecial = regexp.MustCompile("[\\\\`*_\\[\\]<>#|~]")
+	// markdownListStart matches text that would start an ordered list
+	markdownListStart = regexp.MustCompile(`^(\d+)\.`)
+)
+
+// markdownEscape makes s safe to use as inline text, escaping the characters
+// Markdown would treat as formatting and joining its lines
+func markdownEscape(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	s = markdownSpecial.ReplaceAllString(s, `\$0`)
+
+	return markdownListStart.ReplaceAllString(s, `$1\.`)
+}
@@ -0,0 +1,264 @@
+// Package health periodically checks the dependencies of the application and
+// reports the results over the gRPC health protocol and HTTP
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Status of a dependency
+type Status string
+
+const (
+	// StatusUnknown is reported until a dependency has been checked
+	StatusUnknown Status = "unknown"
+	// StatusOK is reported when a dependency's last check succeeded
+	StatusOK Status = "ok"
+	// StatusUnavailable is reported when a dependency's last check failed
+	StatusUnavailable Status = "unavailable"
+)
+
+// Check is a dependency that must be available for the application to serve requests
+type Check struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Result is the outcome of the last run of a Check
+type Result struct {
+	Status    Status     `json:"status"`
+	Error     string     `json:"error,omitempty"`
+	CheckedAt *time.Time `json:"checkedAt,omitempty"`
+}
+
+// Report is the JSON body served by the HTTP handlers
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type Config struct {
+	// Interval is how often the checks are run
+	Interval time.Duration
+
+	// Timeout is how long each check is given
+	Timeout time.Duration
+
+	// Services are the gRPC services whose serving status follows the checks,
+	// in addition to the overall server status
+	Services []string
+
+	Checks []Check
+}
+
+// Checker runs the configured checks and records their results
+type Checker struct {
+	interval time.Duration
+	timeout  time.Duration
+	services []string
+	checks   []Check
+
+	grpc *grpchealth.Server
+
+	mu           sync.RWMutex
+	results      map[string]Result
+	shuttingDown bool
+
+	now func() time.Time
+}
+
+// New returns a Checker. Nothing is reported as healthy until the checks have run.
+func New(c Config) *Checker {
+	ch := &Checker{
+		interval: c.Interval,
+		timeout:  c.Timeout,
+		services: c.Services,
+		checks:   c.Checks,
+
+		grpc:    grpchealth.NewServer(),
+		results: make(map[string]Result, len(c.Checks)),
+
+		now: time.Now,
+	}
+
+	for _, check := range c.Checks {
+		ch.results[check.Name] = Result{Status: StatusUnknown}
+	}
+
+	ch.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+
+	return ch
+}
+
+// Register adds the grpc.health.v1.Health service to s
+func (c *Checker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, c.grpc)
+}
+
+// Run checks the dependencies every interval until ctx is done
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckNow(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckNow runs every check concurrently and records the results
+func (c *Checker) CheckNow(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, check := range c.checks {
+		wg.Add(1)
+
+		go func(check Check) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
+			defer cancel()
+
+			err := check.Check(checkCtx)
+			c.record(check.Name, err)
+		}(check)
+	}
+
+	wg.Wait()
+
+	if c.Ready() {
+		c.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		c.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+}
+
+// Shutdown reports every service as not serving from now on, so that traffic
+// is moved elsewhere while the application stops
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	c.shuttingDown = true
+	c.mu.Unlock()
+
+	c.grpc.Shutdown()
+}
+
+// Ready reports whether every check passed the last time it was run
+func (c *Checker) Ready() bool {
+	return c.Report().Status == StatusOK
+}
+
+// Report returns the result of every check
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	r := Report{
+		Status: StatusOK,
+		Checks: make(map[string]Result, len(c.results)),
+	}
+
+	if c.shuttingDown {
+		r.Status = StatusUnavailable
+	}
+
+	for name, result := range c.results {
+		r.Checks[name] = result
+
+		if result.Status != StatusOK {
+			r.Status = StatusUnavailable
+		}
+	}
+
+	return r
+}
+
+// LivenessHandler reports whether the application is running. It always
+// responds 200 OK, with the result of every check for information.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report()
+		report.Status = StatusOK
+
+		writeReport(w, http.StatusOK, report)
+	})
+}
+
+// ReadinessHandler reports whether the application can serve requests. It
+// responds 503 Service Unavailable if any check failed.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Report()
+
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+
+		writeReport(w, code, report)
+	})
+}
+
+func (c *Checker) record(name string, err error) {
+	checkedAt := c.now()
+
+	result := Result{Status: StatusOK, CheckedAt: &checkedAt}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+	}
+
+	c.mu.Lock()
+	previous := c.results[name]
+	c.results[name] = result
+	c.mu.Unlock()
+
+	if previous.Status == result.Status {
+		return
+	}
+
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"check": name,
+			"error": err,
+		}).Error("Health check failed")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"check": name,
+	}).Info("Health check passed")
+}
+
+func (c *Checker) setServingStatus(s healthpb.HealthCheckResponse_ServingStatus) {
+	c.grpc.SetServingStatus("", s)
+
+	for _, service := range c.services {
+		c.grpc.SetServingStatus(service, s)
+	}
+}
+
+func writeReport(w http.ResponseWriter, code int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error writing response")
+	}
+}
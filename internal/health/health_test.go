@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const service = "mywordoftheday.v1alpha1.MyWordOfTheDayService"
+
+func servingStatus(t *testing.T, c *Checker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	rsp, err := c.grpc.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	assert.NoError(t, err)
+
+	return rsp.GetStatus()
+}
+
+func serve(t *testing.T, h http.Handler) (int, Report) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report Report
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+
+	return rec.Code, report
+}
+
+func TestChecker(t *testing.T) {
+	var smtpErr error
+
+	c := New(Config{
+		Interval: time.Minute,
+		Timeout:  time.Second,
+		Services: []string{service},
+		Checks: []Check{
+			{Name: "database", Check: func(context.Context) error { return nil }},
+			{Name: "smtp", Check: func(context.Context) error { return smtpErr }},
+		},
+	})
+
+	checkedAt := time.Date(2022, 2, 1, 9, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return checkedAt }
+
+	t.Run("Given a new Checker", func(t *testing.T) {
+		t.Run("When the checks haven't run", func(t *testing.T) {
+			t.Run("Then it isn't ready and every check is unknown", func(t *testing.T) {
+				assert.False(t, c.Ready())
+				assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, c, ""))
+
+				code, report := serve(t, c.ReadinessHandler())
+				assert.Equal(t, http.StatusServiceUnavailable, code)
+				assert.Equal(t, Report{
+					Status: StatusUnavailable,
+					Checks: map[string]Result{
+						"database": {Status: StatusUnknown},
+						"smtp":     {Status: StatusUnknown},
+					},
+				}, report)
+			})
+		})
+		t.Run("When every check passes", func(t *testing.T) {
+			t.Run("Then it is ready and serving", func(t *testing.T) {
+				c.CheckNow(context.Background())
+
+				assert.True(t, c.Ready())
+				assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, c, ""))
+				assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, c, service))
+
+				code, report := serve(t, c.ReadinessHandler())
+				assert.Equal(t, http.StatusOK, code)
+				assert.Equal(t, StatusOK, report.Status)
+				assert.Equal(t, checkedAt, *report.Checks["smtp"].CheckedAt)
+			})
+		})
+		t.Run("When a check fails", func(t *testing.T) {
+			t.Run("Then it isn't ready and the failure is reported", func(t *testing.T) {
+				smtpErr = errors.New("connection refused")
+				c.CheckNow(context.Background())
+
+				assert.False(t, c.Ready())
+				assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, c, service))
+
+				code, report := serve(t, c.ReadinessHandler())
+				assert.Equal(t, http.StatusServiceUnavailable, code)
+				assert.Equal(t, StatusUnavailable, report.Status)
+				assert.Equal(t, StatusOK, report.Checks["database"].Status)
+				assert.Equal(t, Result{Status: StatusUnavailable, Error: "connection refused", CheckedAt: &checkedAt}, report.Checks["smtp"])
+			})
+			t.Run("Then it is still live", func(t *testing.T) {
+				code, report := serve(t, c.LivenessHandler())
+				assert.Equal(t, http.StatusOK, code)
+				assert.Equal(t, StatusOK, report.Status)
+				assert.Equal(t, StatusUnavailable, report.Checks["smtp"].Status)
+			})
+		})
+		t.Run("When it is shut down", func(t *testing.T) {
+			t.Run("Then it stays unready even if the checks pass", func(t *testing.T) {
+				smtpErr = nil
+				c.Shutdown()
+				c.CheckNow(context.Background())
+
+				assert.False(t, c.Ready())
+				assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus(t, c, ""))
+
+				code, _ := serve(t, c.ReadinessHandler())
+				assert.Equal(t, http.StatusServiceUnavailable, code)
+			})
+		})
+	})
+}
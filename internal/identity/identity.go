@@ -0,0 +1,23 @@
+// Package identity carries the user a request is being made on behalf of
+package identity
+
+import "context"
+
+// User identifies the caller of a request
+type User struct {
+	ID       int32
+	Username string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying u
+func NewContext(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// FromContext returns the User carried by ctx, if any
+func FromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(contextKey{}).(User)
+	return u, ok
+}
@@ -0,0 +1,320 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	pkgerrors "github.com/pkg/errors"
+	// Registers the sqlite driver used to read Anki collections
+	_ "modernc.org/sqlite"
+)
+
+// ankiTextReader reads the Notes in Plain Text export of Anki. Exports from
+// Anki 2.1.55 onwards start with #key:value header lines describing the
+// separator and which columns hold the note type, deck and tags.
+type ankiTextReader struct {
+	r *csv.Reader
+
+	html bool
+	// headerLines is the number of header lines before the first note
+	headerLines int
+	// tagsColumn is the index of the tags column, or -1 if there isn't one
+	tagsColumn int
+	// skipColumns are the indexes of the columns that aren't note fields
+	skipColumns map[int]bool
+}
+
+// ankiSeparators are the names Anki gives separators in its header
+var ankiSeparators = map[string]rune{
+	"tab":       '\t',
+	"comma":     ',',
+	"semicolon": ';',
+	"space":     ' ',
+	"pipe":      '|',
+	"colon":     ':',
+}
+
+func newAnkiTextReader(r io.Reader) (*ankiTextReader, error) {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	a := &ankiTextReader{
+		// Exports without a header include HTML unless told not to
+		html:        true,
+		tagsColumn:  -1,
+		skipColumns: map[int]bool{},
+	}
+	separator := '\t'
+
+	for {
+		b, err := br.Peek(1)
+		if err != nil || b[0] != '#' {
+			break
+		}
+
+		line, err := br.ReadString('\n')
+		a.headerLines++
+
+		key, value, _ := cut(strings.TrimSpace(strings.TrimPrefix(line, "#")), ":")
+		switch key {
+		case "separator":
+			sep, ok := ankiSeparators[strings.ToLower(value)]
+			if !ok {
+				sep, _ = utf8.DecodeRuneInString(value)
+			}
+
+			separator = sep
+		case "html":
+			a.html = value == "true"
+		case "tags column", "notetype column", "deck column", "guid column":
+			column, err := strconv.Atoi(value)
+			if err != nil || column < 1 {
+				return nil, &RowError{Line: a.headerLines, Err: fmt.Errorf("invalid %s %q", key, value)}
+			}
+
+			a.skipColumns[column-1] = true
+			if key == "tags column" {
+				a.tagsColumn = column - 1
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	a.r = csv.NewReader(br)
+	a.r.Comma = separator
+	a.r.FieldsPerRecord = -1
+	a.r.LazyQuotes = true
+
+	return a, nil
+}
+
+func (a *ankiTextReader) Read() (Record, error) {
+	columns, err := a.r.Read()
+	if err != nil {
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return Record{}, &RowError{Line: a.headerLines + pe.StartLine, Err: pe.Err}
+		}
+
+		return Record{}, err
+	}
+
+	line, _ := a.r.FieldPos(0)
+
+	rec := Record{Line: a.headerLines + line}
+
+	var fields []string
+	for i, c := range columns {
+		switch {
+		case i == a.tagsColumn:
+			rec.Tags = splitTags(c, unicode.IsSpace)
+		case !a.skipColumns[i]:
+			fields = append(fields, a.text(c))
+		}
+	}
+
+	if len(fields) > 0 {
+		rec.Word = fields[0]
+	}
+
+	if len(fields) > 1 {
+		rec.Definition = fields[1]
+	}
+
+	return rec, nil
+}
+
+func (a *ankiTextReader) text(s string) string {
+	if a.html {
+		return stripHTML(s)
+	}
+
+	return strings.TrimSpace(s)
+}
+
+func (a *ankiTextReader) Close() error {
+	return nil
+}
+
+// maxCollectionSize limits how large the collection in an Anki package can be
+// once decompressed. It's a variable so tests can lower it.
+var maxCollectionSize int64 = 512 * 1024 * 1024
+
+// ErrCollectionTooLarge is returned for an Anki package whose collection is
+// larger than maxCollectionSize once decompressed
+var ErrCollectionTooLarge = errors.New("collection too large")
+
+// ankiPackageReader reads the notes of an Anki package, which is a zip file
+// holding the SQLite database of an Anki collection
+type ankiPackageReader struct {
+	// dir holds the package and its collection while they are read
+	dir  string
+	db   *sql.DB
+	rows *sql.Rows
+	note int
+}
+
+func newAnkiPackageReader(r io.Reader) (*ankiPackageReader, error) {
+	dir, err := os.MkdirTemp("", "mywordoftheday-apkg-")
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "unable to create temporary directory")
+	}
+
+	a := &ankiPackageReader{dir: dir}
+	if err := a.open(r); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// open copies the package to disk, as zip files need random access, and
+// queries the notes of the collection inside it
+func (a *ankiPackageReader) open(r io.Reader) error {
+	pkg := filepath.Join(a.dir, "package.apkg")
+	if _, err := writeFile(pkg, r); err != nil {
+		return pkgerrors.Wrap(err, "unable to save package")
+	}
+
+	zr, err := zip.OpenReader(pkg)
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to open package")
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	// Packages that support older versions of Anki include both, with a
+	// placeholder in collection.anki2
+	collection := files["collection.anki21"]
+	if collection == nil {
+		collection = files["collection.anki2"]
+	}
+
+	if collection == nil {
+		if files["collection.anki21b"] != nil {
+			return errors.New("the package needs Anki 2.1.50 or later, export it with \"Support older Anki versions\" ticked")
+		}
+
+		return errors.New("the package has no collection")
+	}
+
+	cr, err := collection.Open()
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to open collection")
+	}
+	defer cr.Close()
+
+	// Reading a byte past the limit tells a collection that's too large apart
+	// from one that's exactly the limit
+	path := filepath.Join(a.dir, "collection.db")
+	n, err := writeFile(path, io.LimitReader(cr, maxCollectionSize+1))
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to extract collection")
+	}
+
+	if n > maxCollectionSize {
+		return ErrCollectionTooLarge
+	}
+
+	a.db, err = sql.Open("sqlite", path)
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to open collection")
+	}
+
+	a.rows, err = a.db.Query("SELECT flds, tags FROM notes ORDER BY id")
+	if err != nil {
+		return pkgerrors.Wrap(err, "unable to read notes")
+	}
+
+	return nil
+}
+
+func (a *ankiPackageReader) Read() (Record, error) {
+	if !a.rows.Next() {
+		if err := a.rows.Err(); err != nil {
+			return Record{}, pkgerrors.Wrap(err, "unable to read notes")
+		}
+
+		return Record{}, io.EOF
+	}
+
+	a.note++
+
+	var flds, tags string
+	if err := a.rows.Scan(&flds, &tags); err != nil {
+		return Record{}, &RowError{Line: a.note, Err: err}
+	}
+
+	// Fields are separated by the unit separator
+	fields := strings.Split(flds, "\x1f")
+
+	rec := Record{
+		Line: a.note,
+		Word: stripHTML(fields[0]),
+		Tags: splitTags(tags, unicode.IsSpace),
+	}
+
+	if len(fields) > 1 {
+		rec.Definition = stripHTML(fields[1])
+	}
+
+	return rec, nil
+}
+
+func (a *ankiPackageReader) Close() error {
+	if a.rows != nil {
+		a.rows.Close()
+	}
+
+	if a.db != nil {
+		a.db.Close()
+	}
+
+	return os.RemoveAll(a.dir)
+}
+
+// writeFile copies r to a new file at path, returning how many bytes it wrote
+func writeFile(path string, r io.Reader) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return n, err
+	}
+
+	return n, f.Close()
+}
+
+// cut slices s around the first instance of sep, as strings.Cut does from Go 1.18
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,172 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnkiTextReader(t *testing.T) {
+	t.Run("Given a Notes in Plain Text export with a header", func(t *testing.T) {
+		f, err := os.Open("testdata/anki.txt")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		r, err := NewReader(FormatAnkiText, f)
+		assert.NoError(t, err)
+		defer r.Close()
+
+		t.Run("When it's read", func(t *testing.T) {
+			records, errs := readAll(t, r)
+
+			t.Run("Then the note type column is skipped, HTML is stripped and tags are split on spaces", func(t *testing.T) {
+				assert.Empty(t, errs)
+				assert.Equal(t, []Record{
+					{Line: 5, Word: "petrichor", Definition: "The smell of rain\nafter a dry spell", Tags: []string{"weather", "GRE::nouns"}},
+					{Line: 6, Word: "sonder", Definition: "The realisation that everyone has a complex life"},
+				}, records)
+			})
+		})
+	})
+
+	t.Run("Given an export with another separator and no HTML", func(t *testing.T) {
+		r, err := NewReader(FormatAnkiText, strings.NewReader("#separator:semicolon\n#html:false\npetrichor;<rain>\n"))
+		assert.NoError(t, err)
+
+		t.Run("Then fields are split on it and kept as they are", func(t *testing.T) {
+			records, errs := readAll(t, r)
+			assert.Empty(t, errs)
+			assert.Equal(t, []Record{{Line: 3, Word: "petrichor", Definition: "<rain>"}}, records)
+		})
+	})
+
+	t.Run("Given an export without a header", func(t *testing.T) {
+		r, err := NewReader(FormatAnkiText, strings.NewReader("petrichor\tThe smell of rain\n"))
+		assert.NoError(t, err)
+
+		t.Run("Then fields are split on tabs", func(t *testing.T) {
+			records, errs := readAll(t, r)
+			assert.Empty(t, errs)
+			assert.Equal(t, []Record{{Line: 1, Word: "petrichor", Definition: "The smell of rain"}}, records)
+		})
+	})
+
+	t.Run("Given a header with an invalid column", func(t *testing.T) {
+		_, err := NewReader(FormatAnkiText, strings.NewReader("#tags column:last\n"))
+
+		t.Run("Then an error is returned", func(t *testing.T) {
+			assert.EqualError(t, err, `line 1: invalid tags column "last"`)
+		})
+	})
+}
+
+// ankiPackage builds an Anki package holding the given notes in a collection
+// with the given name
+func ankiPackage(t *testing.T, collection string, notes [][2]string) []byte {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "collection.db")
+
+	db, err := sql.Open("sqlite", path)
+	assert.NoError(t, err)
+
+	_, err = db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY, flds TEXT NOT NULL, tags TEXT NOT NULL)")
+	assert.NoError(t, err)
+
+	for i, n := range notes {
+		_, err = db.Exec("INSERT INTO notes(id, flds, tags) VALUES(?, ?, ?)", 1000-i, n[0], n[1])
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, db.Close())
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+
+	w, err := zw.Create(collection)
+	assert.NoError(t, err)
+
+	_, err = w.Write(b)
+	assert.NoError(t, err)
+
+	w, err = zw.Create("media")
+	assert.NoError(t, err)
+
+	_, err = w.Write([]byte("{}"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestAnkiPackageReader(t *testing.T) {
+	t.Run("Given an Anki package", func(t *testing.T) {
+		pkg := ankiPackage(t, "collection.anki21", [][2]string{
+			{"sonder\x1fThe realisation that everyone<br>has a complex life", ""},
+			{"<b>petrichor</b>\x1fThe smell of rain\x1fextra field", " weather GRE::nouns "},
+		})
+
+		r, err := NewReader(FormatAnkiPackage, bytes.NewReader(pkg))
+		assert.NoError(t, err)
+
+		t.Run("When it's read", func(t *testing.T) {
+			records, errs := readAll(t, r)
+
+			t.Run("Then the first two fields of each note are read in the order they were added", func(t *testing.T) {
+				assert.Empty(t, errs)
+				assert.Equal(t, []Record{
+					{Line: 1, Word: "petrichor", Definition: "The smell of rain", Tags: []string{"weather", "GRE::nouns"}},
+					{Line: 2, Word: "sonder", Definition: "The realisation that everyone\nhas a complex life"},
+				}, records)
+			})
+		})
+
+		t.Run("When it's closed", func(t *testing.T) {
+			dir := r.(*ankiPackageReader).dir
+			assert.NoError(t, r.Close())
+
+			t.Run("Then its temporary files are removed", func(t *testing.T) {
+				_, err := os.Stat(dir)
+				assert.True(t, os.IsNotExist(err))
+			})
+		})
+	})
+
+	t.Run("Given a package that only newer versions of Anki can read", func(t *testing.T) {
+		pkg := ankiPackage(t, "collection.anki21b", nil)
+
+		t.Run("Then an error explains how to export it", func(t *testing.T) {
+			_, err := NewReader(FormatAnkiPackage, bytes.NewReader(pkg))
+			assert.EqualError(t, err, `the package needs Anki 2.1.50 or later, export it with "Support older Anki versions" ticked`)
+		})
+	})
+
+	t.Run("Given a package whose collection is too large", func(t *testing.T) {
+		pkg := ankiPackage(t, "collection.anki21", [][2]string{{"sonder", ""}})
+
+		defer func(size int64) { maxCollectionSize = size }(maxCollectionSize)
+		maxCollectionSize = 1024
+
+		t.Run("Then ErrCollectionTooLarge is returned", func(t *testing.T) {
+			_, err := NewReader(FormatAnkiPackage, bytes.NewReader(pkg))
+			assert.ErrorIs(t, err, ErrCollectionTooLarge)
+		})
+	})
+
+	t.Run("Given a file that isn't a package", func(t *testing.T) {
+		t.Run("Then an error is returned", func(t *testing.T) {
+			_, err := NewReader(FormatAnkiPackage, strings.NewReader("word,definition"))
+			assert.EqualError(t, err, "unable to open package: zip: not a valid zip file")
+		})
+	})
+}
@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// utf8BOM is written at the start of CSV files by some spreadsheets
+const utf8BOM = "\ufeff"
+
+type csvReader struct {
+	r *csv.Reader
+
+	// columns maps each field to a column index, once the first row is read
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	br := bufio.NewReader(r)
+	if b, err := br.Peek(len(utf8BOM)); err == nil && string(b) == utf8BOM {
+		_, _ = br.Discard(len(utf8BOM))
+	}
+
+	cr := csv.NewReader(br)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	return &csvReader{r: cr}
+}
+
+// csvHeaders are the column names recognised in a header row, and the field
+// they hold
+var csvHeaders = map[string]string{
+	"word":              "word",
+	"definition":        "definition",
+	"custom_definition": "definition",
+	"customdefinition":  "definition",
+	"tags":              "tags",
+}
+
+func (c *csvReader) Read() (Record, error) {
+	fields, err := c.r.Read()
+	if err != nil {
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			return Record{}, &RowError{Line: pe.StartLine, Err: pe.Err}
+		}
+
+		return Record{}, err
+	}
+
+	line, _ := c.r.FieldPos(0)
+
+	if c.columns == nil {
+		c.columns = c.header(fields)
+		if c.columns != nil {
+			return c.Read()
+		}
+
+		c.columns = map[string]int{"word": 0, "definition": 1, "tags": 2}
+	}
+
+	field := func(name string) string {
+		i, ok := c.columns[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+
+		return strings.TrimSpace(fields[i])
+	}
+
+	return Record{
+		Line:       line,
+		Word:       field("word"),
+		Definition: field("definition"),
+		Tags:       splitTags(field("tags"), func(r rune) bool { return r == ';' }),
+	}, nil
+}
+
+// header returns the columns named by fields if they are a header row with a
+// word column, or nil if they're the first word
+func (c *csvReader) header(fields []string) map[string]int {
+	columns := map[string]int{}
+	for i, f := range fields {
+		if name, ok := csvHeaders[strings.ToLower(strings.TrimSpace(f))]; ok {
+			columns[name] = i
+		}
+	}
+
+	if _, ok := columns["word"]; !ok {
+		return nil
+	}
+
+	return columns
+}
+
+func (c *csvReader) Close() error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVReader(t *testing.T) {
+	t.Run("Given a CSV file with a header row", func(t *testing.T) {
+		f, err := os.Open("testdata/words.csv")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		r, err := NewReader(FormatCSV, f)
+		assert.NoError(t, err)
+		defer r.Close()
+
+		t.Run("When it's read", func(t *testing.T) {
+			records, errs := readAll(t, r)
+
+			t.Run("Then the columns are matched by name", func(t *testing.T) {
+				assert.Equal(t, []Record{
+					{Line: 2, Word: "petrichor", Definition: "The smell of rain, after a dry spell", Tags: []string{"weather", "nature"}},
+					{Line: 3, Word: "sonder"},
+					{Line: 5, Word: "susurrus", Definition: "A whispering sound"},
+				}, records)
+			})
+
+			t.Run("Then malformed rows are reported with their line", func(t *testing.T) {
+				assert.Equal(t, []string{`line 4: bare " in non-quoted-field`}, errs)
+			})
+		})
+	})
+
+	t.Run("Given a CSV file without a header row", func(t *testing.T) {
+		r, err := NewReader(FormatCSV, strings.NewReader("petrichor,The smell of rain,weather\nsonder\n"))
+		assert.NoError(t, err)
+
+		t.Run("Then the columns are word, definition and tags", func(t *testing.T) {
+			records, errs := readAll(t, r)
+			assert.Empty(t, errs)
+			assert.Equal(t, []Record{
+				{Line: 1, Word: "petrichor", Definition: "The smell of rain", Tags: []string{"weather"}},
+				{Line: 2, Word: "sonder"},
+			}, records)
+		})
+	})
+}
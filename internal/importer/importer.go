@@ -0,0 +1,148 @@
+// Package importer reads words from files exported by spreadsheets and other
+// flashcard tools, so a list can be seeded in bulk
+package importer
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Format is a kind of file words can be imported from
+type Format string
+
+const (
+	// FormatCSV is comma separated values with word, definition and tags
+	// columns, in that order unless the first row names them. Tags are
+	// separated by semicolons.
+	FormatCSV Format = "csv"
+	// FormatJSONL is JSON Lines, with an object per word holding word,
+	// customDefinition (or definition) and tags fields
+	FormatJSONL Format = "jsonl"
+	// FormatAnkiText is the Notes in Plain Text export of Anki, with the word
+	// in the first field and its definition in the second
+	FormatAnkiText Format = "anki"
+	// FormatAnkiPackage is an Anki deck package (.apkg), with the word in the
+	// first field of each note and its definition in the second
+	FormatAnkiPackage Format = "apkg"
+)
+
+// ParseFormat validates s as a Format
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSONL, FormatAnkiText, FormatAnkiPackage:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q, must be one of csv, jsonl, anki or apkg", s)
+	}
+}
+
+// FormatFromFilename guesses the Format of a file from its extension, returning
+// an empty Format if it can't tell
+func FormatFromFilename(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return FormatCSV
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".txt", ".tsv":
+		return FormatAnkiText
+	case ".apkg":
+		return FormatAnkiPackage
+	default:
+		return ""
+	}
+}
+
+// Record is a word read from a file
+type Record struct {
+	// Line is the line of the file the word was read from, or for Anki
+	// packages, the position of its note
+	Line       int
+	Word       string
+	Definition string
+	Tags       []string
+}
+
+// RowError is returned for a record that couldn't be read. Reading can carry on
+// with the following records.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads records from a file
+type Reader interface {
+	// Read returns the next record, a *RowError if the next record is
+	// malformed, or io.EOF once there are no more records
+	Read() (Record, error)
+	// Close releases anything held by the reader, but not the io.Reader it
+	// was created with
+	Close() error
+}
+
+// NewReader returns a Reader of records in format f from r
+func NewReader(f Format, r io.Reader) (Reader, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVReader(r), nil
+	case FormatJSONL:
+		return newJSONLReader(r), nil
+	case FormatAnkiText:
+		return newAnkiTextReader(r)
+	case FormatAnkiPackage:
+		return newAnkiPackageReader(r)
+	default:
+		return nil, fmt.Errorf("unknown format %q", f)
+	}
+}
+
+var (
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li)>`)
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	ankiSound = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	blankRuns = regexp.MustCompile(`[ \t]+`)
+)
+
+// stripHTML converts an Anki field to plain text, dropping formatting, media
+// and sound references
+func stripHTML(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	s = ankiSound.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, l := range lines {
+		if l = strings.TrimSpace(blankRuns.ReplaceAllString(l, " ")); l != "" {
+			kept = append(kept, l)
+		}
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// splitTags splits s on sep, dropping empty tags
+func splitTags(s string, sep func(rune) bool) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(s, sep) {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+
+	return tags
+}
@@ -0,0 +1,101 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		format   string
+		expected Format
+		err      string
+	}{
+		{desc: "CSV should be parsed", format: "csv", expected: FormatCSV},
+		{desc: "Formats should be case-insensitive", format: "APKG", expected: FormatAnkiPackage},
+		{desc: "Unknown formats should be rejected", format: "xlsx", err: `unknown format "xlsx", must be one of csv, jsonl, anki or apkg`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			f, err := ParseFormat(tC.format)
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, f)
+		})
+	}
+}
+
+func TestFormatFromFilename(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		name     string
+		expected Format
+	}{
+		{desc: "CSV files should be recognised", name: "words.CSV", expected: FormatCSV},
+		{desc: "NDJSON files should be JSON Lines", name: "/tmp/words.ndjson", expected: FormatJSONL},
+		{desc: "Text files should be Anki exports", name: "deck.txt", expected: FormatAnkiText},
+		{desc: "Anki packages should be recognised", name: "deck.apkg", expected: FormatAnkiPackage},
+		{desc: "Unknown extensions should be empty", name: "words.xlsx", expected: ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, FormatFromFilename(tC.name))
+		})
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		field    string
+		expected string
+	}{
+		{desc: "Plain text should be unchanged", field: "petrichor", expected: "petrichor"},
+		{desc: "Formatting should be dropped", field: "<b>bold</b> <i>move</i>", expected: "bold move"},
+		{desc: "Breaks should become new lines", field: "<div>one</div><div>two<br/>three</div>", expected: "one\ntwo\nthree"},
+		{desc: "Entities should be unescaped", field: "salt&nbsp;&amp;&nbsp;pepper", expected: "salt & pepper"},
+		{desc: "Sounds should be dropped", field: "word [sound:word.mp3]", expected: "word"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, stripHTML(tC.field))
+		})
+	}
+}
+
+// readAll reads every record from r, collecting the row errors
+func readAll(t *testing.T, r Reader) ([]Record, []string) {
+	t.Helper()
+
+	var (
+		records []Record
+		errs    []string
+	)
+
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return records, errs
+		}
+
+		var re *RowError
+		if errors.As(err, &re) {
+			errs = append(errs, re.Error())
+			continue
+		}
+
+		if !assert.NoError(t, err) {
+			return records, errs
+		}
+
+		records = append(records, rec)
+	}
+}
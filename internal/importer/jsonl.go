@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonlRecord is a line of a JSON Lines file
+type jsonlRecord struct {
+	Word             string   `json:"word"`
+	CustomDefinition string   `json:"customDefinition"`
+	Definition       string   `json:"definition"`
+	Tags             []string `json:"tags"`
+}
+
+type jsonlReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	return &jsonlReader{s: s}
+}
+
+func (j *jsonlReader) Read() (Record, error) {
+	for j.s.Scan() {
+		j.line++
+
+		b := j.s.Bytes()
+		if len(strings.TrimSpace(string(b))) == 0 {
+			continue
+		}
+
+		var jr jsonlRecord
+		if err := json.Unmarshal(b, &jr); err != nil {
+			return Record{}, &RowError{Line: j.line, Err: errors.Wrap(err, "invalid JSON")}
+		}
+
+		definition := jr.CustomDefinition
+		if definition == "" {
+			definition = jr.Definition
+		}
+
+		return Record{
+			Line:       j.line,
+			Word:       strings.TrimSpace(jr.Word),
+			Definition: strings.TrimSpace(definition),
+			Tags:       jr.Tags,
+		}, nil
+	}
+
+	if err := j.s.Err(); err != nil {
+		return Record{}, errors.Wrapf(err, "unable to read line %d", j.line+1)
+	}
+
+	return Record{}, io.EOF
+}
+
+func (j *jsonlReader) Close() error {
+	return nil
+}
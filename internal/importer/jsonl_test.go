@@ -0,0 +1,36 @@
+package importer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLReader(t *testing.T) {
+	t.Run("Given a JSON Lines file", func(t *testing.T) {
+		f, err := os.Open("testdata/words.jsonl")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		r, err := NewReader(FormatJSONL, f)
+		assert.NoError(t, err)
+		defer r.Close()
+
+		t.Run("When it's read", func(t *testing.T) {
+			records, errs := readAll(t, r)
+
+			t.Run("Then blank lines are skipped and either definition field is used", func(t *testing.T) {
+				assert.Equal(t, []Record{
+					{Line: 1, Word: "petrichor", Definition: "The smell of rain", Tags: []string{"weather", "nature"}},
+					{Line: 3, Word: "sonder", Definition: "The realisation that everyone has a complex life"},
+					{Line: 5, Word: "susurrus"},
+				}, records)
+			})
+
+			t.Run("Then invalid JSON is reported with its line", func(t *testing.T) {
+				assert.Equal(t, []string{"line 4: invalid JSON: unexpected end of JSON input"}, errs)
+			})
+		})
+	})
+}
@@ -0,0 +1,126 @@
+// Package lifecycle runs the long lived parts of the application and shuts
+// them down cleanly
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Component is a part of the application that is started and stopped by a Manager
+type Component struct {
+	Name string
+
+	// Start runs the component, blocking until it stops. It should return nil
+	// once it has been stopped by Stop. It may be nil for components that are
+	// already running and only need stopping.
+	Start func() error
+
+	// Stop asks the component to stop, returning once in-flight work has
+	// finished or ctx is done. It may be nil.
+	Stop func(ctx context.Context) error
+
+	// Timeout is how long Stop is given, defaulting to the Manager's timeout
+	Timeout time.Duration
+}
+
+// Manager starts components and stops them in the reverse order they were added
+type Manager struct {
+	timeout    time.Duration
+	components []Component
+}
+
+// New returns a Manager giving each component timeout to stop, unless the
+// component sets its own
+func New(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout}
+}
+
+// Add registers c with the Manager. Components should be added in the order
+// they depend on each other, so a component is stopped before anything it uses.
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Run starts every component and blocks until ctx is done or one of them stops
+// by itself, then stops every component. The error that caused the shutdown is
+// returned, or otherwise the first error returned while stopping.
+func (m *Manager) Run(ctx context.Context) error {
+	stopped := make(chan error, len(m.components))
+
+	for _, c := range m.components {
+		if c.Start == nil {
+			continue
+		}
+
+		c := c
+		go func() {
+			err := c.Start()
+			if err == nil {
+				err = fmt.Errorf("%s stopped unexpectedly", c.Name)
+			}
+
+			stopped <- errors.Wrapf(err, "%s failed", c.Name)
+		}()
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logrus.Info("Shutting down")
+	case runErr = <-stopped:
+		logrus.WithFields(logrus.Fields{
+			"error": runErr,
+		}).Error("Shutting down after a component stopped")
+	}
+
+	if err := m.stop(); runErr == nil {
+		runErr = err
+	}
+
+	return runErr
+}
+
+// stop stops every component in reverse order, returning the first error
+func (m *Manager) stop() error {
+	var stopErr error
+
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		if c.Stop == nil {
+			continue
+		}
+
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = m.timeout
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := c.Stop(ctx)
+		cancel()
+
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"component": c.Name,
+				"error":     err,
+			}).Error("Unable to stop component")
+
+			if stopErr == nil {
+				stopErr = errors.Wrapf(err, "unable to stop %s", c.Name)
+			}
+
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"component": c.Name,
+		}).Info("Component stopped successfully")
+	}
+
+	return stopErr
+}
@@ -0,0 +1,104 @@
+package lifecycle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingComponent runs until it is stopped, recording the order it was stopped in
+func blockingComponent(name string, order *[]string) Component {
+	done := make(chan struct{})
+
+	return Component{
+		Name: name,
+		Start: func() error {
+			<-done
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			*order = append(*order, name)
+			close(done)
+			return nil
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Run("Given a Manager with several components", func(t *testing.T) {
+		t.Run("When the context is cancelled", func(t *testing.T) {
+			t.Run("Then every component is stopped in reverse order", func(t *testing.T) {
+				var order []string
+
+				m := New(time.Second)
+				m.Add(blockingComponent("db", &order))
+				m.Add(blockingComponent("grpc", &order))
+				m.Add(blockingComponent("http", &order))
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				assert.NoError(t, m.Run(ctx))
+				assert.Equal(t, []string{"http", "grpc", "db"}, order)
+			})
+		})
+		t.Run("When a component fails", func(t *testing.T) {
+			t.Run("Then the others are stopped and its error is returned", func(t *testing.T) {
+				var order []string
+
+				m := New(time.Second)
+				m.Add(blockingComponent("db", &order))
+				m.Add(Component{
+					Name:  "grpc",
+					Start: func() error { return errors.New("address in use") },
+				})
+
+				err := m.Run(context.Background())
+				assert.EqualError(t, err, "grpc failed: address in use")
+				assert.Equal(t, []string{"db"}, order)
+			})
+		})
+		t.Run("When a component stops without an error", func(t *testing.T) {
+			t.Run("Then the others are stopped and an error is returned", func(t *testing.T) {
+				var order []string
+
+				m := New(time.Second)
+				m.Add(blockingComponent("db", &order))
+				m.Add(Component{
+					Name:  "grpc",
+					Start: func() error { return nil },
+				})
+
+				err := m.Run(context.Background())
+				assert.EqualError(t, err, "grpc failed: grpc stopped unexpectedly")
+				assert.Equal(t, []string{"db"}, order)
+			})
+		})
+		t.Run("When a component doesn't stop in time", func(t *testing.T) {
+			t.Run("Then the rest are still stopped and the error is returned", func(t *testing.T) {
+				var order []string
+
+				m := New(time.Second)
+				m.Add(blockingComponent("db", &order))
+				m.Add(Component{
+					Name:    "scheduler",
+					Timeout: 10 * time.Millisecond,
+					Stop: func(ctx context.Context) error {
+						<-ctx.Done()
+						return ctx.Err()
+					},
+				})
+
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				err := m.Run(ctx)
+				assert.EqualError(t, err, "unable to stop scheduler: context deadline exceeded")
+				assert.Equal(t, []string{"db"}, order)
+			})
+		})
+	})
+}
@@ -0,0 +1,82 @@
+package mail
+
+import "time"
+
+// TemplateData is what email templates are rendered with. Templates written by
+// users depend on its fields, so they're only ever added to.
+//
+// For example, in a template:
+//
+//	{{.Word}}{{with .Pronunciation}} {{.}}{{end}}
+//	{{range .Senses}}{{.PartOfSpeech}}: {{.Definition}}{{end}}
+//	Sent on {{.Date.Format "Monday 2 January"}}, day {{.Streak}} of your streak
+//	{{with .UnsubscribeURL}}<a href="{{.}}">Unsubscribe</a>{{end}}
+type TemplateData struct {
+	// Word is the word of the day
+	Word string
+	// Definition is the word's custom definition, which is shown instead of
+	// the dictionary's senses when set
+	Definition string
+	// Pronunciation is the first IPA pronunciation found in a dictionary, or
+	// failing that, given for one of the word's senses
+	Pronunciation string
+
+	// Senses are those written by hand followed, if there's no custom
+	// definition, by the dictionary's
+	Senses []Sense
+	// Examples are the examples of every sense, in order, for templates that
+	// show them apart from the senses
+	Examples []string
+
+	// Date is when the word is sent
+	Date time.Time
+	// Streak is how many days in a row, including today, the user has been
+	// sent a word
+	Streak int
+	// UnsubscribeURL is where the user can stop the emails, or empty if no
+	// unsubscribe link is configured
+	UnsubscribeURL string
+}
+
+// Subject returns the subject of the email, which templates can use too
+func (d TemplateData) Subject() string {
+	return "My Word Of The Day: " + d.Word
+}
+
+// Sense is one meaning of the word, as shown in an email
+type Sense struct {
+	PartOfSpeech string
+	Definition   string
+	// IPA is the pronunciation of the word in this sense, for words like "lead"
+	// whose pronunciation depends on their meaning
+	IPA       string
+	Examples  []string
+	Synonyms  []string
+	Antonyms  []string
+	Etymology string
+}
+
+// SampleTemplateData returns data for rendering templates without a real word,
+// with every field set so a template that renders it uses them correctly
+func SampleTemplateData() TemplateData {
+	return TemplateData{
+		Word:          "sonder",
+		Definition:    "The feeling that everyone else's life is as vivid as your own.",
+		Pronunciation: "/ˈsɒndə/",
+		Senses: []Sense{
+			{
+				PartOfSpeech: "noun",
+				Definition:   "The realization that each passerby has a life as vivid and complex as one's own.",
+				IPA:          "/ˈsɒndə/",
+				Examples:     []string{"A sudden sense of sonder came over her on the train."},
+				Synonyms:     []string{"empathy"},
+				Antonyms:     []string{"solipsism"},
+				Etymology:    "Coined in The Dictionary of Obscure Sorrows.",
+			},
+		},
+		Examples:       []string{"A sudden sense of sonder came over her on the train."},
+		Date:           time.Date(2022, 3, 4, 9, 0, 0, 0, time.UTC),
+		Streak:         7,
+		UnsubscribeURL: "https://example.com/unsubscribe",
+	}
+}
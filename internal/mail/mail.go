@@ -1,15 +1,25 @@
 package mail
 
 import (
-	"bytes"
-	"embed"
-	"fmt"
-	pkgtemplate "html/template"
+	"context"
+	"crypto/tls"
+	"io/fs"
+	netmail "net/mail"
 	"net/smtp"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mywordoftheday/backend/internal/metrics"
 )
 
+var tracer = otel.Tracer("github.com/mywordoftheday/backend/internal/mail")
+
 type Config struct {
 	SMTPHost        string
 	SMTPPort        string
@@ -17,50 +27,163 @@ type Config struct {
 	SMTPPassword    string
 	SMTPFromAddress string
 	SMTPToAddresses []string
+
+	// SMTPTLSMode is one of none, starttls or tls, defaulting to tls for port
+	// 465 and starttls otherwise
+	SMTPTLSMode string
+	// SMTPAuthMechanism is one of plain (the default), login, cram-md5 or none
+	SMTPAuthMechanism string
+	// SMTPCAFile is a PEM file of CA certificates trusted as well as the
+	// system's, for servers with certificates from a private CA
+	SMTPCAFile string
+	// SMTPInsecureSkipVerify accepts any certificate the server presents
+	SMTPInsecureSkipVerify bool
 }
 
 type Client struct {
-	auth smtp.Auth
+	// auth is nil when the server isn't authenticated with
+	auth      smtp.Auth
+	tlsMode   TLSMode
+	tlsConfig *tls.Config
+
 	host string
 	port string
 	from string
 	to   []string
 
-	template *pkgtemplate.Template
+	// templates are those emails are rendered from unless others are given
+	templates *Templates
 }
 
-// New accepts Config and an optional template and returns a configered Client
-//
-// If a template is not required, simply pass an empty string
-func New(c Config, template embed.FS, patterns ...string) (*Client, error) {
-	auth := smtp.PlainAuth("", c.SMTPFromAddress, c.SMTPPassword, c.SMTPHost)
+// New accepts Config and the templates the HTML and plain text parts of emails
+// are rendered from, which are matched by htmlPattern and textPattern in
+// templates, and returns a configured Client
+func New(c Config, templates fs.FS, htmlPattern string, textPattern string) (*Client, error) {
+	tlsMode, err := ParseTLSMode(c.SMTPTLSMode, c.SMTPPort)
+	if err != nil {
+		return nil, err
+	}
+
+	mechanism, err := ParseAuthMechanism(c.SMTPAuthMechanism)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(c.SMTPHost, c.SMTPCAFile, c.SMTPInsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	// The from address was used as the username before it could be set, so it
+	// still is when it isn't
+	username := c.SMTPUsername
+	if username == "" {
+		username = c.SMTPFromAddress
+	}
 
-	t, err := pkgtemplate.ParseFS(template, patterns...)
+	t, err := parseTemplatesFS(templates, htmlPattern, textPattern)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		auth: auth,
+		auth:      newAuth(mechanism, username, c.SMTPPassword, c.SMTPHost),
+		tlsMode:   tlsMode,
+		tlsConfig: tlsConfig,
+
 		host: c.SMTPHost,
 		port: c.SMTPPort,
 		from: c.SMTPFromAddress,
 		to:   c.SMTPToAddresses,
 
-		template: t,
+		templates: t,
 	}, nil
 }
 
-func (c *Client) SendMailFromTemplate(subject string, data interface{}) error {
-	mimeHeaders := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
+// SendMailFromTemplate sends the rendered template to the configured SMTPToAddresses
+func (c *Client) SendMailFromTemplate(ctx context.Context, subject string, data interface{}) error {
+	return c.SendMailFromTemplateTo(ctx, c.to, subject, data)
+}
+
+// SendMailFromTemplateTo sends the rendered template to the given addresses
+func (c *Client) SendMailFromTemplateTo(ctx context.Context, to []string, subject string, data interface{}) error {
+	msg, err := c.NewMessage(to, subject, data)
+	if err != nil {
+		return err
+	}
+
+	body, err := msg.Bytes()
+	if err != nil {
+		return errors.Wrap(err, "unable to build message")
+	}
+
+	return c.SendMessage(ctx, to, body)
+}
+
+// SendMessage sends a rendered message, such as one built by NewMessage, from
+// the configured SMTPFromAddress to the given addresses. Errors the server
+// says are permanent are reported as a *PermanentError.
+func (c *Client) SendMessage(ctx context.Context, to []string, msg []byte) (err error) {
+	ctx, span := tracer.Start(ctx, "smtp send",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.NetPeerNameKey.String(c.host),
+			attribute.Int("mail.recipients", len(to)),
+		),
+	)
+
+	defer func(start time.Time) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+
+		metrics.ObserveMail(start, err)
+	}(time.Now())
+
+	from, err := netmail.ParseAddress(c.from)
+	if err != nil {
+		return errors.Wrapf(err, "invalid from address %q", c.from)
+	}
+
+	return c.send(ctx, from.Address, to, msg)
+}
+
+// NewMessage renders the Client's templates with data into a message from the
+// configured SMTPFromAddress, dated now
+func (c *Client) NewMessage(to []string, subject string, data interface{}) (*Message, error) {
+	return c.NewMessageFrom(c.templates, to, subject, data)
+}
 
-	var body bytes.Buffer
-	body.Write([]byte(fmt.Sprintf("Subject: %s \n%s\n\n", subject, mimeHeaders)))
+// NewMessageFrom renders t with data into a message as NewMessage does, for
+// users who have chosen their own templates
+func (c *Client) NewMessageFrom(t *Templates, to []string, subject string, data interface{}) (*Message, error) {
+	html, text, err := t.Render(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "error executing template")
+	}
 
-	if err := c.template.Execute(&body, data); err != nil {
-		return errors.Wrap(err, "error executing template")
+	return &Message{
+		From:    c.from,
+		To:      to,
+		Subject: subject,
+		Date:    time.Now(),
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+// Ping checks the SMTP server is reachable by connecting to it and waiting for
+// its greeting, without sending anything
+func (c *Client) Ping(ctx context.Context) error {
+	client, stop, err := c.dial(ctx)
+	if err != nil {
+		return err
 	}
+	defer stop()
+	defer client.Close()
 
-	addr := fmt.Sprintf("%s:%s", c.host, c.port)
-	return smtp.SendMail(addr, c.auth, c.from, c.to, body.Bytes())
+	return client.Quit()
 }
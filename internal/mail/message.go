@@ -0,0 +1,156 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// crlf ends every line of a message, as RFC 5322 requires
+const crlf = "\r\n"
+
+// maxHeaderLineLength is the length header lines are folded at, the limit
+// RFC 5322 recommends
+const maxHeaderLineLength = 78
+
+// Message is an email with an HTML body and a plain text alternative for mail
+// clients that can't, or choose not to, show HTML
+type Message struct {
+	// From is the sender's address, optionally with a display name, e.g.
+	// "My Word Of The Day <words@example.com>"
+	From string
+	To   []string
+	// Subject may contain any Unicode, which is encoded as RFC 2047 requires
+	Subject string
+	Date    time.Time
+	// MessageID uniquely identifies the message, without the angle brackets.
+	// It's generated by Bytes if empty.
+	MessageID string
+
+	Text string
+	HTML string
+}
+
+// Bytes renders the message as an RFC 5322 message with a multipart/alternative
+// body, ready to be sent over SMTP
+func (m *Message) Bytes() ([]byte, error) {
+	from, err := netmail.ParseAddress(m.From)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid from address %q", m.From)
+	}
+
+	to := make([]string, len(m.To))
+	for i, addr := range m.To {
+		a, err := netmail.ParseAddress(addr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid to address %q", addr)
+		}
+
+		to[i] = a.String()
+	}
+
+	if m.MessageID == "" {
+		if m.MessageID, err = newMessageID(from.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+
+	// The boundary is derived from the content, so it never appears in it and
+	// rendering the same message twice gives the same bytes
+	sum := sha256.Sum256([]byte(m.Text + m.HTML))
+	boundary := "mwotd-" + hex.EncodeToString(sum[:12])
+
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", strings.Join(to, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("UTF-8", m.Subject))
+	writeHeader(&buf, "Date", m.Date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", "<"+m.MessageID+">")
+	writeHeader(&buf, "MIME-Version", "1.0")
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": boundary}))
+	buf.WriteString(crlf)
+
+	mw := multipart.NewWriter(&buf)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return nil, errors.Wrap(err, "unable to set boundary")
+	}
+
+	// Parts are in increasing order of preference, so HTML comes last
+	if err := writePart(mw, "text/plain", m.Text); err != nil {
+		return nil, errors.Wrap(err, "unable to write text part")
+	}
+
+	if err := writePart(mw, "text/html", m.HTML); err != nil {
+		return nil, errors.Wrap(err, "unable to write html part")
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, errors.Wrap(err, "unable to close body")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeHeader writes a header field, folding it at the spaces between words so
+// its lines aren't longer than maxHeaderLineLength where possible
+func writeHeader(w io.StringWriter, name string, value string) {
+	line := name + ":"
+	for _, word := range strings.Fields(value) {
+		if len(line)+1+len(word) > maxHeaderLineLength && strings.TrimSpace(line) != name+":" {
+			_, _ = w.WriteString(line + crlf)
+			line = ""
+		}
+
+		line += " " + word
+	}
+
+	_, _ = w.WriteString(line + crlf)
+}
+
+// writePart writes body as a quoted-printable UTF-8 part of mediaType, which
+// keeps lines short and converts its line endings to CRLF
+func writePart(mw *multipart.Writer, mediaType string, body string) error {
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", mime.FormatMediaType(mediaType, map[string]string{"charset": "UTF-8"}))
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+
+	pw, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	qw := quotedprintable.NewWriter(pw)
+	if _, err := io.WriteString(qw, body); err != nil {
+		return err
+	}
+
+	return qw.Close()
+}
+
+// newMessageID generates a Message-ID in the domain of the sender's address
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "unable to generate message id")
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = from[i+1:]
+	}
+
+	return fmt.Sprintf("%s@%s", hex.EncodeToString(b), domain), nil
+}
@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// date is when the messages in the tests were sent
+var date = time.Date(2022, 3, 4, 7, 30, 0, 0, time.FixedZone("", 60*60))
+
+func TestMessageBytes(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		message Message
+		golden  string
+	}{
+		{
+			desc: "An ASCII message should only have its body encoded",
+			message: Message{
+				From:      "words@example.com",
+				To:        []string{"alice@example.com"},
+				Subject:   "My Word Of The Day: petrichor",
+				Date:      date,
+				MessageID: "0123456789abcdef@example.com",
+				Text:      "Word: petrichor\n\nDefinition:\nThe smell of rain, after a dry spell\n",
+				HTML:      "<!DOCTYPE html>\n<html>\n<body>\n    <h3>Word:</h3><span>petrichor</span><br/><br/>\n</body>\n</html>",
+			},
+			golden: "ascii.eml",
+		},
+		{
+			desc: "Non-ASCII subjects, names and bodies should be encoded",
+			message: Message{
+				From:      "Mot du Jour <mots@example.fr>",
+				To:        []string{"Zoë <zoe@example.com>", "bob@example.com", "A Very Long Display Name Indeed <a.very.long.address@example.com>"},
+				Subject:   "My Word Of The Day: naïveté, or the lack of experience, wisdom and judgement",
+				Date:      date,
+				MessageID: "fedcba9876543210@example.fr",
+				Text:      "Word: naïveté /naɪˈiːv.teɪ/\n\nDefinition:\n" + strings.Repeat("Lack of experience, wisdom or judgement. ", 3) + "\n",
+				HTML:      "<span>naïveté</span> <span>/naɪˈiːv.teɪ/</span>",
+			},
+			golden: "non_ascii.eml",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			b, err := tC.message.Bytes()
+			assert.NoError(t, err)
+
+			expected, err := os.ReadFile(filepath.Join("testdata", tC.golden))
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), string(b))
+
+			assertParts(t, b, tC.message)
+		})
+	}
+}
+
+// assertParts parses the message as a mail client would and checks the
+// headers and parts match those it was built from
+func assertParts(t *testing.T, b []byte, expected Message) {
+	t.Helper()
+
+	assert.NotContains(t, strings.ReplaceAll(string(b), "\r\n", ""), "\n", "every line should end with CRLF")
+
+	for _, line := range strings.Split(string(b), "\r\n") {
+		assert.LessOrEqual(t, len(line), 998, "lines shouldn't be longer than RFC 5322 allows")
+	}
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(b))
+	assert.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Subject, subject)
+
+	to, err := msg.Header.AddressList("To")
+	assert.NoError(t, err)
+	assert.Len(t, to, len(expected.To))
+
+	sent, err := msg.Header.Date()
+	assert.NoError(t, err)
+	assert.True(t, expected.Date.Equal(sent))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ mediaType, body string }{{"text/plain", expected.Text}, {"text/html", expected.HTML}} {
+		p, err := mr.NextPart()
+		assert.NoError(t, err)
+
+		assert.Equal(t, want.mediaType+"; charset=UTF-8", p.Header.Get("Content-Type"))
+
+		// NextPart decodes quoted-printable, leaving the CRLF line endings
+		body, err := io.ReadAll(p)
+		assert.NoError(t, err)
+		assert.Equal(t, want.body, strings.ReplaceAll(string(body), "\r\n", "\n"))
+	}
+
+	_, err = mr.NextPart()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestMessageBytesInvalidAddress(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		message Message
+		err     string
+	}{
+		{desc: "An invalid from address should be rejected", message: Message{From: "words", To: []string{"alice@example.com"}}, err: `invalid from address "words": mail: missing '@' or angle-addr`},
+		{desc: "An invalid to address should be rejected", message: Message{From: "words@example.com", To: []string{"alice@example.com", "bob"}}, err: `invalid to address "bob": mail: missing '@' or angle-addr`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := tC.message.Bytes()
+			assert.EqualError(t, err, tC.err)
+		})
+	}
+}
+
+func TestMessageBytesGeneratesMessageID(t *testing.T) {
+	m := Message{From: "Words <words@example.com>", To: []string{"alice@example.com"}, Date: date}
+
+	b, err := m.Bytes()
+	assert.NoError(t, err)
+
+	assert.Regexp(t, `^[0-9a-f]{32}@example\.com$`, m.MessageID)
+	assert.Contains(t, string(b), "\r\nMessage-ID: <"+m.MessageID+">\r\n")
+}
+
+func TestClientNewMessage(t *testing.T) {
+	c, err := New(Config{SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+	assert.NoError(t, err)
+
+	m, err := c.NewMessage([]string{"alice@example.com"}, "My Word Of The Day: petrichor", struct {
+		Word       string
+		Definition string
+		Synonyms   []string
+	}{Word: "petrichor", Definition: "rain & earth", Synonyms: []string{"geosmin", "rain smell"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "words@example.com", m.From)
+	assert.Equal(t, []string{"alice@example.com"}, m.To)
+	assert.Equal(t, "petrichor: rain & earth\nSynonyms: geosmin, rain smell\n", m.Text, "the text part shouldn't be HTML escaped")
+	assert.Equal(t, "<p>petrichor: rain &amp; earth</p>\n", m.HTML)
+	assert.WithinDuration(t, time.Now(), m.Date, time.Minute)
+}
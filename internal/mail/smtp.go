@@ -0,0 +1,315 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// TLSMode is how connections to the SMTP server are secured
+type TLSMode string
+
+const (
+	// TLSNone sends everything in plain text, which is only suitable for
+	// servers on the same host or a trusted network
+	TLSNone TLSMode = "none"
+	// TLSStartTLS connects in plain text and upgrades the connection with
+	// STARTTLS before authenticating, failing if the server doesn't support it
+	TLSStartTLS TLSMode = "starttls"
+	// TLSImplicit connects with TLS from the start, as servers listening on
+	// port 465 expect
+	TLSImplicit TLSMode = "tls"
+)
+
+// ParseTLSMode validates s as a TLSMode, defaulting to TLSImplicit for port
+// 465 and TLSStartTLS for any other port when s is empty
+func ParseTLSMode(s string, port string) (TLSMode, error) {
+	switch m := TLSMode(strings.ToLower(s)); m {
+	case "":
+		if port == "465" {
+			return TLSImplicit, nil
+		}
+
+		return TLSStartTLS, nil
+	case TLSNone, TLSStartTLS, TLSImplicit:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown tls mode %q, must be one of none, starttls or tls", s)
+	}
+}
+
+// AuthMechanism is the SASL mechanism used to authenticate with the SMTP server
+type AuthMechanism string
+
+const (
+	// AuthPlain sends the username and password, so needs TLS unless the
+	// server is on the same host
+	AuthPlain AuthMechanism = "plain"
+	// AuthLogin sends the username and password in response to the server's
+	// prompts, for servers that don't support PLAIN
+	AuthLogin AuthMechanism = "login"
+	// AuthCRAMMD5 proves the password is known without sending it
+	AuthCRAMMD5 AuthMechanism = "cram-md5"
+	// AuthNone doesn't authenticate, for servers that relay mail from trusted
+	// hosts
+	AuthNone AuthMechanism = "none"
+)
+
+// ParseAuthMechanism validates s as an AuthMechanism, defaulting to AuthPlain
+// when s is empty
+func ParseAuthMechanism(s string) (AuthMechanism, error) {
+	switch m := AuthMechanism(strings.ToLower(s)); m {
+	case "":
+		return AuthPlain, nil
+	case AuthPlain, AuthLogin, AuthCRAMMD5, AuthNone:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown auth mechanism %q, must be one of plain, login, cram-md5 or none", s)
+	}
+}
+
+// newAuth returns the smtp.Auth for the mechanism, or nil for AuthNone
+func newAuth(m AuthMechanism, username string, password string, host string) smtp.Auth {
+	switch m {
+	case AuthLogin:
+		return &loginAuth{username: username, password: password, host: host}
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(username, password)
+	case AuthNone:
+		return nil
+	default:
+		return smtp.PlainAuth("", username, password, host)
+	}
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp doesn't provide. It
+// isn't standardised, but is the only mechanism some servers support.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	// Refuse to send the password in plain text, as smtp.PlainAuth does
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch strings.ToLower(strings.TrimSuffix(string(fromServer), ":")) {
+	case "username":
+		return []byte(a.username), nil
+	case "password":
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
+
+// newTLSConfig returns the config used to verify the SMTP server's
+// certificate, trusting the CAs in the PEM encoded caFile as well as the
+// system's when it's set
+func newTLSConfig(host string, caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	c := &tls.Config{
+		ServerName:         host,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if caFile == "" {
+		return c, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read ca file")
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in ca file %q", caFile)
+	}
+
+	c.RootCAs = pool
+
+	return c, nil
+}
+
+// PermanentError is a failure to send a message that the SMTP server says
+// won't go away if it's sent again, such as a recipient that doesn't exist.
+// Other errors, like being unable to connect, may be temporary.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err is, or wraps, a *PermanentError
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
+
+// permanent makes err a *PermanentError if it's a 5xx reply to a command
+// about the message. Replies to authentication are about the configuration
+// rather than the message, so aren't made permanent.
+func permanent(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return &PermanentError{Err: err}
+	}
+
+	return err
+}
+
+// dial connects to the SMTP server, with TLS for TLSImplicit, and reads its
+// greeting. The connection times out at ctx's deadline and is closed if ctx
+// is cancelled, until the returned stop func is called.
+func (c *Client) dial(ctx context.Context) (*smtp.Client, func(), error) {
+	addr := net.JoinHostPort(c.host, c.port)
+
+	var (
+		conn net.Conn
+		err  error
+	)
+
+	if c.tlsMode == TLSImplicit {
+		d := tls.Dialer{Config: c.tlsConfig}
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	} else {
+		var d net.Dialer
+		conn, err = d.DialContext(ctx, "tcp", addr)
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to connect to smtp server")
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return nil, nil, errors.Wrap(err, "unable to set deadline")
+		}
+	}
+
+	// The deadline doesn't cover ctx being cancelled, so closing the
+	// connection interrupts whatever it's waiting for
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	stop := func() { close(done) }
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		stop()
+		conn.Close()
+		return nil, nil, errors.Wrap(err, "unable to read smtp greeting")
+	}
+
+	return client, stop, nil
+}
+
+// send sends msg from the envelope sender from to the addresses in to,
+// securing the connection and authenticating as configured
+func (c *Client) send(ctx context.Context, from string, to []string, msg []byte) error {
+	client, stop, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+	defer client.Close()
+
+	if c.tlsMode == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+
+		if err := client.StartTLS(c.tlsConfig); err != nil {
+			return errors.Wrap(err, "unable to start tls")
+		}
+	}
+
+	if c.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New("smtp server doesn't support AUTH")
+		}
+
+		if err := client.Auth(c.auth); err != nil {
+			return errors.Wrap(err, "unable to authenticate")
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return permanent(errors.Wrap(err, "unable to set sender"))
+	}
+
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return permanent(errors.Wrapf(err, "unable to add recipient %q", addr))
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return permanent(errors.Wrap(err, "unable to start message"))
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return errors.Wrap(err, "unable to write message")
+	}
+
+	if err := w.Close(); err != nil {
+		return permanent(errors.Wrap(err, "unable to send message"))
+	}
+
+	// The server has accepted the message, so failing to say goodbye mustn't
+	// get it sent again
+	if err := client.Quit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Warn("Error quitting smtp session after the message was accepted")
+	}
+
+	return nil
+}
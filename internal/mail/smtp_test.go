@@ -0,0 +1,659 @@
+package mail
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receivedMessage is a message accepted by a fakeSMTPServer
+type receivedMessage struct {
+	From string
+	To   []string
+	Data string
+	// TLS reports whether the connection was encrypted when it was sent
+	TLS bool
+	// Mechanism and Username are how the client authenticated, if it did
+	Mechanism string
+	Username  string
+}
+
+// fakeSMTPServer is an in-process SMTP server, just capable enough to test the
+// client's TLS modes and auth mechanisms
+type fakeSMTPServer struct {
+	ln net.Listener
+
+	tlsConfig *tls.Config
+	// implicitTLS makes the listener accept TLS connections only
+	implicitTLS bool
+	// startTLS advertises and accepts STARTTLS
+	startTLS bool
+	// mechanisms are the AUTH mechanisms advertised, in upper case
+	mechanisms []string
+	username   string
+	password   string
+	// rejected maps recipients to the reply to RCPT for them
+	rejected map[string]string
+	// dropOnQuit closes the connection instead of replying to QUIT
+	dropOnQuit bool
+	// stallData makes the server never reply once it has read a message
+	stallData bool
+
+	mu       sync.Mutex
+	messages []receivedMessage
+}
+
+// newFakeSMTPServer starts a fakeSMTPServer on a random port of 127.0.0.1,
+// with a certificate that's only trusted when caFile is
+func newFakeSMTPServer(t *testing.T, cert tls.Certificate, configure func(*fakeSMTPServer)) *fakeSMTPServer {
+	t.Helper()
+
+	s := &fakeSMTPServer{
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12},
+		username:  "user",
+		password:  "secret",
+	}
+
+	if configure != nil {
+		configure(s)
+	}
+
+	var err error
+	if s.implicitTLS {
+		s.ln, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConfig)
+	} else {
+		s.ln, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	t.Cleanup(func() { s.ln.Close() })
+
+	go s.serve()
+
+	return s
+}
+
+func (s *fakeSMTPServer) port() string {
+	return fmt.Sprint(s.ln.Addr().(*net.TCPAddr).Port)
+}
+
+func (s *fakeSMTPServer) received() []receivedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]receivedMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	_, isTLS := conn.(*tls.Conn)
+	tp := textproto.NewConn(conn)
+	msg := receivedMessage{TLS: isTLS}
+
+	reply := func(format string, args ...interface{}) bool {
+		return tp.PrintfLine(format, args...) == nil
+	}
+
+	// readResponse reads a base64 encoded response to an AUTH challenge
+	readResponse := func() (string, bool) {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", false
+		}
+
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			reply("501 invalid base64")
+			return "", false
+		}
+
+		return string(b), true
+	}
+
+	authenticated := func(mechanism string, username string, password string) bool {
+		if username != s.username || password != s.password {
+			return reply("535 5.7.8 Authentication credentials invalid")
+		}
+
+		msg.Mechanism, msg.Username = mechanism, username
+
+		return reply("235 2.7.0 Authentication successful")
+	}
+
+	if !reply("220 fake.example.com ESMTP") {
+		return
+	}
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"fake.example.com"}
+			if s.startTLS && !msg.TLS {
+				lines = append(lines, "STARTTLS")
+			}
+
+			if len(s.mechanisms) > 0 {
+				lines = append(lines, "AUTH "+strings.Join(s.mechanisms, " "))
+			}
+
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+
+				if !reply("250%s%s", sep, l) {
+					return
+				}
+			}
+		case "STARTTLS":
+			if !s.startTLS || msg.TLS {
+				reply("502 5.5.1 Not supported")
+				continue
+			}
+
+			if !reply("220 2.0.0 Ready to start TLS") {
+				return
+			}
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg = receivedMessage{TLS: true}
+		case "AUTH":
+			mechanism, initial, _ := cut(arg, " ")
+
+			switch strings.ToUpper(mechanism) {
+			case "PLAIN":
+				if initial == "" {
+					reply("334 ")
+
+					var ok bool
+					if initial, ok = readResponse(); !ok {
+						return
+					}
+				} else {
+					b, _ := base64.StdEncoding.DecodeString(initial)
+					initial = string(b)
+				}
+
+				parts := strings.Split(initial, "\x00")
+				if len(parts) != 3 {
+					reply("501 malformed PLAIN response")
+					continue
+				}
+
+				authenticated("PLAIN", parts[1], parts[2])
+			case "LOGIN":
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Username:")))
+				username, ok := readResponse()
+				if !ok {
+					return
+				}
+
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte("Password:")))
+				password, ok := readResponse()
+				if !ok {
+					return
+				}
+
+				authenticated("LOGIN", username, password)
+			case "CRAM-MD5":
+				challenge := "<1896.697170952@fake.example.com>"
+				reply("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+
+				response, ok := readResponse()
+				if !ok {
+					return
+				}
+
+				username, digest, _ := cut(response, " ")
+
+				mac := hmac.New(md5.New, []byte(s.password))
+				mac.Write([]byte(challenge))
+
+				password := ""
+				if digest == hex.EncodeToString(mac.Sum(nil)) {
+					password = s.password
+				}
+
+				authenticated("CRAM-MD5", username, password)
+			default:
+				reply("504 5.5.4 Unrecognized authentication type")
+			}
+		case "MAIL":
+			msg.From = pathArg(arg, "FROM:")
+			reply("250 2.1.0 Ok")
+		case "RCPT":
+			to := pathArg(arg, "TO:")
+			if r, ok := s.rejected[to]; ok {
+				reply(r)
+				continue
+			}
+
+			msg.To = append(msg.To, to)
+			reply("250 2.1.5 Ok")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+
+			b, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+
+			msg.Data = string(b)
+
+			if s.stallData {
+				_, _ = io.Copy(io.Discard, conn)
+				return
+			}
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+
+			reply("250 2.0.0 Ok: queued")
+		case "RSET", "NOOP":
+			reply("250 2.0.0 Ok")
+		case "QUIT":
+			if !s.dropOnQuit {
+				reply("221 2.0.0 Bye")
+			}
+			return
+		default:
+			reply("502 5.5.2 Error: command not recognized")
+		}
+	}
+}
+
+// pathArg returns the address in a MAIL or RCPT argument, e.g. FROM:<a@b.c>
+func pathArg(arg string, prefix string) string {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return ""
+	}
+
+	return strings.Trim(arg[len(prefix):], "<>")
+}
+
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
+
+// newCertificate generates a self-signed certificate for 127.0.0.1, returning
+// it and the path of a PEM file it's written to, to be used as the CA file
+func newCertificate(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake.example.com"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create certificate: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("unable to write ca file: %v", err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caFile
+}
+
+func TestParseTLSMode(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		mode     string
+		port     string
+		expected TLSMode
+		err      string
+	}{
+		{desc: "Port 465 should default to implicit TLS", port: "465", expected: TLSImplicit},
+		{desc: "Other ports should default to STARTTLS", port: "587", expected: TLSStartTLS},
+		{desc: "Modes should be case insensitive", mode: "NONE", port: "465", expected: TLSNone},
+		{desc: "Unknown modes should be rejected", mode: "ssl", err: `unknown tls mode "ssl", must be one of none, starttls or tls`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := ParseTLSMode(tC.mode, tC.port)
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+}
+
+func TestParseAuthMechanism(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		mechanism string
+		expected  AuthMechanism
+		err       string
+	}{
+		{desc: "PLAIN should be the default", expected: AuthPlain},
+		{desc: "Mechanisms should be case insensitive", mechanism: "CRAM-MD5", expected: AuthCRAMMD5},
+		{desc: "Unknown mechanisms should be rejected", mechanism: "xoauth2", err: `unknown auth mechanism "xoauth2", must be one of plain, login, cram-md5 or none`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			m, err := ParseAuthMechanism(tC.mechanism)
+			if tC.err != "" {
+				assert.EqualError(t, err, tC.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, m)
+		})
+	}
+}
+
+func TestNewInvalidCAFile(t *testing.T) {
+	notPEM := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(notPEM, []byte("not a certificate"), 0o600))
+
+	_, err := New(Config{SMTPHost: "127.0.0.1", SMTPCAFile: notPEM}, os.DirFS("testdata"), "template.html", "template.txt")
+	assert.EqualError(t, err, fmt.Sprintf("no certificates found in ca file %q", notPEM))
+
+	_, err = New(Config{SMTPHost: "127.0.0.1", SMTPCAFile: filepath.Join(t.TempDir(), "missing.pem")}, os.DirFS("testdata"), "template.html", "template.txt")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestSendMailFromTemplateTo(t *testing.T) {
+	cert, caFile := newCertificate(t)
+
+	testCases := []struct {
+		desc      string
+		server    func(*fakeSMTPServer)
+		config    Config
+		err       string
+		tls       bool
+		mechanism string
+		username  string
+	}{
+		{
+			desc:   "Without TLS or auth the message should be sent in plain text",
+			config: Config{SMTPTLSMode: "none", SMTPAuthMechanism: "none"},
+		},
+		{
+			desc:      "With STARTTLS and PLAIN the configured username should authenticate over TLS",
+			server:    func(s *fakeSMTPServer) { s.startTLS, s.mechanisms = true, []string{"PLAIN", "LOGIN"} },
+			config:    Config{SMTPTLSMode: "starttls", SMTPUsername: "user", SMTPPassword: "secret", SMTPCAFile: caFile},
+			tls:       true,
+			mechanism: "PLAIN",
+			username:  "user",
+		},
+		{
+			desc: "Without a username the from address should authenticate",
+			server: func(s *fakeSMTPServer) {
+				s.startTLS, s.mechanisms, s.username = true, []string{"PLAIN"}, "words@example.com"
+			},
+			config:    Config{SMTPPassword: "secret", SMTPCAFile: caFile},
+			tls:       true,
+			mechanism: "PLAIN",
+			username:  "words@example.com",
+		},
+		{
+			desc:      "With implicit TLS and LOGIN the message should be sent",
+			server:    func(s *fakeSMTPServer) { s.implicitTLS, s.mechanisms = true, []string{"LOGIN"} },
+			config:    Config{SMTPTLSMode: "tls", SMTPAuthMechanism: "login", SMTPUsername: "user", SMTPPassword: "secret", SMTPCAFile: caFile},
+			tls:       true,
+			mechanism: "LOGIN",
+			username:  "user",
+		},
+		{
+			desc:      "CRAM-MD5 should authenticate without TLS",
+			server:    func(s *fakeSMTPServer) { s.mechanisms = []string{"CRAM-MD5"} },
+			config:    Config{SMTPTLSMode: "none", SMTPAuthMechanism: "cram-md5", SMTPUsername: "user", SMTPPassword: "secret"},
+			mechanism: "CRAM-MD5",
+			username:  "user",
+		},
+		{
+			desc:   "Skipping verification should accept an untrusted certificate",
+			server: func(s *fakeSMTPServer) { s.implicitTLS = true },
+			config: Config{SMTPTLSMode: "tls", SMTPAuthMechanism: "none", SMTPInsecureSkipVerify: true},
+			tls:    true,
+		},
+		{
+			desc:   "An untrusted certificate should be rejected",
+			server: func(s *fakeSMTPServer) { s.startTLS = true },
+			config: Config{SMTPTLSMode: "starttls", SMTPAuthMechanism: "none"},
+			err:    "certificate signed by unknown authority",
+		},
+		{
+			desc:   "STARTTLS should be required when it's the mode",
+			config: Config{SMTPTLSMode: "starttls", SMTPAuthMechanism: "none"},
+			err:    "smtp server doesn't support STARTTLS",
+		},
+		{
+			desc:   "AUTH should be required unless the mechanism is none",
+			config: Config{SMTPTLSMode: "none", SMTPUsername: "user", SMTPPassword: "secret"},
+			err:    "smtp server doesn't support AUTH",
+		},
+		{
+			desc:   "A wrong password should fail to authenticate",
+			server: func(s *fakeSMTPServer) { s.mechanisms = []string{"LOGIN"} },
+			config: Config{SMTPTLSMode: "none", SMTPAuthMechanism: "login", SMTPUsername: "user", SMTPPassword: "wrong"},
+			err:    "unable to authenticate: 535",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newFakeSMTPServer(t, cert, tC.server)
+
+			config := tC.config
+			config.SMTPHost = "127.0.0.1"
+			config.SMTPPort = s.port()
+			config.SMTPFromAddress = "words@example.com"
+
+			c, err := New(config, os.DirFS("testdata"), "template.html", "template.txt")
+			assert.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			err = c.SendMailFromTemplateTo(ctx, []string{"alice@example.com", "bob@example.com"}, "My Word Of The Day: petrichor", struct {
+				Word       string
+				Definition string
+				Synonyms   []string
+			}{Word: "petrichor", Definition: "The smell of rain"})
+			if tC.err != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tC.err)
+				}
+				assert.Empty(t, s.received())
+				return
+			}
+
+			assert.NoError(t, err)
+
+			received := s.received()
+			if assert.Len(t, received, 1) {
+				m := received[0]
+				assert.Equal(t, "words@example.com", m.From)
+				assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, m.To)
+				assert.Equal(t, tC.tls, m.TLS)
+				assert.Equal(t, tC.mechanism, m.Mechanism)
+				assert.Equal(t, tC.username, m.Username)
+				assert.Contains(t, m.Data, "Subject: My Word Of The Day: petrichor\n")
+				assert.Contains(t, m.Data, "petrichor: The smell of rain\n")
+			}
+		})
+	}
+}
+
+func TestSendMessageErrors(t *testing.T) {
+	cert, _ := newCertificate(t)
+
+	s := newFakeSMTPServer(t, cert, func(s *fakeSMTPServer) {
+		s.rejected = map[string]string{
+			"nobody@example.com": "550 5.1.1 No such user",
+			"busy@example.com":   "451 4.3.0 Try again later",
+		}
+	})
+
+	c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: s.port(), SMTPTLSMode: "none", SMTPAuthMechanism: "none", SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		desc      string
+		to        string
+		permanent bool
+	}{
+		{desc: "A 5xx reply should be permanent", to: "nobody@example.com", permanent: true},
+		{desc: "A 4xx reply should be temporary", to: "busy@example.com"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := c.SendMessage(context.Background(), []string{tC.to}, []byte("Subject: hi\r\n\r\nhi\r\n"))
+			assert.Error(t, err)
+			assert.Equal(t, tC.permanent, IsPermanent(err))
+		})
+	}
+
+	t.Run("Failing to quit once the message is accepted should succeed", func(t *testing.T) {
+		s := newFakeSMTPServer(t, cert, func(s *fakeSMTPServer) { s.dropOnQuit = true })
+
+		c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: s.port(), SMTPTLSMode: "none", SMTPAuthMechanism: "none", SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+		assert.NoError(t, err)
+
+		err = c.SendMessage(context.Background(), []string{"alice@example.com"}, []byte("Subject: hi\r\n\r\nhi\r\n"))
+		assert.NoError(t, err)
+		assert.Len(t, s.received(), 1)
+	})
+
+	t.Run("Cancelling the context should interrupt the message being sent", func(t *testing.T) {
+		s := newFakeSMTPServer(t, cert, func(s *fakeSMTPServer) { s.stallData = true })
+
+		c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: s.port(), SMTPTLSMode: "none", SMTPAuthMechanism: "none", SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(100*time.Millisecond, cancel)
+
+		errs := make(chan error, 1)
+		go func() {
+			errs <- c.SendMessage(ctx, []string{"alice@example.com"}, []byte("Subject: hi\r\n\r\nhi\r\n"))
+		}()
+
+		select {
+		case err := <-errs:
+			assert.Error(t, err)
+			assert.False(t, IsPermanent(err))
+		case <-time.After(5 * time.Second):
+			t.Fatal("the message wasn't interrupted")
+		}
+	})
+
+	t.Run("Failing to connect should be temporary", func(t *testing.T) {
+		c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: "1", SMTPTLSMode: "none", SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+		assert.NoError(t, err)
+
+		err = c.SendMessage(context.Background(), []string{"alice@example.com"}, []byte("hi"))
+		assert.Error(t, err)
+		assert.False(t, IsPermanent(err))
+	})
+}
+
+func TestPing(t *testing.T) {
+	cert, caFile := newCertificate(t)
+
+	t.Run("Given an SMTP server listening with implicit TLS", func(t *testing.T) {
+		s := newFakeSMTPServer(t, cert, func(s *fakeSMTPServer) { s.implicitTLS = true })
+
+		t.Run("When its certificate is trusted", func(t *testing.T) {
+			t.Run("Then it should be reachable", func(t *testing.T) {
+				c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: s.port(), SMTPTLSMode: "tls", SMTPCAFile: caFile}, os.DirFS("testdata"), "template.html", "template.txt")
+				assert.NoError(t, err)
+
+				assert.NoError(t, c.Ping(context.Background()))
+			})
+		})
+
+		t.Run("When it's pinged without TLS", func(t *testing.T) {
+			t.Run("Then the greeting should time out", func(t *testing.T) {
+				c, err := New(Config{SMTPHost: "127.0.0.1", SMTPPort: s.port(), SMTPTLSMode: "none"}, os.DirFS("testdata"), "template.html", "template.txt")
+				assert.NoError(t, err)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				err = c.Ping(ctx)
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), "unable to read smtp greeting")
+			})
+		})
+	})
+}
@@ -0,0 +1,114 @@
+package mail
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// textFuncs are the functions available to plain text templates, which can't
+// lay lists out with markup as HTML templates do
+var textFuncs = texttemplate.FuncMap{
+	"join": strings.Join,
+}
+
+// TemplateError is returned when one of the templates of an email doesn't
+// parse or render
+type TemplateError struct {
+	// Part is the part of the email the template is for, html or text
+	Part string
+	Err  error
+}
+
+func (e *TemplateError) Error() string {
+	return e.Part + " template: " + e.Err.Error()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Templates are what the HTML and plain text parts of an email are rendered
+// from
+type Templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// ParseTemplates parses the templates of the HTML and plain text parts of an
+// email, returning a *TemplateError if either doesn't parse
+func ParseTemplates(html string, text string) (*Templates, error) {
+	h, err := htmltemplate.New("html").Parse(html)
+	if err != nil {
+		return nil, &TemplateError{Part: "html", Err: err}
+	}
+
+	t, err := texttemplate.New("text").Funcs(textFuncs).Parse(text)
+	if err != nil {
+		return nil, &TemplateError{Part: "text", Err: err}
+	}
+
+	return &Templates{html: h, text: t}, nil
+}
+
+// parseTemplatesFS parses the templates matched by htmlPattern and textPattern
+// in fsys
+func parseTemplatesFS(fsys fs.FS, htmlPattern string, textPattern string) (*Templates, error) {
+	html, err := htmltemplate.ParseFS(fsys, htmlPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse html template")
+	}
+
+	text, err := texttemplate.New(path.Base(textPattern)).Funcs(textFuncs).ParseFS(fsys, textPattern)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse text template")
+	}
+
+	return &Templates{html: html, text: text}, nil
+}
+
+// Render renders the HTML and plain text parts with data, returning a
+// *TemplateError if either fails
+func (t *Templates) Render(data interface{}) (html string, text string, err error) {
+	var h, p bytes.Buffer
+
+	if err := t.html.Execute(&h, data); err != nil {
+		return "", "", &TemplateError{Part: "html", Err: err}
+	}
+
+	if err := t.text.Execute(&p, data); err != nil {
+		return "", "", &TemplateError{Part: "text", Err: err}
+	}
+
+	return h.String(), p.String(), nil
+}
+
+// Validate checks the templates render with SampleTemplateData, which catches
+// templates that parse but use fields TemplateData doesn't have, and with
+// minimalTemplateData, which catches those that assume a word has senses
+func (t *Templates) Validate() error {
+	for _, data := range []TemplateData{SampleTemplateData(), minimalTemplateData()} {
+		if _, _, err := t.Render(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// minimalTemplateData returns data for a word with only a custom definition,
+// and none of the fields that are only set for some words
+func minimalTemplateData() TemplateData {
+	return TemplateData{
+		Word:       "sonder",
+		Definition: "The feeling that everyone else's life is as vivid as your own.",
+		Date:       time.Date(2022, 3, 4, 9, 0, 0, 0, time.UTC),
+		Streak:     1,
+	}
+}
@@ -0,0 +1,151 @@
+package mail
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTemplates(t *testing.T) {
+	testCases := []struct {
+		desc string
+		html string
+		text string
+		part string
+	}{
+		{desc: "An HTML template that doesn't parse should be reported", html: "<p>{{.Word</p>", text: "{{.Word}}", part: "html"},
+		{desc: "A text template that doesn't parse should be reported", html: "<p>{{.Word}}</p>", text: "{{range .Senses}}", part: "text"},
+		{desc: "A text template using an unknown function should be reported", html: "<p>{{.Word}}</p>", text: `{{shout .Word}}`, part: "text"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := ParseTemplates(tC.html, tC.text)
+
+			var tErr *TemplateError
+			if assert.True(t, errors.As(err, &tErr)) {
+				assert.Equal(t, tC.part, tErr.Part)
+			}
+		})
+	}
+}
+
+func TestTemplatesRender(t *testing.T) {
+	t.Run("Given templates that parse", func(t *testing.T) {
+		tmpl, err := ParseTemplates(
+			`<h1>{{.Word}}</h1>{{range .Senses}}<p>{{.Definition}}</p>{{end}}`,
+			`{{.Word}}: {{range .Senses}}{{join .Synonyms ", "}}{{end}}`,
+		)
+		assert.NoError(t, err)
+
+		t.Run("When they're rendered", func(t *testing.T) {
+			html, text, err := tmpl.Render(TemplateData{
+				Word:   "<sonder>",
+				Senses: []Sense{{Definition: "A feeling", Synonyms: []string{"empathy", "wonder"}}},
+			})
+
+			t.Run("Then the HTML should be escaped and the text shouldn't", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, "<h1>&lt;sonder&gt;</h1><p>A feeling</p>", html)
+				assert.Equal(t, "<sonder>: empathy, wonder", text)
+			})
+		})
+
+		t.Run("Then they should be valid", func(t *testing.T) {
+			assert.NoError(t, tmpl.Validate())
+		})
+	})
+
+	t.Run("Given templates that use a field that doesn't exist", func(t *testing.T) {
+		tmpl, err := ParseTemplates(`<h1>{{.Word}}</h1>`, `{{.Word}} ({{.Language}})`)
+		assert.NoError(t, err)
+
+		t.Run("Then they shouldn't be valid", func(t *testing.T) {
+			var tErr *TemplateError
+			if assert.True(t, errors.As(tmpl.Validate(), &tErr)) {
+				assert.Equal(t, "text", tErr.Part)
+				assert.Contains(t, tErr.Error(), "can't evaluate field Language")
+			}
+		})
+	})
+}
+
+func TestTemplatesValidate(t *testing.T) {
+	t.Run("Given templates that assume the word has senses", func(t *testing.T) {
+		tmpl, err := ParseTemplates(`<h1>{{.Word}}</h1>`, `{{(index .Senses 0).Definition}}`)
+		assert.NoError(t, err)
+
+		t.Run("Then they shouldn't be valid", func(t *testing.T) {
+			var tErr *TemplateError
+			if assert.True(t, errors.As(tmpl.Validate(), &tErr)) {
+				assert.Equal(t, "text", tErr.Part)
+			}
+		})
+	})
+
+	t.Run("Given templates that check the word has senses", func(t *testing.T) {
+		tmpl, err := ParseTemplates(`<h1>{{.Word}}</h1>`, `{{with .Senses}}{{(index . 0).Definition}}{{else}}{{.Definition}}{{end}}`)
+		assert.NoError(t, err)
+
+		t.Run("Then they should be valid", func(t *testing.T) {
+			assert.NoError(t, tmpl.Validate())
+		})
+	})
+}
+
+func TestDefaultTemplates(t *testing.T) {
+	t.Run("Given the templates emails are rendered from by default", func(t *testing.T) {
+		tmpl, err := parseTemplatesFS(os.DirFS("../../templates"), "template.html", "template.txt")
+		assert.NoError(t, err)
+
+		t.Run("Then they should be valid", func(t *testing.T) {
+			assert.NoError(t, tmpl.Validate())
+		})
+
+		t.Run("When they're rendered with the sample data", func(t *testing.T) {
+			html, text, err := tmpl.Render(SampleTemplateData())
+
+			t.Run("Then every field should be shown", func(t *testing.T) {
+				assert.NoError(t, err)
+
+				for _, part := range []string{html, text} {
+					assert.Contains(t, part, "sonder")
+					assert.Contains(t, part, "Friday 4 March 2022")
+					assert.Contains(t, part, "day 7 of your streak")
+					assert.Contains(t, part, "https://example.com/unsubscribe")
+				}
+			})
+		})
+	})
+}
+
+func TestClientNewMessageFrom(t *testing.T) {
+	c, err := New(Config{SMTPFromAddress: "words@example.com"}, os.DirFS("testdata"), "template.html", "template.txt")
+	assert.NoError(t, err)
+
+	t.Run("Given templates other than the client's", func(t *testing.T) {
+		tmpl, err := ParseTemplates(`<b>{{.Word}}</b>`, `{{.Word}}!`)
+		assert.NoError(t, err)
+
+		t.Run("Then the message should be rendered from them", func(t *testing.T) {
+			m, err := c.NewMessageFrom(tmpl, []string{"alice@example.com"}, "My Word Of The Day: sonder", TemplateData{Word: "sonder"})
+			assert.NoError(t, err)
+			assert.Equal(t, "<b>sonder</b>", m.HTML)
+			assert.Equal(t, "sonder!", m.Text)
+			assert.Equal(t, "words@example.com", m.From)
+		})
+	})
+
+	t.Run("Given templates that fail to render", func(t *testing.T) {
+		tmpl, err := ParseTemplates(`<b>{{.Word}}</b>`, `{{.Missing}}`)
+		assert.NoError(t, err)
+
+		t.Run("Then an error should be returned", func(t *testing.T) {
+			_, err := c.NewMessageFrom(tmpl, []string{"alice@example.com"}, "My Word Of The Day: sonder", TemplateData{Word: "sonder"})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "error executing template: text template:")
+			}
+		})
+	})
+}
@@ -0,0 +1,268 @@
+// Package metrics exposes Prometheus metrics for the RPCs, the HTTP endpoints,
+// the database and the delivery of mail
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+const namespace = "mywordoftheday"
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+var (
+	rpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "Number of gRPC requests handled, by method and status code.",
+	}, []string{"method", "code"})
+
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle gRPC requests, by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of requests handled by the HTTP endpoints that aren't proxied to gRPC, by route and status code.",
+	}, []string{"route", "code"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle requests by the HTTP endpoints that aren't proxied to gRPC, by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route"})
+
+	mailSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "mail",
+		Name:      "sent_total",
+		Help:      "Number of emails sent, by result.",
+	}, []string{"result"})
+
+	mailDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "mail",
+		Name:      "send_duration_seconds",
+		Help:      "Time taken to send emails, by result.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"result"})
+
+	jobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "runs_total",
+		Help:      "Number of times scheduled jobs have run, by job and result.",
+	}, []string{"job", "result"})
+
+	jobLastSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time scheduled jobs last ran without any failures, by job.",
+	}, []string{"job"})
+)
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// UnaryServerInterceptor counts and times every RPC
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		rsp, err := handler(ctx, req)
+
+		rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		rpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+
+		return rsp, err
+	}
+}
+
+// HTTPHandler counts and times every request h handles, labelled with route
+func HTTPHandler(route string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		sr := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+		h.ServeHTTP(sr, r)
+
+		httpDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+		httpRequests.WithLabelValues(route, strconv.Itoa(sr.code)).Inc()
+	})
+}
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if !sr.wroteHeader {
+		sr.code = code
+		sr.wroteHeader = true
+	}
+
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	sr.wroteHeader = true
+	return sr.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through, if the underlying writer can flush
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// ObserveMail records an email that started sending at start, failing if err isn't nil
+func ObserveMail(start time.Time, err error) {
+	r := result(err)
+
+	mailDuration.WithLabelValues(r).Observe(time.Since(start).Seconds())
+	mailSent.WithLabelValues(r).Inc()
+}
+
+// ObserveJob records a run of the named scheduled job, failing if err isn't nil
+func ObserveJob(job string, err error) {
+	jobRuns.WithLabelValues(job, result(err)).Inc()
+
+	if err == nil {
+		jobLastSuccess.WithLabelValues(job).SetToCurrentTime()
+	}
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+
+	return resultSuccess
+}
+
+// DBStats is the source of the database metrics
+type DBStats interface {
+	PoolStats() db.PoolStats
+	CountWords(context.Context) (map[string]int64, error)
+}
+
+var (
+	poolAcquiredConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquired_connections"),
+		"Number of connections currently in use.", nil, nil)
+	poolIdleConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "idle_connections"),
+		"Number of idle connections.", nil, nil)
+	poolConstructingConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "constructing_connections"),
+		"Number of connections being established.", nil, nil)
+	poolTotalConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "connections"),
+		"Number of open connections.", nil, nil)
+	poolMaxConns = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "max_connections"),
+		"Maximum number of open connections.", nil, nil)
+	poolAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquires_total"),
+		"Number of connections acquired from the pool.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "acquire_duration_seconds_total"),
+		"Total time spent acquiring connections from the pool.", nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "empty_acquires_total"),
+		"Number of acquires that had to wait for a connection.", nil, nil)
+	poolCanceledAcquires = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "db_pool", "canceled_acquires_total"),
+		"Number of acquires cancelled before getting a connection.", nil, nil)
+
+	words = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "words"),
+		"Number of words, by user.", []string{"user"}, nil)
+)
+
+// dbCollector reads the database metrics from DBStats when scraped
+type dbCollector struct {
+	stats   DBStats
+	timeout time.Duration
+}
+
+// NewDBCollector returns a collector for the connection pool statistics and
+// the number of words each user has. Counting the words is given timeout.
+func NewDBCollector(stats DBStats, timeout time.Duration) prometheus.Collector {
+	return &dbCollector{stats: stats, timeout: timeout}
+}
+
+func (c *dbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolConstructingConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquires
+	ch <- poolAcquireDuration
+	ch <- poolEmptyAcquires
+	ch <- poolCanceledAcquires
+	ch <- words
+}
+
+func (c *dbCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stats.PoolStats()
+
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(poolConstructingConns, prometheus.GaugeValue, float64(s.ConstructingConns))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, s.AcquireDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(s.CanceledAcquireCount))
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	counts, err := c.stats.CountWords(ctx)
+	if err != nil {
+		// The pool metrics are still worth reporting, so the word counts are
+		// left out rather than failing the whole scrape
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error counting words for metrics")
+		return
+	}
+
+	for user, count := range counts {
+		ch <- prometheus.MustNewConstMetric(words, prometheus.GaugeValue, float64(count), user)
+	}
+}
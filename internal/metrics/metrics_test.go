@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+type dbStatsMock struct {
+	poolStats db.PoolStats
+	counts    map[string]int64
+	err       error
+}
+
+func (f dbStatsMock) PoolStats() db.PoolStats {
+	return f.poolStats
+}
+
+func (f dbStatsMock) CountWords(context.Context) (map[string]int64, error) {
+	return f.counts, f.err
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	interceptor := UnaryServerInterceptor()
+
+	call := func(method string, err error) {
+		_, _ = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+
+	t.Run("Given the metrics interceptor", func(t *testing.T) {
+		t.Run("When RPCs are handled", func(t *testing.T) {
+			t.Run("Then they are counted by method and status code", func(t *testing.T) {
+				call("/test.Service/Succeeds", nil)
+				call("/test.Service/Succeeds", nil)
+				call("/test.Service/Fails", status.Error(codes.NotFound, "not found"))
+
+				assert.Equal(t, float64(2), testutil.ToFloat64(rpcRequests.WithLabelValues("/test.Service/Succeeds", "OK")))
+				assert.Equal(t, float64(1), testutil.ToFloat64(rpcRequests.WithLabelValues("/test.Service/Fails", "NotFound")))
+			})
+		})
+	})
+}
+
+func TestHTTPHandler(t *testing.T) {
+	h := HTTPHandler("GET /test/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/test/missing" {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	t.Run("Given the HTTP metrics middleware", func(t *testing.T) {
+		t.Run("When requests are handled", func(t *testing.T) {
+			t.Run("Then they are counted by route and status code", func(t *testing.T) {
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/1", nil))
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/2", nil))
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test/missing", nil))
+
+				assert.Equal(t, float64(2), testutil.ToFloat64(httpRequests.WithLabelValues("GET /test/{id}", "200")))
+				assert.Equal(t, float64(1), testutil.ToFloat64(httpRequests.WithLabelValues("GET /test/{id}", "404")))
+			})
+		})
+	})
+}
+
+func TestObserveMail(t *testing.T) {
+	t.Run("Given emails have been sent", func(t *testing.T) {
+		t.Run("When one of them failed", func(t *testing.T) {
+			t.Run("Then the successes and failures are counted separately", func(t *testing.T) {
+				ObserveMail(time.Now(), nil)
+				ObserveMail(time.Now(), errors.New("connection refused"))
+
+				assert.Equal(t, float64(1), testutil.ToFloat64(mailSent.WithLabelValues(resultSuccess)))
+				assert.Equal(t, float64(1), testutil.ToFloat64(mailSent.WithLabelValues(resultFailure)))
+			})
+		})
+	})
+}
+
+func TestObserveJob(t *testing.T) {
+	t.Run("Given a scheduled job", func(t *testing.T) {
+		t.Run("When it fails", func(t *testing.T) {
+			t.Run("Then the last success time isn't updated", func(t *testing.T) {
+				ObserveJob("failing", errors.New("an error"))
+
+				assert.Equal(t, float64(1), testutil.ToFloat64(jobRuns.WithLabelValues("failing", resultFailure)))
+				assert.Equal(t, float64(0), testutil.ToFloat64(jobLastSuccess.WithLabelValues("failing")))
+			})
+		})
+		t.Run("When it succeeds", func(t *testing.T) {
+			t.Run("Then the last success time is updated", func(t *testing.T) {
+				ObserveJob("succeeding", nil)
+
+				assert.Equal(t, float64(1), testutil.ToFloat64(jobRuns.WithLabelValues("succeeding", resultSuccess)))
+				assert.InDelta(t, float64(time.Now().Unix()), testutil.ToFloat64(jobLastSuccess.WithLabelValues("succeeding")), 5)
+			})
+		})
+	})
+}
+
+func TestDBCollector(t *testing.T) {
+	stats := dbStatsMock{
+		poolStats: db.PoolStats{AcquiredConns: 2, IdleConns: 3, TotalConns: 5, MaxConns: 10, AcquireCount: 42},
+		counts:    map[string]int64{"default": 7, "simon": 3},
+	}
+
+	t.Run("Given a DB collector", func(t *testing.T) {
+		t.Run("When it is collected", func(t *testing.T) {
+			t.Run("Then the pool statistics and word counts are reported", func(t *testing.T) {
+				expected := `
+# HELP mywordoftheday_db_pool_acquired_connections Number of connections currently in use.
+# TYPE mywordoftheday_db_pool_acquired_connections gauge
+mywordoftheday_db_pool_acquired_connections 2
+# HELP mywordoftheday_db_pool_acquires_total Number of connections acquired from the pool.
+# TYPE mywordoftheday_db_pool_acquires_total counter
+mywordoftheday_db_pool_acquires_total 42
+# HELP mywordoftheday_words Number of words, by user.
+# TYPE mywordoftheday_words gauge
+mywordoftheday_words{user="default"} 7
+mywordoftheday_words{user="simon"} 3
+`
+				err := testutil.CollectAndCompare(NewDBCollector(stats, time.Second), strings.NewReader(expected),
+					"mywordoftheday_db_pool_acquired_connections", "mywordoftheday_db_pool_acquires_total", "mywordoftheday_words")
+				assert.NoError(t, err)
+			})
+		})
+		t.Run("When the words can't be counted", func(t *testing.T) {
+			t.Run("Then the pool statistics are still reported", func(t *testing.T) {
+				stats.err = errors.New("connection refused")
+
+				c := NewDBCollector(stats, time.Second)
+				assert.Equal(t, 9, testutil.CollectAndCount(c))
+				assert.Equal(t, 0, testutil.CollectAndCount(c, "mywordoftheday_words"))
+			})
+		})
+	})
+}
@@ -0,0 +1,49 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+)
+
+// Slack is a Notifier posting to a Slack incoming webhook, or one of the many
+// compatible with it, such as Mattermost's
+type Slack struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack returns a Slack posting to the incoming webhook at url
+func NewSlack(url string, client *http.Client) *Slack {
+	return &Slack{url: url, client: client}
+}
+
+func (s *Slack) Notify(ctx context.Context, n Notification) error {
+	payload := struct {
+		Text string `json:"text"`
+	}{
+		Text: "*" + n.Title + "*\n" + n.Text(),
+	}
+
+	return sendJSON(ctx, s.client, http.MethodPost, s.url, payload, nil)
+}
+
+// Discord is a Notifier posting to a Discord channel's webhook
+type Discord struct {
+	url    string
+	client *http.Client
+}
+
+// NewDiscord returns a Discord posting to the webhook at url
+func NewDiscord(url string, client *http.Client) *Discord {
+	return &Discord{url: url, client: client}
+}
+
+func (d *Discord) Notify(ctx context.Context, n Notification) error {
+	payload := struct {
+		Content string `json:"content"`
+	}{
+		Content: "**" + n.Title + "**\n" + n.Text(),
+	}
+
+	return sendJSON(ctx, d.client, http.MethodPost, d.url, payload, nil)
+}
@@ -0,0 +1,43 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlack(t *testing.T) {
+	t.Run("Given a Slack incoming webhook", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := NewSlack(srv.URL+"/services/T000/B000/XXXX", srv.Client()).Notify(context.Background(), notification)
+
+			t.Run("Then the text should be posted with the title in bold", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, received.method)
+				assert.Equal(t, "/services/T000/B000/XXXX", received.path)
+				assert.JSONEq(t, `{"text": "*My Word Of The Day: sonder*\nsonder /ˈsɒndə/\n• (noun) The realization that each passerby has a life as vivid and complex as one's own.\n• (verb) To sound out or probe."}`, received.body)
+			})
+		})
+	})
+}
+
+func TestDiscord(t *testing.T) {
+	t.Run("Given a Discord webhook", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusNoContent)
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := NewDiscord(srv.URL+"/api/webhooks/1234/XXXX", srv.Client()).Notify(context.Background(), Notification{Title: "My Word Of The Day: sonder", Word: "sonder"})
+
+			t.Run("Then the content should be posted with the title in bold", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, received.method)
+				assert.Equal(t, "/api/webhooks/1234/XXXX", received.path)
+				assert.JSONEq(t, `{"content": "**My Word Of The Day: sonder**\nsonder"}`, received.body)
+			})
+		})
+	})
+}
@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// ErrForbiddenAddress is returned for a channel on a loopback, link-local or
+// private address. Channel URLs are given by users, so they mustn't be able to
+// reach the server itself, its cloud metadata service or its network.
+var ErrForbiddenAddress = errors.New("channel url must not be a loopback, link-local or private address")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which
+// net.IP.IsPrivate doesn't include
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// forbiddenIP reports whether notifications mustn't be sent to ip
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() ||
+		ip.IsUnspecified() ||
+		ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkHost returns ErrForbiddenAddress if host is obviously one notifications
+// mustn't be sent to. Other names are checked once they're resolved, when
+// they're dialled.
+func checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+
+	if ip := net.ParseIP(host); ip != nil && forbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// dialControl refuses connections to forbidden addresses. It's run with the
+// address a name resolved to, so names that resolve to them are caught too.
+func dialControl(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || forbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// NewClient returns the client notifications are sent with if New isn't given
+// one. It won't connect to loopback, link-local or private addresses,
+// including after a redirect. Proxies aren't used as they'd make the
+// connection on its behalf, without the check.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Transport: otelhttp.NewTransport(transport)}
+}
@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Matrix is a Notifier sending messages to a Matrix room as a user, typically
+// a bot, that has joined it
+type Matrix struct {
+	homeserver  string
+	room        string
+	accessToken string
+	client      *http.Client
+}
+
+// NewMatrix returns a Matrix sending messages to the room, by its id or alias,
+// through the homeserver with the user's access token
+func NewMatrix(homeserver string, room string, accessToken string, client *http.Client) *Matrix {
+	return &Matrix{
+		homeserver:  strings.TrimSuffix(homeserver, "/"),
+		room:        room,
+		accessToken: accessToken,
+		client:      client,
+	}
+}
+
+func (m *Matrix) Notify(ctx context.Context, n Notification) error {
+	// The transaction id makes the homeserver ignore retries of a request it
+	// has already handled
+	txn := make([]byte, 16)
+	if _, err := rand.Read(txn); err != nil {
+		return errors.Wrap(err, "unable to generate transaction id")
+	}
+
+	var formatted strings.Builder
+	formatted.WriteString("<strong>" + html.EscapeString(n.Title) + "</strong><br>")
+	formatted.WriteString(html.EscapeString(n.Word))
+	if n.Pronunciation != "" {
+		formatted.WriteString(" <em>" + html.EscapeString(n.Pronunciation) + "</em>")
+	}
+
+	if len(n.Definitions) > 0 {
+		formatted.WriteString("<ul>")
+		for _, d := range n.Definitions {
+			formatted.WriteString("<li>" + html.EscapeString(d) + "</li>")
+		}
+		formatted.WriteString("</ul>")
+	}
+
+	payload := struct {
+		MsgType       string `json:"msgtype"`
+		Body          string `json:"body"`
+		Format        string `json:"format"`
+		FormattedBody string `json:"formatted_body"`
+	}{
+		MsgType:       "m.text",
+		Body:          n.Title + "\n" + n.Text(),
+		Format:        "org.matrix.custom.html",
+		FormattedBody: formatted.String(),
+	}
+
+	u := m.homeserver + "/_matrix/client/v3/rooms/" + url.PathEscape(m.room) + "/send/m.room.message/" + hex.EncodeToString(txn)
+
+	return sendJSON(ctx, m.client, http.MethodPut, u, payload, func(req *http.Request, _ []byte) {
+		req.Header.Set("Authorization", "Bearer "+m.accessToken)
+	})
+}
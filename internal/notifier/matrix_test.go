@@ -0,0 +1,53 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatrix(t *testing.T) {
+	t.Run("Given a Matrix room", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		m := NewMatrix(srv.URL+"/", "!abc:matrix.org", "syt_XXXX", srv.Client())
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := m.Notify(context.Background(), Notification{
+				Title:         "My Word Of The Day: <sonder>",
+				Word:          "sonder",
+				Pronunciation: "/ˈsɒndə/",
+				Definitions:   []string{"(verb) To sound out & probe."},
+			})
+
+			t.Run("Then a message should be sent to the room with the access token", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPut, received.method)
+				assert.Regexp(t, regexp.MustCompile(`^/_matrix/client/v3/rooms/!abc:matrix.org/send/m.room.message/[0-9a-f]{32}$`), received.path)
+				assert.Equal(t, "Bearer syt_XXXX", received.header.Get("Authorization"))
+			})
+
+			t.Run("Then the message should have a plain body and an escaped HTML one", func(t *testing.T) {
+				assert.JSONEq(t, `{
+					"msgtype": "m.text",
+					"body": "My Word Of The Day: <sonder>\nsonder /ˈsɒndə/\n• (verb) To sound out & probe.",
+					"format": "org.matrix.custom.html",
+					"formatted_body": "<strong>My Word Of The Day: &lt;sonder&gt;</strong><br>sonder <em>/ˈsɒndə/</em><ul><li>(verb) To sound out &amp; probe.</li></ul>"
+				}`, received.body)
+			})
+		})
+
+		t.Run("When it's notified again", func(t *testing.T) {
+			first := received.path
+			err := m.Notify(context.Background(), notification)
+
+			t.Run("Then the message should have a new transaction id", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.NotEqual(t, first, received.path)
+			})
+		})
+	})
+}
@@ -0,0 +1,213 @@
+// Package notifier sends the word of the day to chat rooms, push services and
+// webhooks, for recipients who'd rather not, or not only, get an email
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Notification is the word of the day as it's sent to a Notifier
+type Notification struct {
+	// Title summarises the notification, e.g. "My Word Of The Day: sonder"
+	Title string
+
+	Word          string
+	Pronunciation string
+	// Definitions are the word's definitions, most relevant first, each on a
+	// single line
+	Definitions []string
+}
+
+// Text returns the notification as plain text, for services that don't take
+// structured messages
+func (n Notification) Text() string {
+	var b strings.Builder
+
+	b.WriteString(n.Word)
+	if n.Pronunciation != "" {
+		b.WriteString(" " + n.Pronunciation)
+	}
+
+	for _, d := range n.Definitions {
+		b.WriteString("\n• " + d)
+	}
+
+	return b.String()
+}
+
+// Notifier sends notifications to a single channel
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Kinds are the kinds of channel New understands, as used in the scheme of
+// their URLs
+var Kinds = []string{"webhook", "slack", "discord", "matrix", "ntfy", "gotify"}
+
+// New returns the Notifier for the channel URL, whose scheme is the kind of
+// channel followed by the scheme used to reach it, e.g.
+//
+//	webhook+https://example.com/hook?secret=s3cret
+//	slack+https://hooks.slack.com/services/T000/B000/XXXX
+//	discord+https://discord.com/api/webhooks/1234/XXXX
+//	matrix+https://matrix.org?room=!abc:matrix.org&token=syt_XXXX
+//	ntfy+https://ntfy.sh/my-words?token=tk_XXXX
+//	gotify+https://gotify.example.com?token=AXXXX
+//
+// Mattermost and other services with Slack-compatible incoming webhooks use
+// slack. ErrForbiddenAddress is returned if the host is a loopback,
+// link-local or private address. If client is nil the client returned by
+// NewClient is used. Errors never include the URL, as it holds the channel's
+// secrets.
+func New(rawURL string, client *http.Client) (Notifier, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.New("channel url must be a valid url")
+	}
+
+	kind, scheme, ok := cutScheme(u.Scheme)
+	if !ok || (scheme != "http" && scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("channel url must look like <kind>+https://host/path")
+	}
+
+	if err := checkHost(u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	u.Scheme = scheme
+
+	if client == nil {
+		client = NewClient()
+	}
+
+	// Secrets are taken out of the query so they aren't sent in the URL
+	q := u.Query()
+	secret, room, token := q.Get("secret"), q.Get("room"), q.Get("token")
+	q.Del("secret")
+	q.Del("room")
+	q.Del("token")
+	u.RawQuery = q.Encode()
+
+	target := u.String()
+
+	switch kind {
+	case "webhook":
+		return NewWebhook(target, secret, client), nil
+	case "slack":
+		return NewSlack(target, client), nil
+	case "discord":
+		return NewDiscord(target, client), nil
+	case "matrix":
+		if room == "" || token == "" {
+			return nil, fmt.Errorf("matrix channel url must have room and token query parameters")
+		}
+
+		return NewMatrix(target, room, token, client), nil
+	case "ntfy":
+		return NewNtfy(target, token, client), nil
+	case "gotify":
+		if token == "" {
+			return nil, fmt.Errorf("gotify channel url must have a token query parameter")
+		}
+
+		return NewGotify(target, token, client), nil
+	default:
+		return nil, fmt.Errorf("unknown channel kind %q, must be one of %s", kind, strings.Join(Kinds, ", "))
+	}
+}
+
+// Redact returns the channel URL with its secrets replaced, so it can be
+// shown or logged
+func Redact(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid url"
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), "redacted")
+	}
+
+	q := u.Query()
+	for _, key := range []string{"secret", "token"} {
+		if q.Get(key) != "" {
+			q.Set(key, "redacted")
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	// Incoming webhooks carry their secret in the path
+	if kind, _, _ := cutScheme(u.Scheme); kind == "slack" || kind == "discord" {
+		if i := strings.LastIndex(u.Path, "/"); i >= 0 && i < len(u.Path)-1 {
+			u.Path = u.Path[:i+1] + "redacted"
+			u.RawPath = ""
+		}
+	}
+
+	return u.String()
+}
+
+// cutScheme splits a channel URL's scheme into the kind of channel and the
+// scheme used to reach it
+func cutScheme(s string) (kind string, scheme string, ok bool) {
+	i := strings.Index(s, "+")
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+1:], true
+}
+
+// sendJSON sends v as JSON to url with method, and any headers set by
+// prepare, expecting a 2xx response
+func sendJSON(ctx context.Context, client *http.Client, method string, url string, v interface{}, prepare func(*http.Request, []byte)) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return errors.Wrap(err, "unable to encode notification")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if prepare != nil {
+		prepare(req, body)
+	}
+
+	return do(client, req)
+}
+
+// do sends req, expecting a 2xx response
+func do(client *http.Client, req *http.Request) error {
+	rsp, err := client.Do(req)
+	if err != nil {
+		// The URL it fails with can hold the channel's secrets
+		var uErr *url.Error
+		if errors.As(err, &uErr) {
+			err = uErr.Err
+		}
+
+		return errors.Wrap(err, "unable to send notification")
+	}
+	defer rsp.Body.Close()
+
+	// Drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, rsp.Body)
+
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response sending notification: %s", rsp.Status)
+	}
+
+	return nil
+}
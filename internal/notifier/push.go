@@ -0,0 +1,73 @@
+package notifier
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Ntfy is a Notifier publishing to an ntfy topic, which pushes notifications
+// to the phones and browsers subscribed to it
+type Ntfy struct {
+	topicURL    string
+	accessToken string
+	client      *http.Client
+}
+
+// NewNtfy returns an Ntfy publishing to the topic at topicURL, e.g.
+// https://ntfy.sh/my-words. The access token is only needed for topics that
+// are protected.
+func NewNtfy(topicURL string, accessToken string, client *http.Client) *Ntfy {
+	return &Ntfy{topicURL: topicURL, accessToken: accessToken, client: client}
+}
+
+func (n *Ntfy) Notify(ctx context.Context, notification Notification) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.topicURL, strings.NewReader(notification.Text()))
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+
+	// Headers can't carry UTF-8, so titles that need it are RFC 2047 encoded,
+	// which ntfy decodes
+	req.Header.Set("Title", mime.QEncoding.Encode("UTF-8", notification.Title))
+	req.Header.Set("Tags", "books")
+
+	if n.accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+n.accessToken)
+	}
+
+	return do(n.client, req)
+}
+
+// Gotify is a Notifier pushing messages to a Gotify server as an application
+type Gotify struct {
+	serverURL string
+	appToken  string
+	client    *http.Client
+}
+
+// NewGotify returns a Gotify pushing messages to the server at serverURL with
+// the application's token
+func NewGotify(serverURL string, appToken string, client *http.Client) *Gotify {
+	return &Gotify{serverURL: strings.TrimSuffix(serverURL, "/"), appToken: appToken, client: client}
+}
+
+func (g *Gotify) Notify(ctx context.Context, n Notification) error {
+	payload := struct {
+		Title    string `json:"title"`
+		Message  string `json:"message"`
+		Priority int    `json:"priority"`
+	}{
+		Title:   n.Title,
+		Message: n.Text(),
+		// The middle of Gotify's scale of 0 to 10
+		Priority: 5,
+	}
+
+	return sendJSON(ctx, g.client, http.MethodPost, g.serverURL+"/message", payload, func(req *http.Request, _ []byte) {
+		req.Header.Set("X-Gotify-Key", g.appToken)
+	})
+}
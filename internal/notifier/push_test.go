@@ -0,0 +1,64 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNtfy(t *testing.T) {
+	t.Run("Given a protected ntfy topic", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := NewNtfy(srv.URL+"/my-words", "tk_XXXX", srv.Client()).Notify(context.Background(), notification)
+
+			t.Run("Then the text should be published with the title and access token", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, received.method)
+				assert.Equal(t, "/my-words", received.path)
+				assert.Equal(t, notificationText, received.body)
+				assert.Equal(t, "My Word Of The Day: sonder", received.header.Get("Title"))
+				assert.Equal(t, "Bearer tk_XXXX", received.header.Get("Authorization"))
+			})
+		})
+	})
+
+	t.Run("Given a public ntfy topic", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		t.Run("When it's notified of a word that isn't ASCII", func(t *testing.T) {
+			err := NewNtfy(srv.URL+"/my-words", "", srv.Client()).Notify(context.Background(), Notification{Title: "My Word Of The Day: café", Word: "café"})
+
+			t.Run("Then the title should be encoded", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, "=?UTF-8?q?My_Word_Of_The_Day:_caf=C3=A9?=", received.header.Get("Title"))
+				assert.Empty(t, received.header.Get("Authorization"))
+			})
+		})
+	})
+}
+
+func TestGotify(t *testing.T) {
+	t.Run("Given a Gotify server", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := NewGotify(srv.URL+"/", "AXXXX", srv.Client()).Notify(context.Background(), notification)
+
+			t.Run("Then a message should be pushed with the application's token", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, received.method)
+				assert.Equal(t, "/message", received.path)
+				assert.Equal(t, "AXXXX", received.header.Get("X-Gotify-Key"))
+				assert.JSONEq(t, `{
+					"title": "My Word Of The Day: sonder",
+					"message": "sonder /ˈsɒndə/\n• (noun) The realization that each passerby has a life as vivid and complex as one's own.\n• (verb) To sound out or probe.",
+					"priority": 5
+				}`, received.body)
+			})
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the timestamp, a full stop
+	// and the body of a webhook request, prefixed with sha256=
+	SignatureHeader = "X-MyWordOfTheDay-Signature"
+	// TimestampHeader carries the Unix time a webhook request was signed at, so
+	// receivers can reject requests that are replayed later
+	TimestampHeader = "X-MyWordOfTheDay-Timestamp"
+)
+
+// webhookPayload is the body of a webhook request
+type webhookPayload struct {
+	Title         string   `json:"title"`
+	Word          string   `json:"word"`
+	Pronunciation string   `json:"pronunciation,omitempty"`
+	Definitions   []string `json:"definitions"`
+	Text          string   `json:"text"`
+	SentAt        int64    `json:"sentAt"`
+}
+
+// Webhook is a Notifier that POSTs notifications as JSON to any URL, signed
+// with a shared secret so the receiver can tell they're genuine
+type Webhook struct {
+	url    string
+	secret string
+	client *http.Client
+
+	now func() time.Time
+}
+
+// NewWebhook returns a Webhook sending notifications to url. Requests are only
+// signed if secret is set.
+func NewWebhook(url string, secret string, client *http.Client) *Webhook {
+	return &Webhook{url: url, secret: secret, client: client, now: time.Now}
+}
+
+func (w *Webhook) Notify(ctx context.Context, n Notification) error {
+	now := w.now()
+
+	definitions := n.Definitions
+	if definitions == nil {
+		definitions = []string{}
+	}
+
+	payload := webhookPayload{
+		Title:         n.Title,
+		Word:          n.Word,
+		Pronunciation: n.Pronunciation,
+		Definitions:   definitions,
+		Text:          n.Text(),
+		SentAt:        now.Unix(),
+	}
+
+	return sendJSON(ctx, w.client, http.MethodPost, w.url, payload, func(req *http.Request, body []byte) {
+		if w.secret == "" {
+			return
+		}
+
+		ts := strconv.FormatInt(now.Unix(), 10)
+		req.Header.Set(TimestampHeader, ts)
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.secret, ts, body))
+	})
+}
+
+// Sign returns the hex HMAC-SHA256 a webhook request with the timestamp and
+// body is signed with, for receivers to compare with SignatureHeader
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook(t *testing.T) {
+	now := time.Date(2022, 3, 4, 7, 30, 0, 0, time.UTC)
+
+	t.Run("Given a webhook with a secret", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusNoContent)
+
+		w := NewWebhook(srv.URL+"/hook", "s3cret", srv.Client())
+		w.now = func() time.Time { return now }
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := w.Notify(context.Background(), notification)
+
+			t.Run("Then the notification should be posted as JSON", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, http.MethodPost, received.method)
+				assert.Equal(t, "/hook", received.path)
+				assert.Equal(t, "application/json", received.header.Get("Content-Type"))
+				assert.JSONEq(t, `{
+					"title": "My Word Of The Day: sonder",
+					"word": "sonder",
+					"pronunciation": "/ˈsɒndə/",
+					"definitions": [
+						"(noun) The realization that each passerby has a life as vivid and complex as one's own.",
+						"(verb) To sound out or probe."
+					],
+					"text": "sonder /ˈsɒndə/\n• (noun) The realization that each passerby has a life as vivid and complex as one's own.\n• (verb) To sound out or probe.",
+					"sentAt": 1646379000
+				}`, received.body)
+			})
+
+			t.Run("Then it should be signed with the timestamp and body", func(t *testing.T) {
+				assert.Equal(t, "1646379000", received.header.Get(TimestampHeader))
+				assert.Equal(t, "sha256="+Sign("s3cret", "1646379000", []byte(received.body)), received.header.Get(SignatureHeader))
+			})
+		})
+	})
+
+	t.Run("Given a webhook without a secret", func(t *testing.T) {
+		srv, received := newStandIn(t, http.StatusOK)
+
+		t.Run("When it's notified", func(t *testing.T) {
+			err := NewWebhook(srv.URL, "", srv.Client()).Notify(context.Background(), Notification{Word: "sonder"})
+
+			t.Run("Then it shouldn't be signed", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Empty(t, received.header.Get(SignatureHeader))
+				assert.Empty(t, received.header.Get(TimestampHeader))
+				assert.Contains(t, received.body, `"definitions":[]`)
+			})
+		})
+	})
+}
+
+func TestSign(t *testing.T) {
+	t.Run("Given a secret, timestamp and body", func(t *testing.T) {
+		t.Run("Then the signature should be the HMAC-SHA256 of the timestamp, a full stop and the body", func(t *testing.T) {
+			// echo -n '1646379000.{}' | openssl dgst -sha256 -hmac s3cret
+			assert.Equal(t, "34118467909549903182f74ec1d75cadba4ac41c30297929048173e1fa9b8435", Sign("s3cret", "1646379000", []byte("{}")))
+		})
+	})
+}
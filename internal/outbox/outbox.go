@@ -0,0 +1,227 @@
+// Package outbox delivers the emails queued in the database's outbox, retrying
+// those that fail temporarily and dead-lettering those that fail permanently
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/mail"
+)
+
+// Store is where messages are queued
+type Store interface {
+	ClaimMessages(ctx context.Context, limit int, lease time.Duration) ([]db.OutboxMessage, error)
+	MarkMessageSent(ctx context.Context, id int32) error
+	RetryMessageAt(ctx context.Context, id int32, next time.Time, lastError string) error
+	FailMessage(ctx context.Context, id int32, lastError string) error
+	ReleaseMessages(ctx context.Context, ids []int32) error
+}
+
+// recordTimeout is how long recording the outcome of a message is given. It's
+// recorded even once the worker is stopping, so a message that was sent isn't
+// sent again.
+const recordTimeout = 10 * time.Second
+
+// Sender sends a rendered message, returning a *mail.PermanentError if
+// sending it again would fail the same way
+type Sender interface {
+	SendMessage(ctx context.Context, to []string, msg []byte) error
+}
+
+// Config controls how often messages are sent and retried
+type Config struct {
+	// Interval is how long the worker waits before looking for messages to
+	// send when there were none
+	Interval time.Duration
+	// BatchSize is how many messages are claimed at a time
+	BatchSize int
+	// SendTimeout is how long sending a message is given. Messages are claimed
+	// for a little longer, so they aren't sent twice.
+	SendTimeout time.Duration
+	// MaxAttempts is how many times a message is tried before it's
+	// dead-lettered, even if every failure was temporary
+	MaxAttempts int
+	// MinBackoff is how long the first retry waits, doubling for each attempt
+	// after it up to MaxBackoff
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Worker sends the messages in a Store
+type Worker struct {
+	store  Store
+	sender Sender
+	config Config
+
+	now func() time.Time
+}
+
+// New returns a Worker sending the messages in store with sender. Zero values
+// in c are replaced with defaults.
+func New(store Store, sender Sender, c Config) *Worker {
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 10
+	}
+
+	if c.SendTimeout <= 0 {
+		c.SendTimeout = time.Minute
+	}
+
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 8
+	}
+
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = time.Minute
+	}
+
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = c.MinBackoff
+	}
+
+	return &Worker{store: store, sender: sender, config: c, now: time.Now}
+}
+
+// Run sends messages as they become due until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		n, err := w.Process(ctx)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Error processing outbox")
+		}
+
+		// Carry straight on while there may be more messages waiting
+		if err == nil && n == w.config.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.config.Interval):
+		}
+	}
+}
+
+// Process claims a batch of due messages and tries to send each of them,
+// returning how many were claimed
+func (w *Worker) Process(ctx context.Context) (int, error) {
+	// Messages are claimed for long enough to try them all, and a little more,
+	// so they aren't claimed again while this batch is still being sent
+	lease := time.Duration(w.config.BatchSize+1) * w.config.SendTimeout
+
+	messages, err := w.store.ClaimMessages(ctx, w.config.BatchSize, lease)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range messages {
+		// Once stopping, the rest of the batch is left for the next worker
+		// rather than tried
+		if ctx.Err() != nil {
+			w.release(messages[i:])
+			break
+		}
+
+		w.send(ctx, msg)
+	}
+
+	return len(messages), nil
+}
+
+// send tries to send msg, recording the outcome in the store. Failing to
+// record it is only logged, as the message is tried again once its lease
+// expires.
+func (w *Worker) send(ctx context.Context, msg db.OutboxMessage) {
+	sendCtx, cancel := context.WithTimeout(ctx, w.config.SendTimeout)
+	err := w.sender.SendMessage(sendCtx, []string{msg.Recipient}, msg.Message)
+	cancel()
+
+	fields := logrus.Fields{
+		"id":       msg.ID,
+		"userID":   msg.UserID,
+		"attempts": msg.Attempts,
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	var recordErr error
+
+	switch {
+	case err == nil:
+		recordErr = w.store.MarkMessageSent(recordCtx, msg.ID)
+		logrus.WithFields(fields).Info("Message sent successfully")
+	case ctx.Err() != nil:
+		// Interrupted by the worker stopping rather than rejected, so it
+		// isn't counted as an attempt
+		recordErr = w.store.ReleaseMessages(recordCtx, []int32{msg.ID})
+		fields["error"] = err
+		logrus.WithFields(fields).Warn("Message released while stopping")
+	case mail.IsPermanent(err) || int(msg.Attempts) >= w.config.MaxAttempts:
+		recordErr = w.store.FailMessage(recordCtx, msg.ID, err.Error())
+		fields["error"] = err
+		logrus.WithFields(fields).Error("Message dead-lettered")
+	default:
+		next := w.now().Add(w.backoff(int(msg.Attempts)))
+		recordErr = w.store.RetryMessageAt(recordCtx, msg.ID, next, err.Error())
+		fields["error"] = err
+		fields["nextAttemptAt"] = next
+		logrus.WithFields(fields).Warn("Message will be retried")
+	}
+
+	if recordErr != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": recordErr,
+			"id":    msg.ID,
+		}).Error("Error recording outcome of message")
+	}
+}
+
+// release returns messages that weren't tried to the store. Failing to is only
+// logged, as they're tried again once their lease expires.
+func (w *Worker) release(messages []db.OutboxMessage) {
+	ids := make([]int32, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	if err := w.store.ReleaseMessages(ctx, ids); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"ids":   ids,
+		}).Error("Error releasing messages")
+	}
+}
+
+// backoff returns how long to wait before the attempt after the given one,
+// doubling from MinBackoff up to MaxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.config.MinBackoff
+	for i := 1; i < attempts && d < w.config.MaxBackoff; i++ {
+		d *= 2
+	}
+
+	if d > w.config.MaxBackoff {
+		return w.config.MaxBackoff
+	}
+
+	return d
+}
@@ -0,0 +1,208 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/mail"
+)
+
+type storeMock struct {
+	claimed   []db.OutboxMessage
+	claimErr  error
+	lastLimit int
+	lastLease time.Duration
+
+	sent     []int32
+	retried  map[int32]time.Time
+	failed   map[int32]string
+	released []int32
+
+	// recordCtxErr is the error of the context the last outcome was recorded
+	// with
+	recordCtxErr error
+}
+
+func (s *storeMock) ClaimMessages(_ context.Context, limit int, lease time.Duration) ([]db.OutboxMessage, error) {
+	s.lastLimit, s.lastLease = limit, lease
+	return s.claimed, s.claimErr
+}
+
+func (s *storeMock) MarkMessageSent(ctx context.Context, id int32) error {
+	s.sent = append(s.sent, id)
+	s.recordCtxErr = ctx.Err()
+	return nil
+}
+
+func (s *storeMock) RetryMessageAt(_ context.Context, id int32, next time.Time, _ string) error {
+	s.retried[id] = next
+	return nil
+}
+
+func (s *storeMock) FailMessage(_ context.Context, id int32, lastError string) error {
+	s.failed[id] = lastError
+	return nil
+}
+
+func (s *storeMock) ReleaseMessages(ctx context.Context, ids []int32) error {
+	s.released = append(s.released, ids...)
+	s.recordCtxErr = ctx.Err()
+	return nil
+}
+
+// senderMock fails to send to the recipients in errs, and calls onSend, if
+// set, after each message
+type senderMock struct {
+	errs   map[string]error
+	to     []string
+	onSend func()
+}
+
+func (s *senderMock) SendMessage(_ context.Context, to []string, _ []byte) error {
+	s.to = append(s.to, to...)
+	if s.onSend != nil {
+		s.onSend()
+	}
+	return s.errs[to[0]]
+}
+
+func TestProcess(t *testing.T) {
+	now := time.Date(2022, 3, 4, 7, 30, 0, 0, time.UTC)
+
+	t.Run("Given a batch of due messages", func(t *testing.T) {
+		store := &storeMock{
+			claimed: []db.OutboxMessage{
+				{ID: 1, Recipient: "alice@example.com", Attempts: 1},
+				{ID: 2, Recipient: "bob@example.com", Attempts: 1},
+				{ID: 3, Recipient: "carol@example.com", Attempts: 3},
+				{ID: 4, Recipient: "dave@example.com", Attempts: 5},
+			},
+			retried: map[int32]time.Time{},
+			failed:  map[int32]string{},
+		}
+
+		sender := &senderMock{errs: map[string]error{
+			"bob@example.com":   &mail.PermanentError{Err: errors.New("550 no such user")},
+			"carol@example.com": errors.New("451 try again later"),
+			"dave@example.com":  errors.New("451 try again later"),
+		}}
+
+		w := New(store, sender, Config{BatchSize: 4, SendTimeout: time.Minute, MaxAttempts: 5, MinBackoff: time.Minute, MaxBackoff: time.Hour})
+		w.now = func() time.Time { return now }
+
+		n, err := w.Process(context.Background())
+
+		t.Run("Then each should be sent to its recipient alone", func(t *testing.T) {
+			assert.NoError(t, err)
+			assert.Equal(t, 4, n)
+			assert.Equal(t, []string{"alice@example.com", "bob@example.com", "carol@example.com", "dave@example.com"}, sender.to)
+			assert.Equal(t, 4, store.lastLimit)
+			assert.Equal(t, 5*time.Minute, store.lastLease)
+		})
+
+		t.Run("Then the message that was sent should be marked sent", func(t *testing.T) {
+			assert.Equal(t, []int32{1}, store.sent)
+		})
+
+		t.Run("Then the temporary failure should be retried with backoff", func(t *testing.T) {
+			assert.Equal(t, map[int32]time.Time{3: now.Add(4 * time.Minute)}, store.retried)
+		})
+
+		t.Run("Then the permanent failure and the message out of attempts should be dead-lettered", func(t *testing.T) {
+			assert.Equal(t, map[int32]string{2: "550 no such user", 4: "451 try again later"}, store.failed)
+		})
+	})
+
+	t.Run("Given the worker is stopped while a message is being sent", func(t *testing.T) {
+		store := &storeMock{
+			claimed: []db.OutboxMessage{
+				{ID: 1, Recipient: "alice@example.com", Attempts: 1},
+				{ID: 2, Recipient: "bob@example.com", Attempts: 1},
+				{ID: 3, Recipient: "carol@example.com", Attempts: 1},
+			},
+			retried: map[int32]time.Time{},
+			failed:  map[int32]string{},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sender := &senderMock{onSend: cancel}
+
+		n, err := New(store, sender, Config{BatchSize: 3}).Process(ctx)
+
+		t.Run("Then the message should be marked sent despite the worker stopping", func(t *testing.T) {
+			assert.NoError(t, err)
+			assert.Equal(t, 3, n)
+			assert.Equal(t, []string{"alice@example.com"}, sender.to)
+			assert.Equal(t, []int32{1}, store.sent)
+			assert.NoError(t, store.recordCtxErr)
+		})
+
+		t.Run("Then the rest of the batch should be released rather than tried", func(t *testing.T) {
+			assert.Equal(t, []int32{2, 3}, store.released)
+			assert.Empty(t, store.retried)
+			assert.Empty(t, store.failed)
+		})
+	})
+
+	t.Run("Given the worker is stopped and sending fails", func(t *testing.T) {
+		store := &storeMock{
+			claimed: []db.OutboxMessage{{ID: 1, Recipient: "alice@example.com", Attempts: 1}},
+			retried: map[int32]time.Time{},
+			failed:  map[int32]string{},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sender := &senderMock{errs: map[string]error{"alice@example.com": context.Canceled}, onSend: cancel}
+
+		_, err := New(store, sender, Config{BatchSize: 1}).Process(ctx)
+
+		t.Run("Then the message should be released without counting as an attempt", func(t *testing.T) {
+			assert.NoError(t, err)
+			assert.Equal(t, []int32{1}, store.released)
+			assert.NoError(t, store.recordCtxErr)
+			assert.Empty(t, store.retried)
+		})
+	})
+
+	t.Run("Given the store fails", func(t *testing.T) {
+		store := &storeMock{claimErr: errors.New("an error")}
+		sender := &senderMock{}
+
+		n, err := New(store, sender, Config{}).Process(context.Background())
+
+		t.Run("Then nothing should be sent", func(t *testing.T) {
+			assert.EqualError(t, err, "an error")
+			assert.Zero(t, n)
+			assert.Empty(t, sender.to)
+		})
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	w := New(&storeMock{}, &senderMock{}, Config{MinBackoff: time.Minute, MaxBackoff: 10 * time.Minute})
+
+	testCases := []struct {
+		desc     string
+		attempts int
+		expected time.Duration
+	}{
+		{desc: "The first retry should wait the minimum", attempts: 1, expected: time.Minute},
+		{desc: "Each retry should wait twice as long", attempts: 3, expected: 4 * time.Minute},
+		{desc: "Retries should wait no longer than the maximum", attempts: 5, expected: 10 * time.Minute},
+		{desc: "Many attempts shouldn't overflow", attempts: 100, expected: 10 * time.Minute},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, w.backoff(tC.attempts))
+		})
+	}
+}
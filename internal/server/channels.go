@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/notifier"
+)
+
+type channelStore interface {
+	InsertChannel(context.Context, int32, string) (db.Channel, error)
+	DeleteChannel(context.Context, int32, int32) (db.Channel, error)
+	ListChannels(context.Context, int32) ([]db.Channel, error)
+}
+
+// AddChannel adds a channel the caller is sent their word of the day to, as a
+// URL understood by notifier.New. Channels on loopback, link-local or private
+// addresses are rejected.
+func (s *Server) AddChannel(ctx context.Context, url string) (db.Channel, error) {
+	url = strings.TrimSpace(url)
+
+	if url == "" {
+		return db.Channel{}, invalidField("url", "must not be empty")
+	}
+
+	// notifier.New's errors don't quote the URL, so they can be returned
+	// without giving away the channel's secrets
+	if _, err := notifier.New(url, nil); err != nil {
+		return db.Channel{}, invalidField("url", "%s", err)
+	}
+
+	rsp, err := s.channelStore.InsertChannel(ctx, s.userID(ctx), url)
+	if err != nil {
+		return db.Channel{}, statusError(err, "unable to add channel")
+	}
+
+	return rsp, nil
+}
+
+// DeleteChannel stops the caller being sent their word of the day to the
+// channel with the id
+func (s *Server) DeleteChannel(ctx context.Context, id int32) (db.Channel, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return db.Channel{}, err
+	}
+
+	rsp, err := s.channelStore.DeleteChannel(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Channel{}, status.Errorf(codes.NotFound, "channel %d not found", id)
+	}
+
+	if err != nil {
+		return db.Channel{}, statusError(err, "unable to delete channel")
+	}
+
+	return rsp, nil
+}
+
+// ListChannels returns the caller's channels in the order they were added
+func (s *Server) ListChannels(ctx context.Context) ([]db.Channel, error) {
+	rsp, err := s.channelStore.ListChannels(ctx, s.userID(ctx))
+	if err != nil {
+		return nil, statusError(err, "unable to list channels")
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestAddChannel(t *testing.T) {
+	cm := &channelMock{}
+	s := Server{channelStore: cm}
+
+	testCases := []struct {
+		desc        string
+		url         string
+		err         error
+		expectedURL string
+		expectedErr string
+		code        codes.Code
+	}{
+		{desc: "A channel should be added without surrounding space", url: " ntfy+https://ntfy.sh/my-words ", expectedURL: "ntfy+https://ntfy.sh/my-words"},
+		{desc: "An empty URL should be rejected", url: " ", code: codes.InvalidArgument, expectedErr: "invalid request: url must not be empty"},
+		{desc: "An unknown kind of channel should be rejected", url: "telegram+https://api.telegram.org/", code: codes.InvalidArgument, expectedErr: `invalid request: url unknown channel kind "telegram", must be one of webhook, slack, discord, matrix, ntfy, gotify`},
+		{desc: "A channel missing its token should be rejected", url: "gotify+https://gotify.example.com/", code: codes.InvalidArgument, expectedErr: "invalid request: url gotify channel url must have a token query parameter"},
+		{desc: "A URL that doesn't parse should be rejected without being quoted", url: "ntfy+https://ntfy.sh/%zz?token=tk_XXXX", code: codes.InvalidArgument, expectedErr: "invalid request: url channel url must be a valid url"},
+		{desc: "A loopback URL should be rejected", url: "webhook+http://127.0.0.1:8080/hook", code: codes.InvalidArgument, expectedErr: "invalid request: url channel url must not be a loopback, link-local or private address"},
+		{desc: "The cloud metadata service should be rejected", url: "webhook+http://169.254.169.254/latest/meta-data/", code: codes.InvalidArgument, expectedErr: "invalid request: url channel url must not be a loopback, link-local or private address"},
+		{desc: "A database error should be returned", url: "slack+https://hooks.slack.com/services/XXXX", err: errors.New("an error"), code: codes.Internal, expectedErr: "unable to add channel"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cm.err = tC.err
+			cm.insertedURL = ""
+			cm.insertResponse = db.Channel{ID: 1, URL: tC.expectedURL}
+
+			c, err := s.AddChannel(context.Background(), tC.url)
+			if tC.expectedErr != "" {
+				assertStatusError(t, err, tC.code, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedURL, cm.insertedURL)
+			assert.Equal(t, cm.insertResponse, c)
+		})
+	}
+}
+
+func TestDeleteChannel(t *testing.T) {
+	cm := &channelMock{}
+	s := Server{channelStore: cm}
+
+	t.Run("Given an invalid id", func(t *testing.T) {
+		t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+			_, err := s.DeleteChannel(context.Background(), 0)
+			assertStatusError(t, err, codes.InvalidArgument, "invalid request: id must be a positive id")
+		})
+	})
+
+	t.Run("Given a channel that doesn't exist", func(t *testing.T) {
+		t.Run("Then a NotFound error is returned", func(t *testing.T) {
+			cm.err = db.ErrNotFound
+
+			_, err := s.DeleteChannel(context.Background(), 3)
+			assertStatusError(t, err, codes.NotFound, "channel 3 not found")
+		})
+	})
+
+	t.Run("Given a channel that exists", func(t *testing.T) {
+		t.Run("Then it is deleted", func(t *testing.T) {
+			cm.err = nil
+			cm.deleteResponse = db.Channel{ID: 3, URL: "ntfy+https://ntfy.sh/my-words"}
+
+			c, err := s.DeleteChannel(context.Background(), 3)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(3), cm.deletedID)
+			assert.Equal(t, cm.deleteResponse, c)
+		})
+	})
+}
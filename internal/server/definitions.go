@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+)
+
+// Definitions returns the senses and pronunciations of the word with the given
+// id, both those written by hand and those looked up in a dictionary, or a
+// NotFound error
+func (s *Server) Definitions(ctx context.Context, id int32) (db.Definitions, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return db.Definitions{}, err
+	}
+
+	d, err := s.definitionStore.GetDefinitions(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Definitions{}, status.Errorf(codes.NotFound, "word %d not found", id)
+	}
+
+	if err != nil {
+		return db.Definitions{}, statusError(err, "unable to get definitions")
+	}
+
+	return d, nil
+}
+
+// AddSense adds a sense written by hand to the word with the given id. Its ID,
+// WordID and Source are ignored.
+func (s *Server) AddSense(ctx context.Context, wordID int32, sense db.Sense) (db.Sense, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", wordID)
+	validateSense(&v, sense)
+
+	if err := v.err(); err != nil {
+		return db.Sense{}, err
+	}
+
+	added, err := s.definitionStore.AddSense(ctx, s.userID(ctx), wordID, cleanSense(sense))
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Sense{}, status.Errorf(codes.NotFound, "word %d not found", wordID)
+	}
+
+	if err != nil {
+		return db.Sense{}, statusError(err, "unable to add sense")
+	}
+
+	return added, nil
+}
+
+// UpdateSense replaces the fields of the sense with the given sense's ID. A
+// sense from a dictionary becomes one written by hand, so it isn't replaced
+// when the word is looked up again.
+func (s *Server) UpdateSense(ctx context.Context, wordID int32, sense db.Sense) (db.Sense, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", wordID)
+	validateID(&v, "sense.id", sense.ID)
+	validateSense(&v, sense)
+
+	if err := v.err(); err != nil {
+		return db.Sense{}, err
+	}
+
+	updated, err := s.definitionStore.UpdateSense(ctx, s.userID(ctx), wordID, cleanSense(sense))
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Sense{}, status.Errorf(codes.NotFound, "sense %d of word %d not found", sense.ID, wordID)
+	}
+
+	if err != nil {
+		return db.Sense{}, statusError(err, "unable to update sense")
+	}
+
+	return updated, nil
+}
+
+// DeleteSense deletes a sense of the word with the given id
+func (s *Server) DeleteSense(ctx context.Context, wordID int32, id int32) (db.Sense, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", wordID)
+	validateID(&v, "sense_id", id)
+
+	if err := v.err(); err != nil {
+		return db.Sense{}, err
+	}
+
+	deleted, err := s.definitionStore.DeleteSense(ctx, s.userID(ctx), wordID, id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Sense{}, status.Errorf(codes.NotFound, "sense %d of word %d not found", id, wordID)
+	}
+
+	if err != nil {
+		return db.Sense{}, statusError(err, "unable to delete sense")
+	}
+
+	return deleted, nil
+}
+
+// cleanSense trims the space around the fields of a sense written by hand
+func cleanSense(sense db.Sense) db.Sense {
+	sense.PartOfSpeech = strings.TrimSpace(sense.PartOfSpeech)
+	sense.Definition = strings.TrimSpace(sense.Definition)
+	sense.Etymology = strings.TrimSpace(sense.Etymology)
+	sense.IPA = strings.TrimSpace(sense.IPA)
+	sense.Examples = trimAll(sense.Examples)
+	sense.Synonyms = trimAll(sense.Synonyms)
+	sense.Antonyms = trimAll(sense.Antonyms)
+
+	return sense
+}
+
+func trimAll(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	trimmed := make([]string, len(s))
+	for i := range s {
+		trimmed[i] = strings.TrimSpace(s[i])
+	}
+
+	return trimmed
+}
+
+// lookupDefinitions stores what the dictionary knows about word. Failing to
+// look it up doesn't stop the word being added, so errors are only logged.
+func (s *Server) lookupDefinitions(ctx context.Context, word db.Word) {
+	if s.definitions == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.definitionsTimeout)
+	defer cancel()
+
+	e, err := s.definitions.Lookup(ctx, word.Word)
+	if errors.Is(err, definitions.ErrNotFound) {
+		logrus.WithFields(logrus.Fields{
+			"id":   word.ID,
+			"word": word.Word,
+		}).Info("No dictionary definition found")
+		return
+	}
+
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"id":    word.ID,
+		}).Error("Error looking up definition")
+		return
+	}
+
+	d := db.Definitions{
+		Senses:         make([]db.Sense, len(e.Senses)),
+		Pronunciations: make([]db.Pronunciation, len(e.Pronunciations)),
+	}
+
+	for i, sense := range e.Senses {
+		d.Senses[i] = db.Sense{
+			PartOfSpeech: sense.PartOfSpeech,
+			Definition:   sense.Definition,
+			Examples:     sense.Examples,
+			Synonyms:     sense.Synonyms,
+			Antonyms:     sense.Antonyms,
+			Etymology:    sense.Etymology,
+			IPA:          sense.IPA,
+		}
+	}
+
+	for i, p := range e.Pronunciations {
+		d.Pronunciations[i] = db.Pronunciation{
+			IPA:      p.IPA,
+			AudioURL: p.AudioURL,
+		}
+	}
+
+	if err := s.definitionStore.ReplaceDefinitions(ctx, word.UserID, word.ID, e.Source, d); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"id":    word.ID,
+		}).Error("Error storing definition")
+	}
+}
+
+// relookupDefinitions replaces the dictionary's definitions of word with those
+// of its new spelling, keeping the ones written by hand. The word has already
+// been updated, so errors are only logged.
+func (s *Server) relookupDefinitions(ctx context.Context, word db.Word) {
+	if err := s.definitionStore.DeleteDictionaryDefinitions(ctx, word.UserID, word.ID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"id":    word.ID,
+		}).Error("Error deleting dictionary definitions")
+		return
+	}
+
+	s.lookupDefinitions(ctx, word)
+}
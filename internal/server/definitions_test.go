@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+func TestAddWordDefinitions(t *testing.T) {
+	wm := &wordMock{insertWordResponse: db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sonder"}}
+	dm := &definitionMock{}
+	pm := &providerMock{}
+
+	s := Server{wordModifier: wm, definitionStore: dm, definitions: pm, definitionsTimeout: time.Second}
+
+	req := &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: "sonder"}}
+
+	t.Run("Given a dictionary provider", func(t *testing.T) {
+		t.Run("When a word it knows is added", func(t *testing.T) {
+			t.Run("Then its definitions are stored against the new word", func(t *testing.T) {
+				pm.entry = definitions.Entry{
+					Source:         "wiktionary",
+					Pronunciations: []definitions.Pronunciation{{IPA: "/ˈsɒndə/"}},
+					Senses: []definitions.Sense{{
+						PartOfSpeech: "verb",
+						Definition:   "To sound out or probe.",
+						Examples:     []string{"We sondered the depths."},
+						Synonyms:     []string{"probe"},
+						Etymology:    "From German sondern.",
+						IPA:          "/ˈsɒndə/",
+					}},
+				}
+
+				_, err := s.AddWord(context.Background(), req)
+				assert.NoError(t, err)
+
+				assert.Equal(t, int32(45), dm.replacedID)
+				assert.Equal(t, "wiktionary", dm.replacedSource)
+				assert.Equal(t, db.Definitions{
+					Senses: []db.Sense{{
+						PartOfSpeech: "verb",
+						Definition:   "To sound out or probe.",
+						Examples:     []string{"We sondered the depths."},
+						Synonyms:     []string{"probe"},
+						Etymology:    "From German sondern.",
+						IPA:          "/ˈsɒndə/",
+					}},
+					Pronunciations: []db.Pronunciation{{IPA: "/ˈsɒndə/"}},
+				}, dm.replaced)
+			})
+		})
+		t.Run("When the lookup fails", func(t *testing.T) {
+			t.Run("Then the word is still added", func(t *testing.T) {
+				*dm = definitionMock{}
+				pm.err = errors.New("connection refused")
+
+				r, err := s.AddWord(context.Background(), req)
+				assert.NoError(t, err)
+				assert.Equal(t, "sonder", r.GetWord().GetWord())
+				assert.Zero(t, dm.replacedID)
+			})
+		})
+		t.Run("When the word isn't in the dictionary", func(t *testing.T) {
+			t.Run("Then nothing is stored", func(t *testing.T) {
+				*dm = definitionMock{}
+				pm.err = definitions.ErrNotFound
+
+				_, err := s.AddWord(context.Background(), req)
+				assert.NoError(t, err)
+				assert.Zero(t, dm.replacedID)
+			})
+		})
+	})
+}
+
+func TestDefinitions(t *testing.T) {
+	dm := &definitionMock{}
+	s := Server{definitionStore: dm}
+
+	t.Run("Given a request for a word's definitions", func(t *testing.T) {
+		t.Run("When the id is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.Definitions(context.Background(), 0)
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: id must be a positive id")
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				dm.err = db.ErrNotFound
+
+				_, err := s.Definitions(context.Background(), 45)
+				assertStatusError(t, err, codes.NotFound, "word 45 not found")
+			})
+		})
+		t.Run("When the word exists", func(t *testing.T) {
+			t.Run("Then its definitions are returned", func(t *testing.T) {
+				dm.err = nil
+				dm.getDefinitionsResponse = db.Definitions{Senses: []db.Sense{{Source: "wiktionary", Definition: "a definition"}}}
+
+				d, err := s.Definitions(context.Background(), 45)
+				assert.NoError(t, err)
+				assert.Equal(t, dm.getDefinitionsResponse, d)
+			})
+		})
+	})
+}
+
+func TestAddSense(t *testing.T) {
+	dm := &definitionMock{}
+	s := Server{definitionStore: dm}
+
+	testCases := []struct {
+		desc  string
+		sense db.Sense
+		msg   string
+	}{
+		{
+			desc:  "no definition",
+			sense: db.Sense{Definition: " "},
+			msg:   "invalid request: sense.definition is required",
+		},
+		{
+			desc:  "an empty example",
+			sense: db.Sense{Definition: "a definition", Examples: []string{"An example.", ""}},
+			msg:   "invalid request: sense.examples[1] must not be empty",
+		},
+		{
+			desc:  "a synonym that isn't a word",
+			sense: db.Sense{Definition: "a definition", Synonyms: []string{"probe"}, Antonyms: []string{"<ignore>"}},
+			msg:   `invalid request: sense.antonyms[0] must not contain '<'`,
+		},
+		{
+			desc:  "a part of speech that is too long",
+			sense: db.Sense{Definition: "a definition", PartOfSpeech: strings.Repeat("n", maxPartOfSpeechLength+1)},
+			msg:   "invalid request: sense.part_of_speech must be at most 64 characters",
+		},
+	}
+
+	t.Run("Given a sense written by hand", func(t *testing.T) {
+		for _, tC := range testCases {
+			t.Run("When it has "+tC.desc, func(t *testing.T) {
+				t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+					_, err := s.AddSense(context.Background(), 45, tC.sense)
+					assertStatusError(t, err, codes.InvalidArgument, tC.msg)
+				})
+			})
+		}
+
+		t.Run("When it is valid", func(t *testing.T) {
+			t.Run("Then it is added with its fields trimmed", func(t *testing.T) {
+				added, err := s.AddSense(context.Background(), 45, db.Sense{
+					PartOfSpeech: " noun ",
+					Definition:   "A feeling of wonder at strangers' lives.\n",
+					Synonyms:     []string{" empathy"},
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, db.Sense{
+					ID:           1,
+					WordID:       45,
+					PartOfSpeech: "noun",
+					Definition:   "A feeling of wonder at strangers' lives.",
+					Synonyms:     []string{"empathy"},
+				}, added)
+			})
+		})
+
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				dm.err = db.ErrNotFound
+
+				_, err := s.AddSense(context.Background(), 45, db.Sense{Definition: "a definition"})
+				assertStatusError(t, err, codes.NotFound, "word 45 not found")
+			})
+		})
+	})
+}
+
+func TestUpdateSense(t *testing.T) {
+	dm := &definitionMock{}
+	s := Server{definitionStore: dm}
+
+	t.Run("Given an update to a sense", func(t *testing.T) {
+		t.Run("When the sense id is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.UpdateSense(context.Background(), 45, db.Sense{Definition: "a definition"})
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: sense.id must be a positive id")
+			})
+		})
+		t.Run("When the sense came from a dictionary", func(t *testing.T) {
+			t.Run("Then it becomes one written by hand", func(t *testing.T) {
+				updated, err := s.UpdateSense(context.Background(), 45, db.Sense{ID: 7, Source: "wiktionary", Definition: "a definition"})
+				assert.NoError(t, err)
+				assert.Equal(t, "", updated.Source)
+				assert.Equal(t, int32(7), dm.sense.ID)
+			})
+		})
+		t.Run("When the sense does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				dm.err = db.ErrNotFound
+
+				_, err := s.UpdateSense(context.Background(), 45, db.Sense{ID: 7, Definition: "a definition"})
+				assertStatusError(t, err, codes.NotFound, "sense 7 of word 45 not found")
+			})
+		})
+	})
+}
+
+func TestDeleteSense(t *testing.T) {
+	dm := &definitionMock{}
+	s := Server{definitionStore: dm}
+
+	t.Run("Given a sense to delete", func(t *testing.T) {
+		t.Run("When the sense id is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.DeleteSense(context.Background(), 45, -1)
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: sense_id must be a positive id")
+			})
+		})
+		t.Run("When the sense exists", func(t *testing.T) {
+			t.Run("Then it is deleted", func(t *testing.T) {
+				deleted, err := s.DeleteSense(context.Background(), 45, 7)
+				assert.NoError(t, err)
+				assert.Equal(t, db.Sense{ID: 7, WordID: 45}, deleted)
+			})
+		})
+	})
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// alreadyExistsError returns an AlreadyExists status carrying the id of the
+// existing word in a ResourceInfo detail
+func alreadyExistsError(existing db.Word) error {
+	st := status.New(codes.AlreadyExists, fmt.Sprintf("word %q already exists with id %d", existing.Word, existing.ID))
+
+	detailed, err := st.WithDetails(&errdetails.ResourceInfo{
+		ResourceType: "word",
+		ResourceName: strconv.Itoa(int(existing.ID)),
+		Description:  "a word with the same spelling already exists",
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// statusError converts an error returned by the db package into a status
+// error with the matching code, prefixing its message with msg. Internal errors
+// are logged and only msg is returned, so database details aren't leaked to
+// callers. Errors that already carry a status are returned unchanged.
+func statusError(err error, msg string) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := codes.Internal
+
+	switch db.Classify(err) {
+	case db.ErrNotFound:
+		code = codes.NotFound
+	case db.ErrAlreadyExists:
+		code = codes.AlreadyExists
+	case db.ErrVersionConflict:
+		code = codes.Aborted
+	case db.ErrTimeout, context.DeadlineExceeded:
+		code = codes.DeadlineExceeded
+	case db.ErrUnavailable:
+		code = codes.Unavailable
+	case db.ErrTooLong:
+		code = codes.InvalidArgument
+	case context.Canceled:
+		code = codes.Canceled
+	}
+
+	if code == codes.Internal {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Errorf("Internal error: %s", msg)
+
+		return status.Error(code, msg)
+	}
+
+	return status.Errorf(code, "%s: %v", msg, err)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// assertStatusError checks err is a status error with the given code and message
+func assertStatusError(t *testing.T, err error, code codes.Code, msg string) {
+	t.Helper()
+
+	st := status.Convert(err)
+	assert.Equal(t, code, st.Code())
+	assert.Equal(t, msg, st.Message())
+}
+
+func TestStatusError(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		err      error
+		expected codes.Code
+	}{
+		{desc: "Not found should be NotFound", err: errors.Wrap(db.ErrNotFound, "unable to get word"), expected: codes.NotFound},
+		{desc: "Unique violation should be AlreadyExists", err: &pgconn.PgError{Code: "23505"}, expected: codes.AlreadyExists},
+		{desc: "Version conflict should be Aborted", err: db.ErrVersionConflict, expected: codes.Aborted},
+		{desc: "Statement timeout should be DeadlineExceeded", err: &pgconn.PgError{Code: "57014"}, expected: codes.DeadlineExceeded},
+		{desc: "Context deadline should be DeadlineExceeded", err: context.DeadlineExceeded, expected: codes.DeadlineExceeded},
+		{desc: "Cancelled context should be Canceled", err: errors.Wrap(context.Canceled, "unable to get words"), expected: codes.Canceled},
+		{desc: "Value too long should be InvalidArgument", err: &pgconn.PgError{Code: "22001"}, expected: codes.InvalidArgument},
+		{desc: "Unreachable database should be Unavailable", err: &pgconn.PgError{Code: "08006"}, expected: codes.Unavailable},
+		{desc: "Status errors should be unchanged", err: status.Error(codes.PermissionDenied, "no"), expected: codes.PermissionDenied},
+		{desc: "Other errors should be Internal", err: errors.New("an error"), expected: codes.Internal},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			assert.Equal(t, tC.expected, status.Code(statusError(tC.err, "unable to do something")))
+		})
+	}
+
+	t.Run("Internal errors should not include the database error", func(t *testing.T) {
+		err := statusError(errors.New(`password authentication failed for user "mywordoftheday"`), "unable to add word")
+		assertStatusError(t, err, codes.Internal, "unable to add word")
+	})
+
+	t.Run("Other errors should include the database error", func(t *testing.T) {
+		err := statusError(db.ErrNotFound, "unable to get word")
+		assertStatusError(t, err, codes.NotFound, "unable to get word: not found")
+	})
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/exporter"
+)
+
+type wordExporter interface {
+	ExportWords(context.Context, int32, string, func(db.ExportedWord) error) error
+}
+
+// ExportTo writes each of the words, or those with the tag if it's set, to w
+// with their tags and senses, in the order they were added. Words are read as
+// they're written, so the whole list is never held in memory. Exporting is
+// only served over HTTP, as the proto has no RPC for it.
+func (s *Server) ExportTo(ctx context.Context, w exporter.Writer, tag string) error {
+	if tag != "" {
+		v := fieldViolations{}
+		validateTag(&v, "tag", tag)
+
+		if err := v.err(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.wordExporter.ExportWords(ctx, s.userID(ctx), tag, w.Write); err != nil {
+		return statusError(err, "unable to export words")
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// exportWriter is an exporter.Writer collecting the words written
+type exportWriter struct {
+	words []db.ExportedWord
+	err   error
+}
+
+func (f *exportWriter) Write(word db.ExportedWord) error {
+	f.words = append(f.words, word)
+	return f.err
+}
+
+func (f *exportWriter) Close() error {
+	return nil
+}
+
+func TestExportTo(t *testing.T) {
+	em := &exportMock{words: []db.ExportedWord{
+		{Word: db.Word{ID: 1, Word: "petrichor"}, Tags: []string{"weather"}},
+		{Word: db.Word{ID: 2, Word: "sonder"}},
+	}}
+	s := Server{wordExporter: em}
+
+	t.Run("Given a request to export words", func(t *testing.T) {
+		t.Run("When the tag is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				err := s.ExportTo(context.Background(), &exportWriter{}, "a/b")
+				assertStatusError(t, err, codes.InvalidArgument, `invalid request: tag must not contain '/'`)
+			})
+		})
+		t.Run("When there are words", func(t *testing.T) {
+			t.Run("Then each is written", func(t *testing.T) {
+				w := &exportWriter{}
+
+				err := s.ExportTo(context.Background(), w, "weather")
+				assert.NoError(t, err)
+				assert.Equal(t, em.words, w.words)
+				assert.Equal(t, "weather", em.tag)
+			})
+		})
+		t.Run("When a word can't be written", func(t *testing.T) {
+			t.Run("Then the export stops", func(t *testing.T) {
+				w := &exportWriter{err: errors.New("broken pipe")}
+
+				err := s.ExportTo(context.Background(), w, "")
+				assertStatusError(t, err, codes.Internal, "unable to export words")
+				assert.Len(t, w.words, 1)
+			})
+		})
+		t.Run("When the database fails", func(t *testing.T) {
+			t.Run("Then the error is returned", func(t *testing.T) {
+				em.err = db.ErrUnavailable
+				defer func() { em.err = nil }()
+
+				err := s.ExportTo(context.Background(), &exportWriter{}, "")
+				assertStatusError(t, err, codes.Unavailable, "unable to export words: database unavailable")
+			})
+		})
+	})
+}
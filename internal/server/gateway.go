@@ -0,0 +1,1176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/mywordoftheday/backend/internal/auth"
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/exporter"
+	"github.com/mywordoftheday/backend/internal/importer"
+	"github.com/mywordoftheday/backend/internal/metrics"
+	"github.com/mywordoftheday/backend/internal/notifier"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+// reviewedWord is the JSON representation of a word and its review schedule
+type reviewedWord struct {
+	ID               int32     `json:"id"`
+	Word             string    `json:"word"`
+	CustomDefinition string    `json:"customDefinition,omitempty"`
+	EaseFactor       float64   `json:"easeFactor"`
+	IntervalDays     int32     `json:"intervalDays"`
+	Repetitions      int32     `json:"repetitions"`
+	DueAt            time.Time `json:"dueAt"`
+}
+
+// wordPage is the JSON representation of a ListWordsPageResponse
+type wordPage struct {
+	Words         []json.RawMessage `json:"words"`
+	NextPageToken string            `json:"nextPageToken,omitempty"`
+}
+
+// wordSense is the JSON representation of a db.Sense
+type wordSense struct {
+	ID           int32    `json:"id"`
+	Source       string   `json:"source,omitempty"`
+	PartOfSpeech string   `json:"partOfSpeech,omitempty"`
+	Definition   string   `json:"definition"`
+	Examples     []string `json:"examples"`
+	Synonyms     []string `json:"synonyms"`
+	Antonyms     []string `json:"antonyms"`
+	Etymology    string   `json:"etymology,omitempty"`
+	IPA          string   `json:"ipa,omitempty"`
+}
+
+func newWordSense(s db.Sense) wordSense {
+	return wordSense{
+		ID:           s.ID,
+		Source:       s.Source,
+		PartOfSpeech: s.PartOfSpeech,
+		Definition:   s.Definition,
+		Examples:     emptyIfNil(s.Examples),
+		Synonyms:     emptyIfNil(s.Synonyms),
+		Antonyms:     emptyIfNil(s.Antonyms),
+		Etymology:    s.Etymology,
+		IPA:          s.IPA,
+	}
+}
+
+func (ws wordSense) sense() db.Sense {
+	return db.Sense{
+		ID:           ws.ID,
+		PartOfSpeech: ws.PartOfSpeech,
+		Definition:   ws.Definition,
+		Examples:     ws.Examples,
+		Synonyms:     ws.Synonyms,
+		Antonyms:     ws.Antonyms,
+		Etymology:    ws.Etymology,
+		IPA:          ws.IPA,
+	}
+}
+
+// emptyIfNil makes nil lists encode as [] rather than null
+func emptyIfNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+
+	return s
+}
+
+// wordPronunciation is the JSON representation of a db.Pronunciation
+type wordPronunciation struct {
+	Source   string `json:"source,omitempty"`
+	IPA      string `json:"ipa,omitempty"`
+	AudioURL string `json:"audioUrl,omitempty"`
+}
+
+// wordDefinitions is the JSON representation of db.Definitions
+type wordDefinitions struct {
+	Senses         []wordSense         `json:"senses"`
+	Pronunciations []wordPronunciation `json:"pronunciations"`
+}
+
+// wordTag is the JSON representation of a db.Tag
+type wordTag struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	WordCount int32  `json:"wordCount"`
+}
+
+// wordTags is the JSON representation of a list of tags
+type wordTags struct {
+	Tags []wordTag `json:"tags"`
+}
+
+func newWordTag(t db.Tag) wordTag {
+	return wordTag{
+		ID:        t.ID,
+		Name:      t.Name,
+		WordCount: t.WordCount,
+	}
+}
+
+func newWordTags(tags []db.Tag) wordTags {
+	rsp := wordTags{Tags: make([]wordTag, len(tags))}
+	for i, t := range tags {
+		rsp.Tags[i] = newWordTag(t)
+	}
+
+	return rsp
+}
+
+// outboxMessage is the JSON representation of a db.OutboxMessage, without the
+// message itself
+type outboxMessage struct {
+	ID            int32      `json:"id"`
+	WordID        int32      `json:"wordId,omitempty"`
+	Recipient     string     `json:"recipient"`
+	Subject       string     `json:"subject"`
+	Status        string     `json:"status"`
+	Attempts      int32      `json:"attempts"`
+	LastError     string     `json:"lastError,omitempty"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	SentAt        *time.Time `json:"sentAt,omitempty"`
+}
+
+// outboxMessages is the JSON representation of a list of messages
+type outboxMessages struct {
+	Messages []outboxMessage `json:"messages"`
+}
+
+func newOutboxMessages(messages []db.OutboxMessage) outboxMessages {
+	rsp := outboxMessages{Messages: make([]outboxMessage, len(messages))}
+	for i, m := range messages {
+		rsp.Messages[i] = outboxMessage{
+			ID:            m.ID,
+			WordID:        m.WordID,
+			Recipient:     m.Recipient,
+			Subject:       m.Subject,
+			Status:        string(m.Status),
+			Attempts:      m.Attempts,
+			LastError:     m.LastError,
+			NextAttemptAt: m.NextAttemptAt,
+			CreatedAt:     m.CreatedAt,
+			SentAt:        m.SentAt,
+		}
+	}
+
+	return rsp
+}
+
+// channel is the JSON representation of a db.Channel, with the secrets in its
+// URL redacted
+type channel struct {
+	ID        int32     `json:"id"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// channels is the JSON representation of a list of channels
+type channels struct {
+	Channels []channel `json:"channels"`
+}
+
+func newChannel(c db.Channel) channel {
+	return channel{
+		ID:        c.ID,
+		URL:       notifier.Redact(c.URL),
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+func newChannels(cs []db.Channel) channels {
+	rsp := channels{Channels: make([]channel, len(cs))}
+	for i, c := range cs {
+		rsp.Channels[i] = newChannel(c)
+	}
+
+	return rsp
+}
+
+// emailTemplate is the JSON representation of a db.Template
+type emailTemplate struct {
+	ID        int32     `json:"id"`
+	Name      string    `json:"name"`
+	HTML      string    `json:"html"`
+	Text      string    `json:"text"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// emailTemplates is the JSON representation of a list of templates
+type emailTemplates struct {
+	Templates []emailTemplate `json:"templates"`
+}
+
+// templatePreview is the JSON representation of a TemplatePreview
+type templatePreview struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+func newEmailTemplate(t db.Template) emailTemplate {
+	return emailTemplate{
+		ID:        t.ID,
+		Name:      t.Name,
+		HTML:      t.HTML,
+		Text:      t.Text,
+		Active:    t.Active,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+func newEmailTemplates(ts []db.Template) emailTemplates {
+	rsp := emailTemplates{Templates: make([]emailTemplate, len(ts))}
+	for i, t := range ts {
+		rsp.Templates[i] = newEmailTemplate(t)
+	}
+
+	return rsp
+}
+
+// importScope and exportScope are needed to import and export words. The
+// proto has no RPCs for either, so there are no method scopes to take them
+// from.
+const (
+	importScope = auth.ScopeWrite
+	exportScope = auth.ScopeRead
+)
+
+// gatewayRoute is an HTTP endpoint served directly by the Server
+type gatewayRoute struct {
+	method  string
+	pattern string
+	scope   auth.Scope
+	handler func(mux *runtime.ServeMux) runtime.HandlerFunc
+}
+
+// RegisterGatewayHandlers adds the HTTP endpoints that are served directly by
+// the Server, rather than generated from the proto definitions, to mux. They
+// don't go through the gRPC interceptors, so each is traced, counted and
+// authenticated here instead.
+//
+// The mux gives precedence to the most recently registered handlers, so this
+// must be called after the generated handlers are registered for GET
+// /v1alpha1/words to be replaced by the paginated version.
+func (s *Server) RegisterGatewayHandlers(mux *runtime.ServeMux) error {
+	routes := []gatewayRoute{
+		{method: http.MethodGet, pattern: "/v1alpha1/words", scope: auth.ScopeRead, handler: s.handleListWords},
+		{method: http.MethodGet, pattern: "/v1alpha1/words/find", scope: auth.ScopeRead, handler: s.handleFindWord},
+		{method: http.MethodPost, pattern: "/v1alpha1/words/import", scope: importScope, handler: s.handleImportWords},
+		{method: http.MethodGet, pattern: "/v1alpha1/words/export", scope: exportScope, handler: s.handleExportWords},
+		{method: http.MethodGet, pattern: "/v1alpha1/word/{id}", scope: auth.ScopeRead, handler: s.handleGetWord},
+		{method: http.MethodGet, pattern: "/v1alpha1/word/{id}/definitions", scope: auth.ScopeRead, handler: s.handleGetDefinitions},
+		{method: http.MethodPost, pattern: "/v1alpha1/word/{id}/senses", scope: auth.ScopeWrite, handler: s.handleAddSense},
+		{method: http.MethodPut, pattern: "/v1alpha1/word/{id}/senses/{sense_id}", scope: auth.ScopeWrite, handler: s.handleUpdateSense},
+		{method: http.MethodDelete, pattern: "/v1alpha1/word/{id}/senses/{sense_id}", scope: auth.ScopeWrite, handler: s.handleDeleteSense},
+		{method: http.MethodGet, pattern: "/v1alpha1/word/{id}/tags", scope: auth.ScopeRead, handler: s.handleWordTags},
+		{method: http.MethodPut, pattern: "/v1alpha1/word/{id}/tags/{tag}", scope: auth.ScopeWrite, handler: s.handleTagWord},
+		{method: http.MethodDelete, pattern: "/v1alpha1/word/{id}/tags/{tag}", scope: auth.ScopeWrite, handler: s.handleUntagWord},
+		{method: http.MethodGet, pattern: "/v1alpha1/tags", scope: auth.ScopeRead, handler: s.handleListTags},
+		{method: http.MethodDelete, pattern: "/v1alpha1/tags/{tag}", scope: auth.ScopeWrite, handler: s.handleDeleteTag},
+		{method: http.MethodGet, pattern: "/v1alpha1/outbox", scope: auth.ScopeRead, handler: s.handleListOutbox},
+		{method: http.MethodPost, pattern: "/v1alpha1/outbox/retry", scope: auth.ScopeWrite, handler: s.handleRetryOutbox},
+		{method: http.MethodGet, pattern: "/v1alpha1/channels", scope: auth.ScopeRead, handler: s.handleListChannels},
+		{method: http.MethodPost, pattern: "/v1alpha1/channels", scope: auth.ScopeWrite, handler: s.handleAddChannel},
+		{method: http.MethodDelete, pattern: "/v1alpha1/channels/{id}", scope: auth.ScopeWrite, handler: s.handleDeleteChannel},
+		{method: http.MethodGet, pattern: "/v1alpha1/templates", scope: auth.ScopeRead, handler: s.handleListTemplates},
+		{method: http.MethodPost, pattern: "/v1alpha1/templates", scope: auth.ScopeWrite, handler: s.handleCreateTemplate},
+		{method: http.MethodGet, pattern: "/v1alpha1/templates/{id}", scope: auth.ScopeRead, handler: s.handleGetTemplate},
+		{method: http.MethodDelete, pattern: "/v1alpha1/templates/{id}", scope: auth.ScopeWrite, handler: s.handleDeleteTemplate},
+		{method: http.MethodPost, pattern: "/v1alpha1/templates/{id}/preview", scope: auth.ScopeRead, handler: s.handlePreviewTemplate},
+		{method: http.MethodPost, pattern: "/v1alpha1/templates/{id}/activate", scope: auth.ScopeWrite, handler: s.handleActivateTemplate},
+		{method: http.MethodPost, pattern: "/v1alpha1/templates/deactivate", scope: auth.ScopeWrite, handler: s.handleDeactivateTemplate},
+		// Registered after /v1alpha1/word/{id} so it isn't shadowed
+		{method: http.MethodGet, pattern: "/v1alpha1/word/random", scope: auth.ScopeRead, handler: s.handleRandomWord},
+		{method: http.MethodPatch, pattern: "/v1alpha1/word/{id}", scope: auth.ScopeWrite, handler: s.handleUpdateWord},
+		{method: http.MethodPost, pattern: "/v1alpha1/word/{id}/review", scope: auth.ScopeWrite, handler: s.handleReviewWord},
+	}
+
+	for _, rt := range routes {
+		if err := mux.HandlePath(rt.method, rt.pattern, instrument(rt, s.withUser(mux, rt.scope, rt.handler(mux)))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pathParamsKey carries the path parameters of a request through the
+// middleware in instrument
+type pathParamsKey struct{}
+
+// instrument records a span and the HTTP metrics for each request to the
+// route, as the otelgrpc and metrics interceptors do for the RPCs
+func instrument(rt gatewayRoute, h runtime.HandlerFunc) runtime.HandlerFunc {
+	name := rt.method + " " + rt.pattern
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+		h(w, r, params)
+	})
+
+	traced := otelhttp.NewHandler(otelhttp.WithRouteTag(rt.pattern, metrics.HTTPHandler(name, handler)), name)
+
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		traced.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params)))
+	}
+}
+
+// handleListWords returns a page of words. The page is controlled by the
+// page_size, page_token, prefix, contains, tag and order_by query parameters.
+func (s *Server) handleListWords(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		q := r.URL.Query()
+
+		req := &ListWordsPageRequest{
+			PageToken: q.Get("page_token"),
+			Prefix:    q.Get("prefix"),
+			Contains:  q.Get("contains"),
+			Tag:       q.Get("tag"),
+			OrderBy:   q.Get("order_by"),
+		}
+
+		if ps := q.Get("page_size"); ps != "" {
+			size, err := strconv.ParseInt(ps, 10, 32)
+			if err != nil {
+				writeGatewayError(mux, w, r, invalidField("page_size", "must be an integer"))
+				return
+			}
+
+			req.PageSize = int32(size)
+		}
+
+		rsp, err := s.ListWordsPage(r.Context(), req)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		page := wordPage{
+			Words:         make([]json.RawMessage, len(rsp.Words)),
+			NextPageToken: rsp.NextPageToken,
+		}
+
+		for i, word := range rsp.Words {
+			b, err := outbound.Marshal(word)
+			if err != nil {
+				writeGatewayError(mux, w, r, err)
+				return
+			}
+
+			page.Words[i] = b
+		}
+
+		writeGatewayJSON(w, page)
+	}
+}
+
+// handleRandomWord returns a random word, with the tag query parameter if set
+func (s *Server) handleRandomWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		rsp, err := s.RandomWordWithTag(r.Context(), r.URL.Query().Get("tag"))
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayProto(mux, w, r, rsp)
+	}
+}
+
+// handleGetWord returns the word in the path
+func (s *Server) handleGetWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		rsp, err := s.GetWord(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayProto(mux, w, r, rsp)
+	}
+}
+
+// handleGetDefinitions returns the dictionary definitions of the word in the path
+func (s *Server) handleGetDefinitions(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		d, err := s.Definitions(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		rsp := wordDefinitions{
+			Senses:         make([]wordSense, len(d.Senses)),
+			Pronunciations: make([]wordPronunciation, len(d.Pronunciations)),
+		}
+
+		for i, sense := range d.Senses {
+			rsp.Senses[i] = newWordSense(sense)
+		}
+
+		for i, p := range d.Pronunciations {
+			rsp.Pronunciations[i] = wordPronunciation{
+				Source:   p.Source,
+				IPA:      p.IPA,
+				AudioURL: p.AudioURL,
+			}
+		}
+
+		writeGatewayJSON(w, rsp)
+	}
+}
+
+// handleAddSense adds the sense in the request body to the word in the path
+func (s *Server) handleAddSense(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req wordSense
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		sense, err := s.AddSense(r.Context(), id, req.sense())
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		writeGatewayJSON(w, newWordSense(sense))
+	}
+}
+
+// handleUpdateSense replaces the sense in the path with the one in the request body
+func (s *Server) handleUpdateSense(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req wordSense
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		req.ID, err = parseSenseID(params["sense_id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		sense, err := s.UpdateSense(r.Context(), id, req.sense())
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordSense(sense))
+	}
+}
+
+// handleDeleteSense deletes the sense in the path
+func (s *Server) handleDeleteSense(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		senseID, err := parseSenseID(params["sense_id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		sense, err := s.DeleteSense(r.Context(), id, senseID)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordSense(sense))
+	}
+}
+
+// handleWordTags returns the tags of the word in the path
+func (s *Server) handleWordTags(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		tags, err := s.WordTags(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordTags(tags))
+	}
+}
+
+// handleTagWord gives the word in the path the tag in the path
+func (s *Server) handleTagWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		tag, err := s.TagWord(r.Context(), id, params["tag"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordTag(tag))
+	}
+}
+
+// handleUntagWord removes the tag in the path from the word in the path
+func (s *Server) handleUntagWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		if err := s.UntagWord(r.Context(), id, params["tag"]); err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleListTags returns every tag with the number of words that have it
+func (s *Server) handleListTags(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tags, err := s.ListTags(r.Context())
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordTags(tags))
+	}
+}
+
+// handleDeleteTag deletes the tag in the path
+func (s *Server) handleDeleteTag(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		tag, err := s.DeleteTag(r.Context(), params["tag"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newWordTag(tag))
+	}
+}
+
+// handleListOutbox returns the most recent emails queued for the user, with
+// the status and limit query parameters if set
+func (s *Server) handleListOutbox(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		q := r.URL.Query()
+
+		var limit int64
+		if l := q.Get("limit"); l != "" {
+			var err error
+			if limit, err = strconv.ParseInt(l, 10, 32); err != nil {
+				writeGatewayError(mux, w, r, invalidField("limit", "must be an integer"))
+				return
+			}
+		}
+
+		messages, err := s.ListOutbox(r.Context(), q.Get("status"), int32(limit))
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newOutboxMessages(messages))
+	}
+}
+
+// handleRetryOutbox sends the failed emails with the ids in the request body
+// again, or all of them if the body is empty or has no ids
+func (s *Server) handleRetryOutbox(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req struct {
+			IDs []int32 `json:"ids"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		messages, err := s.RetryOutbox(r.Context(), req.IDs)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newOutboxMessages(messages))
+	}
+}
+
+// handleListChannels returns the channels the user is sent their word of the
+// day to
+func (s *Server) handleListChannels(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		cs, err := s.ListChannels(r.Context())
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newChannels(cs))
+	}
+}
+
+// handleAddChannel adds the channel with the URL in the request body
+func (s *Server) handleAddChannel(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req struct {
+			URL string `json:"url"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		c, err := s.AddChannel(r.Context(), req.URL)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newChannel(c))
+	}
+}
+
+// handleDeleteChannel deletes the channel in the path
+func (s *Server) handleDeleteChannel(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		c, err := s.DeleteChannel(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newChannel(c))
+	}
+}
+
+// handleListTemplates returns the templates the user has written for their
+// emails
+func (s *Server) handleListTemplates(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		ts, err := s.ListTemplates(r.Context())
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newEmailTemplates(ts))
+	}
+}
+
+// handleCreateTemplate adds the template in the request body, which isn't
+// used until it's activated
+func (s *Server) handleCreateTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req struct {
+			Name string `json:"name"`
+			HTML string `json:"html"`
+			Text string `json:"text"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		t, err := s.CreateTemplate(r.Context(), db.Template{Name: req.Name, HTML: req.HTML, Text: req.Text})
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newEmailTemplate(t))
+	}
+}
+
+// handleGetTemplate returns the template in the path
+func (s *Server) handleGetTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		t, err := s.GetTemplate(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newEmailTemplate(t))
+	}
+}
+
+// handleDeleteTemplate deletes the template in the path
+func (s *Server) handleDeleteTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		t, err := s.DeleteTemplate(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newEmailTemplate(t))
+	}
+}
+
+// handlePreviewTemplate renders the template in the path with the word in the
+// word_id query parameter, or with sample data if it isn't set
+func (s *Server) handlePreviewTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		var wordID int64
+		if wid := r.URL.Query().Get("word_id"); wid != "" {
+			if wordID, err = strconv.ParseInt(wid, 10, 32); err != nil {
+				writeGatewayError(mux, w, r, invalidField("word_id", "must be an integer"))
+				return
+			}
+		}
+
+		p, err := s.PreviewTemplate(r.Context(), id, int32(wordID))
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, templatePreview{Subject: p.Subject, HTML: p.HTML, Text: p.Text})
+	}
+}
+
+// handleActivateTemplate makes the template in the path the one the user's
+// emails are rendered from
+func (s *Server) handleActivateTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		t, err := s.ActivateTemplate(r.Context(), id)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, newEmailTemplate(t))
+	}
+}
+
+// handleDeactivateTemplate makes the user's emails be rendered from the
+// default template again
+func (s *Server) handleDeactivateTemplate(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if err := s.DeactivateTemplate(r.Context()); err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, struct{}{})
+	}
+}
+
+// handleFindWord returns the word spelt as the word query parameter
+func (s *Server) handleFindWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		rsp, err := s.FindWord(r.Context(), r.URL.Query().Get("word"))
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayProto(mux, w, r, rsp)
+	}
+}
+
+// handleUpdateWord applies the Word in the request body to the word in the
+// path. The fields to update are taken from the update_mask query parameter
+// and the expected etag from the If-Match header.
+func (s *Server) handleUpdateWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		inbound, _ := runtime.MarshalerForRequest(mux, r)
+
+		word := &v1alpha1.Word{}
+		if err := inbound.NewDecoder(r.Body).Decode(word); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		word.Id = id
+
+		var mask *fieldmaskpb.FieldMask
+		if m := r.URL.Query().Get("update_mask"); m != "" {
+			mask = &fieldmaskpb.FieldMask{}
+			for _, p := range strings.Split(m, ",") {
+				mask.Paths = append(mask.Paths, protoFieldName(strings.TrimSpace(p)))
+			}
+		}
+
+		rsp, err := s.UpdateWord(r.Context(), &UpdateWordRequest{
+			Word:       word,
+			UpdateMask: mask,
+			Etag:       r.Header.Get("If-Match"),
+		})
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		w.Header().Set("ETag", rsp.Etag)
+		writeGatewayProto(mux, w, r, rsp.Word)
+	}
+}
+
+// handleReviewWord grades the recall of the word in the path
+func (s *Server) handleReviewWord(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var req struct {
+			Grade string `json:"grade"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeGatewayError(mux, w, r, status.Error(codes.InvalidArgument, "invalid request body"))
+			return
+		}
+
+		id, err := parseID(params["id"])
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		rsp, err := s.ReviewWord(r.Context(), id, req.Grade)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, struct {
+			Word reviewedWord `json:"word"`
+		}{
+			Word: reviewedWord{
+				ID:               rsp.ID,
+				Word:             rsp.Word,
+				CustomDefinition: rsp.CustomDefinition,
+				EaseFactor:       rsp.EaseFactor,
+				IntervalDays:     rsp.IntervalDays,
+				Repetitions:      rsp.Repetitions,
+				DueAt:            rsp.DueAt,
+			},
+		})
+	}
+}
+
+// maxImportSize limits the size of a file uploaded to be imported
+const maxImportSize = 64 * 1024 * 1024
+
+// importContentTypes are the formats of uploads with these content types
+var importContentTypes = map[string]importer.Format{
+	"text/csv":                  importer.FormatCSV,
+	"application/x-ndjson":      importer.FormatJSONL,
+	"application/jsonl":         importer.FormatJSONL,
+	"text/plain":                importer.FormatAnkiText,
+	"text/tab-separated-values": importer.FormatAnkiText,
+	"application/zip":           importer.FormatAnkiPackage,
+	"application/apkg":          importer.FormatAnkiPackage,
+}
+
+// handleImportWords imports the words in the file in the request body. Its
+// format is given by the format query parameter, or else the Content-Type, and
+// the import is controlled by the dry_run, on_duplicate and skip_invalid query
+// parameters.
+func (s *Server) handleImportWords(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		q := r.URL.Query()
+
+		format, err := importFormat(q.Get("format"), r.Header.Get("Content-Type"))
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		opts := ImportOptions{OnDuplicate: q.Get("on_duplicate")}
+
+		if opts.DryRun, err = parseBool("dry_run", q.Get("dry_run")); err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		if opts.SkipInvalid, err = parseBool("skip_invalid", q.Get("skip_invalid")); err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		reader, err := importer.NewReader(format, http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			writeGatewayError(mux, w, r, status.Errorf(codes.InvalidArgument, "unable to read file: %v", err))
+			return
+		}
+		defer reader.Close()
+
+		rsp, err := s.ImportFrom(r.Context(), reader, opts)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		writeGatewayJSON(w, rsp)
+	}
+}
+
+// importFormat returns the format named by the format query parameter, or
+// implied by the content type if it's empty
+func importFormat(name string, contentType string) (importer.Format, error) {
+	if name != "" {
+		format, err := importer.ParseFormat(name)
+		if err != nil {
+			return "", invalidField("format", "must be one of csv, jsonl, anki or apkg")
+		}
+
+		return format, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if format, ok := importContentTypes[mediaType]; ok {
+		return format, nil
+	}
+
+	return "", invalidField("format", "is required unless the Content-Type is that of a csv, jsonl, anki or apkg file")
+}
+
+// handleExportWords downloads the words, or those with the tag query
+// parameter, in the format query parameter, which defaults to csv
+func (s *Server) handleExportWords(mux *runtime.ServeMux) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		q := r.URL.Query()
+
+		format := exporter.FormatCSV
+		if name := q.Get("format"); name != "" {
+			var err error
+			if format, err = exporter.ParseFormat(name); err != nil {
+				writeGatewayError(mux, w, r, invalidField("format", "must be one of csv, jsonl, markdown or anki"))
+				return
+			}
+		}
+
+		rw := &exportResponseWriter{ResponseWriter: w}
+
+		ew, err := exporter.NewWriter(format, rw)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="words%s"`, format.Extension()))
+
+		err = s.ExportTo(r.Context(), ew, q.Get("tag"))
+		if err == nil {
+			err = ew.Close()
+		}
+
+		if err == nil {
+			return
+		}
+
+		if rw.written {
+			// The status has been sent, so all that can be done is cut the
+			// download short
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+			}).Error("Error writing export")
+			return
+		}
+
+		w.Header().Del("Content-Disposition")
+		writeGatewayError(mux, w, r, err)
+	}
+}
+
+// exportResponseWriter records whether any of the export has been written, as
+// an error can only be returned until then
+type exportResponseWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *exportResponseWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// parseBool parses the value of a boolean query parameter, which is false if
+// it's empty
+func parseBool(field string, s string) (bool, error) {
+	if s == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, invalidField(field, "must be true or false")
+	}
+
+	return b, nil
+}
+
+func parseID(s string) (int32, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, invalidField("id", "must be an integer")
+	}
+
+	return int32(id), nil
+}
+
+func parseSenseID(s string) (int32, error) {
+	id, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, invalidField("sense_id", "must be an integer")
+	}
+
+	return int32(id), nil
+}
+
+// protoFieldName converts a lowerCamelCase JSON field name into its proto
+// name, leaving names that are already snake_case untouched
+func protoFieldName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if unicode.IsUpper(r) {
+			b.WriteByte('_')
+			r = unicode.ToLower(r)
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// writeGatewayError writes err in the same format the generated gateway handlers use
+func writeGatewayError(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request, err error) {
+	_, outbound := runtime.MarshalerForRequest(mux, r)
+	runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+}
+
+// writeGatewayProto writes m using the same marshaler the generated gateway handlers use
+func writeGatewayProto(mux *runtime.ServeMux, w http.ResponseWriter, r *http.Request, m proto.Message) {
+	_, outbound := runtime.MarshalerForRequest(mux, r)
+
+	w.Header().Set("Content-Type", outbound.ContentType(m))
+
+	if err := outbound.NewEncoder(w).Encode(m); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error writing response")
+	}
+}
+
+func writeGatewayJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error writing response")
+	}
+}
@@ -0,0 +1,816 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mywordoftheday/backend/internal/auth"
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func newTestGateway(t *testing.T, s *Server) *runtime.ServeMux {
+	t.Helper()
+
+	mux := runtime.NewServeMux()
+	assert.NoError(t, s.RegisterGatewayHandlers(mux))
+
+	return mux
+}
+
+func TestGatewayInstrumentation(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+	wm := &wordMock{getWordResponse: db.Word{ID: 45, Word: "petrichor"}}
+	mux := newTestGateway(t, &Server{wordQuerier: wm})
+
+	t.Run("Given a request to an endpoint served directly by the gateway", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/45", nil))
+
+		t.Run("Then the handler is given the path parameters", func(t *testing.T) {
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Contains(t, rec.Body.String(), "petrichor")
+		})
+
+		t.Run("Then a span named after the route is recorded", func(t *testing.T) {
+			spans := sr.Ended()
+			if assert.NotEmpty(t, spans) {
+				span := spans[len(spans)-1]
+				assert.Equal(t, "GET /v1alpha1/word/{id}", span.Name())
+				assert.Contains(t, span.Attributes(), attribute.String("http.route", "/v1alpha1/word/{id}"))
+			}
+		})
+	})
+}
+
+func TestGatewayReviewWord(t *testing.T) {
+	rm := &reviewMock{}
+	mux := newTestGateway(t, &Server{wordReviewer: rm})
+
+	t.Run("Given a POST request to the review endpoint", func(t *testing.T) {
+		t.Run("When the id is invalid", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/abc/review", strings.NewReader(`{"grade": "good"}`)))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a 404 is returned", func(t *testing.T) {
+				rm.err = db.ErrNotFound
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/review", strings.NewReader(`{"grade": "good"}`)))
+
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			})
+		})
+		t.Run("When the review succeeds", func(t *testing.T) {
+			t.Run("Then the rescheduled word is returned as JSON", func(t *testing.T) {
+				rm.err = nil
+				rm.reviewWordResponse = db.Word{ID: 45, Word: "word1", EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1, DueAt: time.Date(2022, 1, 2, 9, 0, 0, 0, time.UTC)}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/review", strings.NewReader(`{"grade": "good"}`)))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp struct {
+					Word reviewedWord `json:"word"`
+				}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+				assert.Equal(t, int32(45), rsp.Word.ID)
+				assert.Equal(t, int32(1), rsp.Word.IntervalDays)
+				assert.True(t, rm.reviewWordResponse.DueAt.Equal(rsp.Word.DueAt))
+			})
+		})
+	})
+}
+
+func TestGatewayAuthentication(t *testing.T) {
+	authenticator, err := auth.New(auth.Config{
+		Enabled: true,
+		APIKeys: []auth.APIKey{{Key: "read-key", Username: "simon", Scopes: []auth.Scope{auth.ScopeRead}}},
+	})
+	assert.NoError(t, err)
+
+	mux := newTestGateway(t, &Server{wordReviewer: &reviewMock{}, authenticator: authenticator})
+
+	t.Run("Given authentication is enabled", func(t *testing.T) {
+		t.Run("When a request is made without credentials", func(t *testing.T) {
+			t.Run("Then a 401 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/review", strings.NewReader(`{"grade": "good"}`)))
+
+				assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			})
+		})
+		t.Run("When a request is made without the required scope", func(t *testing.T) {
+			t.Run("Then a 403 is returned", func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/review", strings.NewReader(`{"grade": "good"}`))
+				req.Header.Set("Authorization", "Bearer read-key")
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusForbidden, rec.Code)
+			})
+		})
+	})
+}
+
+func TestGatewayUpdateWord(t *testing.T) {
+	wm := &wordMock{}
+	mux := newTestGateway(t, &Server{wordModifier: wm})
+
+	t.Run("Given a PATCH request to the word endpoint", func(t *testing.T) {
+		t.Run("When the etag is out of date", func(t *testing.T) {
+			t.Run("Then a 409 is returned", func(t *testing.T) {
+				wm.err = db.ErrVersionConflict
+
+				req := httptest.NewRequest(http.MethodPatch, "/v1alpha1/word/45?update_mask=customDefinition", strings.NewReader(`{"customDefinition": "a definition"}`))
+				req.Header.Set("If-Match", `"1"`)
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusConflict, rec.Code)
+			})
+		})
+		t.Run("When the update succeeds", func(t *testing.T) {
+			t.Run("Then the word is returned with its new etag", func(t *testing.T) {
+				wm.err = nil
+				wm.updateWordResponse = db.Word{ID: 45, Word: "word1", CustomDefinition: "a definition", Version: 2}
+
+				req := httptest.NewRequest(http.MethodPatch, "/v1alpha1/word/45?update_mask=customDefinition", strings.NewReader(`{"customDefinition": "a definition"}`))
+				req.Header.Set("If-Match", `"1"`)
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+				assert.Nil(t, wm.lastUpdate.Word)
+				assert.Equal(t, "a definition", *wm.lastUpdate.CustomDefinition)
+				assert.Equal(t, int32(1), wm.lastUpdate.ExpectedVersion)
+
+				var rsp map[string]interface{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+				assert.Equal(t, "a definition", rsp["customDefinition"])
+			})
+		})
+	})
+}
+
+func TestGatewayGetWord(t *testing.T) {
+	wm := &wordMock{}
+	mux := newTestGateway(t, &Server{wordQuerier: wm})
+
+	t.Run("Given a GET request to the word endpoint", func(t *testing.T) {
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a 404 is returned", func(t *testing.T) {
+				wm.err = db.ErrNotFound
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/45", nil))
+
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			})
+		})
+		t.Run("When the word exists", func(t *testing.T) {
+			t.Run("Then the word is returned", func(t *testing.T) {
+				wm.err = nil
+				wm.getWordResponse = db.Word{ID: 45, Word: "word1", CustomDefinition: "a definition"}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/45", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp map[string]interface{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+				assert.Equal(t, "word1", rsp["word"])
+				assert.Equal(t, "a definition", rsp["customDefinition"])
+			})
+		})
+	})
+
+	t.Run("Given a GET request to the find endpoint", func(t *testing.T) {
+		t.Run("When no word is given", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/find", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the word exists", func(t *testing.T) {
+			t.Run("Then the word is returned", func(t *testing.T) {
+				wm.err = nil
+				wm.findWordResponse = db.Word{ID: 45, Word: "word1"}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/find?word=Word1", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+
+				var rsp map[string]interface{}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+				assert.Equal(t, "word1", rsp["word"])
+			})
+		})
+	})
+}
+
+func TestGatewayGetDefinitions(t *testing.T) {
+	dm := &definitionMock{}
+	mux := newTestGateway(t, &Server{definitionStore: dm})
+
+	t.Run("Given a GET request to the definitions endpoint", func(t *testing.T) {
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a 404 is returned", func(t *testing.T) {
+				dm.err = db.ErrNotFound
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/45/definitions", nil))
+
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			})
+		})
+		t.Run("When the word has definitions", func(t *testing.T) {
+			t.Run("Then they are returned", func(t *testing.T) {
+				dm.err = nil
+				dm.getDefinitionsResponse = db.Definitions{
+					Senses: []db.Sense{
+						{ID: 3, Etymology: "Coined by John Koenig.", Definition: "A feeling of wonder at strangers' lives.", Synonyms: []string{"empathy"}},
+						{ID: 2, Source: "wiktionary", PartOfSpeech: "verb", Definition: "To sound out or probe.", IPA: "/ˈsɒndə/"},
+					},
+					Pronunciations: []db.Pronunciation{{Source: "wiktionary", IPA: "/ˈsɒndə/"}},
+				}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/45/definitions", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{
+					"senses": [
+						{"id": 3, "definition": "A feeling of wonder at strangers' lives.", "etymology": "Coined by John Koenig.", "examples": [], "synonyms": ["empathy"], "antonyms": []},
+						{"id": 2, "source": "wiktionary", "partOfSpeech": "verb", "definition": "To sound out or probe.", "ipa": "/ˈsɒndə/", "examples": [], "synonyms": [], "antonyms": []}
+					],
+					"pronunciations": [{"source": "wiktionary", "ipa": "/ˈsɒndə/"}]
+				}`, rec.Body.String())
+			})
+		})
+	})
+}
+
+func TestGatewaySenses(t *testing.T) {
+	dm := &definitionMock{}
+	mux := newTestGateway(t, &Server{definitionStore: dm})
+
+	t.Run("Given a POST request to the senses endpoint", func(t *testing.T) {
+		t.Run("When the sense is valid", func(t *testing.T) {
+			t.Run("Then it is added and returned with a 201", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/senses", strings.NewReader(`{
+					"partOfSpeech": "noun",
+					"definition": "A feeling of wonder at strangers' lives.",
+					"examples": ["A wave of sonder."],
+					"antonyms": ["solipsism"],
+					"ipa": "/ˈsɒndə/"
+				}`)))
+
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				assert.Equal(t, int32(45), dm.senseWordID)
+				assert.JSONEq(t, `{
+					"id": 1,
+					"partOfSpeech": "noun",
+					"definition": "A feeling of wonder at strangers' lives.",
+					"examples": ["A wave of sonder."],
+					"synonyms": [],
+					"antonyms": ["solipsism"],
+					"ipa": "/ˈsɒndə/"
+				}`, rec.Body.String())
+			})
+		})
+		t.Run("When the sense has no definition", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/word/45/senses", strings.NewReader(`{"partOfSpeech": "noun"}`)))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+	})
+
+	t.Run("Given a PUT request to a sense", func(t *testing.T) {
+		t.Run("When the sense id is not an integer", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1alpha1/word/45/senses/abc", strings.NewReader(`{"definition": "a definition"}`)))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the sense exists", func(t *testing.T) {
+			t.Run("Then the sense id is taken from the path", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1alpha1/word/45/senses/7", strings.NewReader(`{"id": 9, "definition": "a definition"}`)))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, int32(7), dm.sense.ID)
+				assert.Equal(t, "a definition", dm.sense.Definition)
+			})
+		})
+	})
+
+	t.Run("Given a DELETE request to a sense", func(t *testing.T) {
+		t.Run("When the sense does not exist", func(t *testing.T) {
+			t.Run("Then a 404 is returned", func(t *testing.T) {
+				dm.err = db.ErrNotFound
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1alpha1/word/45/senses/7", nil))
+
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			})
+		})
+	})
+}
+
+func TestGatewayTags(t *testing.T) {
+	tm := &tagMock{}
+	wm := &wordMock{}
+	mux := newTestGateway(t, &Server{wordTagger: tm, wordQuerier: wm})
+
+	t.Run("Given a PUT request to a word's tag", func(t *testing.T) {
+		t.Run("When the tag contains an escaped space", func(t *testing.T) {
+			t.Run("Then the word is given the unescaped tag", func(t *testing.T) {
+				tm.tagWordResponse = db.Tag{ID: 3, Name: "GRE prep", WordCount: 1}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/v1alpha1/word/45/tags/GRE%20prep", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "GRE prep", tm.tag)
+				assert.Equal(t, int32(45), tm.taggedID)
+				assert.JSONEq(t, `{"id": 3, "name": "GRE prep", "wordCount": 1}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a DELETE request to a word's tag", func(t *testing.T) {
+		t.Run("When the word has the tag", func(t *testing.T) {
+			t.Run("Then a 204 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1alpha1/word/45/tags/legal", nil))
+
+				assert.Equal(t, http.StatusNoContent, rec.Code)
+				assert.Equal(t, "legal", tm.tag)
+			})
+		})
+		t.Run("When the word doesn't have the tag", func(t *testing.T) {
+			t.Run("Then a 404 is returned", func(t *testing.T) {
+				tm.err = db.ErrNotFound
+				defer func() { tm.err = nil }()
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1alpha1/word/45/tags/legal", nil))
+
+				assert.Equal(t, http.StatusNotFound, rec.Code)
+			})
+		})
+	})
+
+	t.Run("Given a GET request to the tags endpoint", func(t *testing.T) {
+		t.Run("When the user has tags", func(t *testing.T) {
+			t.Run("Then they are returned with their word counts", func(t *testing.T) {
+				tm.listTagsResponse = []db.Tag{{ID: 1, Name: "GRE", WordCount: 2}, {ID: 2, Name: "legal"}}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/tags", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{"tags": [{"id": 1, "name": "GRE", "wordCount": 2}, {"id": 2, "name": "legal", "wordCount": 0}]}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a GET request for a random word with a tag", func(t *testing.T) {
+		t.Run("When there are words with the tag", func(t *testing.T) {
+			t.Run("Then one of them is returned", func(t *testing.T) {
+				wm.randomWordResponse = db.Word{ID: 7, Word: "estoppel"}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/random?tag=legal", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "legal", wm.lastRandomTag)
+			})
+		})
+	})
+}
+
+func TestGatewayListWords(t *testing.T) {
+	wm := &wordMock{}
+	mux := newTestGateway(t, &Server{wordQuerier: wm})
+
+	t.Run("Given a GET request to the words endpoint", func(t *testing.T) {
+		t.Run("When the page size is invalid", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words?page_size=abc", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When there are more words than the page size", func(t *testing.T) {
+			t.Run("Then the page and next page token are returned", func(t *testing.T) {
+				wm.listWordsResponse = []db.Word{{ID: 1, Word: "alpha"}, {ID: 2, Word: "bravo"}}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words?page_size=1&contains=a&order_by=newest", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "a", wm.lastListOptions.Contains)
+				assert.Equal(t, db.OrderNewest, wm.lastListOptions.Order)
+
+				var rsp struct {
+					Words []struct {
+						ID   int32  `json:"id"`
+						Word string `json:"word"`
+					} `json:"words"`
+					NextPageToken string `json:"nextPageToken"`
+				}
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+				assert.Len(t, rsp.Words, 1)
+				assert.Equal(t, "alpha", rsp.Words[0].Word)
+				assert.NotEmpty(t, rsp.NextPageToken)
+			})
+		})
+	})
+
+	t.Run("Given a GET request to the random word endpoint", func(t *testing.T) {
+		t.Run("When it is made", func(t *testing.T) {
+			t.Run("Then it isn't mistaken for a word id", func(t *testing.T) {
+				wm.randomWordResponse = db.Word{ID: 1, Word: "alpha"}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/word/random", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+			})
+		})
+	})
+}
+
+func TestGatewayImportWords(t *testing.T) {
+	im := &importMock{}
+	mux := newTestGateway(t, &Server{wordImporter: im})
+
+	t.Run("Given a POST request to the import endpoint", func(t *testing.T) {
+		t.Run("When the format is given by the Content-Type", func(t *testing.T) {
+			t.Run("Then the file is imported with the options in the query", func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/v1alpha1/words/import?dry_run=true&on_duplicate=error", strings.NewReader(`{"word":"petrichor","tags":["weather"]}`+"\n"))
+				req.Header.Set("Content-Type", "application/x-ndjson; charset=utf-8")
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, db.ImportOptions{DryRun: true, OnDuplicate: db.DuplicateError}, im.opts)
+				assert.JSONEq(t, `{"added": 1, "merged": 0, "skipped": 0, "failed": 0, "errors": [], "committed": false}`, rec.Body.String())
+			})
+		})
+		t.Run("When the format can't be told", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, "/v1alpha1/words/import", strings.NewReader("petrichor"))
+				req.Header.Set("Content-Type", "application/octet-stream")
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, req)
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Contains(t, rec.Body.String(), "format is required")
+			})
+		})
+		t.Run("When a query parameter is invalid", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/words/import?format=csv&skip_invalid=maybe", strings.NewReader("petrichor")))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+				assert.Contains(t, rec.Body.String(), "skip_invalid must be true or false")
+			})
+		})
+	})
+}
+
+func TestGatewayExportWords(t *testing.T) {
+	em := &exportMock{}
+	mux := newTestGateway(t, &Server{wordExporter: em})
+
+	t.Run("Given a GET request to the export endpoint", func(t *testing.T) {
+		t.Run("When a format is given", func(t *testing.T) {
+			t.Run("Then the words are downloaded in it", func(t *testing.T) {
+				em.words = []db.ExportedWord{{Word: db.Word{Word: "petrichor", CreatedAt: time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)}, Tags: []string{"weather"}}}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/export?format=jsonl&tag=weather", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "weather", em.tag)
+				assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+				assert.Equal(t, `attachment; filename="words.jsonl"`, rec.Header().Get("Content-Disposition"))
+				assert.JSONEq(t, `{"word": "petrichor", "tags": ["weather"], "senses": [], "createdAt": "2022-01-02T00:00:00Z", "updatedAt": "0001-01-01T00:00:00Z"}`, rec.Body.String())
+			})
+		})
+		t.Run("When no format is given", func(t *testing.T) {
+			t.Run("Then the words are downloaded as CSV", func(t *testing.T) {
+				em.words = nil
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/export", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+				assert.Equal(t, "word,customDefinition,tags,definitions,createdAt,updatedAt\n", rec.Body.String())
+			})
+		})
+		t.Run("When the export fails before anything is written", func(t *testing.T) {
+			t.Run("Then an error is returned instead of a download", func(t *testing.T) {
+				em.err = db.ErrUnavailable
+				defer func() { em.err = nil }()
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/export?format=markdown", nil))
+
+				assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+				assert.Empty(t, rec.Header().Get("Content-Disposition"))
+			})
+		})
+		t.Run("When the format is unknown", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/words/export?format=xlsx", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+	})
+}
+
+func TestGatewayOutbox(t *testing.T) {
+	om := &outboxMock{}
+	mux := newTestGateway(t, &Server{outboxStore: om})
+
+	sentAt := time.Date(2022, 3, 4, 7, 30, 5, 0, time.UTC)
+	createdAt := time.Date(2022, 3, 4, 7, 30, 0, 0, time.UTC)
+
+	t.Run("Given a GET request to the outbox endpoint", func(t *testing.T) {
+		t.Run("When the limit isn't an integer", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/outbox?limit=ten", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the user has messages with the status", func(t *testing.T) {
+			t.Run("Then they are returned without their content", func(t *testing.T) {
+				om.listResponse = []db.OutboxMessage{
+					{ID: 2, WordID: 45, Recipient: "alice@example.com", Subject: "My Word Of The Day: petrichor", Message: []byte("..."), Status: db.OutboxSent, Attempts: 1, NextAttemptAt: createdAt, CreatedAt: createdAt, SentAt: &sentAt},
+					{ID: 1, Recipient: "bob@example", Subject: "My Word Of The Day: sonder", Status: db.OutboxFailed, Attempts: 1, LastError: "550 no such user", NextAttemptAt: createdAt, CreatedAt: createdAt},
+				}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/outbox?status=sent&limit=2", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, db.OutboxSent, om.listStatus)
+				assert.Equal(t, 2, om.listLimit)
+				assert.JSONEq(t, `{"messages": [
+					{"id": 2, "wordId": 45, "recipient": "alice@example.com", "subject": "My Word Of The Day: petrichor", "status": "sent", "attempts": 1, "nextAttemptAt": "2022-03-04T07:30:00Z", "createdAt": "2022-03-04T07:30:00Z", "sentAt": "2022-03-04T07:30:05Z"},
+					{"id": 1, "recipient": "bob@example", "subject": "My Word Of The Day: sonder", "status": "failed", "attempts": 1, "lastError": "550 no such user", "nextAttemptAt": "2022-03-04T07:30:00Z", "createdAt": "2022-03-04T07:30:00Z"}
+				]}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a POST request to the outbox retry endpoint", func(t *testing.T) {
+		t.Run("When the body is empty", func(t *testing.T) {
+			t.Run("Then every failed message is retried", func(t *testing.T) {
+				om.retryResponse = []db.OutboxMessage{}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/outbox/retry", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Nil(t, om.retryIDs)
+				assert.JSONEq(t, `{"messages": []}`, rec.Body.String())
+			})
+		})
+		t.Run("When the body isn't JSON", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/outbox/retry", strings.NewReader("ids=1")))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the body has ids", func(t *testing.T) {
+			t.Run("Then those messages are retried", func(t *testing.T) {
+				om.retryResponse = []db.OutboxMessage{{ID: 1, Recipient: "bob@example", Status: db.OutboxPending, NextAttemptAt: createdAt, CreatedAt: createdAt}}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/outbox/retry", strings.NewReader(`{"ids": [1]}`)))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, []int32{1}, om.retryIDs)
+				assert.Contains(t, rec.Body.String(), `"status":"pending"`)
+			})
+		})
+	})
+}
+
+func TestGatewayChannels(t *testing.T) {
+	cm := &channelMock{}
+	mux := newTestGateway(t, &Server{channelStore: cm})
+
+	createdAt := time.Date(2022, 3, 4, 7, 30, 0, 0, time.UTC)
+
+	t.Run("Given a GET request to the channels endpoint", func(t *testing.T) {
+		t.Run("When the user has channels", func(t *testing.T) {
+			t.Run("Then they are returned with their secrets redacted", func(t *testing.T) {
+				cm.listResponse = []db.Channel{
+					{ID: 1, URL: "slack+https://hooks.slack.com/services/T000/B000/XXXX", CreatedAt: createdAt},
+					{ID: 2, URL: "gotify+https://gotify.example.com/?token=AXXXX", CreatedAt: createdAt},
+				}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1alpha1/channels", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{"channels": [
+					{"id": 1, "url": "slack+https://hooks.slack.com/services/T000/B000/redacted", "createdAt": "2022-03-04T07:30:00Z"},
+					{"id": 2, "url": "gotify+https://gotify.example.com/?token=redacted", "createdAt": "2022-03-04T07:30:00Z"}
+				]}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a POST request to the channels endpoint", func(t *testing.T) {
+		t.Run("When the body isn't JSON", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/channels", strings.NewReader("url=ntfy")))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the URL is invalid", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/channels", strings.NewReader(`{"url": "https://ntfy.sh/my-words"}`)))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the URL is valid", func(t *testing.T) {
+			t.Run("Then the channel is added", func(t *testing.T) {
+				cm.insertResponse = db.Channel{ID: 3, URL: "ntfy+https://ntfy.sh/my-words?token=tk_XXXX", CreatedAt: createdAt}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/channels", strings.NewReader(`{"url": "ntfy+https://ntfy.sh/my-words?token=tk_XXXX"}`)))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "ntfy+https://ntfy.sh/my-words?token=tk_XXXX", cm.insertedURL)
+				assert.JSONEq(t, `{"id": 3, "url": "ntfy+https://ntfy.sh/my-words?token=redacted", "createdAt": "2022-03-04T07:30:00Z"}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a DELETE request to the channel endpoint", func(t *testing.T) {
+		t.Run("When the id isn't an integer", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1alpha1/channels/three", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the channel exists", func(t *testing.T) {
+			t.Run("Then it is deleted", func(t *testing.T) {
+				cm.deleteResponse = db.Channel{ID: 3, URL: "ntfy+https://ntfy.sh/my-words", CreatedAt: createdAt}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/v1alpha1/channels/3", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, int32(3), cm.deletedID)
+				assert.JSONEq(t, `{"id": 3, "url": "ntfy+https://ntfy.sh/my-words", "createdAt": "2022-03-04T07:30:00Z"}`, rec.Body.String())
+			})
+		})
+	})
+}
+
+func TestGatewayTemplates(t *testing.T) {
+	tm := &templateMock{}
+	mux := newTestGateway(t, &Server{templateStore: tm})
+
+	createdAt := time.Date(2022, 3, 4, 7, 30, 0, 0, time.UTC)
+
+	t.Run("Given a POST request to the templates endpoint", func(t *testing.T) {
+		t.Run("When the body isn't JSON", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates", strings.NewReader("name=plain")))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the template doesn't parse", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates", strings.NewReader(`{"name": "plain", "html": "{{.Word", "text": "{{.Word}}"}`)))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When the template is valid", func(t *testing.T) {
+			t.Run("Then it is created", func(t *testing.T) {
+				tm.insertResponse = db.Template{ID: 3, Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}", CreatedAt: createdAt}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates", strings.NewReader(`{"name": "plain", "html": "<p>{{.Word}}</p>", "text": "{{.Word}}"}`)))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, "plain", tm.inserted.Name)
+				assert.JSONEq(t, `{"id": 3, "name": "plain", "html": "<p>{{.Word}}</p>", "text": "{{.Word}}", "active": false, "createdAt": "2022-03-04T07:30:00Z"}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a POST request to the template preview endpoint", func(t *testing.T) {
+		t.Run("When the word id isn't an integer", func(t *testing.T) {
+			t.Run("Then a 400 is returned", func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates/3/preview?word_id=five", nil))
+
+				assert.Equal(t, http.StatusBadRequest, rec.Code)
+			})
+		})
+		t.Run("When there's no word id", func(t *testing.T) {
+			t.Run("Then the template is rendered with the sample data", func(t *testing.T) {
+				tm.getResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates/3/preview", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.JSONEq(t, `{"subject": "My Word Of The Day: sonder", "html": "<p>sonder</p>", "text": "sonder"}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a POST request to the template activate endpoint", func(t *testing.T) {
+		t.Run("When the template exists", func(t *testing.T) {
+			t.Run("Then it is activated", func(t *testing.T) {
+				tm.getResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}
+				tm.activateResponse = db.Template{ID: 3, Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}", Active: true, CreatedAt: createdAt}
+
+				rec := httptest.NewRecorder()
+				mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates/3/activate", nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Equal(t, int32(3), tm.activatedID)
+				assert.JSONEq(t, `{"id": 3, "name": "plain", "html": "<p>{{.Word}}</p>", "text": "{{.Word}}", "active": true, "createdAt": "2022-03-04T07:30:00Z"}`, rec.Body.String())
+			})
+		})
+	})
+
+	t.Run("Given a POST request to the template deactivate endpoint", func(t *testing.T) {
+		t.Run("Then the user's templates are deactivated", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1alpha1/templates/deactivate", nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.True(t, tm.deactivated)
+		})
+	})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+// GetWord returns the word with the given id, or a NotFound error
+func (s *Server) GetWord(ctx context.Context, id int32) (*v1alpha1.Word, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	rsp, err := s.wordQuerier.GetWord(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "word %d not found", id)
+	}
+
+	if err != nil {
+		return nil, statusError(err, "unable to get word")
+	}
+
+	return &v1alpha1.Word{
+		Id:               rsp.ID,
+		Word:             rsp.Word,
+		CustomDefinition: rsp.CustomDefinition,
+	}, nil
+}
+
+// FindWord returns the word with the given spelling, ignoring case and
+// surrounding whitespace, or a NotFound error
+func (s *Server) FindWord(ctx context.Context, spelling string) (*v1alpha1.Word, error) {
+	v := fieldViolations{}
+	validateSpelling(&v, "word", spelling)
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	rsp, err := s.wordQuerier.FindWord(ctx, s.userID(ctx), spelling)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "word %q not found", spelling)
+	}
+
+	if err != nil {
+		return nil, statusError(err, "unable to find word")
+	}
+
+	return &v1alpha1.Word{
+		Id:               rsp.ID,
+		Word:             rsp.Word,
+		CustomDefinition: rsp.CustomDefinition,
+	}, nil
+}
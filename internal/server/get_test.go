@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestGetWord(t *testing.T) {
+	wm := &wordMock{}
+	s := Server{wordQuerier: wm}
+
+	t.Run("Given a request to GetWord", func(t *testing.T) {
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				wm.err = db.ErrNotFound
+
+				r, err := s.GetWord(context.Background(), 45)
+				assert.Equal(t, codes.NotFound, status.Code(err))
+				assert.Nil(t, r)
+			})
+		})
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				wm.err = errors.New("an error")
+
+				r, err := s.GetWord(context.Background(), 45)
+				assertStatusError(t, err, codes.Internal, "unable to get word")
+				assert.Nil(t, r)
+			})
+		})
+		t.Run("When no error is returned", func(t *testing.T) {
+			t.Run("Then the Word is returned to the caller", func(t *testing.T) {
+				wm.err = nil
+				wm.getWordResponse = db.Word{ID: 45, Word: "word1", CustomDefinition: "a definition"}
+
+				r, err := s.GetWord(context.Background(), 45)
+				assert.NoError(t, err)
+
+				assert.Equal(t, wm.getWordResponse.ID, r.Id)
+				assert.Equal(t, wm.getWordResponse.Word, r.Word)
+				assert.Equal(t, wm.getWordResponse.CustomDefinition, r.CustomDefinition)
+			})
+		})
+	})
+}
+
+func TestFindWord(t *testing.T) {
+	wm := &wordMock{}
+	s := Server{wordQuerier: wm}
+
+	t.Run("Given a request to FindWord", func(t *testing.T) {
+		t.Run("When the spelling is empty", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.FindWord(context.Background(), "  ")
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				wm.err = db.ErrNotFound
+
+				_, err := s.FindWord(context.Background(), "word1")
+				assert.Equal(t, codes.NotFound, status.Code(err))
+			})
+		})
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				wm.err = errors.New("an error")
+
+				_, err := s.FindWord(context.Background(), "word1")
+				assertStatusError(t, err, codes.Internal, "unable to find word")
+			})
+		})
+		t.Run("When no error is returned", func(t *testing.T) {
+			t.Run("Then the Word is returned to the caller", func(t *testing.T) {
+				wm.err = nil
+				wm.findWordResponse = db.Word{ID: 45, Word: "Word1"}
+
+				r, err := s.FindWord(context.Background(), "word1")
+				assert.NoError(t, err)
+
+				assert.Equal(t, wm.findWordResponse.ID, r.Id)
+				assert.Equal(t, wm.findWordResponse.Word, r.Word)
+			})
+		})
+	})
+}
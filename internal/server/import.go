@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/importer"
+)
+
+type wordImporter interface {
+	ImportWords(context.Context, int32, func() (db.ImportRow, error), db.ImportOptions) (db.ImportResult, error)
+}
+
+// ImportOptions controls how ImportFrom treats the words it reads
+type ImportOptions struct {
+	// DryRun reports what importing would do without changing anything
+	DryRun bool
+	// OnDuplicate is what to do with words that already exist, one of skip
+	// (the default), merge or error
+	OnDuplicate string
+	// SkipInvalid imports the valid words even if others fail. Otherwise any
+	// failure stops every word being imported.
+	SkipInvalid bool
+}
+
+// ImportError is a row that failed to import
+type ImportError struct {
+	Line    int32  `json:"line"`
+	Word    string `json:"word,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport reports what happened to the rows of an import. The counts are
+// of what was tried, even if nothing was committed.
+type ImportReport struct {
+	Added   int32          `json:"added"`
+	Merged  int32          `json:"merged"`
+	Skipped int32          `json:"skipped"`
+	Failed  int32          `json:"failed"`
+	Errors  []*ImportError `json:"errors"`
+	// Committed reports whether the words were imported, which they aren't in
+	// a dry run or if a row failed without SkipInvalid
+	Committed bool `json:"committed"`
+}
+
+// ImportFrom imports the words read by r in a single transaction, reporting
+// the outcome of each row. Importing is only served over HTTP, as the proto
+// has no RPC for it.
+func (s *Server) ImportFrom(ctx context.Context, r importer.Reader, opts ImportOptions) (*ImportReport, error) {
+	next := func() (db.ImportRow, error) {
+		rec, err := r.Read()
+
+		var rowErr *importer.RowError
+		if errors.As(err, &rowErr) {
+			return db.ImportRow{Line: rowErr.Line, Err: rowErr.Err}, nil
+		}
+
+		if err != nil {
+			return db.ImportRow{}, err
+		}
+
+		return newImportRow(rec.Line, rec.Word, rec.Definition, rec.Tags), nil
+	}
+
+	onDuplicate, err := db.ParseDuplicatePolicy(opts.OnDuplicate)
+	if err != nil {
+		return nil, invalidField("options.on_duplicate", "must be one of skip, merge or error")
+	}
+
+	result, err := s.wordImporter.ImportWords(ctx, s.userID(ctx), next, db.ImportOptions{
+		DryRun:      opts.DryRun,
+		OnDuplicate: onDuplicate,
+		SkipInvalid: opts.SkipInvalid,
+	})
+	if err != nil {
+		return nil, statusError(err, "unable to import words")
+	}
+
+	rsp := &ImportReport{
+		Added:     int32(result.Added),
+		Merged:    int32(result.Merged),
+		Skipped:   int32(result.Skipped),
+		Failed:    int32(result.Failed),
+		Errors:    make([]*ImportError, len(result.Errors)),
+		Committed: result.Committed,
+	}
+
+	for i, e := range result.Errors {
+		rsp.Errors[i] = &ImportError{
+			Line:    int32(e.Line),
+			Word:    e.Word,
+			Message: e.Err.Error(),
+		}
+	}
+
+	return rsp, nil
+}
+
+// newImportRow validates a row as AddWord and TagWord would, failing it if
+// it's invalid
+func newImportRow(line int, word string, definition string, tags []string) db.ImportRow {
+	row := db.ImportRow{
+		Line:             line,
+		Word:             strings.TrimSpace(word),
+		CustomDefinition: strings.TrimSpace(definition),
+		Tags:             trimAll(tags),
+	}
+
+	v := fieldViolations{}
+	validateSpelling(&v, "word", row.Word)
+	validateDefinition(&v, "custom_definition", row.CustomDefinition)
+
+	for i, t := range row.Tags {
+		validateTag(&v, fmt.Sprintf("tags[%d]", i), t)
+	}
+
+	if len(v) > 0 {
+		row.Err = errors.New(v.String())
+	}
+
+	return row
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/importer"
+)
+
+func TestImportFrom(t *testing.T) {
+	im := &importMock{}
+	s := Server{wordImporter: im}
+
+	read := func(t *testing.T, f importer.Format, file string) importer.Reader {
+		t.Helper()
+
+		r, err := importer.NewReader(f, strings.NewReader(file))
+		assert.NoError(t, err)
+
+		return r
+	}
+
+	t.Run("Given a file with some invalid rows", func(t *testing.T) {
+		r := read(t, importer.FormatJSONL, `{"word": " petrichor ", "customDefinition": "the smell of rain", "tags": [" weather "]}`+"\n"+`{"word": "<bad>", "tags": ["a/b"]}`+"\n")
+
+		t.Run("When it's imported as a dry run merging duplicates", func(t *testing.T) {
+			rsp, err := s.ImportFrom(context.Background(), r, ImportOptions{DryRun: true, OnDuplicate: "merge"})
+
+			t.Run("Then valid rows are imported and the others fail with their reason", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, db.ImportOptions{DryRun: true, OnDuplicate: db.DuplicateMerge}, im.opts)
+
+				if assert.Len(t, im.rows, 2) {
+					assert.Equal(t, db.ImportRow{Line: 1, Word: "petrichor", CustomDefinition: "the smell of rain", Tags: []string{"weather"}}, im.rows[0])
+				}
+
+				assert.Equal(t, &ImportReport{
+					Added:  1,
+					Failed: 1,
+					Errors: []*ImportError{
+						{Line: 2, Word: "<bad>", Message: `word must not contain '<', tags[0] must not contain '/'`},
+					},
+				}, rsp)
+			})
+		})
+	})
+
+	t.Run("Given an empty file", func(t *testing.T) {
+		im.rows = nil
+
+		rsp, err := s.ImportFrom(context.Background(), read(t, importer.FormatJSONL, ""), ImportOptions{})
+
+		t.Run("Then nothing is imported", func(t *testing.T) {
+			assert.NoError(t, err)
+			assert.Empty(t, im.rows)
+			assert.Equal(t, &ImportReport{Errors: []*ImportError{}, Committed: true}, rsp)
+		})
+	})
+
+	t.Run("Given an unknown duplicate policy", func(t *testing.T) {
+		t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+			_, err := s.ImportFrom(context.Background(), read(t, importer.FormatJSONL, ""), ImportOptions{OnDuplicate: "replace"})
+			assertStatusError(t, err, codes.InvalidArgument, "invalid request: options.on_duplicate must be one of skip, merge or error")
+		})
+	})
+
+	t.Run("Given the import fails", func(t *testing.T) {
+		im.err = errors.New("connection reset")
+		defer func() { im.err = nil }()
+
+		t.Run("Then an Internal error is returned", func(t *testing.T) {
+			_, err := s.ImportFrom(context.Background(), read(t, importer.FormatJSONL, ""), ImportOptions{})
+			assertStatusError(t, err, codes.Internal, "unable to import words")
+		})
+	})
+
+	t.Run("Given a CSV file with a malformed row", func(t *testing.T) {
+		r, err := importer.NewReader(importer.FormatCSV, strings.NewReader("word,tags\npetrichor,weather;nature\nbad\"quote\n"))
+		assert.NoError(t, err)
+
+		t.Run("When it's imported skipping invalid rows", func(t *testing.T) {
+			rsp, err := s.ImportFrom(context.Background(), r, ImportOptions{SkipInvalid: true})
+
+			t.Run("Then the rest are committed and the malformed row reported by line", func(t *testing.T) {
+				assert.NoError(t, err)
+				assert.Equal(t, db.ImportRow{Line: 2, Word: "petrichor", Tags: []string{"weather", "nature"}}, im.rows[0])
+				assert.Equal(t, &ImportReport{
+					Added:     1,
+					Failed:    1,
+					Errors:    []*ImportError{{Line: 3, Message: `bare " in non-quoted-field`}},
+					Committed: true,
+				}, rsp)
+			})
+		})
+	})
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+const (
+	// defaultPageSize is the number of words returned by a ListWordsPage call
+	// without a page size, and by ListWords
+	defaultPageSize = 100
+	// maxPageSize is the largest number of words returned by a single
+	// ListWordsPage call. Larger page sizes are reduced to it.
+	maxPageSize = 1000
+)
+
+// ListWordsPageRequest asks for a single page of the caller's words
+type ListWordsPageRequest struct {
+	// PageSize is the maximum number of words to return. If it is 0,
+	// defaultPageSize words are returned.
+	PageSize int32
+	// PageToken is the NextPageToken of the previous page, if any
+	PageToken string
+
+	// Prefix, if set, only returns words starting with it, ignoring case
+	Prefix string
+	// Contains, if set, only returns words containing it, ignoring case
+	Contains string
+	// Tag, if set, only returns words with the tag
+	Tag string
+	// OrderBy is one of alphabetical, newest or oldest, defaulting to oldest
+	OrderBy string
+}
+
+type ListWordsPageResponse struct {
+	Words []*v1alpha1.Word
+	// NextPageToken fetches the following page, and is empty on the last page
+	NextPageToken string
+}
+
+// pageToken is the decoded form of a page token. The filters and order are
+// included so a token can't be used with a different listing.
+type pageToken struct {
+	Prefix   string       `json:"p,omitempty"`
+	Contains string       `json:"c,omitempty"`
+	Tag      string       `json:"t,omitempty"`
+	Order    db.WordOrder `json:"o"`
+	ID       int32        `json:"i"`
+	Word     string       `json:"w,omitempty"`
+}
+
+func encodePageToken(t pageToken) string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	t := pageToken{}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return t, err
+	}
+
+	return t, json.Unmarshal(b, &t)
+}
+
+// ListWordsPage returns a page of the caller's words, filtered and ordered as requested
+func (s *Server) ListWordsPage(ctx context.Context, req *ListWordsPageRequest) (*ListWordsPageResponse, error) {
+	if err := validateListWordsPageRequest(req); err != nil {
+		return nil, err
+	}
+
+	order, _ := db.ParseWordOrder(req.OrderBy)
+
+	opts := db.ListWordsOptions{
+		Prefix:   req.Prefix,
+		Contains: req.Contains,
+		Tag:      req.Tag,
+		Order:    order,
+	}
+
+	if req.PageToken != "" {
+		t, err := decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, invalidField("page_token", "is invalid")
+		}
+
+		if t.Prefix != req.Prefix || t.Contains != req.Contains || t.Tag != req.Tag || t.Order != order {
+			return nil, invalidField("page_token", "does not match the filters and order of the request")
+		}
+
+		opts.After = &db.WordCursor{ID: t.ID, Word: t.Word}
+	}
+
+	size := req.PageSize
+	if size == 0 {
+		size = defaultPageSize
+	}
+
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	// Fetch one more than asked for to find out whether there's another page
+	opts.Limit = size + 1
+
+	rsp, err := s.wordQuerier.ListWords(ctx, s.userID(ctx), opts)
+	if err != nil {
+		return nil, statusError(err, "unable to list words")
+	}
+
+	page := &ListWordsPageResponse{}
+
+	if len(rsp) > int(size) {
+		rsp = rsp[:size]
+		last := rsp[len(rsp)-1]
+
+		page.NextPageToken = encodePageToken(pageToken{
+			Prefix:   req.Prefix,
+			Contains: req.Contains,
+			Tag:      req.Tag,
+			Order:    order,
+			ID:       last.ID,
+			Word:     last.Word,
+		})
+	}
+
+	page.Words = make([]*v1alpha1.Word, len(rsp))
+	for i, word := range rsp {
+		page.Words[i] = &v1alpha1.Word{
+			Id:               word.ID,
+			Word:             word.Word,
+			CustomDefinition: word.CustomDefinition,
+		}
+	}
+
+	return page, nil
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestListWordsPage(t *testing.T) {
+	wm := &wordMock{}
+	s := Server{wordQuerier: wm}
+
+	t.Run("Given a request to ListWordsPage", func(t *testing.T) {
+		t.Run("When the page size is negative", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: -1})
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: page_size must not be negative")
+			})
+		})
+		t.Run("When the page size is too large", func(t *testing.T) {
+			t.Run("Then it's reduced to the largest page size", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: maxPageSize + 1})
+				assert.NoError(t, err)
+				assert.Equal(t, int32(maxPageSize+1), wm.lastListOptions.Limit)
+			})
+		})
+		t.Run("When the order is unknown", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{OrderBy: "random"})
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When the tag is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{Tag: "a/b"})
+				assertStatusError(t, err, codes.InvalidArgument, `invalid request: tag must not contain '/'`)
+			})
+		})
+		t.Run("When a tag is given", func(t *testing.T) {
+			t.Run("Then only words with the tag are listed", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{Tag: "GRE"})
+				assert.NoError(t, err)
+				assert.Equal(t, "GRE", wm.lastListOptions.Tag)
+			})
+		})
+		t.Run("When the page token is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageToken: "!!!"})
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				wm.err = errors.New("an error")
+
+				_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{})
+				assertStatusError(t, err, codes.Internal, "unable to list words")
+			})
+		})
+		t.Run("When there are more words than the page size", func(t *testing.T) {
+			t.Run("Then a page is returned with a token for the next page", func(t *testing.T) {
+				wm.err = nil
+				wm.listWordsResponse = []db.Word{{ID: 1, Word: "alpha"}, {ID: 2, Word: "bravo"}, {ID: 3, Word: "charlie"}}
+
+				r, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: 2, Prefix: "a", OrderBy: "alphabetical"})
+				assert.NoError(t, err)
+
+				assert.Equal(t, int32(3), wm.lastListOptions.Limit)
+				assert.Equal(t, "a", wm.lastListOptions.Prefix)
+				assert.Equal(t, db.OrderAlphabetical, wm.lastListOptions.Order)
+				assert.Nil(t, wm.lastListOptions.After)
+
+				assert.Len(t, r.Words, 2)
+				assert.NotEmpty(t, r.NextPageToken)
+
+				t.Run("And the token is used to fetch the next page", func(t *testing.T) {
+					wm.listWordsResponse = []db.Word{{ID: 3, Word: "charlie"}}
+
+					next, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: 2, Prefix: "a", OrderBy: "alphabetical", PageToken: r.NextPageToken})
+					assert.NoError(t, err)
+
+					assert.Equal(t, &db.WordCursor{ID: 2, Word: "bravo"}, wm.lastListOptions.After)
+					assert.Len(t, next.Words, 1)
+					assert.Empty(t, next.NextPageToken)
+				})
+
+				t.Run("And the token is used with different filters", func(t *testing.T) {
+					_, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: 2, Prefix: "b", OrderBy: "alphabetical", PageToken: r.NextPageToken})
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+					_, err = s.ListWordsPage(context.Background(), &ListWordsPageRequest{PageSize: 2, Prefix: "a", Tag: "GRE", OrderBy: "alphabetical", PageToken: r.NextPageToken})
+					assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				})
+			})
+		})
+		t.Run("When no page size is given", func(t *testing.T) {
+			t.Run("Then a page of the default size is returned", func(t *testing.T) {
+				wm.listWordsResponse = []db.Word{{ID: 1, Word: "alpha"}, {ID: 2, Word: "bravo"}}
+
+				r, err := s.ListWordsPage(context.Background(), &ListWordsPageRequest{})
+				assert.NoError(t, err)
+
+				assert.Equal(t, int32(defaultPageSize+1), wm.lastListOptions.Limit)
+				assert.Len(t, r.Words, 2)
+				assert.Empty(t, r.NextPageToken)
+			})
+		})
+	})
+}
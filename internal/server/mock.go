@@ -2,14 +2,28 @@ package server
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+	"github.com/mywordoftheday/backend/internal/srs"
 )
 
 type wordMock struct {
 	insertWordResponse db.Word
 	deleteWordResponse db.Word
+	updateWordResponse db.Word
+	mergeWordResponse  db.Word
+	merged             bool
+	lastUpdate         db.WordUpdate
+	getWordResponse    db.Word
+	findWordResponse   db.Word
+	randomWordResponse db.Word
+	lastRandomTag      string
 	listWordsResponse  []db.Word
+	lastListOptions    db.ListWordsOptions
 	err                error
 }
 
@@ -17,10 +31,370 @@ func (f wordMock) InsertWord(context.Context, db.Word) (db.Word, error) {
 	return f.insertWordResponse, f.err
 }
 
-func (f wordMock) DeleteWord(context.Context, int32) (db.Word, error) {
+func (f wordMock) DeleteWord(context.Context, int32, int32) (db.Word, error) {
 	return f.deleteWordResponse, f.err
 }
 
-func (f wordMock) ListWords(context.Context) ([]db.Word, error) {
+func (f *wordMock) MergeWord(context.Context, db.Word) (db.Word, error) {
+	f.merged = true
+	return f.mergeWordResponse, f.err
+}
+
+func (f *wordMock) UpdateWord(_ context.Context, _ int32, _ int32, update db.WordUpdate) (db.Word, error) {
+	f.lastUpdate = update
+	return f.updateWordResponse, f.err
+}
+
+func (f wordMock) GetWord(context.Context, int32, int32) (db.Word, error) {
+	return f.getWordResponse, f.err
+}
+
+func (f wordMock) FindWord(context.Context, int32, string) (db.Word, error) {
+	return f.findWordResponse, f.err
+}
+
+func (f *wordMock) RandomWord(_ context.Context, _ int32, tag string) (db.Word, error) {
+	f.lastRandomTag = tag
+	return f.randomWordResponse, f.err
+}
+
+func (f *wordMock) ListWords(_ context.Context, _ int32, opts db.ListWordsOptions) ([]db.Word, error) {
+	f.lastListOptions = opts
 	return f.listWordsResponse, f.err
 }
+
+type definitionMock struct {
+	getDefinitionsResponse db.Definitions
+	replaced               db.Definitions
+	replacedSource         string
+	replacedID             int32
+	deletedDictionaryID    int32
+	sense                  db.Sense
+	senseWordID            int32
+	err                    error
+}
+
+func (f *definitionMock) ReplaceDefinitions(_ context.Context, _ int32, id int32, source string, d db.Definitions) error {
+	f.replacedID = id
+	f.replacedSource = source
+	f.replaced = d
+	return f.err
+}
+
+func (f *definitionMock) DeleteDictionaryDefinitions(_ context.Context, _ int32, id int32) error {
+	f.deletedDictionaryID = id
+	return f.err
+}
+
+func (f *definitionMock) GetDefinitions(context.Context, int32, int32) (db.Definitions, error) {
+	return f.getDefinitionsResponse, f.err
+}
+
+func (f *definitionMock) AddSense(_ context.Context, _ int32, wordID int32, s db.Sense) (db.Sense, error) {
+	f.senseWordID = wordID
+	f.sense = s
+	s.ID = 1
+	s.WordID = wordID
+	return s, f.err
+}
+
+func (f *definitionMock) UpdateSense(_ context.Context, _ int32, wordID int32, s db.Sense) (db.Sense, error) {
+	f.senseWordID = wordID
+	f.sense = s
+	s.WordID = wordID
+	s.Source = ""
+	return s, f.err
+}
+
+func (f *definitionMock) DeleteSense(_ context.Context, _ int32, wordID int32, id int32) (db.Sense, error) {
+	f.senseWordID = wordID
+	f.sense = db.Sense{ID: id}
+	return db.Sense{ID: id, WordID: wordID}, f.err
+}
+
+type providerMock struct {
+	entry definitions.Entry
+	err   error
+}
+
+func (f providerMock) Lookup(context.Context, string) (definitions.Entry, error) {
+	return f.entry, f.err
+}
+
+type deliveryMock struct {
+	nextWordResponse db.Word
+	nextWordMode     db.RotationMode
+	nextWordTag      string
+	recorded         []int32
+	streak           int
+	err              error
+}
+
+func (f *deliveryMock) NextWord(_ context.Context, _ int32, mode db.RotationMode, tag string) (db.Word, error) {
+	f.nextWordMode = mode
+	f.nextWordTag = tag
+	return f.nextWordResponse, f.err
+}
+
+func (f *deliveryMock) RecordDelivery(_ context.Context, _ int32, id int32) error {
+	if f.err == nil {
+		f.recorded = append(f.recorded, id)
+	}
+	return f.err
+}
+
+func (f *deliveryMock) DeliveryStreak(context.Context, int32) (int, error) {
+	return f.streak, f.err
+}
+
+type reviewMock struct {
+	reviewWordResponse db.Word
+	grade              srs.Grade
+	err                error
+}
+
+func (f *reviewMock) ReviewWord(_ context.Context, _ int32, _ int32, grade srs.Grade) (db.Word, error) {
+	f.grade = grade
+	return f.reviewWordResponse, f.err
+}
+
+type userMock struct {
+	getUserResponse   db.User
+	listUsersResponse []db.User
+	err               error
+}
+
+func (f userMock) GetUserByUsername(context.Context, string) (db.User, error) {
+	return f.getUserResponse, f.err
+}
+
+func (f userMock) ListUsers(context.Context) ([]db.User, error) {
+	return f.listUsersResponse, f.err
+}
+
+type databaseMock struct {
+	pingErr error
+	closed  bool
+}
+
+func (f *databaseMock) Ping(context.Context) error {
+	return f.pingErr
+}
+
+func (f *databaseMock) Close() {
+	f.closed = true
+}
+
+func (f *databaseMock) PoolStats() db.PoolStats {
+	return db.PoolStats{}
+}
+
+func (f *databaseMock) CountWords(context.Context) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+type tagMock struct {
+	tagWordResponse  db.Tag
+	listTagsResponse []db.Tag
+	taggedID         int32
+	tag              string
+	err              error
+}
+
+func (f *tagMock) TagWord(_ context.Context, _ int32, id int32, tag string) (db.Tag, error) {
+	f.taggedID = id
+	f.tag = tag
+	return f.tagWordResponse, f.err
+}
+
+func (f *tagMock) UntagWord(_ context.Context, _ int32, id int32, tag string) error {
+	f.taggedID = id
+	f.tag = tag
+	return f.err
+}
+
+func (f *tagMock) DeleteTag(_ context.Context, _ int32, tag string) (db.Tag, error) {
+	f.tag = tag
+	return f.tagWordResponse, f.err
+}
+
+func (f *tagMock) ListTags(context.Context, int32) ([]db.Tag, error) {
+	return f.listTagsResponse, f.err
+}
+
+func (f *tagMock) WordTags(_ context.Context, _ int32, id int32) ([]db.Tag, error) {
+	f.taggedID = id
+	return f.listTagsResponse, f.err
+}
+
+// importMock imports every row without a database, adding each valid one
+type importMock struct {
+	rows []db.ImportRow
+	opts db.ImportOptions
+	err  error
+}
+
+func (f *importMock) ImportWords(_ context.Context, _ int32, next func() (db.ImportRow, error), opts db.ImportOptions) (db.ImportResult, error) {
+	f.opts = opts
+	result := db.ImportResult{Errors: []db.ImportRowError{}}
+
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		f.rows = append(f.rows, row)
+
+		if row.Err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, db.ImportRowError{Line: row.Line, Word: row.Word, Err: row.Err})
+			continue
+		}
+
+		result.Added++
+	}
+
+	result.Committed = !opts.DryRun && (result.Failed == 0 || opts.SkipInvalid)
+
+	return result, f.err
+}
+
+type exportMock struct {
+	words []db.ExportedWord
+	tag   string
+	err   error
+}
+
+func (f *exportMock) ExportWords(_ context.Context, _ int32, tag string, fn func(db.ExportedWord) error) error {
+	f.tag = tag
+
+	for _, w := range f.words {
+		if err := fn(w); err != nil {
+			return err
+		}
+	}
+
+	return f.err
+}
+
+type outboxMock struct {
+	enqueued      []db.OutboxMessage
+	enqueuedWord  int32
+	listResponse  []db.OutboxMessage
+	listStatus    db.OutboxStatus
+	listLimit     int
+	retryResponse []db.OutboxMessage
+	retryIDs      []int32
+	err           error
+}
+
+func (f *outboxMock) EnqueueMessages(_ context.Context, _ int32, wordID int32, messages []db.OutboxMessage) error {
+	f.enqueuedWord = wordID
+	f.enqueued = messages
+	return f.err
+}
+
+func (f *outboxMock) ClaimMessages(context.Context, int, time.Duration) ([]db.OutboxMessage, error) {
+	return nil, f.err
+}
+
+func (f *outboxMock) MarkMessageSent(context.Context, int32) error {
+	return f.err
+}
+
+func (f *outboxMock) RetryMessageAt(context.Context, int32, time.Time, string) error {
+	return f.err
+}
+
+func (f *outboxMock) FailMessage(context.Context, int32, string) error {
+	return f.err
+}
+
+func (f *outboxMock) ReleaseMessages(context.Context, []int32) error {
+	return f.err
+}
+
+func (f *outboxMock) ListMessages(_ context.Context, _ int32, status db.OutboxStatus, limit int) ([]db.OutboxMessage, error) {
+	f.listStatus = status
+	f.listLimit = limit
+	return f.listResponse, f.err
+}
+
+func (f *outboxMock) RetryMessages(_ context.Context, _ int32, ids []int32) ([]db.OutboxMessage, error) {
+	f.retryIDs = ids
+	return f.retryResponse, f.err
+}
+
+type channelMock struct {
+	insertResponse db.Channel
+	insertedURL    string
+	deleteResponse db.Channel
+	deletedID      int32
+	listResponse   []db.Channel
+	err            error
+}
+
+func (f *channelMock) InsertChannel(_ context.Context, _ int32, url string) (db.Channel, error) {
+	f.insertedURL = url
+	return f.insertResponse, f.err
+}
+
+func (f *channelMock) DeleteChannel(_ context.Context, _ int32, id int32) (db.Channel, error) {
+	f.deletedID = id
+	return f.deleteResponse, f.err
+}
+
+func (f *channelMock) ListChannels(context.Context, int32) ([]db.Channel, error) {
+	return f.listResponse, f.err
+}
+
+type templateMock struct {
+	insertResponse   db.Template
+	inserted         db.Template
+	getResponse      db.Template
+	activeResponse   db.Template
+	listResponse     []db.Template
+	activateResponse db.Template
+	activatedID      int32
+	deactivated      bool
+	deleteResponse   db.Template
+	deletedID        int32
+	err              error
+}
+
+func (f *templateMock) InsertTemplate(_ context.Context, t db.Template) (db.Template, error) {
+	f.inserted = t
+	return f.insertResponse, f.err
+}
+
+func (f *templateMock) GetTemplate(context.Context, int32, int32) (db.Template, error) {
+	return f.getResponse, f.err
+}
+
+func (f *templateMock) ActiveTemplate(context.Context, int32) (db.Template, error) {
+	return f.activeResponse, f.err
+}
+
+func (f *templateMock) ListTemplates(context.Context, int32) ([]db.Template, error) {
+	return f.listResponse, f.err
+}
+
+func (f *templateMock) ActivateTemplate(_ context.Context, _ int32, id int32) (db.Template, error) {
+	f.activatedID = id
+	return f.activateResponse, f.err
+}
+
+func (f *templateMock) DeactivateTemplates(context.Context, int32) error {
+	f.deactivated = f.err == nil
+	return f.err
+}
+
+func (f *templateMock) DeleteTemplate(_ context.Context, _ int32, id int32) (db.Template, error) {
+	f.deletedID = id
+	return f.deleteResponse, f.err
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/outbox"
+)
+
+type outboxStore interface {
+	outbox.Store
+	EnqueueMessages(context.Context, int32, int32, []db.OutboxMessage) error
+	ListMessages(context.Context, int32, db.OutboxStatus, int) ([]db.OutboxMessage, error)
+	RetryMessages(context.Context, int32, []int32) ([]db.OutboxMessage, error)
+}
+
+const (
+	// defaultOutboxLimit is how many messages ListOutbox returns by default
+	defaultOutboxLimit = 50
+	// maxOutboxLimit is the most messages ListOutbox returns
+	maxOutboxLimit = 500
+)
+
+// EnqueueMessages queues messages about the word with the given id to be sent
+// by the outbox worker, and records the word's delivery to the calling user so
+// that the rotation can take it into account
+func (s *Server) EnqueueMessages(ctx context.Context, wordID int32, messages []db.OutboxMessage) error {
+	err := s.outboxStore.EnqueueMessages(ctx, s.userID(ctx), wordID, messages)
+	if errors.Is(err, db.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "word %d not found", wordID)
+	}
+
+	if err != nil {
+		return statusError(err, "unable to enqueue messages")
+	}
+
+	return nil
+}
+
+// OutboxWorker returns a worker sending the messages queued by
+// EnqueueMessages with sender
+func (s *Server) OutboxWorker(sender outbox.Sender, c outbox.Config) *outbox.Worker {
+	return outbox.New(s.outboxStore, sender, c)
+}
+
+// ListOutbox returns the calling user's most recent messages with the status,
+// one of pending, sent or failed, or with any status if it's empty. A limit of
+// 0 returns defaultOutboxLimit messages.
+func (s *Server) ListOutbox(ctx context.Context, outboxStatus string, limit int32) ([]db.OutboxMessage, error) {
+	v := fieldViolations{}
+
+	st, err := db.ParseOutboxStatus(outboxStatus)
+	if err != nil {
+		v.add("status", "must be one of pending, sent or failed")
+	}
+
+	if limit < 0 || limit > maxOutboxLimit {
+		v.add("limit", "must be between 0 and %d", maxOutboxLimit)
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	if limit == 0 {
+		limit = defaultOutboxLimit
+	}
+
+	messages, err := s.outboxStore.ListMessages(ctx, s.userID(ctx), st, int(limit))
+	if err != nil {
+		return nil, statusError(err, "unable to list messages")
+	}
+
+	return messages, nil
+}
+
+// RetryOutbox queues the calling user's failed messages with the given ids, or
+// all of them if ids is empty, to be sent again, returning those that were.
+// A NotFound error is returned if none of the ids are of failed messages.
+func (s *Server) RetryOutbox(ctx context.Context, ids []int32) ([]db.OutboxMessage, error) {
+	v := fieldViolations{}
+	for i, id := range ids {
+		validateID(&v, fmt.Sprintf("ids[%d]", i), id)
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	retried, err := s.outboxStore.RetryMessages(ctx, s.userID(ctx), ids)
+	if err != nil {
+		return nil, statusError(err, "unable to retry messages")
+	}
+
+	if len(ids) > 0 && len(retried) == 0 {
+		return nil, status.Error(codes.NotFound, "no failed messages found")
+	}
+
+	return retried, nil
+}
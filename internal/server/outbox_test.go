@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestEnqueueMessages(t *testing.T) {
+	om := &outboxMock{}
+	s := Server{outboxStore: om}
+
+	t.Run("Given messages about a word", func(t *testing.T) {
+		messages := []db.OutboxMessage{{Recipient: "alice@example.com"}, {Recipient: "bob@example.com"}}
+
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				om.err = db.ErrNotFound
+
+				err := s.EnqueueMessages(context.Background(), 45, messages)
+				assertStatusError(t, err, codes.NotFound, "word 45 not found")
+			})
+		})
+		t.Run("When the word exists", func(t *testing.T) {
+			t.Run("Then they are enqueued", func(t *testing.T) {
+				om.err = nil
+
+				assert.NoError(t, s.EnqueueMessages(context.Background(), 45, messages))
+				assert.Equal(t, int32(45), om.enqueuedWord)
+				assert.Equal(t, messages, om.enqueued)
+			})
+		})
+	})
+}
+
+func TestListOutbox(t *testing.T) {
+	om := &outboxMock{}
+	s := Server{outboxStore: om}
+
+	testCases := []struct {
+		desc          string
+		status        string
+		limit         int32
+		err           error
+		expectedLimit int
+		expectedErr   string
+		code          codes.Code
+	}{
+		{desc: "The default limit should be used when none is given", status: "failed", expectedLimit: defaultOutboxLimit},
+		{desc: "The given limit should be used", limit: 10, expectedLimit: 10},
+		{desc: "An unknown status and a limit that's too large should both be rejected", status: "bounced", limit: 501, code: codes.InvalidArgument, expectedErr: "invalid request: status must be one of pending, sent or failed, limit must be between 0 and 500"},
+		{desc: "A database error should be returned", err: errors.New("an error"), code: codes.Internal, expectedErr: "unable to list messages"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			om.err = tC.err
+			om.listResponse = []db.OutboxMessage{{ID: 1, Status: db.OutboxFailed}}
+
+			messages, err := s.ListOutbox(context.Background(), tC.status, tC.limit)
+			if tC.expectedErr != "" {
+				assertStatusError(t, err, tC.code, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, om.listResponse, messages)
+			assert.Equal(t, db.OutboxStatus(tC.status), om.listStatus)
+			assert.Equal(t, tC.expectedLimit, om.listLimit)
+		})
+	}
+}
+
+func TestRetryOutbox(t *testing.T) {
+	om := &outboxMock{}
+	s := Server{outboxStore: om}
+
+	t.Run("Given a request to retry failed messages", func(t *testing.T) {
+		t.Run("When an id is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.RetryOutbox(context.Background(), []int32{3, 0})
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: ids[1] must be a positive id")
+			})
+		})
+		t.Run("When none of the ids are of failed messages", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				om.retryResponse = []db.OutboxMessage{}
+
+				_, err := s.RetryOutbox(context.Background(), []int32{3})
+				assertStatusError(t, err, codes.NotFound, "no failed messages found")
+			})
+		})
+		t.Run("When no ids are given and nothing has failed", func(t *testing.T) {
+			t.Run("Then nothing is retried without an error", func(t *testing.T) {
+				messages, err := s.RetryOutbox(context.Background(), nil)
+				assert.NoError(t, err)
+				assert.Empty(t, messages)
+				assert.Nil(t, om.retryIDs)
+			})
+		})
+		t.Run("When the ids are of failed messages", func(t *testing.T) {
+			t.Run("Then the retried messages are returned", func(t *testing.T) {
+				om.retryResponse = []db.OutboxMessage{{ID: 3, Status: db.OutboxPending}}
+
+				messages, err := s.RetryOutbox(context.Background(), []int32{3})
+				assert.NoError(t, err)
+				assert.Equal(t, om.retryResponse, messages)
+				assert.Equal(t, []int32{3}, om.retryIDs)
+			})
+		})
+	})
+}
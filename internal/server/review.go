@@ -0,0 +1,33 @@
+package server
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/srs"
+)
+
+// ReviewWord grades how well the word with the given id was recalled, one of
+// again, hard, good or easy, and returns the word with its updated schedule
+func (s *Server) ReviewWord(ctx context.Context, id int32, grade string) (db.Word, error) {
+	if err := validateReviewWordRequest(id, grade); err != nil {
+		return db.Word{}, err
+	}
+
+	g, _ := srs.ParseGrade(grade)
+
+	rsp, err := s.wordReviewer.ReviewWord(ctx, s.userID(ctx), id, g)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Word{}, status.Errorf(codes.NotFound, "word %d not found", id)
+	}
+
+	if err != nil {
+		return db.Word{}, statusError(err, "unable to review word")
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/srs"
+)
+
+func TestReviewWord(t *testing.T) {
+	rm := &reviewMock{}
+	s := Server{wordReviewer: rm}
+
+	t.Run("Given a request to ReviewWord", func(t *testing.T) {
+		t.Run("When the grade is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.ReviewWord(context.Background(), 45, "perfect")
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				rm.err = db.ErrNotFound
+
+				_, err := s.ReviewWord(context.Background(), 45, "good")
+				assert.Equal(t, codes.NotFound, status.Code(err))
+			})
+		})
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				rm.err = errors.New("an error")
+
+				_, err := s.ReviewWord(context.Background(), 45, "good")
+				assertStatusError(t, err, codes.Internal, "unable to review word")
+			})
+		})
+		t.Run("When no error is returned", func(t *testing.T) {
+			t.Run("Then the rescheduled Word is returned to the caller", func(t *testing.T) {
+				rm.err = nil
+				rm.reviewWordResponse = db.Word{ID: 45, Word: "word1", EaseFactor: 2.6, IntervalDays: 6, Repetitions: 2, DueAt: time.Now()}
+
+				w, err := s.ReviewWord(context.Background(), 45, "easy")
+				assert.NoError(t, err)
+				assert.Equal(t, srs.Easy, rm.grade)
+				assert.Equal(t, rm.reviewWordResponse, w)
+			})
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+// NextWord returns the word that should be delivered next to the calling user,
+// according to the configured rotation mode and tag. A nil Word is returned if
+// the user hasn't added any words with the tag.
+func (s *Server) NextWord(ctx context.Context) (*v1alpha1.Word, error) {
+	rsp, err := s.deliveryTracker.NextWord(ctx, s.userID(ctx), s.rotationMode, s.rotationTag)
+	if err != nil {
+		return nil, statusError(err, "unable to get next word")
+	}
+
+	if rsp.ID == 0 {
+		return nil, nil
+	}
+
+	return &v1alpha1.Word{
+		Id:               rsp.ID,
+		Word:             rsp.Word,
+		CustomDefinition: rsp.CustomDefinition,
+	}, nil
+}
+
+// RecordDelivery stores that the word with the given id has been delivered to
+// the calling user, so that the rotation can take it into account
+func (s *Server) RecordDelivery(ctx context.Context, id int32) error {
+	if err := s.deliveryTracker.RecordDelivery(ctx, s.userID(ctx), id); err != nil {
+		return statusError(err, "unable to record delivery")
+	}
+
+	return nil
+}
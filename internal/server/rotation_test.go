@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestNextWord(t *testing.T) {
+	dm := &deliveryMock{}
+	s := Server{deliveryTracker: dm, rotationMode: db.RotationShuffleCycle, rotationTag: "GRE"}
+
+	t.Run("Given a request to NextWord", func(t *testing.T) {
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				dm.err = errors.New("an error")
+
+				w, err := s.NextWord(context.Background())
+				assertStatusError(t, err, codes.Internal, "unable to get next word")
+				assert.Nil(t, w)
+			})
+		})
+		t.Run("When there are no words", func(t *testing.T) {
+			t.Run("Then a nil Word is returned", func(t *testing.T) {
+				dm.err = nil
+				dm.nextWordResponse = db.Word{}
+
+				w, err := s.NextWord(context.Background())
+				assert.NoError(t, err)
+				assert.Nil(t, w)
+			})
+		})
+		t.Run("When a word is returned", func(t *testing.T) {
+			t.Run("Then it is picked using the configured rotation mode and tag", func(t *testing.T) {
+				dm.err = nil
+				dm.nextWordResponse = db.Word{ID: 45, Word: "word1", CustomDefinition: "a definition"}
+
+				w, err := s.NextWord(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, db.RotationShuffleCycle, dm.nextWordMode)
+				assert.Equal(t, "GRE", dm.nextWordTag)
+
+				assert.Equal(t, dm.nextWordResponse.ID, w.Id)
+				assert.Equal(t, dm.nextWordResponse.Word, w.Word)
+				assert.Equal(t, dm.nextWordResponse.CustomDefinition, w.CustomDefinition)
+			})
+		})
+	})
+}
+
+func TestRecordDelivery(t *testing.T) {
+	dm := &deliveryMock{}
+	s := Server{deliveryTracker: dm}
+
+	t.Run("Given a request to RecordDelivery", func(t *testing.T) {
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				dm.err = errors.New("an error")
+
+				assertStatusError(t, s.RecordDelivery(context.Background(), 45), codes.Internal, "unable to record delivery")
+			})
+		})
+		t.Run("When no error is returned", func(t *testing.T) {
+			t.Run("Then the delivery is recorded", func(t *testing.T) {
+				dm.err = nil
+
+				assert.NoError(t, s.RecordDelivery(context.Background(), 45))
+				assert.Equal(t, []int32{45}, dm.recorded)
+			})
+		})
+	})
+}
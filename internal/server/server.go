@@ -2,27 +2,105 @@ package server
 
 import (
 	"context"
-	"crypto/rand"
-	"math/big"
+	"time"
 
+	"github.com/mywordoftheday/backend/internal/auth"
 	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+	"github.com/mywordoftheday/backend/internal/srs"
 	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type wordQuerier interface {
-	ListWords(context.Context) ([]db.Word, error)
+	ListWords(context.Context, int32, db.ListWordsOptions) ([]db.Word, error)
+	GetWord(context.Context, int32, int32) (db.Word, error)
+	FindWord(context.Context, int32, string) (db.Word, error)
+	RandomWord(context.Context, int32, string) (db.Word, error)
 }
 
 type wordModifier interface {
 	InsertWord(context.Context, db.Word) (db.Word, error)
-	DeleteWord(context.Context, int32) (db.Word, error)
+	DeleteWord(context.Context, int32, int32) (db.Word, error)
+	UpdateWord(context.Context, int32, int32, db.WordUpdate) (db.Word, error)
+	MergeWord(context.Context, db.Word) (db.Word, error)
+}
+
+type deliveryTracker interface {
+	NextWord(context.Context, int32, db.RotationMode, string) (db.Word, error)
+	RecordDelivery(context.Context, int32, int32) error
+	DeliveryStreak(context.Context, int32) (int, error)
+}
+
+type wordReviewer interface {
+	ReviewWord(context.Context, int32, int32, srs.Grade) (db.Word, error)
+}
+
+type wordTagger interface {
+	TagWord(context.Context, int32, int32, string) (db.Tag, error)
+	UntagWord(context.Context, int32, int32, string) error
+	DeleteTag(context.Context, int32, string) (db.Tag, error)
+	ListTags(context.Context, int32) ([]db.Tag, error)
+	WordTags(context.Context, int32, int32) ([]db.Tag, error)
+}
+
+type userQuerier interface {
+	GetUserByUsername(context.Context, string) (db.User, error)
+	ListUsers(context.Context) ([]db.User, error)
+}
+
+type definitionStore interface {
+	ReplaceDefinitions(context.Context, int32, int32, string, db.Definitions) error
+	DeleteDictionaryDefinitions(context.Context, int32, int32) error
+	GetDefinitions(context.Context, int32, int32) (db.Definitions, error)
+	AddSense(context.Context, int32, int32, db.Sense) (db.Sense, error)
+	UpdateSense(context.Context, int32, int32, db.Sense) (db.Sense, error)
+	DeleteSense(context.Context, int32, int32, int32) (db.Sense, error)
+}
+
+type database interface {
+	Ping(context.Context) error
+	Close()
+	PoolStats() db.PoolStats
+	CountWords(context.Context) (map[string]int64, error)
 }
 
 // Server is the implementation of the mywordofthedayv1alpha1.MyWordOfTheDayServer
 type Server struct {
 	wordQuerier  wordQuerier
 	wordModifier wordModifier
+
+	mergeDuplicates bool
+
+	definitionStore    definitionStore
+	definitions        definitions.Provider
+	definitionsTimeout time.Duration
+
+	deliveryTracker deliveryTracker
+	rotationMode    db.RotationMode
+	rotationTag     string
+
+	wordReviewer wordReviewer
+
+	wordTagger wordTagger
+
+	wordImporter wordImporter
+	wordExporter wordExporter
+
+	outboxStore outboxStore
+
+	channelStore channelStore
+
+	templateStore          templateStore
+	unsubscribeURLTemplate string
+
+	userQuerier userQuerier
+
+	authenticator *auth.Authenticator
+
+	database database
 }
 
 type Config struct {
@@ -31,15 +109,60 @@ type Config struct {
 	DBUsername string
 	DBPassword string
 	DBName     string
+
+	DBMigrateOnStartup bool
+
+	// MergeDuplicates makes AddWord merge the custom definition of a word that
+	// already exists into it, instead of returning an AlreadyExists error
+	MergeDuplicates bool
+
+	// Definitions looks up what a dictionary knows about words when they're
+	// added. It may be nil to not look words up.
+	Definitions definitions.Provider
+
+	// DefinitionsTimeout is how long looking up a word is given
+	DefinitionsTimeout time.Duration
+
+	// RotationMode controls how NextWord picks the scheduled word, one of
+	// random, shuffle-cycle, least-recently-sent or spaced-repetition
+	RotationMode string
+
+	// RotationTag, if set, makes NextWord only pick words with the tag
+	RotationTag string
+
+	// UnsubscribeURL is linked to from emails for users to stop them, with
+	// {username} replaced by the user's username. It may be empty to not link
+	// to anywhere.
+	UnsubscribeURL string
+
+	// Authenticator validates the credentials of requests handled directly by
+	// the gateway. It may be nil if authentication is disabled.
+	Authenticator *auth.Authenticator
 }
 
 func New(c Config) (*Server, error) {
+	rotationMode, err := db.ParseRotationMode(c.RotationMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.RotationTag != "" {
+		v := fieldViolations{}
+		validateTag(&v, "rotation tag", c.RotationTag)
+
+		if len(v) > 0 {
+			return nil, errors.Errorf("invalid rotation tag %q: %s", c.RotationTag, v[0].Description)
+		}
+	}
+
 	dbManager, err := db.New(db.Config{
 		Host:     c.DBHost,
 		Port:     c.DBPort,
 		Username: c.DBUsername,
 		Password: c.DBPassword,
 		Database: c.DBName,
+
+		MigrateOnStartup: c.DBMigrateOnStartup,
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to create db instance")
@@ -48,22 +171,112 @@ func New(c Config) (*Server, error) {
 	return &Server{
 		wordQuerier:  dbManager,
 		wordModifier: dbManager,
+
+		mergeDuplicates: c.MergeDuplicates,
+
+		definitionStore:    dbManager,
+		definitions:        c.Definitions,
+		definitionsTimeout: c.DefinitionsTimeout,
+
+		deliveryTracker: dbManager,
+		rotationMode:    rotationMode,
+		rotationTag:     c.RotationTag,
+
+		wordReviewer: dbManager,
+
+		wordTagger: dbManager,
+
+		wordImporter: dbManager,
+		wordExporter: dbManager,
+
+		outboxStore: dbManager,
+
+		channelStore: dbManager,
+
+		templateStore:          dbManager,
+		unsubscribeURLTemplate: c.UnsubscribeURL,
+
+		userQuerier: dbManager,
+
+		authenticator: c.Authenticator,
+
+		database: dbManager,
 	}, nil
 }
 
+// Close releases the Server's database connections. It must only be called
+// once nothing else is using the Server.
+func (s *Server) Close() {
+	if s.database != nil {
+		s.database.Close()
+	}
+}
+
+// PoolStats returns the state of the database connection pool
+func (s *Server) PoolStats() db.PoolStats {
+	if s.database == nil {
+		return db.PoolStats{}
+	}
+
+	return s.database.PoolStats()
+}
+
+// CountWords returns how many words each user has, keyed by username
+func (s *Server) CountWords(ctx context.Context) (map[string]int64, error) {
+	if s.database == nil {
+		return map[string]int64{}, nil
+	}
+
+	return s.database.CountWords(ctx)
+}
+
+// Ping checks the database is reachable
+func (s *Server) Ping(ctx context.Context) error {
+	if s.database == nil {
+		return nil
+	}
+
+	return s.database.Ping(ctx)
+}
+
+// Heartbeat returns an Unavailable error if the database can't be reached
 func (s *Server) Heartbeat(ctx context.Context, req *v1alpha1.HeartbeatRequest) (*v1alpha1.HeartbeatResponse, error) {
+	if err := s.Ping(ctx); err != nil {
+		return nil, status.Error(codes.Unavailable, "database unavailable")
+	}
+
 	return &v1alpha1.HeartbeatResponse{}, nil
 }
 
 func (s *Server) AddWord(ctx context.Context, req *v1alpha1.AddWordRequest) (*v1alpha1.AddWordResponse, error) {
-	rsp, err := s.wordModifier.InsertWord(ctx, db.Word{
+	if err := validateAddWordRequest(req); err != nil {
+		return nil, err
+	}
+
+	word := db.Word{
+		UserID:           s.userID(ctx),
 		Word:             req.GetWord().GetWord(),
 		CustomDefinition: req.GetWord().GetCustomDefinition(),
-	})
+	}
+
+	insert := s.wordModifier.InsertWord
+	if s.mergeDuplicates {
+		insert = s.wordModifier.MergeWord
+	}
+
+	rsp, err := insert(ctx, word)
+
+	var dup *db.DuplicateWordError
+	if errors.As(err, &dup) {
+		return nil, alreadyExistsError(dup.Existing)
+	}
+
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to add word")
+		return nil, statusError(err, "unable to add word")
 	}
 
+	s.lookupDefinitions(ctx, rsp)
+
 	return &v1alpha1.AddWordResponse{
 		Word: &v1alpha1.Word{
 			Id:               rsp.ID,
@@ -73,10 +286,12 @@ func (s *Server) AddWord(ctx context.Context, req *v1alpha1.AddWordRequest) (*v1
 	}, nil
 }
 
+// ListWords returns the caller's first defaultPageSize words. The request has no
+// page size or token, so the rest can only be listed with ListWordsPage.
 func (s *Server) ListWords(ctx context.Context, req *v1alpha1.ListWordsRequest) (*v1alpha1.ListWordsResponse, error) {
-	rsp, err := s.wordQuerier.ListWords(ctx)
+	rsp, err := s.wordQuerier.ListWords(ctx, s.userID(ctx), db.ListWordsOptions{Limit: defaultPageSize})
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to list words")
+		return nil, statusError(err, "unable to list words")
 	}
 
 	w := make([]*v1alpha1.Word, len(rsp))
@@ -94,9 +309,17 @@ func (s *Server) ListWords(ctx context.Context, req *v1alpha1.ListWordsRequest)
 }
 
 func (s *Server) DeleteWord(ctx context.Context, req *v1alpha1.DeleteWordRequest) (*v1alpha1.DeleteWordResponse, error) {
-	rsp, err := s.wordModifier.DeleteWord(ctx, req.GetId())
+	if err := validateDeleteWordRequest(req); err != nil {
+		return nil, err
+	}
+
+	rsp, err := s.wordModifier.DeleteWord(ctx, s.userID(ctx), req.GetId())
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "word %d not found", req.GetId())
+	}
+
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to delete word")
+		return nil, statusError(err, "unable to delete word")
 	}
 
 	return &v1alpha1.DeleteWordResponse{
@@ -109,29 +332,35 @@ func (s *Server) DeleteWord(ctx context.Context, req *v1alpha1.DeleteWordRequest
 }
 
 func (s *Server) RandomWord(ctx context.Context, req *v1alpha1.RandomWordRequest) (*v1alpha1.RandomWordResponse, error) {
-	rsp, err := s.wordQuerier.ListWords(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "unable to get words")
+	return s.RandomWordWithTag(ctx, "")
+}
+
+// RandomWordWithTag returns a random word with the tag, or any random word if
+// tag is empty. The response holds no word if there aren't any to pick from.
+func (s *Server) RandomWordWithTag(ctx context.Context, tag string) (*v1alpha1.RandomWordResponse, error) {
+	if tag != "" {
+		v := fieldViolations{}
+		validateTag(&v, "tag", tag)
+
+		if err := v.err(); err != nil {
+			return nil, err
+		}
 	}
 
-	if len(rsp) == 0 {
+	rsp, err := s.wordQuerier.RandomWord(ctx, s.userID(ctx), tag)
+	if errors.Is(err, db.ErrNotFound) {
 		return &v1alpha1.RandomWordResponse{}, nil
 	}
 
-	i, err := getRandNumber(0, int64(len(rsp)))
 	if err != nil {
-		return nil, err
+		return nil, statusError(err, "unable to get random word")
 	}
 
 	return &v1alpha1.RandomWordResponse{
 		Word: &v1alpha1.Word{
-			Id:               rsp[i.Int64()].ID,
-			Word:             rsp[i.Int64()].Word,
-			CustomDefinition: rsp[i.Int64()].CustomDefinition,
+			Id:               rsp.ID,
+			Word:             rsp.Word,
+			CustomDefinition: rsp.CustomDefinition,
 		},
 	}, nil
 }
-
-func getRandNumber(min int, max int64) (*big.Int, error) {
-	return rand.Int(rand.Reader, big.NewInt(max))
-}
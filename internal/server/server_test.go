@@ -6,13 +6,17 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/mywordoftheday/backend/internal/db"
 	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
 )
 
 func TestHeartbeat(t *testing.T) {
-	s := Server{}
+	dm := &databaseMock{}
+	s := Server{database: dm}
 
 	t.Run("Given an initialised Server", func(t *testing.T) {
 		t.Run("When a request is made to Heartbeat", func(t *testing.T) {
@@ -22,6 +26,29 @@ func TestHeartbeat(t *testing.T) {
 				assert.Equal(t, &v1alpha1.HeartbeatResponse{}, r)
 			})
 		})
+		t.Run("When the database can't be reached", func(t *testing.T) {
+			t.Run("Then an Unavailable error is returned", func(t *testing.T) {
+				dm.pingErr = errors.New("connection refused")
+
+				r, err := s.Heartbeat(context.Background(), &v1alpha1.HeartbeatRequest{})
+				assert.Nil(t, r)
+				assertStatusError(t, err, codes.Unavailable, "database unavailable")
+			})
+		})
+	})
+}
+
+func TestClose(t *testing.T) {
+	t.Run("Given an initialised Server", func(t *testing.T) {
+		t.Run("When it is closed", func(t *testing.T) {
+			t.Run("Then the database is closed", func(t *testing.T) {
+				dm := &databaseMock{}
+				s := Server{database: dm}
+
+				s.Close()
+				assert.True(t, dm.closed)
+			})
+		})
 	})
 }
 
@@ -30,12 +57,32 @@ func TestAddWord(t *testing.T) {
 	s := Server{wordModifier: wm}
 
 	t.Run("Given a request to AddWord", func(t *testing.T) {
+		t.Run("When the request is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error with the field violations is returned", func(t *testing.T) {
+				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: " ", CustomDefinition: "bell\a"}})
+				assert.Nil(t, r)
+
+				st := status.Convert(err)
+				assert.Equal(t, codes.InvalidArgument, st.Code())
+
+				if assert.Len(t, st.Details(), 1) {
+					br, ok := st.Details()[0].(*errdetails.BadRequest)
+					assert.True(t, ok)
+
+					fields := []string{}
+					for _, fv := range br.GetFieldViolations() {
+						fields = append(fields, fv.GetField())
+					}
+					assert.Equal(t, []string{"word.word", "word.custom_definition"}, fields)
+				}
+			})
+		})
 		t.Run("When an error is returned", func(t *testing.T) {
 			t.Run("Then the error is returned to the caller", func(t *testing.T) {
 				wm.err = errors.New("an error")
 
-				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{})
-				assert.EqualError(t, err, "unable to add word: an error")
+				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: "floccinaucinihilipilification"}})
+				assertStatusError(t, err, codes.Internal, "unable to add word")
 				assert.Nil(t, r)
 			})
 		})
@@ -44,7 +91,7 @@ func TestAddWord(t *testing.T) {
 				wm.err = nil
 				wm.insertWordResponse = db.Word{ID: 45, Word: "floccinaucinihilipilification", CustomDefinition: "estimation of worthlessness"}
 
-				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{})
+				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: "floccinaucinihilipilification"}})
 				assert.NoError(t, err)
 
 				assert.Equal(t, wm.insertWordResponse.ID, r.Word.Id)
@@ -52,6 +99,40 @@ func TestAddWord(t *testing.T) {
 				assert.Equal(t, wm.insertWordResponse.CustomDefinition, r.Word.CustomDefinition)
 			})
 		})
+		t.Run("When the word already exists", func(t *testing.T) {
+			t.Run("Then an AlreadyExists error with the existing word's id is returned", func(t *testing.T) {
+				wm.err = &db.DuplicateWordError{Existing: db.Word{ID: 45, Word: "floccinaucinihilipilification"}}
+
+				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: "floccinaucinihilipilification"}})
+				assert.Nil(t, r)
+
+				st := status.Convert(err)
+				assert.Equal(t, codes.AlreadyExists, st.Code())
+
+				if assert.Len(t, st.Details(), 1) {
+					info, ok := st.Details()[0].(*errdetails.ResourceInfo)
+					assert.True(t, ok)
+					assert.Equal(t, "45", info.GetResourceName())
+				}
+			})
+		})
+	})
+
+	t.Run("Given a Server that merges duplicates", func(t *testing.T) {
+		wm := &wordMock{}
+		s := Server{wordModifier: wm, mergeDuplicates: true}
+
+		t.Run("When a request is made to AddWord", func(t *testing.T) {
+			t.Run("Then the word is merged and the merged Word is returned", func(t *testing.T) {
+				wm.mergeWordResponse = db.Word{ID: 45, Word: "sonder", CustomDefinition: "first; second"}
+
+				r, err := s.AddWord(context.Background(), &v1alpha1.AddWordRequest{Word: &v1alpha1.Word{Word: "Sonder", CustomDefinition: "second"}})
+				assert.NoError(t, err)
+
+				assert.True(t, wm.merged)
+				assert.Equal(t, wm.mergeWordResponse.CustomDefinition, r.Word.CustomDefinition)
+			})
+		})
 	})
 }
 
@@ -65,7 +146,7 @@ func TestListWord(t *testing.T) {
 				wm.err = errors.New("an error")
 
 				r, err := s.ListWords(context.Background(), &v1alpha1.ListWordsRequest{})
-				assert.EqualError(t, err, "unable to list words: an error")
+				assertStatusError(t, err, codes.Internal, "unable to list words")
 				assert.Nil(t, r)
 			})
 		})
@@ -77,6 +158,7 @@ func TestListWord(t *testing.T) {
 				r, err := s.ListWords(context.Background(), &v1alpha1.ListWordsRequest{})
 				assert.NoError(t, err)
 				assert.Len(t, r.Words, 2)
+				assert.Equal(t, int32(defaultPageSize), wm.lastListOptions.Limit)
 
 				expected := make(map[int32]db.Word)
 				for _, e := range wm.listWordsResponse {
@@ -105,8 +187,17 @@ func TestDeleteWord(t *testing.T) {
 			t.Run("Then the error is returned to the caller", func(t *testing.T) {
 				fm.err = errors.New("an error")
 
-				r, err := s.DeleteWord(context.Background(), &v1alpha1.DeleteWordRequest{})
-				assert.EqualError(t, err, "unable to delete word: an error")
+				r, err := s.DeleteWord(context.Background(), &v1alpha1.DeleteWordRequest{Id: 45})
+				assertStatusError(t, err, codes.Internal, "unable to delete word")
+				assert.Nil(t, r)
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				fm.err = db.ErrNotFound
+
+				r, err := s.DeleteWord(context.Background(), &v1alpha1.DeleteWordRequest{Id: 45})
+				assert.Equal(t, codes.NotFound, status.Code(err))
 				assert.Nil(t, r)
 			})
 		})
@@ -115,7 +206,7 @@ func TestDeleteWord(t *testing.T) {
 				fm.err = nil
 				fm.deleteWordResponse = db.Word{ID: 45, Word: "a word", CustomDefinition: "a definition goes here"}
 
-				r, err := s.DeleteWord(context.Background(), &v1alpha1.DeleteWordRequest{})
+				r, err := s.DeleteWord(context.Background(), &v1alpha1.DeleteWordRequest{Id: 45})
 				assert.NoError(t, err)
 
 				assert.Equal(t, fm.deleteWordResponse.ID, r.Word.Id)
@@ -130,29 +221,54 @@ func TestRandomWord(t *testing.T) {
 	wm := &wordMock{}
 	s := Server{wordQuerier: wm}
 
-	t.Run("Given a request to ListWords", func(t *testing.T) {
+	t.Run("Given a request to RandomWord", func(t *testing.T) {
 		t.Run("When an error is returned", func(t *testing.T) {
 			t.Run("Then the error is returned to the caller", func(t *testing.T) {
 				wm.err = errors.New("an error")
 
 				r, err := s.RandomWord(context.Background(), &v1alpha1.RandomWordRequest{})
-				assert.EqualError(t, err, "unable to get words: an error")
+				assertStatusError(t, err, codes.Internal, "unable to get random word")
 				assert.Nil(t, r)
 			})
 		})
+		t.Run("When there are no words", func(t *testing.T) {
+			t.Run("Then an empty response is returned", func(t *testing.T) {
+				wm.err = db.ErrNotFound
+
+				r, err := s.RandomWord(context.Background(), &v1alpha1.RandomWordRequest{})
+				assert.NoError(t, err)
+				assert.Nil(t, r.GetWord())
+			})
+		})
 		t.Run("When no error is returned", func(t *testing.T) {
 			t.Run("Then a random word is returned", func(t *testing.T) {
 				wm.err = nil
-				wm.listWordsResponse = []db.Word{{ID: 45, Word: "word1"}, {ID: 1, Word: "word2", CustomDefinition: "a custom definition here"}}
+				wm.randomWordResponse = db.Word{ID: 1, Word: "word2", CustomDefinition: "a custom definition here"}
 
 				r, err := s.RandomWord(context.Background(), &v1alpha1.RandomWordRequest{})
 				assert.NoError(t, err)
 
-				isValidID := r.GetWord().GetId() == 1 || r.GetWord().GetId() == 45
-				isValidWord := r.GetWord().GetWord() == "word1" || r.GetWord().GetWord() == "word2"
+				assert.Equal(t, wm.randomWordResponse.ID, r.GetWord().GetId())
+				assert.Equal(t, wm.randomWordResponse.Word, r.GetWord().GetWord())
+				assert.Equal(t, wm.randomWordResponse.CustomDefinition, r.GetWord().GetCustomDefinition())
+				assert.Empty(t, wm.lastRandomTag)
+			})
+		})
+	})
 
-				assert.True(t, isValidID)
-				assert.True(t, isValidWord)
+	t.Run("Given a request for a random word with a tag", func(t *testing.T) {
+		t.Run("When the tag is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.RandomWordWithTag(context.Background(), "legal/GRE")
+				assertStatusError(t, err, codes.InvalidArgument, `invalid request: tag must not contain '/'`)
+			})
+		})
+		t.Run("When the tag is valid", func(t *testing.T) {
+			t.Run("Then the word is picked from those with the tag", func(t *testing.T) {
+				r, err := s.RandomWordWithTag(context.Background(), "GRE")
+				assert.NoError(t, err)
+				assert.Equal(t, wm.randomWordResponse.ID, r.GetWord().GetId())
+				assert.Equal(t, "GRE", wm.lastRandomTag)
 			})
 		})
 	})
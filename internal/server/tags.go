@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+// TagWord gives the word with the given id the tag, creating the tag if it's
+// new. The tag is returned with the number of words that now have it.
+func (s *Server) TagWord(ctx context.Context, id int32, tag string) (db.Tag, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+	validateTag(&v, "tag", tag)
+
+	if err := v.err(); err != nil {
+		return db.Tag{}, err
+	}
+
+	rsp, err := s.wordTagger.TagWord(ctx, s.userID(ctx), id, strings.TrimSpace(tag))
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Tag{}, status.Errorf(codes.NotFound, "word %d not found", id)
+	}
+
+	if err != nil {
+		return db.Tag{}, statusError(err, "unable to tag word")
+	}
+
+	return rsp, nil
+}
+
+// UntagWord removes the tag from the word with the given id, returning a
+// NotFound error if the word doesn't have it
+func (s *Server) UntagWord(ctx context.Context, id int32, tag string) error {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+	validateTag(&v, "tag", tag)
+
+	if err := v.err(); err != nil {
+		return err
+	}
+
+	err := s.wordTagger.UntagWord(ctx, s.userID(ctx), id, tag)
+	if errors.Is(err, db.ErrNotFound) {
+		return status.Errorf(codes.NotFound, "word %d with tag %q not found", id, tag)
+	}
+
+	if err != nil {
+		return statusError(err, "unable to untag word")
+	}
+
+	return nil
+}
+
+// DeleteTag removes the tag from every word and deletes it
+func (s *Server) DeleteTag(ctx context.Context, tag string) (db.Tag, error) {
+	v := fieldViolations{}
+	validateTag(&v, "tag", tag)
+
+	if err := v.err(); err != nil {
+		return db.Tag{}, err
+	}
+
+	rsp, err := s.wordTagger.DeleteTag(ctx, s.userID(ctx), tag)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Tag{}, status.Errorf(codes.NotFound, "tag %q not found", tag)
+	}
+
+	if err != nil {
+		return db.Tag{}, statusError(err, "unable to delete tag")
+	}
+
+	return rsp, nil
+}
+
+// ListTags returns the caller's tags in alphabetical order
+func (s *Server) ListTags(ctx context.Context) ([]db.Tag, error) {
+	rsp, err := s.wordTagger.ListTags(ctx, s.userID(ctx))
+	if err != nil {
+		return nil, statusError(err, "unable to list tags")
+	}
+
+	return rsp, nil
+}
+
+// WordTags returns the tags of the word with the given id, or a NotFound error
+func (s *Server) WordTags(ctx context.Context, id int32) ([]db.Tag, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+
+	rsp, err := s.wordTagger.WordTags(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "word %d not found", id)
+	}
+
+	if err != nil {
+		return nil, statusError(err, "unable to get tags")
+	}
+
+	return rsp, nil
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+func TestTagWord(t *testing.T) {
+	tm := &tagMock{}
+	s := Server{wordTagger: tm}
+
+	t.Run("Given a request to tag a word", func(t *testing.T) {
+		t.Run("When the id and tag are invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error lists both", func(t *testing.T) {
+				_, err := s.TagWord(context.Background(), 0, " ")
+				assertStatusError(t, err, codes.InvalidArgument, "invalid request: id must be a positive id, tag is required")
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				tm.err = db.ErrNotFound
+
+				_, err := s.TagWord(context.Background(), 45, "GRE")
+				assertStatusError(t, err, codes.NotFound, "word 45 not found")
+			})
+		})
+		t.Run("When the word exists", func(t *testing.T) {
+			t.Run("Then the trimmed tag is added to it", func(t *testing.T) {
+				tm.err = nil
+				tm.tagWordResponse = db.Tag{ID: 3, Name: "GRE", WordCount: 12}
+
+				tag, err := s.TagWord(context.Background(), 45, " GRE ")
+				assert.NoError(t, err)
+				assert.Equal(t, tm.tagWordResponse, tag)
+				assert.Equal(t, int32(45), tm.taggedID)
+				assert.Equal(t, "GRE", tm.tag)
+			})
+		})
+	})
+}
+
+func TestUntagWord(t *testing.T) {
+	tm := &tagMock{}
+	s := Server{wordTagger: tm}
+
+	t.Run("Given a request to untag a word", func(t *testing.T) {
+		t.Run("When the word doesn't have the tag", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				tm.err = db.ErrNotFound
+
+				err := s.UntagWord(context.Background(), 45, "GRE")
+				assertStatusError(t, err, codes.NotFound, `word 45 with tag "GRE" not found`)
+			})
+		})
+		t.Run("When the word has the tag", func(t *testing.T) {
+			t.Run("Then it is removed", func(t *testing.T) {
+				tm.err = nil
+
+				assert.NoError(t, s.UntagWord(context.Background(), 45, "GRE"))
+				assert.Equal(t, int32(45), tm.taggedID)
+			})
+		})
+	})
+}
+
+func TestDeleteTag(t *testing.T) {
+	tm := &tagMock{}
+	s := Server{wordTagger: tm}
+
+	t.Run("Given a request to delete a tag", func(t *testing.T) {
+		t.Run("When the tag does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				tm.err = db.ErrNotFound
+
+				_, err := s.DeleteTag(context.Background(), "GRE")
+				assertStatusError(t, err, codes.NotFound, `tag "GRE" not found`)
+			})
+		})
+	})
+}
+
+func TestListTags(t *testing.T) {
+	tm := &tagMock{}
+	s := Server{wordTagger: tm}
+
+	t.Run("Given a request to list tags", func(t *testing.T) {
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				tm.err = errors.New("an error")
+
+				_, err := s.ListTags(context.Background())
+				assertStatusError(t, err, codes.Internal, "unable to list tags")
+			})
+		})
+		t.Run("When no error is returned", func(t *testing.T) {
+			t.Run("Then the tags are returned", func(t *testing.T) {
+				tm.err = nil
+				tm.listTagsResponse = []db.Tag{{ID: 1, Name: "GRE", WordCount: 2}, {ID: 2, Name: "legal"}}
+
+				tags, err := s.ListTags(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, tm.listTagsResponse, tags)
+			})
+		})
+	})
+}
+
+func TestWordTags(t *testing.T) {
+	tm := &tagMock{}
+	s := Server{wordTagger: tm}
+
+	t.Run("Given a request for a word's tags", func(t *testing.T) {
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				tm.err = db.ErrNotFound
+
+				_, err := s.WordTags(context.Background(), 45)
+				assertStatusError(t, err, codes.NotFound, "word 45 not found")
+			})
+		})
+	})
+}
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+	"github.com/mywordoftheday/backend/internal/mail"
+)
+
+type templateStore interface {
+	InsertTemplate(context.Context, db.Template) (db.Template, error)
+	GetTemplate(context.Context, int32, int32) (db.Template, error)
+	ActiveTemplate(context.Context, int32) (db.Template, error)
+	ListTemplates(context.Context, int32) ([]db.Template, error)
+	ActivateTemplate(context.Context, int32, int32) (db.Template, error)
+	DeactivateTemplates(context.Context, int32) error
+	DeleteTemplate(context.Context, int32, int32) (db.Template, error)
+}
+
+const (
+	// maxTemplateNameLength is the size of the templates table's name column,
+	// in characters
+	maxTemplateNameLength = 100
+	// maxEmailSenses is the most senses included in an email
+	maxEmailSenses = 5
+)
+
+// TemplatePreview is an email as it would be rendered from a template
+type TemplatePreview struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// CreateTemplate adds an inactive template for the caller's emails. Both parts
+// must parse and pass mail.Templates.Validate.
+func (s *Server) CreateTemplate(ctx context.Context, t db.Template) (db.Template, error) {
+	t.Name = strings.TrimSpace(t.Name)
+
+	v := fieldViolations{}
+
+	switch {
+	case t.Name == "":
+		v.add("name", "must not be empty")
+	case utf8.RuneCountInString(t.Name) > maxTemplateNameLength:
+		v.add("name", "must be at most %d characters", maxTemplateNameLength)
+	}
+
+	if strings.TrimSpace(t.HTML) == "" {
+		v.add("html", "must not be empty")
+	}
+
+	if strings.TrimSpace(t.Text) == "" {
+		v.add("text", "must not be empty")
+	}
+
+	if len(v) == 0 {
+		if _, err := parseTemplate(t); err != nil {
+			addTemplateViolation(&v, err)
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return db.Template{}, err
+	}
+
+	t.UserID = s.userID(ctx)
+
+	rsp, err := s.templateStore.InsertTemplate(ctx, t)
+	if errors.Is(err, db.ErrAlreadyExists) {
+		return db.Template{}, status.Errorf(codes.AlreadyExists, "template %q already exists", t.Name)
+	}
+
+	if err != nil {
+		return db.Template{}, statusError(err, "unable to create template")
+	}
+
+	return rsp, nil
+}
+
+// GetTemplate returns the caller's template with the id
+func (s *Server) GetTemplate(ctx context.Context, id int32) (db.Template, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return db.Template{}, err
+	}
+
+	rsp, err := s.templateStore.GetTemplate(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Template{}, status.Errorf(codes.NotFound, "template %d not found", id)
+	}
+
+	if err != nil {
+		return db.Template{}, statusError(err, "unable to get template")
+	}
+
+	return rsp, nil
+}
+
+// ListTemplates returns the caller's templates in alphabetical order
+func (s *Server) ListTemplates(ctx context.Context) ([]db.Template, error) {
+	rsp, err := s.templateStore.ListTemplates(ctx, s.userID(ctx))
+	if err != nil {
+		return nil, statusError(err, "unable to list templates")
+	}
+
+	return rsp, nil
+}
+
+// DeleteTemplate deletes the caller's template with the id. If it was active
+// their emails are rendered from the default template again.
+func (s *Server) DeleteTemplate(ctx context.Context, id int32) (db.Template, error) {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if err := v.err(); err != nil {
+		return db.Template{}, err
+	}
+
+	rsp, err := s.templateStore.DeleteTemplate(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Template{}, status.Errorf(codes.NotFound, "template %d not found", id)
+	}
+
+	if err != nil {
+		return db.Template{}, statusError(err, "unable to delete template")
+	}
+
+	return rsp, nil
+}
+
+// PreviewTemplate renders the caller's template with the id as it would be
+// emailed with their word with wordID, or with mail.SampleTemplateData if
+// wordID is 0
+func (s *Server) PreviewTemplate(ctx context.Context, id int32, wordID int32) (TemplatePreview, error) {
+	if wordID < 0 {
+		return TemplatePreview{}, invalidField("word_id", "must be a positive id")
+	}
+
+	t, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return TemplatePreview{}, err
+	}
+
+	data := mail.SampleTemplateData()
+	if wordID != 0 {
+		if data, err = s.TemplateData(ctx, wordID); err != nil {
+			return TemplatePreview{}, err
+		}
+	}
+
+	tmpl, err := parseTemplate(t)
+	if err != nil {
+		return TemplatePreview{}, status.Errorf(codes.FailedPrecondition, "template %d doesn't parse/validate: %s", id, err)
+	}
+
+	html, text, err := tmpl.Render(data)
+	if err != nil {
+		return TemplatePreview{}, status.Errorf(codes.FailedPrecondition, "template %d doesn't render: %s", id, err)
+	}
+
+	return TemplatePreview{Subject: data.Subject(), HTML: html, Text: text}, nil
+}
+
+// ActivateTemplate makes the caller's template with the id the one their
+// emails are rendered from, once it's checked that it still parses and
+// validates
+func (s *Server) ActivateTemplate(ctx context.Context, id int32) (db.Template, error) {
+	t, err := s.GetTemplate(ctx, id)
+	if err != nil {
+		return db.Template{}, err
+	}
+
+	if _, err := parseTemplate(t); err != nil {
+		return db.Template{}, status.Errorf(codes.FailedPrecondition, "template %d doesn't parse/validate: %s", id, err)
+	}
+
+	rsp, err := s.templateStore.ActivateTemplate(ctx, s.userID(ctx), id)
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Template{}, status.Errorf(codes.NotFound, "template %d not found", id)
+	}
+
+	if err != nil {
+		return db.Template{}, statusError(err, "unable to activate template")
+	}
+
+	return rsp, nil
+}
+
+// DeactivateTemplate makes the caller's emails be rendered from the default
+// template again
+func (s *Server) DeactivateTemplate(ctx context.Context) error {
+	if err := s.templateStore.DeactivateTemplates(ctx, s.userID(ctx)); err != nil {
+		return statusError(err, "unable to deactivate template")
+	}
+
+	return nil
+}
+
+// ActiveTemplate returns the templates the caller's emails are rendered from,
+// or nil if they use the default
+func (s *Server) ActiveTemplate(ctx context.Context) (*mail.Templates, error) {
+	t, err := s.templateStore.ActiveTemplate(ctx, s.userID(ctx))
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, statusError(err, "unable to get active template")
+	}
+
+	tmpl, err := parseTemplate(t)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "template %d doesn't parse/validate: %s", t.ID, err)
+	}
+
+	return tmpl, nil
+}
+
+// TemplateData returns what the caller's email of the word with the id is
+// rendered with, dated now
+func (s *Server) TemplateData(ctx context.Context, wordID int32) (mail.TemplateData, error) {
+	v := fieldViolations{}
+	validateID(&v, "word_id", wordID)
+
+	if err := v.err(); err != nil {
+		return mail.TemplateData{}, err
+	}
+
+	w, err := s.wordQuerier.GetWord(ctx, s.userID(ctx), wordID)
+	if errors.Is(err, db.ErrNotFound) {
+		return mail.TemplateData{}, status.Errorf(codes.NotFound, "word %d not found", wordID)
+	}
+
+	if err != nil {
+		return mail.TemplateData{}, statusError(err, "unable to get word")
+	}
+
+	data := mail.TemplateData{
+		Word:           w.Word,
+		Definition:     w.CustomDefinition,
+		Date:           time.Now(),
+		UnsubscribeURL: s.unsubscribeURL(ctx),
+	}
+
+	// The email is still worth sending with just the custom definition, and
+	// without the streak
+	if d, err := s.Definitions(ctx, wordID); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"id":    wordID,
+		}).Error("Error getting definitions")
+	} else {
+		addDefinitions(&data, d)
+	}
+
+	if data.Streak, err = s.deliveryTracker.DeliveryStreak(ctx, s.userID(ctx)); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error getting delivery streak")
+	}
+
+	return data, nil
+}
+
+// unsubscribeURL returns the configured unsubscribe URL with {username}
+// replaced by the caller's username
+func (s *Server) unsubscribeURL(ctx context.Context) string {
+	if s.unsubscribeURLTemplate == "" {
+		return ""
+	}
+
+	username := db.DefaultUsername
+	if u, ok := identity.FromContext(ctx); ok {
+		username = u.Username
+	}
+
+	return strings.ReplaceAll(s.unsubscribeURLTemplate, "{username}", url.QueryEscape(username))
+}
+
+// addDefinitions adds the pronunciation and senses from the dictionary to
+// data, leaving the dictionary's senses out if there's a custom definition
+func addDefinitions(data *mail.TemplateData, d db.Definitions) {
+	for _, p := range d.Pronunciations {
+		if p.IPA != "" {
+			data.Pronunciation = p.IPA
+			break
+		}
+	}
+
+	for _, sense := range d.Senses {
+		if data.Pronunciation == "" {
+			data.Pronunciation = sense.IPA
+		}
+
+		if sense.Source != "" && data.Definition != "" {
+			continue
+		}
+
+		if len(data.Senses) < maxEmailSenses {
+			data.Senses = append(data.Senses, mail.Sense{
+				PartOfSpeech: sense.PartOfSpeech,
+				Definition:   sense.Definition,
+				IPA:          sense.IPA,
+				Examples:     sense.Examples,
+				Synonyms:     sense.Synonyms,
+				Antonyms:     sense.Antonyms,
+				Etymology:    sense.Etymology,
+			})
+			data.Examples = append(data.Examples, sense.Examples...)
+		}
+	}
+}
+
+// parseTemplate parses t and checks it with mail.Templates.Validate
+func parseTemplate(t db.Template) (*mail.Templates, error) {
+	tmpl, err := mail.ParseTemplates(t.HTML, t.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmpl.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tmpl, nil
+}
+
+// addTemplateViolation reports err against the part of the template it's
+// about
+func addTemplateViolation(v *fieldViolations, err error) {
+	var tErr *mail.TemplateError
+	if errors.As(err, &tErr) {
+		v.add(tErr.Part, "%s", tErr.Err)
+		return
+	}
+
+	v.add("template", "%s", err)
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+	"github.com/mywordoftheday/backend/internal/mail"
+)
+
+func TestCreateTemplate(t *testing.T) {
+	tm := &templateMock{}
+	s := Server{templateStore: tm}
+
+	testCases := []struct {
+		desc         string
+		template     db.Template
+		err          error
+		expectedName string
+		expectedErr  string
+		code         codes.Code
+	}{
+		{desc: "A template should be created without space around its name", template: db.Template{Name: " plain ", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}, expectedName: "plain"},
+		{desc: "An empty name should be rejected", template: db.Template{Name: " ", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}, code: codes.InvalidArgument, expectedErr: "invalid request: name must not be empty"},
+		{desc: "A long name should be rejected", template: db.Template{Name: strings.Repeat("a", 101), HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}, code: codes.InvalidArgument, expectedErr: "invalid request: name must be at most 100 characters"},
+		{desc: "Empty templates should be rejected", template: db.Template{Name: "plain"}, code: codes.InvalidArgument, expectedErr: "invalid request: html must not be empty, text must not be empty"},
+		{desc: "An HTML template that doesn't parse should be rejected", template: db.Template{Name: "plain", HTML: "<p>{{.Word</p>", Text: "{{.Word}}"}, code: codes.InvalidArgument, expectedErr: "invalid request: html template: html:1: bad character U+003C '<'"},
+		{desc: "A text template using a field that doesn't exist should be rejected", template: db.Template{Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Language}}"}, code: codes.InvalidArgument, expectedErr: `invalid request: text template: text:1:2: executing "text" at <.Language>: can't evaluate field Language in type mail.TemplateData`},
+		{desc: "A duplicate name should be rejected", template: db.Template{Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}, err: db.ErrAlreadyExists, code: codes.AlreadyExists, expectedErr: `template "plain" already exists`},
+		{desc: "A database error should be returned", template: db.Template{Name: "plain", HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}, err: errors.New("an error"), code: codes.Internal, expectedErr: "unable to create template"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tm.err = tC.err
+			tm.inserted = db.Template{}
+			tm.insertResponse = db.Template{ID: 1, Name: tC.expectedName}
+
+			rsp, err := s.CreateTemplate(context.Background(), tC.template)
+			if tC.expectedErr != "" {
+				assertStatusError(t, err, tC.code, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expectedName, tm.inserted.Name)
+			assert.Equal(t, db.DefaultUserID, tm.inserted.UserID)
+			assert.Equal(t, tm.insertResponse, rsp)
+		})
+	}
+}
+
+func TestActivateTemplate(t *testing.T) {
+	tm := &templateMock{}
+	s := Server{templateStore: tm}
+
+	t.Run("Given a template that doesn't exist", func(t *testing.T) {
+		t.Run("Then a NotFound error is returned", func(t *testing.T) {
+			tm.err = db.ErrNotFound
+
+			_, err := s.ActivateTemplate(context.Background(), 3)
+			assertStatusError(t, err, codes.NotFound, "template 3 not found")
+		})
+	})
+
+	t.Run("Given a template that no longer renders", func(t *testing.T) {
+		t.Run("Then a FailedPrecondition error is returned and it isn't activated", func(t *testing.T) {
+			tm.err = nil
+			tm.activatedID = 0
+			tm.getResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Language}}"}
+
+			_, err := s.ActivateTemplate(context.Background(), 3)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "template 3 doesn't parse/validate: text template:")
+			}
+			assert.Zero(t, tm.activatedID)
+		})
+	})
+
+	t.Run("Given a template that only renders for words with senses", func(t *testing.T) {
+		t.Run("Then a FailedPrecondition error is returned and it isn't activated", func(t *testing.T) {
+			tm.err = nil
+			tm.activatedID = 0
+			tm.getResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{(index .Senses 0).Definition}}"}
+
+			_, err := s.ActivateTemplate(context.Background(), 3)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "template 3 doesn't parse/validate: text template:")
+			}
+			assert.Zero(t, tm.activatedID)
+		})
+	})
+
+	t.Run("Given a template that renders", func(t *testing.T) {
+		t.Run("Then it is activated", func(t *testing.T) {
+			tm.err = nil
+			tm.getResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}"}
+			tm.activateResponse = db.Template{ID: 3, Active: true}
+
+			rsp, err := s.ActivateTemplate(context.Background(), 3)
+			assert.NoError(t, err)
+			assert.Equal(t, int32(3), tm.activatedID)
+			assert.Equal(t, tm.activateResponse, rsp)
+		})
+	})
+}
+
+func TestActiveTemplate(t *testing.T) {
+	tm := &templateMock{}
+	s := Server{templateStore: tm}
+
+	t.Run("Given a user without an active template", func(t *testing.T) {
+		t.Run("Then nil is returned so the default is used", func(t *testing.T) {
+			tm.err = db.ErrNotFound
+
+			tmpl, err := s.ActiveTemplate(context.Background())
+			assert.NoError(t, err)
+			assert.Nil(t, tmpl)
+		})
+	})
+
+	t.Run("Given a user with an active template", func(t *testing.T) {
+		t.Run("Then it is parsed", func(t *testing.T) {
+			tm.err = nil
+			tm.activeResponse = db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Word}}!", Active: true}
+
+			tmpl, err := s.ActiveTemplate(context.Background())
+			assert.NoError(t, err)
+
+			if assert.NotNil(t, tmpl) {
+				_, text, err := tmpl.Render(mail.TemplateData{Word: "sonder"})
+				assert.NoError(t, err)
+				assert.Equal(t, "sonder!", text)
+			}
+		})
+	})
+}
+
+func TestPreviewTemplate(t *testing.T) {
+	tm := &templateMock{getResponse: db.Template{ID: 3, HTML: "<p>{{.Word}}</p>", Text: "{{.Word}} {{.Streak}}"}}
+	wm := &wordMock{getWordResponse: db.Word{ID: 5, Word: "petrichor", CustomDefinition: "The smell of rain"}}
+	s := Server{
+		templateStore:   tm,
+		wordQuerier:     wm,
+		definitionStore: &definitionMock{},
+		deliveryTracker: &deliveryMock{streak: 2},
+	}
+
+	t.Run("Given no word", func(t *testing.T) {
+		t.Run("Then the template is rendered with the sample data", func(t *testing.T) {
+			p, err := s.PreviewTemplate(context.Background(), 3, 0)
+			assert.NoError(t, err)
+			assert.Equal(t, TemplatePreview{Subject: "My Word Of The Day: sonder", HTML: "<p>sonder</p>", Text: "sonder 7"}, p)
+		})
+	})
+
+	t.Run("Given a word", func(t *testing.T) {
+		t.Run("Then the template is rendered as it would be emailed", func(t *testing.T) {
+			p, err := s.PreviewTemplate(context.Background(), 3, 5)
+			assert.NoError(t, err)
+			assert.Equal(t, TemplatePreview{Subject: "My Word Of The Day: petrichor", HTML: "<p>petrichor</p>", Text: "petrichor 2"}, p)
+		})
+	})
+
+	t.Run("Given a negative word id", func(t *testing.T) {
+		t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+			_, err := s.PreviewTemplate(context.Background(), 3, -1)
+			assertStatusError(t, err, codes.InvalidArgument, "invalid request: word_id must be a positive id")
+		})
+	})
+}
+
+func TestTemplateData(t *testing.T) {
+	wm := &wordMock{getWordResponse: db.Word{ID: 5, Word: "lead", CustomDefinition: "To go first"}}
+	dm := &definitionMock{getDefinitionsResponse: db.Definitions{
+		Senses: []db.Sense{
+			{PartOfSpeech: "verb", Definition: "To guide", Examples: []string{"Lead the way"}, IPA: "/liːd/"},
+			{Source: "wiktionary", PartOfSpeech: "noun", Definition: "A heavy metal", IPA: "/lɛd/"},
+		},
+	}}
+	s := Server{
+		wordQuerier:            wm,
+		definitionStore:        dm,
+		deliveryTracker:        &deliveryMock{streak: 4},
+		unsubscribeURLTemplate: "https://example.com/unsubscribe?user={username}",
+	}
+
+	t.Run("Given a word with a custom definition", func(t *testing.T) {
+		ctx := identity.NewContext(context.Background(), identity.User{ID: 2, Username: "ada lovelace"})
+
+		data, err := s.TemplateData(ctx, 5)
+
+		t.Run("Then only the senses written by hand are included", func(t *testing.T) {
+			assert.NoError(t, err)
+			assert.Equal(t, "lead", data.Word)
+			assert.Equal(t, "To go first", data.Definition)
+			assert.Equal(t, "/liːd/", data.Pronunciation)
+			assert.Equal(t, []mail.Sense{{PartOfSpeech: "verb", Definition: "To guide", Examples: []string{"Lead the way"}, IPA: "/liːd/"}}, data.Senses)
+			assert.Equal(t, []string{"Lead the way"}, data.Examples)
+		})
+
+		t.Run("Then the streak and unsubscribe URL are the user's", func(t *testing.T) {
+			assert.Equal(t, 4, data.Streak)
+			assert.Equal(t, "https://example.com/unsubscribe?user=ada+lovelace", data.UnsubscribeURL)
+			assert.False(t, data.Date.IsZero())
+		})
+	})
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+// UpdateWordRequest is a partial update to a word
+type UpdateWordRequest struct {
+	// Word holds the id of the word to update and the new field values
+	Word *v1alpha1.Word
+	// UpdateMask lists the fields of Word to update, using their proto names.
+	// If it is empty, every mutable field is updated.
+	UpdateMask *fieldmaskpb.FieldMask
+	// Etag, if set, must match the word's current etag for the update to be applied
+	Etag string
+}
+
+type UpdateWordResponse struct {
+	Word *v1alpha1.Word
+	Etag string
+}
+
+// updatableWordFields are the Word fields that can be set by UpdateWord
+var updatableWordFields = []string{"word", "custom_definition"}
+
+// Etag returns the etag for the given version of a word
+func Etag(version int32) string {
+	return strconv.Quote(strconv.Itoa(int(version)))
+}
+
+// parseEtag returns the version held in etag, accepting weak etags
+func parseEtag(etag string) (int32, error) {
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(etag, "W/"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid etag %s", etag)
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 32)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid etag %s", etag)
+	}
+
+	return int32(version), nil
+}
+
+// UpdateWord updates the fields of a word named in the update mask. If an etag
+// is given and the word has been modified since, an Aborted error is returned.
+// If its spelling changes, its definitions from the dictionary are looked up
+// again.
+func (s *Server) UpdateWord(ctx context.Context, req *UpdateWordRequest) (*UpdateWordResponse, error) {
+	paths := req.UpdateMask.GetPaths()
+	if len(paths) == 0 {
+		paths = updatableWordFields
+	}
+
+	if err := validateUpdateWordRequest(req, paths); err != nil {
+		return nil, err
+	}
+
+	update := db.WordUpdate{}
+	for _, p := range paths {
+		switch p {
+		case "word":
+			w := req.Word.GetWord()
+			update.Word = &w
+		case "custom_definition":
+			d := req.Word.GetCustomDefinition()
+			update.CustomDefinition = &d
+		}
+	}
+
+	if req.Etag != "" {
+		// Already validated
+		update.ExpectedVersion, _ = parseEtag(req.Etag)
+	}
+
+	// The dictionary's definitions are of the spelling the word had, so they're
+	// looked up again if it changes. If it can't be found here the update
+	// reports why.
+	var previous string
+	if update.Word != nil {
+		if w, err := s.wordQuerier.GetWord(ctx, s.userID(ctx), req.Word.GetId()); err == nil {
+			previous = w.Word
+		}
+	}
+
+	rsp, err := s.wordModifier.UpdateWord(ctx, s.userID(ctx), req.Word.GetId(), update)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "word %d not found", req.Word.GetId())
+	}
+
+	if errors.Is(err, db.ErrVersionConflict) {
+		return nil, status.Errorf(codes.Aborted, "word %d has been modified, etag %s is out of date", req.Word.GetId(), req.Etag)
+	}
+
+	var dup *db.DuplicateWordError
+	if errors.As(err, &dup) {
+		return nil, alreadyExistsError(dup.Existing)
+	}
+
+	if err != nil {
+		return nil, statusError(err, "unable to update word")
+	}
+
+	if update.Word != nil && rsp.Word != previous {
+		s.relookupDefinitions(ctx, rsp)
+	}
+
+	return &UpdateWordResponse{
+		Word: &v1alpha1.Word{
+			Id:               rsp.ID,
+			Word:             rsp.Word,
+			CustomDefinition: rsp.CustomDefinition,
+		},
+		Etag: Etag(rsp.Version),
+	}, nil
+}
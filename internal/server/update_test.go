@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+func TestUpdateWord(t *testing.T) {
+	wm := &wordMock{}
+	s := Server{wordModifier: wm, wordQuerier: wm, definitionStore: &definitionMock{}}
+
+	word := &v1alpha1.Word{Id: 45, Word: "floccinaucinihilipilification", CustomDefinition: "estimation of worthlessness"}
+
+	t.Run("Given a request to UpdateWord", func(t *testing.T) {
+		t.Run("When the update mask names an unknown field", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"id"}}})
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When the etag is invalid", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error is returned", func(t *testing.T) {
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word, Etag: "abc"})
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		})
+		t.Run("When the word does not exist", func(t *testing.T) {
+			t.Run("Then a NotFound error is returned", func(t *testing.T) {
+				wm.err = db.ErrNotFound
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assert.Equal(t, codes.NotFound, status.Code(err))
+			})
+		})
+		t.Run("When the etag is out of date", func(t *testing.T) {
+			t.Run("Then an Aborted error is returned", func(t *testing.T) {
+				wm.err = db.ErrVersionConflict
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word, Etag: Etag(2)})
+				assert.Equal(t, codes.Aborted, status.Code(err))
+				assert.Equal(t, int32(2), wm.lastUpdate.ExpectedVersion)
+			})
+		})
+		t.Run("When the new spelling belongs to another word", func(t *testing.T) {
+			t.Run("Then an AlreadyExists error is returned", func(t *testing.T) {
+				wm.err = &db.DuplicateWordError{Existing: db.Word{ID: 46}}
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assert.Equal(t, codes.AlreadyExists, status.Code(err))
+			})
+		})
+		t.Run("When an error is returned", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				wm.err = errors.New("an error")
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assertStatusError(t, err, codes.Internal, "unable to update word")
+			})
+		})
+		t.Run("When only the custom definition is in the update mask", func(t *testing.T) {
+			t.Run("Then only the custom definition is updated", func(t *testing.T) {
+				wm.err = nil
+				wm.updateWordResponse = db.Word{ID: 45, Word: "floccinaucinihilipilification", CustomDefinition: "estimation of worthlessness", Version: 3}
+
+				r, err := s.UpdateWord(context.Background(), &UpdateWordRequest{
+					Word:       word,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"custom_definition"}},
+					Etag:       `W/"2"`,
+				})
+				assert.NoError(t, err)
+
+				assert.Nil(t, wm.lastUpdate.Word)
+				assert.Equal(t, word.CustomDefinition, *wm.lastUpdate.CustomDefinition)
+				assert.Equal(t, int32(2), wm.lastUpdate.ExpectedVersion)
+
+				assert.Equal(t, wm.updateWordResponse.CustomDefinition, r.Word.CustomDefinition)
+				assert.Equal(t, `"3"`, r.Etag)
+			})
+		})
+		t.Run("When the update mask is empty", func(t *testing.T) {
+			t.Run("Then every mutable field is updated", func(t *testing.T) {
+				wm.err = nil
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assert.NoError(t, err)
+
+				assert.Equal(t, word.Word, *wm.lastUpdate.Word)
+				assert.Equal(t, word.CustomDefinition, *wm.lastUpdate.CustomDefinition)
+				assert.Equal(t, int32(0), wm.lastUpdate.ExpectedVersion)
+			})
+		})
+	})
+}
+
+func TestUpdateWordDefinitions(t *testing.T) {
+	wm := &wordMock{getWordResponse: db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sondr"}}
+	pm := &providerMock{entry: definitions.Entry{
+		Source: "wiktionary",
+		Senses: []definitions.Sense{{PartOfSpeech: "verb", Definition: "To sound out or probe."}},
+	}}
+
+	word := &v1alpha1.Word{Id: 45, Word: "sonder"}
+
+	t.Run("Given a word looked up in a dictionary", func(t *testing.T) {
+		t.Run("When its spelling changes", func(t *testing.T) {
+			t.Run("Then its dictionary definitions are replaced with those of the new spelling", func(t *testing.T) {
+				dm := &definitionMock{}
+				s := Server{wordModifier: wm, wordQuerier: wm, definitionStore: dm, definitions: pm, definitionsTimeout: time.Second}
+				wm.updateWordResponse = db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sonder", Version: 2}
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assert.NoError(t, err)
+
+				assert.Equal(t, int32(45), dm.deletedDictionaryID)
+				assert.Equal(t, int32(45), dm.replacedID)
+				assert.Equal(t, "wiktionary", dm.replacedSource)
+				assert.Equal(t, []db.Sense{{PartOfSpeech: "verb", Definition: "To sound out or probe."}}, dm.replaced.Senses)
+			})
+		})
+
+		t.Run("When its spelling doesn't change", func(t *testing.T) {
+			t.Run("Then its definitions are left alone", func(t *testing.T) {
+				dm := &definitionMock{}
+				s := Server{wordModifier: wm, wordQuerier: wm, definitionStore: dm, definitions: pm, definitionsTimeout: time.Second}
+				wm.updateWordResponse = db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sondr", Version: 2}
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: &v1alpha1.Word{Id: 45, Word: "sondr"}})
+				assert.NoError(t, err)
+
+				assert.Zero(t, dm.deletedDictionaryID)
+				assert.Zero(t, dm.replacedID)
+			})
+		})
+
+		t.Run("When only its custom definition is updated", func(t *testing.T) {
+			t.Run("Then its definitions are left alone", func(t *testing.T) {
+				dm := &definitionMock{}
+				s := Server{wordModifier: wm, wordQuerier: wm, definitionStore: dm, definitions: pm, definitionsTimeout: time.Second}
+				wm.updateWordResponse = db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sonder", Version: 2}
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{
+					Word:       word,
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"custom_definition"}},
+				})
+				assert.NoError(t, err)
+
+				assert.Zero(t, dm.deletedDictionaryID)
+				assert.Zero(t, dm.replacedID)
+			})
+		})
+
+		t.Run("When its dictionary definitions can't be deleted", func(t *testing.T) {
+			t.Run("Then the update still succeeds and the new spelling isn't looked up", func(t *testing.T) {
+				dm := &definitionMock{err: errors.New("an error")}
+				s := Server{wordModifier: wm, wordQuerier: wm, definitionStore: dm, definitions: pm, definitionsTimeout: time.Second}
+				wm.updateWordResponse = db.Word{ID: 45, UserID: db.DefaultUserID, Word: "sonder", Version: 2}
+
+				_, err := s.UpdateWord(context.Background(), &UpdateWordRequest{Word: word})
+				assert.NoError(t, err)
+
+				assert.Zero(t, dm.replacedID)
+			})
+		})
+	})
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/auth"
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+)
+
+// UserHeader is the gRPC metadata key, and HTTP header, naming the user a
+// request is made on behalf of when authentication is disabled. Requests
+// without it are scoped to the default user.
+const UserHeader = "x-user"
+
+// userID returns the id of the user the request is being made on behalf of
+func (s *Server) userID(ctx context.Context) int32 {
+	if u, ok := identity.FromContext(ctx); ok {
+		return u.ID
+	}
+
+	return db.DefaultUserID
+}
+
+// resolveUser looks up the user with the given username, falling back to the
+// default user when username is empty
+func (s *Server) resolveUser(ctx context.Context, username string) (identity.User, error) {
+	if username == "" {
+		return identity.User{ID: db.DefaultUserID, Username: db.DefaultUsername}, nil
+	}
+
+	u, err := s.userQuerier.GetUserByUsername(ctx, username)
+	if errors.Is(err, db.ErrNotFound) {
+		return identity.User{}, status.Errorf(codes.Unauthenticated, "unknown user %q", username)
+	}
+
+	if err != nil {
+		return identity.User{}, statusError(err, "unable to get user")
+	}
+
+	return identity.User{ID: u.ID, Username: u.Username}, nil
+}
+
+// GetUser returns the user with the given username, so that commands can act
+// on their behalf
+func (s *Server) GetUser(ctx context.Context, username string) (db.User, error) {
+	u, err := s.userQuerier.GetUserByUsername(ctx, username)
+	if err != nil {
+		return u, statusError(err, "unable to get user")
+	}
+
+	return u, nil
+}
+
+// ListUsers returns every user, so that scheduled jobs can act on their behalf
+func (s *Server) ListUsers(ctx context.Context) ([]db.User, error) {
+	users, err := s.userQuerier.ListUsers(ctx)
+	if err != nil {
+		return nil, statusError(err, "unable to list users")
+	}
+
+	return users, nil
+}
+
+// UnaryServerInterceptor scopes every request to the authenticated user or,
+// when authentication is disabled, the user named in the UserHeader metadata.
+// It must run after the auth interceptor.
+func (s *Server) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var username string
+		if p, ok := auth.FromContext(ctx); ok {
+			username = p.Username
+		} else if md, ok := metadata.FromIncomingContext(ctx); ok && !s.authenticator.Enabled() {
+			if v := md.Get(UserHeader); len(v) > 0 {
+				username = v[0]
+			}
+		}
+
+		u, err := s.resolveUser(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(identity.NewContext(ctx, u), req)
+	}
+}
+
+// GatewayHeaderMatcher forwards the UserHeader from HTTP requests to the gRPC
+// server, in addition to the headers forwarded by default
+func GatewayHeaderMatcher(key string) (string, bool) {
+	if http.CanonicalHeaderKey(key) == http.CanonicalHeaderKey(UserHeader) {
+		return UserHeader, true
+	}
+
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// withUser authenticates requests handled directly by the gateway, checking
+// they have been granted scope, and scopes them to the authenticated user or,
+// when authentication is disabled, the user named in the UserHeader
+func (s *Server) withUser(mux *runtime.ServeMux, scope auth.Scope, h runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		username := r.Header.Get(UserHeader)
+		if s.authenticator.Enabled() {
+			p, err := s.authenticator.AuthenticateHTTP(r, scope)
+			if err != nil {
+				writeGatewayError(mux, w, r, err)
+				return
+			}
+
+			username = p.Username
+		}
+
+		u, err := s.resolveUser(r.Context(), username)
+		if err != nil {
+			writeGatewayError(mux, w, r, err)
+			return
+		}
+
+		h(w, r.WithContext(identity.NewContext(r.Context(), u)), params)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/auth"
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	um := &userMock{}
+	s := Server{userQuerier: um}
+	interceptor := s.UnaryServerInterceptor()
+
+	var got identity.User
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = identity.FromContext(ctx)
+		return nil, nil
+	}
+
+	t.Run("Given a request to the gRPC server", func(t *testing.T) {
+		t.Run("When no user is given", func(t *testing.T) {
+			t.Run("Then the request is scoped to the default user", func(t *testing.T) {
+				_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, handler)
+				assert.NoError(t, err)
+				assert.Equal(t, db.DefaultUserID, got.ID)
+			})
+		})
+		t.Run("When a known user is given", func(t *testing.T) {
+			t.Run("Then the request is scoped to that user", func(t *testing.T) {
+				um.getUserResponse = db.User{ID: 7, Username: "simon"}
+
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserHeader, "simon"))
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+				assert.NoError(t, err)
+				assert.Equal(t, identity.User{ID: 7, Username: "simon"}, got)
+			})
+		})
+		t.Run("When an unknown user is given", func(t *testing.T) {
+			t.Run("Then an Unauthenticated error is returned", func(t *testing.T) {
+				um.err = db.ErrNotFound
+
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserHeader, "nobody"))
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+				assert.Equal(t, codes.Unauthenticated, status.Code(err))
+			})
+		})
+		t.Run("When looking up the user fails", func(t *testing.T) {
+			t.Run("Then the error is returned to the caller", func(t *testing.T) {
+				um.err = errors.New("an error")
+
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserHeader, "simon"))
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+				assertStatusError(t, err, codes.Internal, "unable to get user")
+			})
+		})
+	})
+}
+
+func TestUserID(t *testing.T) {
+	s := Server{}
+
+	t.Run("Given a context carrying a user", func(t *testing.T) {
+		t.Run("Then that user's id is returned", func(t *testing.T) {
+			ctx := identity.NewContext(context.Background(), identity.User{ID: 7})
+			assert.Equal(t, int32(7), s.userID(ctx))
+		})
+	})
+	t.Run("Given a context without a user", func(t *testing.T) {
+		t.Run("Then the default user's id is returned", func(t *testing.T) {
+			assert.Equal(t, db.DefaultUserID, s.userID(context.Background()))
+		})
+	})
+}
+
+func TestGatewayHeaderMatcher(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		key      string
+		expected string
+		ok       bool
+	}{
+		{desc: "User header should be forwarded", key: "X-User", expected: UserHeader, ok: true},
+		{desc: "Grpc-Metadata headers should be forwarded", key: "Grpc-Metadata-Foo", expected: "Foo", ok: true},
+		{desc: "Other headers should not be forwarded", key: "X-Foo", ok: false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			key, ok := GatewayHeaderMatcher(tC.key)
+			assert.Equal(t, tC.ok, ok)
+			assert.Equal(t, tC.expected, key)
+		})
+	}
+}
+
+func TestUnaryServerInterceptorWithAuth(t *testing.T) {
+	authenticator, err := auth.New(auth.Config{Enabled: true, JWTSecret: "secret"})
+	assert.NoError(t, err)
+
+	um := &userMock{getUserResponse: db.User{ID: 7, Username: "simon"}}
+	s := Server{userQuerier: um, authenticator: authenticator}
+	interceptor := s.UnaryServerInterceptor()
+
+	var got identity.User
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = identity.FromContext(ctx)
+		return nil, nil
+	}
+
+	t.Run("Given authentication is enabled", func(t *testing.T) {
+		t.Run("When the request has been authenticated", func(t *testing.T) {
+			t.Run("Then it is scoped to the authenticated user", func(t *testing.T) {
+				ctx := auth.NewContext(context.Background(), auth.Principal{Username: "simon"})
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+				assert.NoError(t, err)
+				assert.Equal(t, identity.User{ID: 7, Username: "simon"}, got)
+			})
+		})
+		t.Run("When the user header is set on an unauthenticated request", func(t *testing.T) {
+			t.Run("Then the header is ignored", func(t *testing.T) {
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(UserHeader, "simon"))
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, handler)
+				assert.NoError(t, err)
+				assert.Equal(t, db.DefaultUserID, got.ID)
+			})
+		})
+	})
+}
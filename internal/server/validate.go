@@ -0,0 +1,304 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/srs"
+	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
+)
+
+const (
+	// maxWordLength and maxDefinitionLength are the sizes of the words table
+	// columns, in characters
+	maxWordLength       = 255
+	maxDefinitionLength = 255
+
+	// maxPartOfSpeechLength and maxIPALength are the sizes of the word_senses
+	// table columns, in characters
+	maxPartOfSpeechLength = 64
+	maxIPALength          = 255
+
+	// maxSenseTextLength limits the definition, examples and etymology of a
+	// sense, which are stored as TEXT
+	maxSenseTextLength = 2000
+
+	// maxSenseListLength limits the examples, synonyms and antonyms of a sense
+	maxSenseListLength = 50
+)
+
+// wordPunctuation is the punctuation allowed in a word, alongside letters,
+// marks, digits and spaces
+const wordPunctuation = "-'’."
+
+// maxTagLength is the size of the tags table's name column, in characters
+const maxTagLength = 64
+
+// tagPunctuation is the punctuation allowed in a tag, alongside letters, marks,
+// digits and spaces. Tags appear in URL paths, so / isn't allowed. Colons are,
+// as Anki separates the levels of its tags with ::
+const tagPunctuation = "-_'’.&+#:"
+
+// fieldViolations collects the problems found with the fields of a request
+type fieldViolations []*errdetails.BadRequest_FieldViolation
+
+func (v *fieldViolations) add(field, format string, args ...interface{}) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: fmt.Sprintf(format, args...),
+	})
+}
+
+// String lists every violation
+func (v fieldViolations) String() string {
+	problems := make([]string, len(v))
+	for i, fv := range v {
+		problems[i] = fv.Field + " " + fv.Description
+	}
+
+	return strings.Join(problems, ", ")
+}
+
+// err returns an InvalidArgument error with a BadRequest detail listing every
+// violation, or nil if there are none
+func (v fieldViolations) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+
+	st := status.New(codes.InvalidArgument, "invalid request: "+v.String())
+
+	detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: v})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// invalidField returns an InvalidArgument error for a single field
+func invalidField(field, format string, args ...interface{}) error {
+	v := fieldViolations{}
+	v.add(field, format, args...)
+
+	return v.err()
+}
+
+func validateID(v *fieldViolations, field string, id int32) {
+	if id <= 0 {
+		v.add(field, "must be a positive id")
+	}
+}
+
+// validateSpelling checks s is a non-empty word made up of letters, marks,
+// digits, spaces and wordPunctuation
+func validateSpelling(v *fieldViolations, field string, s string) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case s == "":
+		v.add(field, "is required")
+		return
+	case !utf8.ValidString(s):
+		v.add(field, "must be valid UTF-8")
+		return
+	case utf8.RuneCountInString(s) > maxWordLength:
+		v.add(field, "must be at most %d characters", maxWordLength)
+		return
+	}
+
+	hasLetter := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsMark(r), unicode.IsDigit(r), r == ' ', strings.ContainsRune(wordPunctuation, r):
+		default:
+			v.add(field, "must not contain %q", r)
+			return
+		}
+	}
+
+	if !hasLetter {
+		v.add(field, "must contain a letter")
+	}
+}
+
+// validateTag checks s is a non-empty tag made up of letters, marks, digits,
+// spaces and tagPunctuation
+func validateTag(v *fieldViolations, field string, s string) {
+	s = strings.TrimSpace(s)
+
+	switch {
+	case s == "":
+		v.add(field, "is required")
+		return
+	case !utf8.ValidString(s):
+		v.add(field, "must be valid UTF-8")
+		return
+	case utf8.RuneCountInString(s) > maxTagLength:
+		v.add(field, "must be at most %d characters", maxTagLength)
+		return
+	}
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r) && r != ' ' && !strings.ContainsRune(tagPunctuation, r) {
+			v.add(field, "must not contain %q", r)
+			return
+		}
+	}
+}
+
+// validateDefinition checks s is short enough and has no control characters
+// other than new lines and tabs
+func validateDefinition(v *fieldViolations, field string, s string) {
+	validateText(v, field, s, maxDefinitionLength)
+}
+
+// validateText checks s is at most max characters and has no control
+// characters other than new lines and tabs
+func validateText(v *fieldViolations, field string, s string, max int) {
+	switch {
+	case !utf8.ValidString(s):
+		v.add(field, "must be valid UTF-8")
+		return
+	case utf8.RuneCountInString(s) > max:
+		v.add(field, "must be at most %d characters", max)
+		return
+	}
+
+	for _, r := range s {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			v.add(field, "must not contain control characters")
+			return
+		}
+	}
+}
+
+// validateSense checks the fields of a sense written by hand
+func validateSense(v *fieldViolations, sense db.Sense) {
+	if strings.TrimSpace(sense.Definition) == "" {
+		v.add("sense.definition", "is required")
+	} else {
+		validateText(v, "sense.definition", sense.Definition, maxSenseTextLength)
+	}
+
+	validateText(v, "sense.part_of_speech", sense.PartOfSpeech, maxPartOfSpeechLength)
+	validateText(v, "sense.etymology", sense.Etymology, maxSenseTextLength)
+	validateText(v, "sense.ipa", sense.IPA, maxIPALength)
+
+	if len(sense.Examples) > maxSenseListLength {
+		v.add("sense.examples", "must have at most %d items", maxSenseListLength)
+	}
+
+	for i, ex := range sense.Examples {
+		field := fmt.Sprintf("sense.examples[%d]", i)
+		if strings.TrimSpace(ex) == "" {
+			v.add(field, "must not be empty")
+			continue
+		}
+
+		validateText(v, field, ex, maxSenseTextLength)
+	}
+
+	validateWordList(v, "sense.synonyms", sense.Synonyms)
+	validateWordList(v, "sense.antonyms", sense.Antonyms)
+}
+
+// validateWordList checks each of words is a valid spelling
+func validateWordList(v *fieldViolations, field string, words []string) {
+	if len(words) > maxSenseListLength {
+		v.add(field, "must have at most %d items", maxSenseListLength)
+	}
+
+	for i, w := range words {
+		validateSpelling(v, fmt.Sprintf("%s[%d]", field, i), w)
+	}
+}
+
+func validateAddWordRequest(req *v1alpha1.AddWordRequest) error {
+	v := fieldViolations{}
+
+	if req.GetWord() == nil {
+		v.add("word", "is required")
+		return v.err()
+	}
+
+	validateSpelling(&v, "word.word", req.GetWord().GetWord())
+	validateDefinition(&v, "word.custom_definition", req.GetWord().GetCustomDefinition())
+
+	return v.err()
+}
+
+func validateDeleteWordRequest(req *v1alpha1.DeleteWordRequest) error {
+	v := fieldViolations{}
+	validateID(&v, "id", req.GetId())
+
+	return v.err()
+}
+
+func validateUpdateWordRequest(req *UpdateWordRequest, paths []string) error {
+	v := fieldViolations{}
+
+	if req.Word == nil {
+		v.add("word", "is required")
+		return v.err()
+	}
+
+	validateID(&v, "word.id", req.Word.GetId())
+
+	for _, p := range paths {
+		switch p {
+		case "word":
+			validateSpelling(&v, "word.word", req.Word.GetWord())
+		case "custom_definition":
+			validateDefinition(&v, "word.custom_definition", req.Word.GetCustomDefinition())
+		default:
+			v.add("update_mask", "names field %q which cannot be updated", p)
+		}
+	}
+
+	if req.Etag != "" {
+		if _, err := parseEtag(req.Etag); err != nil {
+			v.add("etag", "must be a quoted version")
+		}
+	}
+
+	return v.err()
+}
+
+func validateReviewWordRequest(id int32, grade string) error {
+	v := fieldViolations{}
+	validateID(&v, "id", id)
+
+	if _, err := srs.ParseGrade(grade); err != nil {
+		v.add("grade", "must be one of again, hard, good or easy")
+	}
+
+	return v.err()
+}
+
+func validateListWordsPageRequest(req *ListWordsPageRequest) error {
+	v := fieldViolations{}
+
+	if req.PageSize < 0 {
+		v.add("page_size", "must not be negative")
+	}
+
+	if _, err := db.ParseWordOrder(req.OrderBy); err != nil {
+		v.add("order_by", "must be one of oldest, newest or alphabetical")
+	}
+
+	if req.Tag != "" {
+		validateTag(&v, "tag", req.Tag)
+	}
+
+	return v.err()
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestValidateSpelling(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		word     string
+		expected string
+	}{
+		{desc: "Plain word should be valid", word: "floccinaucinihilipilification"},
+		{desc: "Accents, spaces and full stops should be valid", word: "crème brûlée e.g."},
+		{desc: "Hyphens, apostrophes and digits should be valid", word: "20th-century o’clock"},
+		{desc: "Empty word should be required", word: "  ", expected: "is required"},
+		{desc: "Long word should be rejected", word: strings.Repeat("a", maxWordLength+1), expected: "must be at most 255 characters"},
+		{desc: "Control characters should be rejected", word: "tab\tseparated", expected: `must not contain '\t'`},
+		{desc: "Symbols should be rejected", word: "<script>", expected: `must not contain '<'`},
+		{desc: "Word without letters should be rejected", word: "123", expected: "must contain a letter"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			v := fieldViolations{}
+			validateSpelling(&v, "word", tC.word)
+
+			if tC.expected == "" {
+				assert.Empty(t, v)
+				return
+			}
+
+			if assert.Len(t, v, 1) {
+				assert.Equal(t, "word", v[0].Field)
+				assert.Equal(t, tC.expected, v[0].Description)
+			}
+		})
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		tag      string
+		expected string
+	}{
+		{desc: "Plain tag should be valid", tag: "legal"},
+		{desc: "Spaces, digits and punctuation should be valid", tag: "GRE 2024 c++ & c#"},
+		{desc: "Non-Latin letters should be valid", tag: "español"},
+		{desc: "Anki hierarchical tags should be valid", tag: "GRE::verbs"},
+		{desc: "Empty tag should be required", tag: " ", expected: "is required"},
+		{desc: "Long tag should be rejected", tag: strings.Repeat("a", maxTagLength+1), expected: "must be at most 64 characters"},
+		{desc: "Slashes should be rejected", tag: "a/b", expected: `must not contain '/'`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			v := fieldViolations{}
+			validateTag(&v, "tag", tC.tag)
+
+			if tC.expected == "" {
+				assert.Empty(t, v)
+				return
+			}
+
+			if assert.Len(t, v, 1) {
+				assert.Equal(t, "tag", v[0].Field)
+				assert.Equal(t, tC.expected, v[0].Description)
+			}
+		})
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		definition string
+		expected   string
+	}{
+		{desc: "Empty definition should be valid", definition: ""},
+		{desc: "New lines and tabs should be valid", definition: "first line\n\tsecond line"},
+		{desc: "Long definition should be rejected", definition: strings.Repeat("é", maxDefinitionLength+1), expected: "must be at most 255 characters"},
+		{desc: "Control characters should be rejected", definition: "null\x00byte", expected: "must not contain control characters"},
+		{desc: "Invalid UTF-8 should be rejected", definition: "\xff", expected: "must be valid UTF-8"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			v := fieldViolations{}
+			validateDefinition(&v, "custom_definition", tC.definition)
+
+			if tC.expected == "" {
+				assert.Empty(t, v)
+				return
+			}
+
+			if assert.Len(t, v, 1) {
+				assert.Equal(t, tC.expected, v[0].Description)
+			}
+		})
+	}
+}
+
+func TestFieldViolationsErr(t *testing.T) {
+	t.Run("Given no violations", func(t *testing.T) {
+		t.Run("When err is called", func(t *testing.T) {
+			t.Run("Then nil is returned", func(t *testing.T) {
+				assert.NoError(t, fieldViolations{}.err())
+			})
+		})
+	})
+
+	t.Run("Given several violations", func(t *testing.T) {
+		v := fieldViolations{}
+		v.add("id", "must be a positive id")
+		v.add("grade", "must be one of %s", "again, hard, good or easy")
+
+		t.Run("When err is called", func(t *testing.T) {
+			t.Run("Then an InvalidArgument error listing every violation is returned", func(t *testing.T) {
+				st := status.Convert(v.err())
+				assert.Equal(t, codes.InvalidArgument, st.Code())
+				assert.Equal(t, "invalid request: id must be a positive id, grade must be one of again, hard, good or easy", st.Message())
+				assert.Len(t, st.Details(), 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,116 @@
+// Package srs implements SM-2 style spaced-repetition scheduling
+package srs
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultEaseFactor is the ease factor given to words that have never been reviewed
+	DefaultEaseFactor = 2.5
+	// MinEaseFactor is the lowest the ease factor is allowed to fall to
+	MinEaseFactor = 1.3
+)
+
+// Grade is how well a word was recalled during a review
+type Grade int
+
+const (
+	// Again means the word was not recalled
+	Again Grade = iota + 1
+	// Hard means the word was recalled with serious difficulty
+	Hard
+	// Good means the word was recalled after some hesitation
+	Good
+	// Easy means the word was recalled perfectly
+	Easy
+)
+
+func (g Grade) String() string {
+	switch g {
+	case Again:
+		return "again"
+	case Hard:
+		return "hard"
+	case Good:
+		return "good"
+	case Easy:
+		return "easy"
+	default:
+		return fmt.Sprintf("Grade(%d)", int(g))
+	}
+}
+
+// ParseGrade converts one of again, hard, good or easy into a Grade
+func ParseGrade(s string) (Grade, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "again":
+		return Again, nil
+	case "hard":
+		return Hard, nil
+	case "good":
+		return Good, nil
+	case "easy":
+		return Easy, nil
+	default:
+		return 0, fmt.Errorf("unknown grade %q", s)
+	}
+}
+
+// quality maps a Grade onto the 0-5 response quality scale used by SM-2
+func (g Grade) quality() float64 {
+	switch g {
+	case Again:
+		return 1
+	case Hard:
+		return 3
+	case Good:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// State is the scheduling state of a single word
+type State struct {
+	EaseFactor   float64
+	IntervalDays int32
+	Repetitions  int32
+	DueAt        time.Time
+}
+
+// Schedule returns the state following a review graded g at time now
+func Schedule(s State, g Grade, now time.Time) State {
+	if s.EaseFactor < MinEaseFactor {
+		s.EaseFactor = DefaultEaseFactor
+	}
+
+	q := g.quality()
+
+	next := State{
+		EaseFactor: math.Max(MinEaseFactor, s.EaseFactor+(0.1-(5-q)*(0.08+(5-q)*0.02))),
+	}
+
+	switch {
+	case q < 3:
+		// Not recalled, so start the repetitions again from the beginning
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	case s.Repetitions == 0:
+		next.Repetitions = 1
+		next.IntervalDays = 1
+	case s.Repetitions == 1:
+		next.Repetitions = 2
+		next.IntervalDays = 6
+	default:
+		next.Repetitions = s.Repetitions + 1
+		next.IntervalDays = int32(math.Round(float64(s.IntervalDays) * s.EaseFactor))
+	}
+
+	next.DueAt = now.AddDate(0, 0, int(next.IntervalDays))
+
+	return next
+}
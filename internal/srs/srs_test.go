@@ -0,0 +1,100 @@
+package srs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGrade(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		grade       string
+		expected    Grade
+		expectedErr string
+	}{
+		{desc: "Again should be parsed", grade: "again", expected: Again},
+		{desc: "Hard should be parsed", grade: "hard", expected: Hard},
+		{desc: "Good should be parsed regardless of case", grade: "Good", expected: Good},
+		{desc: "Easy should be parsed regardless of whitespace", grade: " easy ", expected: Easy},
+		{desc: "Unknown grade should return error", grade: "perfect", expectedErr: `unknown grade "perfect"`},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			g, err := ParseGrade(tC.grade)
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tC.expected, g)
+			assert.Equal(t, tC.expected.String(), g.String())
+		})
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	now := time.Date(2022, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		desc     string
+		state    State
+		grade    Grade
+		expected State
+	}{
+		{
+			desc:     "First successful review should be due the next day",
+			state:    State{EaseFactor: DefaultEaseFactor},
+			grade:    Good,
+			expected: State{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1, DueAt: now.AddDate(0, 0, 1)},
+		},
+		{
+			desc:     "Second successful review should be due in six days",
+			state:    State{EaseFactor: DefaultEaseFactor, IntervalDays: 1, Repetitions: 1},
+			grade:    Easy,
+			expected: State{EaseFactor: 2.6, IntervalDays: 6, Repetitions: 2, DueAt: now.AddDate(0, 0, 6)},
+		},
+		{
+			desc:     "Later successful reviews should multiply the interval by the ease factor",
+			state:    State{EaseFactor: DefaultEaseFactor, IntervalDays: 6, Repetitions: 2},
+			grade:    Good,
+			expected: State{EaseFactor: 2.5, IntervalDays: 15, Repetitions: 3, DueAt: now.AddDate(0, 0, 15)},
+		},
+		{
+			desc:     "Hard reviews should lower the ease factor",
+			state:    State{EaseFactor: DefaultEaseFactor, IntervalDays: 6, Repetitions: 2},
+			grade:    Hard,
+			expected: State{EaseFactor: 2.36, IntervalDays: 15, Repetitions: 3, DueAt: now.AddDate(0, 0, 15)},
+		},
+		{
+			desc:     "Failed reviews should reset the repetitions",
+			state:    State{EaseFactor: DefaultEaseFactor, IntervalDays: 15, Repetitions: 3},
+			grade:    Again,
+			expected: State{EaseFactor: 1.96, IntervalDays: 1, Repetitions: 0, DueAt: now.AddDate(0, 0, 1)},
+		},
+		{
+			desc:     "Ease factor should never fall below the minimum",
+			state:    State{EaseFactor: MinEaseFactor, IntervalDays: 1, Repetitions: 0},
+			grade:    Again,
+			expected: State{EaseFactor: MinEaseFactor, IntervalDays: 1, Repetitions: 0, DueAt: now.AddDate(0, 0, 1)},
+		},
+		{
+			desc:     "Unset ease factor should use the default",
+			state:    State{},
+			grade:    Good,
+			expected: State{EaseFactor: 2.5, IntervalDays: 1, Repetitions: 1, DueAt: now.AddDate(0, 0, 1)},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := Schedule(tC.state, tC.grade, now)
+
+			assert.InDelta(t, tC.expected.EaseFactor, s.EaseFactor, 0.0001)
+			assert.Equal(t, tC.expected.IntervalDays, s.IntervalDays)
+			assert.Equal(t, tC.expected.Repetitions, s.Repetitions)
+			assert.Equal(t, tC.expected.DueAt, s.DueAt)
+		})
+	}
+}
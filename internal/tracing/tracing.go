@@ -0,0 +1,147 @@
+// Package tracing configures OpenTelemetry tracing for the application
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+)
+
+// Exporter is where spans are sent
+type Exporter string
+
+const (
+	// ExporterNone doesn't record any spans, although trace context is still
+	// propagated
+	ExporterNone Exporter = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over gRPC
+	ExporterOTLP Exporter = "otlp"
+	// ExporterStdout writes spans to stdout as JSON
+	ExporterStdout Exporter = "stdout"
+	// ExporterFile writes spans to a file as JSON, one span per line
+	ExporterFile Exporter = "file"
+)
+
+type Config struct {
+	Exporter    Exporter
+	ServiceName string
+
+	// SampleRatio is the fraction of new traces that are recorded, between 0
+	// and 1. Traces started by a caller follow the caller's decision.
+	SampleRatio float64
+
+	// OTLPEndpoint is the host:port of the collector used by ExporterOTLP
+	OTLPEndpoint string
+	// OTLPInsecure disables TLS when connecting to the collector
+	OTLPInsecure bool
+
+	// File is the path written to by ExporterFile
+	File string
+}
+
+// ShutdownFunc flushes any spans that haven't been exported yet and stops tracing
+type ShutdownFunc func(context.Context) error
+
+// Setup installs the global tracer provider and propagator described by c
+func Setup(ctx context.Context, c Config) (ShutdownFunc, error) {
+	// Propagated regardless of the exporter so traces started by callers
+	// aren't broken by passing through the server
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, closer, err := newExporter(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(c.ServiceName),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create resource")
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+
+		if closer != nil {
+			if cerr := closer.Close(); err == nil {
+				err = cerr
+			}
+		}
+
+		return err
+	}, nil
+}
+
+// newExporter returns the exporter for c, along with anything that must be
+// closed once it has been shut down
+func newExporter(ctx context.Context, c Config) (sdktrace.SpanExporter, io.Closer, error) {
+	switch c.Exporter {
+	case ExporterNone, "":
+		return nil, nil, nil
+	case ExporterOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(c.OTLPEndpoint)}
+		if c.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to create otlp exporter")
+		}
+
+		return exporter, nil, nil
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to create stdout exporter")
+		}
+
+		return exporter, nil, nil
+	case ExporterFile:
+		if c.File == "" {
+			return nil, nil, errors.New("file not defined")
+		}
+
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to open trace file")
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return nil, nil, errors.Wrap(err, "unable to create file exporter")
+		}
+
+		return exporter, f, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown exporter %q, must be one of none, otlp, stdout or file", c.Exporter)
+	}
+}
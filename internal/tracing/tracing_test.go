@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// exportedSpan is the part of a span written by the file exporter that the tests check
+type exportedSpan struct {
+	Name   string
+	Parent struct {
+		SpanID string
+	}
+	SpanContext struct {
+		SpanID string
+	}
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("Given the file exporter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+
+		shutdown, err := Setup(context.Background(), Config{
+			Exporter:    ExporterFile,
+			ServiceName: "mywordoftheday-test",
+			SampleRatio: 1,
+			File:        path,
+		})
+		assert.NoError(t, err)
+
+		t.Run("When a span is recorded and tracing is shut down", func(t *testing.T) {
+			ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+			_, child := otel.Tracer("test").Start(ctx, "child")
+			child.End()
+			parent.End()
+
+			assert.NoError(t, shutdown(context.Background()))
+
+			t.Run("Then the spans are written to the file", func(t *testing.T) {
+				b, err := os.ReadFile(path)
+				assert.NoError(t, err)
+
+				var spans []exportedSpan
+
+				dec := json.NewDecoder(bytes.NewReader(b))
+				for dec.More() {
+					var s exportedSpan
+					assert.NoError(t, dec.Decode(&s))
+					spans = append(spans, s)
+				}
+
+				if assert.Len(t, spans, 2) {
+					assert.Equal(t, "child", spans[0].Name)
+					assert.Equal(t, "parent", spans[1].Name)
+					assert.Equal(t, spans[1].SpanContext.SpanID, spans[0].Parent.SpanID)
+				}
+			})
+		})
+	})
+
+	t.Run("Given no exporter", func(t *testing.T) {
+		t.Run("When tracing is set up", func(t *testing.T) {
+			t.Run("Then trace context is still propagated", func(t *testing.T) {
+				shutdown, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+				assert.NoError(t, err)
+				assert.NoError(t, shutdown(context.Background()))
+
+				carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+				ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+
+				out := propagation.MapCarrier{}
+				otel.GetTextMapPropagator().Inject(ctx, out)
+				assert.Equal(t, carrier["traceparent"], out["traceparent"])
+			})
+		})
+	})
+
+	t.Run("Given an unknown exporter", func(t *testing.T) {
+		t.Run("When tracing is set up", func(t *testing.T) {
+			t.Run("Then an error is returned", func(t *testing.T) {
+				_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+				assert.EqualError(t, err, `unknown exporter "zipkin", must be one of none, otlp, stdout or file`)
+			})
+		})
+	})
+}
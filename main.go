@@ -3,22 +3,36 @@ package main
 import (
 	"context"
 	"embed"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/robfig/cron/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/mywordoftheday/backend/internal/auth"
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/definitions"
+	"github.com/mywordoftheday/backend/internal/health"
+	"github.com/mywordoftheday/backend/internal/lifecycle"
 	"github.com/mywordoftheday/backend/internal/mail"
+	"github.com/mywordoftheday/backend/internal/metrics"
+	"github.com/mywordoftheday/backend/internal/outbox"
 	"github.com/mywordoftheday/backend/internal/server"
+	"github.com/mywordoftheday/backend/internal/tracing"
 	v1alpha1 "github.com/mywordoftheday/proto/mywordoftheday/v1alpha1"
 )
 
@@ -57,21 +71,65 @@ func main() {
 	handleBindEnvErr(viper.BindEnv("server.port", "SERVER_PORT"))
 	handleBindEnvErr(viper.BindEnv("server.httpProxy.enabled", "HTTP_PROXY_ENABLED"))
 	handleBindEnvErr(viper.BindEnv("server.httpProxy.port", "HTTP_PROXY_PORT"))
+	handleBindEnvErr(viper.BindEnv("server.shutdownTimeout", "SERVER_SHUTDOWN_TIMEOUT"))
 
 	handleBindEnvErr(viper.BindEnv("db.host", "DB_HOST"))
 	handleBindEnvErr(viper.BindEnv("db.port", "DB_PORT"))
 	handleBindEnvErr(viper.BindEnv("db.username", "DB_USERNAME"))
 	handleBindEnvErr(viper.BindEnv("db.password", "DB_PASSWORD"))
 	handleBindEnvErr(viper.BindEnv("db.name", "DB_NAME"))
+	handleBindEnvErr(viper.BindEnv("db.migrateOnStartup", "DB_MIGRATE_ON_STARTUP"))
+
+	handleBindEnvErr(viper.BindEnv("words.mergeDuplicates", "WORDS_MERGE_DUPLICATES"))
+
+	handleBindEnvErr(viper.BindEnv("definitions.providers", "DEFINITIONS_PROVIDERS"))
+	handleBindEnvErr(viper.BindEnv("definitions.file", "DEFINITIONS_FILE"))
+	handleBindEnvErr(viper.BindEnv("definitions.apiURL", "DEFINITIONS_API_URL"))
+	handleBindEnvErr(viper.BindEnv("definitions.timeout", "DEFINITIONS_TIMEOUT"))
 
 	handleBindEnvErr(viper.BindEnv("smtp.enabled", "SMTP_ENABLED"))
 	handleBindEnvErr(viper.BindEnv("smtp.schedule", "SMTP_SCHEDULE"))
+	handleBindEnvErr(viper.BindEnv("smtp.rotation", "SMTP_ROTATION"))
+	handleBindEnvErr(viper.BindEnv("smtp.tag", "SMTP_TAG"))
 	handleBindEnvErr(viper.BindEnv("smtp.host", "SMTP_HOST"))
 	handleBindEnvErr(viper.BindEnv("smtp.port", "SMTP_PORT"))
 	handleBindEnvErr(viper.BindEnv("smtp.username", "SMTP_USERNAME"))
 	handleBindEnvErr(viper.BindEnv("smtp.password", "SMTP_PASSWORD"))
 	handleBindEnvErr(viper.BindEnv("smtp.fromAddress", "SMTP_FROM_ADDRESS"))
 	handleBindEnvErr(viper.BindEnv("smtp.toAddresses", "SMTP_TO_ADDRESSES"))
+	handleBindEnvErr(viper.BindEnv("smtp.shutdownTimeout", "SMTP_SHUTDOWN_TIMEOUT"))
+	handleBindEnvErr(viper.BindEnv("smtp.tls", "SMTP_TLS"))
+	handleBindEnvErr(viper.BindEnv("smtp.auth", "SMTP_AUTH"))
+	handleBindEnvErr(viper.BindEnv("smtp.caFile", "SMTP_CA_FILE"))
+	handleBindEnvErr(viper.BindEnv("smtp.insecureSkipVerify", "SMTP_INSECURE_SKIP_VERIFY"))
+	handleBindEnvErr(viper.BindEnv("smtp.unsubscribeURL", "SMTP_UNSUBSCRIBE_URL"))
+	handleBindEnvErr(viper.BindEnv("outbox.interval", "OUTBOX_INTERVAL"))
+	handleBindEnvErr(viper.BindEnv("outbox.batchSize", "OUTBOX_BATCH_SIZE"))
+	handleBindEnvErr(viper.BindEnv("outbox.sendTimeout", "OUTBOX_SEND_TIMEOUT"))
+	handleBindEnvErr(viper.BindEnv("outbox.maxAttempts", "OUTBOX_MAX_ATTEMPTS"))
+	handleBindEnvErr(viper.BindEnv("outbox.minBackoff", "OUTBOX_MIN_BACKOFF"))
+	handleBindEnvErr(viper.BindEnv("outbox.maxBackoff", "OUTBOX_MAX_BACKOFF"))
+
+	handleBindEnvErr(viper.BindEnv("notify.enabled", "NOTIFY_ENABLED"))
+	handleBindEnvErr(viper.BindEnv("notify.timeout", "NOTIFY_TIMEOUT"))
+
+	handleBindEnvErr(viper.BindEnv("health.interval", "HEALTH_INTERVAL"))
+	handleBindEnvErr(viper.BindEnv("health.timeout", "HEALTH_TIMEOUT"))
+
+	handleBindEnvErr(viper.BindEnv("metrics.enabled", "METRICS_ENABLED"))
+	handleBindEnvErr(viper.BindEnv("metrics.port", "METRICS_PORT"))
+
+	handleBindEnvErr(viper.BindEnv("tracing.exporter", "TRACING_EXPORTER"))
+	handleBindEnvErr(viper.BindEnv("tracing.serviceName", "TRACING_SERVICE_NAME"))
+	handleBindEnvErr(viper.BindEnv("tracing.sampleRatio", "TRACING_SAMPLE_RATIO"))
+	handleBindEnvErr(viper.BindEnv("tracing.otlp.endpoint", "TRACING_OTLP_ENDPOINT"))
+	handleBindEnvErr(viper.BindEnv("tracing.otlp.insecure", "TRACING_OTLP_INSECURE"))
+	handleBindEnvErr(viper.BindEnv("tracing.file", "TRACING_FILE"))
+
+	handleBindEnvErr(viper.BindEnv("auth.enabled", "AUTH_ENABLED"))
+	handleBindEnvErr(viper.BindEnv("auth.jwt.secret", "AUTH_JWT_SECRET"))
+	handleBindEnvErr(viper.BindEnv("auth.jwt.issuer", "AUTH_JWT_ISSUER"))
+	handleBindEnvErr(viper.BindEnv("auth.jwt.audience", "AUTH_JWT_AUDIENCE"))
 
 	// Merge config
 	if err := viper.MergeInConfig(); err != nil {
@@ -88,6 +146,7 @@ func main() {
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.httpProxy.enabled", false)
 	viper.SetDefault("server.httpProxy.port", 8443)
+	viper.SetDefault("server.shutdownTimeout", "30s")
 
 	// DB defaults
 	viper.SetDefault("db.host", "localhost")
@@ -95,6 +154,48 @@ func main() {
 	viper.SetDefault("db.username", "mywordoftheday")
 	viper.SetDefault("db.password", "")
 	viper.SetDefault("db.name", "mywordoftheday")
+	viper.SetDefault("db.migrateOnStartup", true)
+
+	// Words defaults
+	viper.SetDefault("words.mergeDuplicates", false)
+
+	// Definitions defaults
+	viper.SetDefault("definitions.providers", []string{})
+	viper.SetDefault("definitions.apiURL", definitions.DefaultAPIURL)
+	viper.SetDefault("definitions.timeout", "5s")
+
+	// SMTP defaults
+	viper.SetDefault("smtp.rotation", "random")
+	viper.SetDefault("smtp.shutdownTimeout", "2m")
+	viper.SetDefault("smtp.auth", "plain")
+	viper.SetDefault("outbox.interval", "30s")
+	viper.SetDefault("outbox.batchSize", 10)
+	viper.SetDefault("outbox.sendTimeout", "1m")
+	viper.SetDefault("outbox.maxAttempts", 8)
+	viper.SetDefault("outbox.minBackoff", "1m")
+	viper.SetDefault("outbox.maxBackoff", "6h")
+
+	// Notification defaults
+	viper.SetDefault("notify.timeout", "30s")
+
+	// Health defaults
+	viper.SetDefault("health.interval", "15s")
+	viper.SetDefault("health.timeout", "5s")
+
+	// Metrics defaults
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.port", 0)
+
+	// Tracing defaults
+	viper.SetDefault("tracing.exporter", "none")
+	viper.SetDefault("tracing.serviceName", "mywordoftheday")
+	viper.SetDefault("tracing.sampleRatio", 1)
+	viper.SetDefault("tracing.otlp.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.otlp.insecure", false)
+	viper.SetDefault("tracing.file", "traces.json")
+
+	// Auth defaults
+	viper.SetDefault("auth.enabled", false)
 
 	if err := viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
@@ -110,6 +211,7 @@ func main() {
 		port             = viper.GetInt("server.port")
 		httpProxyEnabled = viper.GetBool("server.httpProxy.enabled")
 		httpProxyPort    = viper.GetInt("server.httpProxy.port")
+		shutdownTimeout  = viper.GetDuration("server.shutdownTimeout")
 
 		dbHost     = viper.GetString("db.host")
 		dbPort     = viper.GetString("db.port")
@@ -117,125 +219,467 @@ func main() {
 		dbPassword = viper.GetString("db.password")
 		dbName     = viper.GetString("db.name")
 
+		dbMigrateOnStartup = viper.GetBool("db.migrateOnStartup")
+
+		wordsMergeDuplicates = viper.GetBool("words.mergeDuplicates")
+
+		definitionsProviders = viper.GetStringSlice("definitions.providers")
+		definitionsFile      = viper.GetString("definitions.file")
+		definitionsAPIURL    = viper.GetString("definitions.apiURL")
+		definitionsTimeout   = viper.GetDuration("definitions.timeout")
+
 		smtpEnabled     = viper.GetBool("smtp.enabled")
 		smtpSchedule    = viper.GetString("smtp.schedule")
+		smtpRotation    = viper.GetString("smtp.rotation")
+		smtpTag         = viper.GetString("smtp.tag")
 		smtpHost        = viper.GetString("smtp.host")
 		smtpPort        = viper.GetString("smtp.port")
 		smtpUsername    = viper.GetString("smtp.username")
 		smtpPassword    = viper.GetString("smtp.password")
 		smtpFromAddress = viper.GetString("smtp.fromAddress")
 		smtpToAddresses = viper.GetStringSlice("smtp.toAddresses")
+
+		smtpShutdownTimeout = viper.GetDuration("smtp.shutdownTimeout")
+
+		smtpTLS                = viper.GetString("smtp.tls")
+		smtpAuth               = viper.GetString("smtp.auth")
+		smtpCAFile             = viper.GetString("smtp.caFile")
+		smtpInsecureSkipVerify = viper.GetBool("smtp.insecureSkipVerify")
+
+		smtpUnsubscribeURL = viper.GetString("smtp.unsubscribeURL")
+
+		outboxInterval    = viper.GetDuration("outbox.interval")
+		outboxBatchSize   = viper.GetInt("outbox.batchSize")
+		outboxSendTimeout = viper.GetDuration("outbox.sendTimeout")
+		outboxMaxAttempts = viper.GetInt("outbox.maxAttempts")
+		outboxMinBackoff  = viper.GetDuration("outbox.minBackoff")
+		outboxMaxBackoff  = viper.GetDuration("outbox.maxBackoff")
+
+		notifyEnabled = viper.GetBool("notify.enabled")
+		notifyTimeout = viper.GetDuration("notify.timeout")
+
+		healthInterval = viper.GetDuration("health.interval")
+		healthTimeout  = viper.GetDuration("health.timeout")
+
+		metricsEnabled = viper.GetBool("metrics.enabled")
+		metricsPort    = viper.GetInt("metrics.port")
+
+		tracingExporter     = viper.GetString("tracing.exporter")
+		tracingServiceName  = viper.GetString("tracing.serviceName")
+		tracingSampleRatio  = viper.GetFloat64("tracing.sampleRatio")
+		tracingOTLPEndpoint = viper.GetString("tracing.otlp.endpoint")
+		tracingOTLPInsecure = viper.GetBool("tracing.otlp.insecure")
+		tracingFile         = viper.GetString("tracing.file")
+
+		authEnabled     = viper.GetBool("auth.enabled")
+		authJWTSecret   = viper.GetString("auth.jwt.secret")
+		authJWTIssuer   = viper.GetString("auth.jwt.issuer")
+		authJWTAudience = viper.GetString("auth.jwt.audience")
 	)
 
+	var authAPIKeys []auth.APIKey
+	if err := viper.UnmarshalKey("auth.apiKeys", &authAPIKeys); err != nil {
+		logrus.Fatalf("unable to read auth api keys: '%+v'", err)
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"Server Port":        port,
 		"HTTP Proxy Enabled": httpProxyEnabled,
 		"HTTP Proxy Port":    httpProxyPort,
+		"Shutdown Timeout":   shutdownTimeout.String(),
 		"Database Name":      dbName,
 		"Database Host":      dbHost,
 		"Database Port":      dbPort,
 		"Database Username":  dbUsername,
+		"Migrate On Startup": dbMigrateOnStartup,
+		"Merge Duplicates":   wordsMergeDuplicates,
+		"Definitions":        definitionsProviders,
 		"SMTP Enabled":       smtpEnabled,
 		"SMTP Schedule":      smtpSchedule,
+		"SMTP Rotation":      smtpRotation,
+		"SMTP Tag":           smtpTag,
+		"SMTP Shutdown":      smtpShutdownTimeout.String(),
+		"SMTP TLS":           smtpTLS,
+		"SMTP Auth":          smtpAuth,
+		"Outbox Interval":    outboxInterval.String(),
+		"Outbox Attempts":    outboxMaxAttempts,
+		"Notify Enabled":     notifyEnabled,
+		"Notify Timeout":     notifyTimeout.String(),
+		"Health Interval":    healthInterval.String(),
+		"Health Timeout":     healthTimeout.String(),
+		"Metrics Enabled":    metricsEnabled,
+		"Metrics Port":       metricsPort,
+		"Tracing Exporter":   tracingExporter,
+		"Auth Enabled":       authEnabled,
+		"Auth API Keys":      len(authAPIKeys),
 	}).Info("Config Initialised")
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(db.Config{Host: dbHost, Port: dbPort, Username: dbUsername, Password: dbPassword, Database: dbName}, os.Args[2:]); err != nil {
+				logrus.Fatalf("Migrate failed: %+v", err)
+			}
+		case "users":
+			if err := runUsers(db.Config{
+				Host: dbHost, Port: dbPort, Username: dbUsername, Password: dbPassword, Database: dbName,
+				MigrateOnStartup: dbMigrateOnStartup,
+			}, os.Args[2:]); err != nil {
+				logrus.Fatalf("Users failed: %+v", err)
+			}
+		case "import":
+			if err := runImport(server.Config{
+				DBHost: dbHost, DBPort: dbPort, DBUsername: dbUsername, DBPassword: dbPassword, DBName: dbName,
+				DBMigrateOnStartup: dbMigrateOnStartup,
+			}, os.Args[2:]); err != nil {
+				logrus.Fatalf("Import failed: %+v", err)
+			}
+		default:
+			logrus.Fatalf("Unknown command %q", os.Args[1])
+		}
+
+		return
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     tracing.Exporter(tracingExporter),
+		ServiceName:  tracingServiceName,
+		SampleRatio:  tracingSampleRatio,
+		OTLPEndpoint: tracingOTLPEndpoint,
+		OTLPInsecure: tracingOTLPInsecure,
+		File:         tracingFile,
+	})
+	if err != nil {
+		logrus.Fatalf("Unable to set up tracing: %+v", err)
+	}
+
+	authenticator, err := auth.New(auth.Config{
+		Enabled:     authEnabled,
+		APIKeys:     authAPIKeys,
+		JWTSecret:   authJWTSecret,
+		JWTIssuer:   authJWTIssuer,
+		JWTAudience: authJWTAudience,
+	})
+	if err != nil {
+		logrus.Fatalf("Unable to initialise authenticator: %+v", err)
+	}
+
+	definitionsProvider, err := newDefinitionsProvider(definitionsProviders, definitionsFile, definitionsAPIURL)
+	if err != nil {
+		logrus.Fatalf("Unable to initialise definitions: %+v", err)
+	}
+
 	svr, err := server.New(
-		server.Config{DBHost: dbHost, DBPort: dbPort, DBUsername: dbUsername, DBPassword: dbPassword, DBName: dbName},
+		server.Config{
+			DBHost: dbHost, DBPort: dbPort, DBUsername: dbUsername, DBPassword: dbPassword, DBName: dbName,
+			DBMigrateOnStartup: dbMigrateOnStartup,
+			MergeDuplicates:    wordsMergeDuplicates,
+			Definitions:        definitionsProvider,
+			DefinitionsTimeout: definitionsTimeout,
+			RotationMode:       smtpRotation,
+			RotationTag:        smtpTag,
+			UnsubscribeURL:     smtpUnsubscribeURL,
+			Authenticator:      authenticator,
+		},
 	)
 	if err != nil {
 		logrus.Fatalf("Unable to initialise new Server: %+v", err)
 	}
 
-	gServer := grpc.NewServer()
+	// Components are stopped in the reverse order they're added, so the
+	// database is closed once nothing else can use it, and tracing last of all
+	lc := lifecycle.New(shutdownTimeout)
+	lc.Add(lifecycle.Component{
+		Name: "tracing",
+		Stop: func(ctx context.Context) error {
+			// Flushes the spans recorded while everything else stopped
+			return shutdownTracing(ctx)
+		},
+	})
+	lc.Add(lifecycle.Component{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			svr.Close()
+			return nil
+		},
+	})
+
+	if metricsEnabled {
+		prometheus.MustRegister(metrics.NewDBCollector(svr, healthTimeout))
+
+		if metricsPort == 0 && !httpProxyEnabled {
+			logrus.Warn("Metrics are enabled but won't be served, enable the http proxy server or set metrics.port")
+		}
+	}
+
+	gServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		// First so requests rejected by the other interceptors are traced and
+		// counted too
+		otelgrpc.UnaryServerInterceptor(),
+		metrics.UnaryServerInterceptor(),
+		authenticator.UnaryServerInterceptor(),
+		svr.UnaryServerInterceptor(),
+	), grpc.ChainStreamInterceptor(
+		authenticator.StreamServerInterceptor(),
+	))
 
 	v1alpha1.RegisterMyWordOfTheDayServiceServer(gServer, svr)
 
+	healthChecks := []health.Check{
+		{Name: "database", Check: svr.Ping},
+	}
+
 	reflection.Register(gServer)
 
 	addr := fmt.Sprintf(":%d", port)
 
-	if httpProxyEnabled {
-		go httpProxyServer(httpProxyPort, addr)
-	}
+	// Stays nil unless emails are enabled
+	var mailClient *mail.Client
 
 	if smtpEnabled {
-		mailClient, err := mail.New(mail.Config{
+		mailClient, err = mail.New(mail.Config{
 			SMTPHost:        smtpHost,
 			SMTPPort:        smtpPort,
 			SMTPUsername:    smtpUsername,
 			SMTPPassword:    smtpPassword,
 			SMTPFromAddress: smtpFromAddress,
 			SMTPToAddresses: smtpToAddresses,
-		}, templates, "templates/template.html")
+
+			SMTPTLSMode:            smtpTLS,
+			SMTPAuthMechanism:      smtpAuth,
+			SMTPCAFile:             smtpCAFile,
+			SMTPInsecureSkipVerify: smtpInsecureSkipVerify,
+		}, templates, "templates/template.html", "templates/template.txt")
 		if err != nil {
 			log.Fatalf("Error creating new mail client: %+v", err)
 		}
 
+		healthChecks = append(healthChecks, health.Check{Name: "smtp", Check: mailClient.Ping})
+
+		worker := svr.OutboxWorker(mailClient, outbox.Config{
+			Interval:    outboxInterval,
+			BatchSize:   outboxBatchSize,
+			SendTimeout: outboxSendTimeout,
+			MaxAttempts: outboxMaxAttempts,
+			MinBackoff:  outboxMinBackoff,
+			MaxBackoff:  outboxMaxBackoff,
+		})
+
+		workerCtx, stopWorker := context.WithCancel(context.Background())
+		workerDone := make(chan struct{})
+
+		lc.Add(lifecycle.Component{
+			Name: "outbox",
+			Start: func() error {
+				defer close(workerDone)
+
+				worker.Run(workerCtx)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				// Interrupts the message being sent, if any, and waits for
+				// its outcome to be recorded and the rest of the claimed batch
+				// to be released
+				stopWorker()
+
+				select {
+				case <-workerDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+	}
+
+	// Words are sent to users' channels on the same schedule as emails
+	if smtpEnabled || notifyEnabled {
 		// Parse the SMTP Schedule and make sure it's valid
 		if _, err := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow).Parse(smtpSchedule); err != nil {
 			log.Fatalf("Error parsing smtp schedule: %+v", err)
 		}
 
+		nc := notifyConfig{Enabled: notifyEnabled, Timeout: notifyTimeout}
+
 		c := cron.New()
 		c.AddFunc(smtpSchedule, func() {
-			rw, err := svr.RandomWord(context.Background(), &v1alpha1.RandomWordRequest{})
-			if err != nil {
-				logrus.WithFields(logrus.Fields{
-					"error": err,
-				}).Error("Error getting random word")
-			}
-
-			if rw.GetWord().GetId() == 0 && rw.GetWord().GetWord() == "" && rw.GetWord().GetCustomDefinition() == "" {
-				logrus.Info("No words have been added - skipping")
-				return
-			}
-
-			if err := mailClient.SendMailFromTemplate("My Word Of The Day", struct {
-				Word       string
-				Definition string
-			}{
-				Word:       rw.GetWord().GetWord(),
-				Definition: rw.GetWord().GetCustomDefinition(),
-			}); err != nil {
-				logrus.WithFields(logrus.Fields{
-					"error": err,
-				}).Error("Error sending mail")
-			}
+			sendDailyWords(svr, mailClient, smtpToAddresses, nc)
 		})
 
 		c.Start()
+
+		lc.Add(lifecycle.Component{
+			Name:    "scheduler",
+			Timeout: smtpShutdownTimeout,
+			Stop: func(ctx context.Context) error {
+				// Waits for any words that are being sent to finish
+				select {
+				case <-c.Stop().Done():
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
 	}
 
+	checker := health.New(health.Config{
+		Interval: healthInterval,
+		Timeout:  healthTimeout,
+		Services: []string{"mywordoftheday.v1alpha1.MyWordOfTheDayService"},
+		Checks:   healthChecks,
+	})
+
+	checker.Register(gServer)
+
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		logrus.Fatal(err, "Failed to create listener")
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"port": port,
-	}).Info("Starting grpc server")
+	lc.Add(lifecycle.Component{
+		Name: "grpc server",
+		Start: func() error {
+			logrus.WithFields(logrus.Fields{
+				"port": port,
+			}).Info("Starting grpc server")
+
+			return gServer.Serve(listener)
+		},
+		Stop: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				gServer.GracefulStop()
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				// Cancels any requests that are still running
+				gServer.Stop()
+				return ctx.Err()
+			}
+		},
+	})
 
-	if err := gServer.Serve(listener); err != nil {
-		logrus.Fatal(err, "Failed to start server")
+	if httpProxyEnabled {
+		// Closes the proxy's connection to the grpc server once the http
+		// server has stopped
+		proxyCtx, cancelProxy := context.WithCancel(context.Background())
+
+		// Metrics are only served by the http proxy server if they don't
+		// have their own port
+		httpServer, err := httpProxyServer(proxyCtx, httpProxyPort, addr, svr, checker, metricsEnabled && metricsPort == 0)
+		if err != nil {
+			logrus.Fatal(err, "Failed to create http proxy server")
+		}
+
+		lc.Add(lifecycle.Component{
+			Name: "http proxy server",
+			Start: func() error {
+				logrus.WithFields(logrus.Fields{
+					"port": httpProxyPort,
+				}).Info("Starting http proxy server")
+
+				if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				defer cancelProxy()
+				return httpServer.Shutdown(ctx)
+			},
+		})
 	}
-}
 
-// httpProxyServer starts a new http server listening on the specified port, proxying
-// requests to the provided grpc service
-func httpProxyServer(port int, grpcAddr string) {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	if metricsEnabled && metricsPort != 0 {
+		r := http.NewServeMux()
+		r.Handle("/metrics", metrics.Handler())
+
+		metricsServer := &http.Server{
+			Addr:    fmt.Sprintf(":%d", metricsPort),
+			Handler: r,
+		}
+
+		lc.Add(lifecycle.Component{
+			Name: "metrics server",
+			Start: func() error {
+				logrus.WithFields(logrus.Fields{
+					"port": metricsPort,
+				}).Info("Starting metrics server")
+
+				if err := metricsServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+					return err
+				}
+
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				return metricsServer.Shutdown(ctx)
+			},
+		})
+	}
 
+	// Added last so it's stopped first, reporting the server as unavailable
+	// while everything else finishes
+	checkCtx, cancelChecks := context.WithCancel(context.Background())
+	lc.Add(lifecycle.Component{
+		Name: "health checker",
+		Start: func() error {
+			checker.Run(checkCtx)
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			checker.Shutdown()
+			cancelChecks()
+			return nil
+		},
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := lc.Run(ctx); err != nil {
+		logrus.Fatalf("Server stopped with error: %+v", err)
+	}
+
+	logrus.Info("Server stopped successfully")
+}
+
+// httpProxyServer returns a new http server listening on the specified port, proxying
+// requests to the provided grpc service and serving the endpoints svr handles directly,
+// along with the checker's health endpoints and, if enabled, the metrics. The
+// connection to the grpc service is closed when ctx is done.
+func httpProxyServer(ctx context.Context, port int, grpcAddr string, svr *server.Server, checker *health.Checker, metricsEnabled bool) (*http.Server, error) {
 	// Register gRPC server endpoint
-	grpcMux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure()}
+	grpcMux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(server.GatewayHeaderMatcher))
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		// Propagates the trace started by the http handler to the grpc server
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+	}
 	if err := v1alpha1.RegisterMyWordOfTheDayServiceHandlerFromEndpoint(ctx, grpcMux, grpcAddr, opts); err != nil {
-		logrus.Fatal(err, "Failed to register http handler")
+		return nil, fmt.Errorf("unable to register http handler: %w", err)
+	}
+
+	// Must be registered after the generated handlers so they take precedence
+	if err := svr.RegisterGatewayHandlers(grpcMux); err != nil {
+		return nil, fmt.Errorf("unable to register gateway handlers: %w", err)
 	}
 
 	r := http.NewServeMux()
 
+	r.Handle("/healthz", checker.LivenessHandler())
+	r.Handle("/readyz", checker.ReadinessHandler())
+
+	if metricsEnabled {
+		r.Handle("/metrics", metrics.Handler())
+	}
+
 	r.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
 		// gateway is generated to match for /v1alpha1/ and not /api/v1alpha1
 		// we could update the gateway proto to match for /api/v1alpha1 but
@@ -244,9 +688,49 @@ func httpProxyServer(port int, grpcAddr string) {
 		grpcMux.ServeHTTP(w, r)
 	})
 
-	logrus.WithFields(logrus.Fields{
-		"port": port,
-	}).Info("Starting http proxy server")
+	// Only API requests are traced, so health checks and metrics scrapes
+	// don't drown them out
+	handler := otelhttp.NewHandler(r, "http proxy",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return strings.HasPrefix(r.URL.Path, "/api/")
+		}),
+	)
+
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: handler,
+	}, nil
+}
+
+// newDefinitionsProvider returns a provider trying each of the named providers,
+// file or api, in turn. It returns nil if no providers are named.
+func newDefinitionsProvider(names []string, file string, apiURL string) (definitions.Provider, error) {
+	var chain definitions.Chain
+
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "file":
+			f, err := definitions.NewFile(file)
+			if err != nil {
+				return nil, err
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"file":  file,
+				"words": f.Len(),
+			}).Info("Dictionary loaded successfully")
+
+			chain = append(chain, f)
+		case "api":
+			chain = append(chain, definitions.NewAPI(apiURL, nil))
+		default:
+			return nil, fmt.Errorf("unknown definitions provider %q, must be file or api", name)
+		}
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
 
-	logrus.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", port), r), "Failed to start http proxy server")
+	return chain, nil
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+const migrateUsage = "usage: mywordoftheday migrate up|down [steps]|status"
+
+// runMigrate handles the migrate subcommand, applying, reverting or
+// reporting on the embedded schema migrations. Errors are returned rather than
+// logged fatally so the db manager is closed before exiting.
+func runMigrate(c db.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	// Migrations are only ever applied explicitly by the subcommand
+	c.MigrateOnStartup = false
+
+	mgr, err := db.New(c)
+	if err != nil {
+		return fmt.Errorf("unable to initialise db manager: %w", err)
+	}
+	defer mgr.Close()
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := mgr.MigrateUp(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to apply migrations: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{"applied": applied}).Info("Migrations applied")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+
+		reverted, err := mgr.MigrateDown(ctx, steps)
+		if err != nil {
+			return fmt.Errorf("unable to revert migrations: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{"reverted": reverted}).Info("Migrations reverted")
+	case "status":
+		status, err := mgr.MigrationStatus(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get migration status: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.Applied {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("unable to write migration status: %w", err)
+		}
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/mywordoftheday/backend/internal/db"
+	"github.com/mywordoftheday/backend/internal/identity"
+	"github.com/mywordoftheday/backend/internal/mail"
+	"github.com/mywordoftheday/backend/internal/metrics"
+	"github.com/mywordoftheday/backend/internal/notifier"
+	"github.com/mywordoftheday/backend/internal/server"
+)
+
+// notification returns the word as it's sent to notification channels
+func notification(data mail.TemplateData) notifier.Notification {
+	n := notifier.Notification{
+		Title:         data.Subject(),
+		Word:          data.Word,
+		Pronunciation: data.Pronunciation,
+	}
+
+	if data.Definition != "" {
+		n.Definitions = append(n.Definitions, data.Definition)
+	}
+
+	for _, s := range data.Senses {
+		d := s.Definition
+		if s.PartOfSpeech != "" {
+			d = "(" + s.PartOfSpeech + ") " + d
+		}
+
+		n.Definitions = append(n.Definitions, d)
+	}
+
+	return n
+}
+
+// notifyConfig controls sending words to the channels users have added
+type notifyConfig struct {
+	Enabled bool
+	// Timeout is how long each channel is given to accept the notification
+	Timeout time.Duration
+	// Client is used to reach the channels. If nil a default client is used.
+	Client *http.Client
+}
+
+// dailyWordsJob is the name the sendDailyWords job is reported under in the
+// metrics and traces
+const dailyWordsJob = "daily_words"
+
+// sendDailyWords sends every user the next word from their own list. If
+// mailClient isn't nil it's emailed, by queueing it for the outbox worker to
+// send, and if notifications are enabled it's sent to each of the user's
+// channels. Users without an email address are only emailed if they're the
+// default user, who falls back to defaultRecipients.
+func sendDailyWords(svr *server.Server, mailClient *mail.Client, defaultRecipients []string, nc notifyConfig) {
+	jobCtx, span := otel.Tracer("github.com/mywordoftheday/backend").Start(context.Background(), dailyWordsJob)
+	defer span.End()
+
+	users, err := svr.ListUsers(jobCtx)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("Error listing users")
+		span.SetStatus(codes.Error, err.Error())
+		metrics.ObserveJob(dailyWordsJob, err)
+		return
+	}
+
+	failed := 0
+	defer func() {
+		var err error
+		if failed > 0 {
+			err = fmt.Errorf("unable to send words to %d of %d users", failed, len(users))
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		metrics.ObserveJob(dailyWordsJob, err)
+	}()
+
+	for _, u := range users {
+		ctx := identity.NewContext(jobCtx, identity.User{ID: u.ID, Username: u.Username})
+
+		var to []string
+		if mailClient != nil {
+			switch {
+			case u.Email != "":
+				to = []string{u.Email}
+			case u.ID == db.DefaultUserID:
+				to = defaultRecipients
+			}
+		}
+
+		var channels []db.Channel
+		if nc.Enabled {
+			cs, err := svr.ListChannels(ctx)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+					"user":  u.Username,
+				}).Error("Error listing channels")
+
+				// The user may still be emailed
+				if len(to) == 0 {
+					failed++
+					continue
+				}
+			}
+
+			channels = cs
+		}
+
+		if len(to) == 0 && len(channels) == 0 {
+			logrus.WithFields(logrus.Fields{"user": u.Username}).Info("User has no email address or channels - skipping")
+			continue
+		}
+
+		w, err := svr.NextWord(ctx)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+				"user":  u.Username,
+			}).Error("Error getting next word")
+			failed++
+			continue
+		}
+
+		if w == nil {
+			logrus.WithFields(logrus.Fields{"user": u.Username}).Info("No words to send - skipping")
+			continue
+		}
+
+		data, err := svr.TemplateData(ctx, w.GetId())
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": err,
+				"user":  u.Username,
+			}).Error("Error getting template data")
+			failed++
+			continue
+		}
+
+		emailed := len(to) > 0 && enqueueEmails(ctx, svr, mailClient, u, w.GetId(), to, data)
+		notified := notifyChannels(ctx, nc, u, channels, notification(data))
+
+		// Enqueueing the emails records the delivery, otherwise it's recorded
+		// once the word has reached at least one channel
+		if !emailed && notified > 0 {
+			if err := svr.RecordDelivery(ctx, w.GetId()); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": err,
+					"user":  u.Username,
+				}).Error("Error recording delivery")
+			}
+		}
+
+		if !emailed && notified == 0 {
+			failed++
+		}
+	}
+}
+
+// enqueueEmails queues an email of the word to each recipient, rendered from
+// the user's active template if they have one, returning whether any were
+func enqueueEmails(ctx context.Context, svr *server.Server, mailClient *mail.Client, u db.User, wordID int32, to []string, data mail.TemplateData) bool {
+	// A template that no longer parses shouldn't stop the user getting their
+	// word, so the default is used instead, as it is by renderMessage for one
+	// that fails to render this word
+	tmpl, err := svr.ActiveTemplate(ctx)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"user":  u.Username,
+		}).Error("Error getting active template - using the default")
+	}
+
+	subject := data.Subject()
+
+	// Each recipient is sent their own message, so one that's rejected
+	// doesn't stop the others getting theirs
+	messages := make([]db.OutboxMessage, 0, len(to))
+	for _, addr := range to {
+		msg, err := renderMessage(mailClient, tmpl, u, addr, subject, data)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"error":     err,
+				"user":      u.Username,
+				"recipient": addr,
+			}).Error("Error rendering mail")
+			continue
+		}
+
+		messages = append(messages, db.OutboxMessage{Recipient: addr, Subject: subject, Message: msg})
+	}
+
+	if len(messages) == 0 {
+		return false
+	}
+
+	if err := svr.EnqueueMessages(ctx, wordID, messages); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+			"user":  u.Username,
+		}).Error("Error enqueueing mail")
+		return false
+	}
+
+	return true
+}
+
+// notifyChannels sends the notification to each of the user's channels,
+// returning how many accepted it. Notifications aren't retried, so a channel
+// that's down misses that day's word.
+func notifyChannels(ctx context.Context, nc notifyConfig, u db.User, channels []db.Channel, n notifier.Notification) int {
+	notified := 0
+
+	for _, c := range channels {
+		fields := logrus.Fields{
+			"user":    u.Username,
+			"channel": c.ID,
+			"url":     notifier.Redact(c.URL),
+		}
+
+		nt, err := notifier.New(c.URL, nc.Client)
+		if err != nil {
+			fields["error"] = err
+			logrus.WithFields(fields).Error("Error creating notifier")
+			continue
+		}
+
+		notifyCtx, cancel := context.WithTimeout(ctx, nc.Timeout)
+		err = nt.Notify(notifyCtx, n)
+		cancel()
+
+		if err != nil {
+			fields["error"] = err
+			logrus.WithFields(fields).Error("Error sending notification")
+			continue
+		}
+
+		logrus.WithFields(fields).Info("Notification sent successfully")
+		notified++
+	}
+
+	return notified
+}
+
+// renderMessage renders the email to a single recipient from tmpl, falling
+// back to the client's own templates if tmpl is nil or fails to render
+func renderMessage(mailClient *mail.Client, tmpl *mail.Templates, u db.User, to string, subject string, data mail.TemplateData) ([]byte, error) {
+	if tmpl != nil {
+		msg, err := mailClient.NewMessageFrom(tmpl, []string{to}, subject, data)
+		if err == nil {
+			return msg.Bytes()
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"error":     err,
+			"user":      u.Username,
+			"recipient": to,
+		}).Error("Error rendering mail from active template - using the default")
+	}
+
+	msg, err := mailClient.NewMessage([]string{to}, subject, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg.Bytes()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mywordoftheday/backend/internal/db"
+)
+
+const usersUsage = "usage: mywordoftheday users add <username> [email]|list|delete <username>"
+
+// runUsers handles the users subcommand, managing the accounts words and
+// scheduled emails are scoped to. Errors are returned rather than logged
+// fatally so the db manager is closed before exiting.
+func runUsers(c db.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+
+	mgr, err := db.New(c)
+	if err != nil {
+		return fmt.Errorf("unable to initialise db manager: %w", err)
+	}
+	defer mgr.Close()
+
+	ctx := context.Background()
+
+	switch {
+	case args[0] == "add" && (len(args) == 2 || len(args) == 3):
+		u := db.User{Username: args[1]}
+		if len(args) == 3 {
+			u.Email = args[2]
+		}
+
+		u, err := mgr.InsertUser(ctx, u)
+		if err != nil {
+			return fmt.Errorf("unable to add user: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{"id": u.ID, "username": u.Username}).Info("User added")
+	case args[0] == "list" && len(args) == 1:
+		users, err := mgr.ListUsers(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to list users: %w", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tCREATED AT")
+		for _, u := range users {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", u.ID, u.Username, u.Email, u.CreatedAt.Format("2006-01-02 15:04:05 MST"))
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("unable to write users: %w", err)
+		}
+	case args[0] == "delete" && len(args) == 2:
+		if args[1] == db.DefaultUsername {
+			return errors.New("the default user cannot be deleted")
+		}
+
+		u, err := mgr.DeleteUser(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("unable to delete user: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{"id": u.ID, "username": u.Username}).Info("User deleted")
+	default:
+		return errors.New(usersUsage)
+	}
+
+	return nil
+}